@@ -0,0 +1,209 @@
+package aggregate
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+)
+
+// aggregateIterator is an iterator over the candlesticks of an aggregate provider, combined from the candlesticks of
+// each of its exchanges at the same time.
+//
+// Exchanges that don't have a candlestick yet are waited for, up to maxExchangeLagSecs after it closed, so that the
+// candlestick is the same no matter when it's read; after that, they are left out of it and tried again on the next
+// candlestick. Exchanges that fail otherwise (e.g. the market pair was delisted) are dropped for good. It only fails
+// when none of them have it.
+type aggregateIterator struct {
+	aggregation         string
+	sources             []*source
+	candlestickInterval int
+	timeNowFunc         func() time.Time
+	startFromNext       bool
+	hasStarted          bool
+	lastErr             error
+	lastSource          string
+
+	// sourceErr is the error of the last exchange that was dropped.
+	sourceErr error
+
+	// ts is the start of the next candlestick.
+	ts int
+}
+
+// maxExchangeLagSecs is for how long after a candlestick closes the exchanges that don't have it yet are waited for.
+// Exchanges usually publish closed candlesticks within seconds, so the ones lagging for longer are probably down.
+const maxExchangeLagSecs = 5 * 60
+
+// source is the iterator of one of the exchanges of an aggregateIterator, and the next candlestick it returned that
+// wasn't used yet, if any.
+type source struct {
+	exchange string
+	it       iterator.Iterator
+	next     *common.Candlestick
+}
+
+func newAggregateIterator(aggregation string, sources []*source, marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) *aggregateIterator {
+	return &aggregateIterator{
+		aggregation:         aggregation,
+		sources:             sources,
+		candlestickInterval: int(candlestickInterval / time.Second),
+		timeNowFunc:         time.Now,
+		ts:                  common.NormalizeTimestamp(startTime, candlestickInterval, marketSource.Provider, false),
+	}
+}
+
+// SetTimeNowFunc overrides time.Now() for testing purposes, both for the aggregate and its exchanges.
+func (it *aggregateIterator) SetTimeNowFunc(f func() time.Time) {
+	it.timeNowFunc = f
+	for _, source := range it.sources {
+		source.it.SetTimeNowFunc(f)
+	}
+}
+
+// SetStartFromNext moves the startTime to one candlestickInterval in the future. It must be called before Next().
+func (it *aggregateIterator) SetStartFromNext(b bool) {
+	if it.hasStarted {
+		panic("SetStartFromNext() cannot be called after Next() is called")
+	}
+	it.startFromNext = b
+}
+
+// Next is the "Next" iterator function, providing the next available Candlestick of the aggregate.
+//
+// It fails with ErrNoNewTicksYet if the candlestick hasn't closed yet, or some exchange doesn't have it yet and it
+// closed less than maxExchangeLagSecs ago, or no exchange has it yet; and with the error of the last exchange that
+// failed if all of them did. If no exchange has a candlestick at the expected time but they all
+// have later ones, it returns the next one any of them has.
+func (it *aggregateIterator) Next() (common.Candlestick, error) {
+	if !it.hasStarted && it.startFromNext {
+		it.ts += it.candlestickInterval
+	}
+	it.hasStarted = true
+
+	for {
+		if it.ts+it.candlestickInterval > int(it.timeNowFunc().Unix()) {
+			return common.Candlestick{}, common.ErrNoNewTicksYet
+		}
+
+		var (
+			candlesticks = []common.Candlestick{}
+			exchanges    = []string{}
+			liveSources  = []*source{}
+			noNewTicks   = false
+			nextTs       = 0
+		)
+		for _, source := range it.sources {
+			candlestick, err := source.candlestickAt(it.ts)
+			if errors.Is(err, common.ErrNoNewTicksYet) {
+				noNewTicks = true
+				liveSources = append(liveSources, source)
+				continue
+			}
+			if err != nil {
+				it.sourceErr = err
+				continue
+			}
+			liveSources = append(liveSources, source)
+			if candlestick == nil {
+				if nextTs == 0 || source.next.Timestamp < nextTs {
+					nextTs = source.next.Timestamp
+				}
+				continue
+			}
+			candlesticks = append(candlesticks, *candlestick)
+			exchanges = append(exchanges, source.exchange)
+		}
+		it.sources = liveSources
+
+		waitForLagging := it.ts+it.candlestickInterval+maxExchangeLagSecs > int(it.timeNowFunc().Unix())
+		switch {
+		case noNewTicks && waitForLagging:
+			return common.Candlestick{}, common.ErrNoNewTicksYet
+		case len(candlesticks) > 0:
+			candlestick, exchanges := it.aggregate(candlesticks, exchanges)
+			it.lastSource = strings.Join(exchanges, ",")
+			it.ts += it.candlestickInterval
+			return candlestick, nil
+		case noNewTicks:
+			return common.Candlestick{}, common.ErrNoNewTicksYet
+		case len(it.sources) == 0:
+			return common.Candlestick{}, it.sourceErr
+		}
+		// Every exchange has a gap here, so skip it.
+		it.ts = nextTs
+	}
+}
+
+// Scan is the Scanner interface implementation. Returns true if the scanning happened without errors. If it returns
+// false, the error is available on iter.Error().
+func (it *aggregateIterator) Scan(candlestick *common.Candlestick) bool {
+	cs, err := it.Next()
+	it.lastErr = err
+	*candlestick = cs
+	return err == nil
+}
+
+// Error returns the error of the last Scan operation, or nil if it was successful.
+func (it *aggregateIterator) Error() error {
+	return it.lastErr
+}
+
+// Source returns the exchange the last candlestick was resolved from, or the comma-separated exchanges it is the
+// median of. It implements core.SourcedIterator.
+func (it *aggregateIterator) Source() string {
+	return it.lastSource
+}
+
+// aggregate combines the candlesticks that the exchanges have at the same time, in order of preference, and returns
+// the exchanges it was combined from.
+func (it *aggregateIterator) aggregate(candlesticks []common.Candlestick, exchanges []string) (common.Candlestick, []string) {
+	if it.aggregation == core.AggregateAny {
+		return candlesticks[0], exchanges[:1]
+	}
+	price := func(f func(common.Candlestick) common.JSONFloat64) common.JSONFloat64 {
+		prices := []float64{}
+		for _, candlestick := range candlesticks {
+			prices = append(prices, float64(f(candlestick)))
+		}
+		return common.JSONFloat64(median(prices))
+	}
+	return common.Candlestick{
+		Timestamp:    candlesticks[0].Timestamp,
+		OpenPrice:    price(func(c common.Candlestick) common.JSONFloat64 { return c.OpenPrice }),
+		ClosePrice:   price(func(c common.Candlestick) common.JSONFloat64 { return c.ClosePrice }),
+		LowestPrice:  price(func(c common.Candlestick) common.JSONFloat64 { return c.LowestPrice }),
+		HighestPrice: price(func(c common.Candlestick) common.JSONFloat64 { return c.HighestPrice }),
+	}, exchanges
+}
+
+// candlestickAt returns the exchange's candlestick at ts, or nil if it has a gap there, reading (and discarding) the
+// candlesticks before it.
+func (s *source) candlestickAt(ts int) (*common.Candlestick, error) {
+	for s.next == nil || s.next.Timestamp < ts {
+		candlestick, err := s.it.Next()
+		if err != nil {
+			return nil, err
+		}
+		s.next = &candlestick
+	}
+	if s.next.Timestamp != ts {
+		return nil, nil
+	}
+	return s.next, nil
+}
+
+// median is the middle value of the sorted values, or the average of the two middle ones if there's an even number
+// of them.
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
+	}
+	return values[middle]
+}
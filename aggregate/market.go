@@ -0,0 +1,60 @@
+// Package aggregate provides the markets of operands with an aggregate provider, which combine the markets of several
+// exchanges, e.g. COIN:ANY:BTC-USDT or COIN:MEDIAN(BINANCE,COINBASE,KUCOIN):BTC-USDT (see core.ParseAggregateProvider).
+//
+// Thus, predictions on aggregate providers keep evolving when one of the exchanges delists the market pair or goes
+// down, as long as any of them still has it.
+package aggregate
+
+import (
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+	"github.com/rs/zerolog/log"
+)
+
+// Market is a core.ICoinMarket that provides iterators over the combined markets of COIN market sources with an
+// aggregate provider, and delegates every other market source to the underlying coin market (e.g. the exchanges).
+type Market struct {
+	market core.ICoinMarket
+}
+
+// NewMarket constructs a Market on top of the supplied coin market, e.g. a candles.Market.
+func NewMarket(market core.ICoinMarket) Market {
+	return Market{market: market}
+}
+
+// Iterator returns a market iterator for a given market source at a given time and for a given candlestick interval.
+//
+// Exchanges that fail to provide an iterator (e.g. the market is not supported on them, or there's no market data for
+// the exchange at all) are left out with a warning, so it only fails if all of them do.
+func (m Market) Iterator(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	if marketSource.Type != common.COIN || !core.IsAggregateProvider(marketSource.Provider) {
+		return m.market.Iterator(marketSource, startTime, candlestickInterval)
+	}
+	aggregation, exchanges, err := core.ParseAggregateProvider(marketSource.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sources = []*source{}
+		lastErr error
+	)
+	for _, exchange := range exchanges {
+		exchangeMarketSource := marketSource
+		exchangeMarketSource.Provider = exchange
+		it, err := m.market.Iterator(exchangeMarketSource, startTime, candlestickInterval)
+		if err != nil {
+			log.Warn().Err(err).Str("exchange", exchange).Str("market", marketSource.String()).Msg("Leaving exchange out of aggregate provider, as it doesn't provide the market.")
+			lastErr = err
+			continue
+		}
+		sources = append(sources, &source{exchange: exchange, it: it})
+	}
+	if len(sources) == 0 {
+		return nil, lastErr
+	}
+	return newAggregateIterator(aggregation, sources, marketSource, startTime, candlestickInterval), nil
+}
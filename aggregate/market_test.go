@@ -0,0 +1,241 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketIterator(t *testing.T) {
+	market := NewMarket(fakeMarket{
+		candlesticks: map[string][]common.Candlestick{
+			"BINANCE":  minuteCandlesticks("2022-01-01 00:00:00", 100, 101),
+			"COINBASE": minuteCandlesticks("2022-01-01 00:00:00", 110, 111, 112, 113),
+			"KUCOIN":   minuteCandlesticks("2022-01-01 00:00:00", 90, 91, 92),
+		},
+		errs: map[string]error{"BINANCE": common.ErrInvalidMarketPair, "KUCOIN": common.ErrNoNewTicksYet},
+	})
+
+	tss := []struct {
+		name            string
+		provider        string
+		expectedCloses  []common.JSONFloat64
+		expectedSources []string
+		expectedErr     error
+	}{
+		{
+			name:            "ANY takes the first exchange that has the candlestick, in order of preference",
+			provider:        "ANY(BINANCE,COINBASE,KUCOIN)",
+			expectedCloses:  []common.JSONFloat64{100, 101, 112, 113},
+			expectedSources: []string{"BINANCE", "BINANCE", "COINBASE", "COINBASE"},
+			expectedErr:     common.ErrNoNewTicksYet,
+		},
+		{
+			name:            "MEDIAN takes the median of the exchanges that have the candlestick",
+			provider:        "MEDIAN(BINANCE,COINBASE,KUCOIN)",
+			expectedCloses:  []common.JSONFloat64{100, 101, 102, 113},
+			expectedSources: []string{"BINANCE,COINBASE,KUCOIN", "BINANCE,COINBASE,KUCOIN", "COINBASE,KUCOIN", "COINBASE"},
+			expectedErr:     common.ErrNoNewTicksYet,
+		},
+		{
+			name:            "Exchanges that don't support the market are left out",
+			provider:        "ANY(BITSTAMP,KUCOIN)",
+			expectedCloses:  []common.JSONFloat64{90, 91, 92},
+			expectedSources: []string{"KUCOIN", "KUCOIN", "KUCOIN"},
+			expectedErr:     common.ErrNoNewTicksYet,
+		},
+		{
+			name:            "Exchanges without market data are left out",
+			provider:        "MEDIAN(KRAKEN,KUCOIN)",
+			expectedCloses:  []common.JSONFloat64{90, 91, 92},
+			expectedSources: []string{"KUCOIN", "KUCOIN", "KUCOIN"},
+			expectedErr:     common.ErrNoNewTicksYet,
+		},
+		{
+			name:            "Fails with the last error once every exchange failed",
+			provider:        "MEDIAN(BINANCE,BITSTAMP)",
+			expectedCloses:  []common.JSONFloat64{100, 101},
+			expectedSources: []string{"BINANCE", "BINANCE"},
+			expectedErr:     common.ErrInvalidMarketPair,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			it, err := market.Iterator(marketSource(ts.provider), tp("2022-01-01 00:00:00"), time.Minute)
+			require.Nil(t, err)
+			it.SetTimeNowFunc(func() time.Time { return tp("2022-01-02 00:00:00") })
+
+			var (
+				closes  = []common.JSONFloat64{}
+				sources = []string{}
+			)
+			for {
+				candlestick, err := it.Next()
+				if err != nil {
+					require.ErrorIs(t, err, ts.expectedErr)
+					break
+				}
+				closes = append(closes, candlestick.ClosePrice)
+				sources = append(sources, it.(core.SourcedIterator).Source())
+			}
+			require.Equal(t, ts.expectedCloses, closes)
+			require.Equal(t, ts.expectedSources, sources)
+		})
+	}
+}
+
+func TestMarketIteratorStartFromNext(t *testing.T) {
+	market := NewMarket(fakeMarket{candlesticks: map[string][]common.Candlestick{
+		"BINANCE":  minuteCandlesticks("2022-01-01 00:00:00", 100, 101),
+		"COINBASE": minuteCandlesticks("2022-01-01 00:00:00", 110, 111),
+	}})
+
+	it, err := market.Iterator(marketSource("MEDIAN(BINANCE,COINBASE)"), tp("2022-01-01 00:00:00"), time.Minute)
+	require.Nil(t, err)
+	it.SetStartFromNext(true)
+	it.SetTimeNowFunc(func() time.Time { return tp("2022-01-02 00:00:00") })
+
+	candlestick, err := it.Next()
+	require.Nil(t, err)
+	require.Equal(t, tInt("2022-01-01 00:01:00"), candlestick.Timestamp)
+	require.Equal(t, common.JSONFloat64(106), candlestick.ClosePrice)
+}
+
+func TestMarketIteratorWaitsForLaggingExchanges(t *testing.T) {
+	market := NewMarket(fakeMarket{candlesticks: map[string][]common.Candlestick{
+		"COINBASE": minuteCandlesticks("2022-01-01 00:00:00", 110, 111),
+		"KUCOIN":   minuteCandlesticks("2022-01-01 00:00:00", 90),
+	}})
+	it, err := market.Iterator(marketSource("MEDIAN(COINBASE,KUCOIN)"), tp("2022-01-01 00:00:00"), time.Minute)
+	require.Nil(t, err)
+	now := tp("2022-01-01 00:02:30")
+	it.SetTimeNowFunc(func() time.Time { return now })
+	publish := func(exchange string, candlesticks []common.Candlestick) {
+		for _, source := range it.(*aggregateIterator).sources {
+			if source.exchange == exchange {
+				source.it.(*fakeIterator).candlesticks = candlesticks
+			}
+		}
+	}
+
+	candlestick, err := it.Next()
+	require.Nil(t, err)
+	require.Equal(t, common.JSONFloat64(100), candlestick.ClosePrice)
+
+	// KUCOIN doesn't have the 00:01 candlestick yet, so it's waited for instead of taking COINBASE's alone.
+	_, err = it.Next()
+	require.ErrorIs(t, err, common.ErrNoNewTicksYet)
+
+	publish("KUCOIN", minuteCandlesticks("2022-01-01 00:01:00", 91))
+	candlestick, err = it.Next()
+	require.Nil(t, err)
+	require.Equal(t, common.JSONFloat64(101), candlestick.ClosePrice)
+	require.Equal(t, "COINBASE,KUCOIN", it.(core.SourcedIterator).Source())
+
+	// But not for longer than maxExchangeLagSecs.
+	publish("COINBASE", minuteCandlesticks("2022-01-01 00:02:00", 112))
+	now = tp("2022-01-01 00:04:00")
+	_, err = it.Next()
+	require.ErrorIs(t, err, common.ErrNoNewTicksYet)
+
+	now = tp("2022-01-01 00:10:00")
+	candlestick, err = it.Next()
+	require.Nil(t, err)
+	require.Equal(t, common.JSONFloat64(112), candlestick.ClosePrice)
+	require.Equal(t, "COINBASE", it.(core.SourcedIterator).Source())
+}
+
+func TestMarketIteratorErrors(t *testing.T) {
+	market := NewMarket(fakeMarket{candlesticks: map[string][]common.Candlestick{
+		"BINANCE": minuteCandlesticks("2022-01-01 00:00:00", 100),
+	}})
+
+	_, err := market.Iterator(marketSource("MEDIAN(BITSTAMP,BITFINEX)"), tp("2022-01-01 00:00:00"), time.Minute)
+	require.ErrorIs(t, err, common.ErrUnsuportedCandlestickProvider)
+
+	_, err = market.Iterator(marketSource("MEDIAN(BINANCE)"), tp("2022-01-01 00:00:00"), time.Minute)
+	require.ErrorIs(t, err, core.ErrInvalidAggregateProvider)
+
+	it, err := market.Iterator(marketSource("ANY(BINANCE,COINBASE)"), tp("2022-01-01 00:00:00"), time.Minute)
+	require.Nil(t, err)
+	it.SetTimeNowFunc(func() time.Time { return tp("2022-01-01 00:00:30") })
+	_, err = it.Next()
+	require.ErrorIs(t, err, common.ErrNoNewTicksYet)
+
+	// Other market sources are delegated to the underlying market.
+	it, err = market.Iterator(marketSource("BINANCE"), tp("2022-01-01 00:00:00"), time.Minute)
+	require.Nil(t, err)
+	candlestick, err := it.Next()
+	require.Nil(t, err)
+	require.Equal(t, common.JSONFloat64(100), candlestick.ClosePrice)
+	_, isSourced := it.(core.SourcedIterator)
+	require.False(t, isSourced)
+}
+
+func marketSource(provider string) common.MarketSource {
+	return common.MarketSource{Type: common.COIN, Provider: provider, BaseAsset: "BTC", QuoteAsset: "USDT"}
+}
+
+// fakeMarket is a market whose candlesticks come from a fixture for each exchange. Once an exchange runs out of
+// candlesticks, it fails with its error in errs, or with ErrNoNewTicksYet if there's none.
+type fakeMarket struct {
+	candlesticks map[string][]common.Candlestick
+	errs         map[string]error
+}
+
+func (m fakeMarket) Iterator(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	candlesticks, ok := m.candlesticks[marketSource.Provider]
+	if !ok {
+		return nil, common.ErrUnsuportedCandlestickProvider
+	}
+	err := m.errs[marketSource.Provider]
+	if err == nil {
+		err = common.ErrNoNewTicksYet
+	}
+	startTs := common.NormalizeTimestamp(startTime, candlestickInterval, marketSource.Provider, false)
+	for len(candlesticks) > 0 && candlesticks[0].Timestamp < startTs {
+		candlesticks = candlesticks[1:]
+	}
+	return &fakeIterator{candlesticks: candlesticks, err: err}, nil
+}
+
+type fakeIterator struct {
+	candlesticks []common.Candlestick
+	err          error
+}
+
+func (i *fakeIterator) Next() (common.Candlestick, error) {
+	if len(i.candlesticks) == 0 {
+		return common.Candlestick{}, i.err
+	}
+	candlestick := i.candlesticks[0]
+	i.candlesticks = i.candlesticks[1:]
+	return candlestick, nil
+}
+
+func (i *fakeIterator) Scan(*common.Candlestick) bool   { return false }
+func (i *fakeIterator) Error() error                    { return nil }
+func (i *fakeIterator) SetStartFromNext(bool)           {}
+func (i *fakeIterator) SetTimeNowFunc(func() time.Time) {}
+
+func minuteCandlesticks(from string, values ...float64) []common.Candlestick {
+	candlesticks := []common.Candlestick{}
+	for i, value := range values {
+		price := common.JSONFloat64(value)
+		candlesticks = append(candlesticks, common.Candlestick{Timestamp: tInt(from) + i*60, OpenPrice: price, ClosePrice: price, LowestPrice: price, HighestPrice: price})
+	}
+	return candlesticks
+}
+
+func tp(s string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04:05", s)
+	return t
+}
+
+func tInt(s string) int {
+	return int(tp(s).Unix())
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -62,7 +64,14 @@ func NewAPI(
 		handlerUndeletePrediction   = nethttp.NewHandler(a.apiPredictionStorageActionWithUUID(a.store.UndeletePrediction, "Undeleting predictions makes them visible to GET calls and updateable by daemon."))
 		handlerRefetchAccount       = nethttp.NewHandler(a.apiPredictionRefetchAccount())
 		handlerPredictionClearState = nethttp.NewHandler(a.apiPredictionClearState())
+		handlerPredictionHistory    = nethttp.NewHandler(a.apiGetPredictionHistory())
 		handlerMaintenance          = nethttp.NewHandler(a.apiMaintenance())
+		handlerGetAccounts          = nethttp.NewHandler(a.apiGetAccounts())
+		handlerGetAccount           = nethttp.NewHandler(a.apiGetAccount())
+		handlerRefreshAccounts      = nethttp.NewHandler(a.apiRefreshAccounts())
+		handlerMergeAccounts        = nethttp.NewHandler(a.apiMergeAccounts())
+		handlerAccountsLeaderboard  = nethttp.NewHandler(a.apiGetAccountsLeaderboard())
+		handlerAccountStats         = nethttp.NewHandler(a.apiGetAccountStats())
 	)
 
 	service.Get("/pages/prediction/{id}", handlerGetPagesPrediction)
@@ -80,6 +89,13 @@ func NewAPI(
 		r.Method(http.MethodPost, "/predictions/{uuid}/undelete", handlerUndeletePrediction)
 		r.Method(http.MethodPost, "/predictions/{uuid}/refetchAccount", handlerRefetchAccount)
 		r.Method(http.MethodPost, "/predictions/{uuid}/clearState", handlerPredictionClearState)
+		r.Method(http.MethodGet, "/predictions/{uuid}/history", handlerPredictionHistory)
+		r.Method(http.MethodGet, "/accounts", handlerGetAccounts)
+		r.Method(http.MethodGet, "/accounts/leaderboard", handlerAccountsLeaderboard)
+		r.Method(http.MethodGet, "/accounts/{id}", handlerGetAccount)
+		r.Method(http.MethodPost, "/accounts/refresh", handlerRefreshAccounts)
+		r.Method(http.MethodPost, "/accounts/merge", handlerMergeAccounts)
+		r.Method(http.MethodGet, "/accounts/{handle}/stats", handlerAccountStats)
 		r.Method(http.MethodPost, "/maintenance/{action}", handlerMaintenance)
 		service.Docs("/docs", swgui.New)
 	})
@@ -94,8 +110,9 @@ func (a *API) SetDebug(b bool) {
 	a.debug = b
 }
 
-// MustBlockinglyListenAndServe serves the API.
-func (a *API) MustBlockinglyListenAndServe(apiURL string) {
+// MustBlockinglyListenAndServe serves the API until ctx is cancelled. Then it stops accepting connections and waits up
+// to shutdownTimeout for in-flight requests to finish before returning.
+func (a *API) MustBlockinglyListenAndServe(ctx context.Context, apiURL string, shutdownTimeout time.Duration) {
 	// If url starts with https?://, remove that part for the listener address
 	var (
 		rawURLParts = strings.Split(apiURL, "//")
@@ -108,7 +125,25 @@ func (a *API) MustBlockinglyListenAndServe(apiURL string) {
 
 	log.Info().Str("docs", fmt.Sprintf("%v/docs", l.Addr().String())).Msgf("API listening on %v", l.Addr().String())
 
-	if err := http.Serve(l, a.mux); err != nil {
+	var (
+		server       = &http.Server{Handler: a.mux}
+		shutdownDone = make(chan struct{})
+	)
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		log.Info().Msg("API shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("API: in-flight requests didn't finish in time; closing their connections.")
+			server.Close()
+		}
+	}()
+
+	if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal().Err(err).Msg("")
 	}
+	<-shutdownDone
+	log.Info().Msg("API shut down.")
 }
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,7 +28,7 @@ func TestAPI(t *testing.T) {
 		{
 			name: "get base case: get all predictions, when there's nothing",
 			test: func(t *testing.T, a *API, ctx testContext) {
-				apiResp := a.getPredictions(apiReqGetPredictions{})
+				apiResp := a.getPredictions(context.Background(), apiReqGetPredictions{})
 
 				require.Equal(t, 200, apiResp.Status)
 				require.Equal(t, "", apiResp.ErrorCode)
@@ -34,7 +38,7 @@ func TestAPI(t *testing.T) {
 		{
 			name: "new base case: invalid json",
 			test: func(t *testing.T, a *API, ctx testContext) {
-				apiResp := a.postPrediction(apiReqPostPrediction{Prediction: "invalid"})
+				apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: "invalid"})
 
 				require.Equal(t, 400, apiResp.Status)
 				require.Equal(t, "ErrInvalidRequestJSON", apiResp.ErrorCode)
@@ -63,7 +67,7 @@ func TestAPI(t *testing.T) {
 						}
 					}
 				`
-				apiResp := a.postPrediction(apiReqPostPrediction{Prediction: rawPrediction})
+				apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: rawPrediction})
 
 				require.Equal(t, apiResp.Status, 200)
 				pred := apiResp.Data.Prediction
@@ -83,32 +87,32 @@ func TestAPI(t *testing.T) {
 		{
 			name: "hide prediction",
 			test: func(t *testing.T, a *API, ctx testContext) {
-				apiResp := a.postPrediction(apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
+				apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
 				require.Equal(t, apiResp.Status, 200, apiResp.InternalErrorMessage)
 				require.Equal(t, apiResp.Data.Stored, true)
 
 				uuid := apiResp.Data.Prediction.UUID
 
-				hideResp := a.predictionStorageActionWithUUID(uuid, ctx.store.HidePrediction)
+				hideResp := a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.HidePrediction)
 				require.Equal(t, hideResp.Status, 200, hideResp.InternalErrorMessage)
 				require.Equal(t, hideResp.Data.Stored, true)
 
-				getResp := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Hidden: pBool(true)})
+				getResp := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Hidden: pBool(true)})
 				require.Equal(t, getResp.Status, 200, getResp.InternalErrorMessage)
 				require.Len(t, getResp.Data.Predictions, 1)
 
 				// When filtering by not hidden, it shouldn't show up
-				getResp2 := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Hidden: pBool(false)})
+				getResp2 := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Hidden: pBool(false)})
 				require.Equal(t, getResp2.Status, 200, getResp2.InternalErrorMessage)
 				require.Len(t, getResp2.Data.Predictions, 0)
 
 				// When posting a new prediction and not hiding it, only the hidden one should show up
 				secondPrediction, _ := compile(t, sampleRawPrediction)
 				secondPrediction.PostURL = "https://twitter.com/differentUser/status/1499475622988595206"
-				apiResp = a.postPrediction(apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
+				apiResp = a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
 				require.Equal(t, apiResp.Status, 200, apiResp.InternalErrorMessage)
 
-				getResp = a.getPredictions(apiReqGetPredictions{Hidden: pBool(true)})
+				getResp = a.getPredictions(context.Background(), apiReqGetPredictions{Hidden: pBool(true)})
 				require.Equal(t, getResp.Status, 200, getResp.InternalErrorMessage)
 				require.Len(t, getResp.Data.Predictions, 1)
 				require.Equal(t, getResp.Data.Predictions[0].UUID, uuid)
@@ -117,32 +121,32 @@ func TestAPI(t *testing.T) {
 		{
 			name: "delete prediction",
 			test: func(t *testing.T, a *API, ctx testContext) {
-				apiResp := a.postPrediction(apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
+				apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
 				require.Equal(t, apiResp.Status, 200, apiResp.InternalErrorMessage)
 				require.Equal(t, apiResp.Data.Stored, true)
 
 				uuid := apiResp.Data.Prediction.UUID
 
-				deleteResp := a.predictionStorageActionWithUUID(uuid, ctx.store.DeletePrediction)
+				deleteResp := a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.DeletePrediction)
 				require.Equal(t, deleteResp.Status, 200, deleteResp.InternalErrorMessage)
 				require.Equal(t, deleteResp.Data.Stored, true)
 
-				getResp := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Deleted: pBool(true)})
+				getResp := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Deleted: pBool(true)})
 				require.Equal(t, getResp.Status, 200, getResp.InternalErrorMessage)
 				require.Len(t, getResp.Data.Predictions, 1)
 
 				// When filtering by not deleted, it shouldn't show up
-				getResp2 := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Deleted: pBool(false)})
+				getResp2 := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Deleted: pBool(false)})
 				require.Equal(t, getResp2.Status, 200, getResp2.InternalErrorMessage)
 				require.Len(t, getResp2.Data.Predictions, 0)
 
 				// When posting a new prediction and not deleting it, only the deleted one should show up
 				secondPrediction, _ := compile(t, sampleRawPrediction)
 				secondPrediction.PostURL = "https://twitter.com/differentUser/status/1499475622988595206"
-				apiResp = a.postPrediction(apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
+				apiResp = a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
 				require.Equal(t, apiResp.Status, 200, apiResp.InternalErrorMessage)
 
-				getResp = a.getPredictions(apiReqGetPredictions{Deleted: pBool(true)})
+				getResp = a.getPredictions(context.Background(), apiReqGetPredictions{Deleted: pBool(true)})
 				require.Equal(t, getResp.Status, 200, getResp.InternalErrorMessage)
 				require.Len(t, getResp.Data.Predictions, 1)
 				require.Equal(t, getResp.Data.Predictions[0].UUID, uuid)
@@ -151,32 +155,32 @@ func TestAPI(t *testing.T) {
 		{
 			name: "pause prediction",
 			test: func(t *testing.T, a *API, ctx testContext) {
-				apiResp := a.postPrediction(apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
+				apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
 				require.Equal(t, apiResp.Status, 200, apiResp.InternalErrorMessage)
 				require.Equal(t, apiResp.Data.Stored, true)
 
 				uuid := apiResp.Data.Prediction.UUID
 
-				pauseResp := a.predictionStorageActionWithUUID(uuid, ctx.store.PausePrediction)
+				pauseResp := a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.PausePrediction)
 				require.Equal(t, pauseResp.Status, 200, pauseResp.InternalErrorMessage)
 				require.Equal(t, pauseResp.Data.Stored, true)
 
-				getResp := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Paused: pBool(true)})
+				getResp := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Paused: pBool(true)})
 				require.Equal(t, getResp.Status, 200, getResp.InternalErrorMessage)
 				require.Len(t, getResp.Data.Predictions, 1)
 
 				// When filtering by not paused, it shouldn't show up
-				getResp2 := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Paused: pBool(false)})
+				getResp2 := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Paused: pBool(false)})
 				require.Equal(t, getResp2.Status, 200, getResp2.InternalErrorMessage)
 				require.Len(t, getResp2.Data.Predictions, 0)
 
 				// When posting a new prediction and not pausing it, only the paused one should show up
 				secondPrediction, _ := compile(t, sampleRawPrediction)
 				secondPrediction.PostURL = "https://twitter.com/differentUser/status/1499475622988595206"
-				apiResp = a.postPrediction(apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
+				apiResp = a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
 				require.Equal(t, apiResp.Status, 200, apiResp.InternalErrorMessage)
 
-				getResp = a.getPredictions(apiReqGetPredictions{Paused: pBool(true)})
+				getResp = a.getPredictions(context.Background(), apiReqGetPredictions{Paused: pBool(true)})
 				require.Equal(t, getResp.Status, 200, getResp.InternalErrorMessage)
 				require.Len(t, getResp.Data.Predictions, 1)
 				require.Equal(t, getResp.Data.Predictions[0].UUID, uuid)
@@ -186,32 +190,32 @@ func TestAPI(t *testing.T) {
 			name: "unhide prediction",
 			test: func(t *testing.T, a *API, ctx testContext) {
 				// Create sample prediction
-				apiResp := a.postPrediction(apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
+				apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
 				uuid := apiResp.Data.Prediction.UUID
 
 				// Hide it
-				a.predictionStorageActionWithUUID(uuid, ctx.store.HidePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.HidePrediction)
 
 				// Unhide it
-				a.predictionStorageActionWithUUID(uuid, ctx.store.UnhidePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.UnhidePrediction)
 
 				// When filtering by not hidden, it should show up
-				getResp := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Hidden: pBool(false)})
+				getResp := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Hidden: pBool(false)})
 				require.Len(t, getResp.Data.Predictions, 1)
 
 				// When filtering by hidden, it shouldn't show up
-				getResp2 := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Hidden: pBool(true)})
+				getResp2 := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Hidden: pBool(true)})
 				require.Len(t, getResp2.Data.Predictions, 0)
 
 				// When posting a new prediction and hiding it, only the hidden one should show up
 				secondPrediction, _ := compile(t, sampleRawPrediction)
 				secondPrediction.PostURL = "https://twitter.com/differentUser/status/1499475622988595206"
-				apiResp = a.postPrediction(apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
+				apiResp = a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
 				secondUUID := apiResp.Data.Prediction.UUID
 
-				a.predictionStorageActionWithUUID(secondUUID, ctx.store.HidePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), secondUUID, ctx.store.HidePrediction)
 
-				getResp = a.getPredictions(apiReqGetPredictions{Hidden: pBool(true)})
+				getResp = a.getPredictions(context.Background(), apiReqGetPredictions{Hidden: pBool(true)})
 				require.Len(t, getResp.Data.Predictions, 1)
 				require.Equal(t, secondUUID, getResp.Data.Predictions[0].UUID)
 			},
@@ -220,32 +224,32 @@ func TestAPI(t *testing.T) {
 			name: "undelete prediction",
 			test: func(t *testing.T, a *API, ctx testContext) {
 				// Create sample prediction
-				apiResp := a.postPrediction(apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
+				apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
 				uuid := apiResp.Data.Prediction.UUID
 
 				// Delete it
-				a.predictionStorageActionWithUUID(uuid, ctx.store.DeletePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.DeletePrediction)
 
 				// Undelete it
-				a.predictionStorageActionWithUUID(uuid, ctx.store.UndeletePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.UndeletePrediction)
 
 				// When filtering by not deleted, it should show up
-				getResp := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Deleted: pBool(false)})
+				getResp := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Deleted: pBool(false)})
 				require.Len(t, getResp.Data.Predictions, 1)
 
 				// When filtering by deleted, it shouldn't show up
-				getResp2 := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Deleted: pBool(true)})
+				getResp2 := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Deleted: pBool(true)})
 				require.Len(t, getResp2.Data.Predictions, 0)
 
 				// When posting a new prediction and deleting it, only the deleted one should show up
 				secondPrediction, _ := compile(t, sampleRawPrediction)
 				secondPrediction.PostURL = "https://twitter.com/differentUser/status/1499475622988595206"
-				apiResp = a.postPrediction(apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
+				apiResp = a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
 				secondUUID := apiResp.Data.Prediction.UUID
 
-				a.predictionStorageActionWithUUID(secondUUID, ctx.store.DeletePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), secondUUID, ctx.store.DeletePrediction)
 
-				getResp = a.getPredictions(apiReqGetPredictions{Deleted: pBool(true)})
+				getResp = a.getPredictions(context.Background(), apiReqGetPredictions{Deleted: pBool(true)})
 				require.Len(t, getResp.Data.Predictions, 1)
 				require.Equal(t, secondUUID, getResp.Data.Predictions[0].UUID)
 			},
@@ -254,32 +258,32 @@ func TestAPI(t *testing.T) {
 			name: "unpause prediction",
 			test: func(t *testing.T, a *API, ctx testContext) {
 				// Create sample prediction
-				apiResp := a.postPrediction(apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
+				apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: string(sampleRawPrediction), Store: true})
 				uuid := apiResp.Data.Prediction.UUID
 
 				// Pause it
-				a.predictionStorageActionWithUUID(uuid, ctx.store.PausePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.PausePrediction)
 
 				// Unpause it
-				a.predictionStorageActionWithUUID(uuid, ctx.store.UnpausePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), uuid, ctx.store.UnpausePrediction)
 
 				// When filtering by not paused, it should show up
-				getResp := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Paused: pBool(false)})
+				getResp := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Paused: pBool(false)})
 				require.Len(t, getResp.Data.Predictions, 1)
 
 				// When filtering by paused, it shouldn't show up
-				getResp2 := a.getPredictions(apiReqGetPredictions{UUIDs: []string{uuid}, Paused: pBool(true)})
+				getResp2 := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{uuid}, Paused: pBool(true)})
 				require.Len(t, getResp2.Data.Predictions, 0)
 
 				// When posting a new prediction and pausing it, only the paused one should show up
 				secondPrediction, _ := compile(t, sampleRawPrediction)
 				secondPrediction.PostURL = "https://twitter.com/differentUser/status/1499475622988595206"
-				apiResp = a.postPrediction(apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
+				apiResp = a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: serialize(t, secondPrediction), Store: true})
 				secondUUID := apiResp.Data.Prediction.UUID
 
-				a.predictionStorageActionWithUUID(secondUUID, ctx.store.PausePrediction)
+				a.predictionStorageActionWithUUID(context.Background(), secondUUID, ctx.store.PausePrediction)
 
-				getResp = a.getPredictions(apiReqGetPredictions{Paused: pBool(true)})
+				getResp = a.getPredictions(context.Background(), apiReqGetPredictions{Paused: pBool(true)})
 				require.Len(t, getResp.Data.Predictions, 1)
 				require.Equal(t, secondUUID, getResp.Data.Predictions[0].UUID)
 			},
@@ -323,10 +327,10 @@ func TestAPI(t *testing.T) {
 
 					samplePreds = append(samplePreds, samplePred)
 
-					apiResp := a.postPrediction(apiReqPostPrediction{Prediction: serialize(t, samplePred), Store: true})
+					apiResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: serialize(t, samplePred), Store: true})
 					require.Equal(t, 200, apiResp.Status)
 				}
-				_, err := ctx.store.UpsertAccounts(sampleAccounts)
+				_, err := ctx.store.UpsertAccounts(context.Background(), sampleAccounts)
 				require.Nil(t, err)
 
 				// Populate the test market with Ticks so that the summary can be created
@@ -339,7 +343,7 @@ func TestAPI(t *testing.T) {
 					curTime.Add(1 * time.Hour)
 				}
 
-				apiResp := a.getPagesPrediction(samplePreds[0].PostURL)
+				apiResp := a.getPagesPrediction(context.Background(), samplePreds[0].PostURL)
 				require.Equal(t, 200, apiResp.Status)
 
 				// The main Prediction's UUID must match the first samplePred
@@ -380,6 +384,275 @@ func TestAPI(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "accounts leaderboard and stats",
+			test: func(t *testing.T, a *API, ctx testContext) {
+				sampleAccounts := []*core.Account{}
+				for i, value := range []core.PredictionStateValue{core.CORRECT, core.INCORRECT, core.ANNULLED, core.ONGOINGPREDICTION} {
+					samplePred, sampleAccount := compile(t, sampleRawPrediction)
+					samplePred.UUID = uuid.NewString()
+					samplePred.PostURL = fmt.Sprintf("https://twitter.com/differentUser/status/%v", i)
+					samplePred.PostAuthor = fmt.Sprintf("User%v", i%2)
+					samplePred.PostAuthorURL = fmt.Sprintf("https://twitter.com/user%v", i%2)
+					samplePred.State.Value = value
+					_, err := ctx.store.UpsertPredictions(context.Background(), []*core.Prediction{&samplePred})
+					require.Nil(t, err)
+
+					sampleAccount.Handle = samplePred.PostAuthor
+					sampleAccount.URL, _ = url.Parse(samplePred.PostAuthorURL)
+					sampleAccounts = append(sampleAccounts, sampleAccount)
+				}
+				_, err := ctx.store.UpsertAccounts(context.Background(), sampleAccounts[:2])
+				require.Nil(t, err)
+
+				// The goal is 30000 and BTC was at 40000 at post time, so it's 25% away from the price
+				ctx.market.ticks["COIN:BINANCE:BTC-USDT"] = []core.Tick{{Value: 40000}}
+				maintenanceResp := a.maintenance(context.Background(), apiReqMaintenance{Action: "calculateDifficultyOnCorrectPredictions"})
+				require.Equal(t, 200, maintenanceResp.Status, maintenanceResp.InternalErrorMessage)
+				require.Equal(t, "Stored the difficulty of 1 out of 1 CORRECT predictions!", maintenanceResp.Data.Message)
+
+				leaderboardResp := a.getAccountsLeaderboard(context.Background(), apiReqGetAccountsLeaderboard{})
+				require.Equal(t, 200, leaderboardResp.Status, leaderboardResp.InternalErrorMessage)
+				require.Equal(t, []apiAccountStats{
+					{Handle: "User0", Correct: 1, Annulled: 1, Accuracy: 1, Score: 1.25},
+					{Handle: "User1", Incorrect: 1, Accuracy: 0, Score: -1},
+				}, leaderboardResp.Data.Accounts)
+
+				leaderboardResp = a.getAccountsLeaderboard(context.Background(), apiReqGetAccountsLeaderboard{Coins: []string{"btc"}, Limit: "1"})
+				require.Equal(t, 200, leaderboardResp.Status, leaderboardResp.InternalErrorMessage)
+				require.Equal(t, []apiAccountStats{{Handle: "User0", Correct: 1, Annulled: 1, Accuracy: 1, Score: 1.25}}, leaderboardResp.Data.Accounts)
+
+				leaderboardResp = a.getAccountsLeaderboard(context.Background(), apiReqGetAccountsLeaderboard{Coins: []string{"ETH"}})
+				require.Equal(t, 200, leaderboardResp.Status, leaderboardResp.InternalErrorMessage)
+				require.Len(t, leaderboardResp.Data.Accounts, 0)
+
+				leaderboardResp = a.getAccountsLeaderboard(context.Background(), apiReqGetAccountsLeaderboard{PredictionTypes: []string{"PREDICTION_TYPE_UNKNOWN"}})
+				require.Equal(t, 400, leaderboardResp.Status)
+
+				for _, invalidLimit := range []string{"0", "-1", "one"} {
+					leaderboardResp = a.getAccountsLeaderboard(context.Background(), apiReqGetAccountsLeaderboard{Limit: invalidLimit})
+					require.Equal(t, 400, leaderboardResp.Status, "for limit %v", invalidLimit)
+				}
+
+				statsResp := a.getAccountStats(context.Background(), apiReqGetAccountStats{Handle: "User1"})
+				require.Equal(t, 200, statsResp.Status, statsResp.InternalErrorMessage)
+				require.Equal(t, apiAccountStats{Handle: "User1", Incorrect: 1, Accuracy: 0, Score: -1}, statsResp.Data.Stats)
+
+				statsResp = a.getAccountStats(context.Background(), apiReqGetAccountStats{Handle: "User1", PostedTo: "2000-01-01T00:00:00Z"})
+				require.Equal(t, 200, statsResp.Status, statsResp.InternalErrorMessage)
+				require.Equal(t, apiAccountStats{Handle: "User1"}, statsResp.Data.Stats)
+
+				statsResp = a.getAccountStats(context.Background(), apiReqGetAccountStats{Handle: "unknown"})
+				require.Equal(t, 404, statsResp.Status)
+
+				// Another account with the same handle, which has no predictions.
+				_, sameHandleAccount := compile(t, sampleRawPrediction)
+				sameHandleAccount.Handle = "User1"
+				sameHandleAccount.URL, _ = url.Parse("https://www.youtube.com/c/user1")
+				_, err = ctx.store.UpsertAccounts(context.Background(), []*core.Account{sameHandleAccount})
+				require.Nil(t, err)
+
+				statsResp = a.getAccountStats(context.Background(), apiReqGetAccountStats{Handle: "User1"})
+				require.Equal(t, 409, statsResp.Status)
+
+				statsResp = a.getAccountStats(context.Background(), apiReqGetAccountStats{Handle: url.PathEscape("https://twitter.com/user1")})
+				require.Equal(t, 200, statsResp.Status, statsResp.InternalErrorMessage)
+				require.Equal(t, apiAccountStats{Handle: "User1", Incorrect: 1, Accuracy: 0, Score: -1}, statsResp.Data.Stats)
+
+				statsResp = a.getAccountStats(context.Background(), apiReqGetAccountStats{Handle: url.PathEscape("https://www.youtube.com/c/user1")})
+				require.Equal(t, 200, statsResp.Status, statsResp.InternalErrorMessage)
+				require.Equal(t, apiAccountStats{Handle: "User1"}, statsResp.Data.Stats)
+			},
+		},
+		{
+			name: "accounts: list, get, refresh and merge",
+			test: func(t *testing.T, a *API, ctx testContext) {
+				sampleAccounts := []*core.Account{}
+				for _, account := range []struct {
+					url           string
+					handle        string
+					followerCount int
+				}{
+					{"https://twitter.com/user0", "User0", 10},
+					{"https://www.youtube.com/channel/user0", "User0", 5},
+					{"https://twitter.com/user1", "User1", 20},
+				} {
+					_, sampleAccount := compile(t, sampleRawPrediction)
+					sampleAccount.URL, _ = url.Parse(account.url)
+					sampleAccount.Handle = account.handle
+					sampleAccount.FollowerCount = account.followerCount
+					sampleAccounts = append(sampleAccounts, sampleAccount)
+				}
+				_, err := ctx.store.UpsertAccounts(context.Background(), sampleAccounts)
+				require.Nil(t, err)
+
+				samplePred, _ := compile(t, sampleRawPrediction)
+				samplePred.PostAuthor = "User0"
+				samplePred.PostAuthorURL = "https://www.youtube.com/channel/user0"
+				_, err = ctx.store.UpsertPredictions(context.Background(), []*core.Prediction{&samplePred})
+				require.Nil(t, err)
+
+				// Listing defaults to follower count descending
+				listResp := a.getAccounts(context.Background(), apiReqGetAccounts{})
+				require.Equal(t, 200, listResp.Status, listResp.InternalErrorMessage)
+				require.Len(t, listResp.Data.Accounts, 3)
+				for i, expectedURL := range []string{"https://twitter.com/user1", "https://twitter.com/user0", "https://www.youtube.com/channel/user0"} {
+					require.Equal(t, expectedURL, listResp.Data.Accounts[i].URL)
+				}
+
+				listResp = a.getAccounts(context.Background(), apiReqGetAccounts{Handles: []string{"User0"}, Limit: "1", Offset: "1"})
+				require.Equal(t, 200, listResp.Status, listResp.InternalErrorMessage)
+				require.Len(t, listResp.Data.Accounts, 1)
+				require.Equal(t, "https://www.youtube.com/channel/user0", listResp.Data.Accounts[0].URL)
+
+				listResp = a.getAccounts(context.Background(), apiReqGetAccounts{OrderBys: []string{"UNKNOWN"}})
+				require.Equal(t, 400, listResp.Status)
+
+				// Getting by handle or url
+				getResp := a.getAccount(context.Background(), "User1")
+				require.Equal(t, 200, getResp.Status, getResp.InternalErrorMessage)
+				require.Equal(t, "https://twitter.com/user1", getResp.Data.Account.URL)
+
+				getResp = a.getAccount(context.Background(), "https://www.youtube.com/channel/user0")
+				require.Equal(t, 200, getResp.Status, getResp.InternalErrorMessage)
+				require.Equal(t, 5, getResp.Data.Account.FollowerCount)
+
+				require.Equal(t, 409, a.getAccount(context.Background(), "User0").Status)
+				require.Equal(t, 404, a.getAccount(context.Background(), "unknown").Status)
+
+				// Merging re-points the duplicate's predictions and deletes it
+				require.Equal(t, 400, a.mergeAccounts(context.Background(), apiReqMergeAccounts{From: "User1", Into: "https://twitter.com/user1"}).Status)
+
+				mergeResp := a.mergeAccounts(context.Background(), apiReqMergeAccounts{From: "https://www.youtube.com/channel/user0", Into: "https://twitter.com/user0"})
+				require.Equal(t, 200, mergeResp.Status, mergeResp.InternalErrorMessage)
+				require.Equal(t, 1, mergeResp.Data.RepointedPredictions)
+				require.Equal(t, "https://twitter.com/user0", mergeResp.Data.Account.URL)
+
+				require.Equal(t, 404, a.getAccount(context.Background(), "https://www.youtube.com/channel/user0").Status)
+				preds, err := ctx.store.GetPredictions(context.Background(), core.APIFilters{AuthorURLs: []string{"https://twitter.com/user0"}}, []string{}, 0, 0)
+				require.Nil(t, err)
+				require.Len(t, preds, 1)
+				require.Equal(t, "User0", preds[0].PostAuthor)
+
+				// The merged account doesn't come back when its metadata is stored again
+				_, err = ctx.store.UpsertAccounts(context.Background(), sampleAccounts[1:2])
+				require.Nil(t, err)
+				require.Equal(t, 404, a.getAccount(context.Background(), "https://www.youtube.com/channel/user0").Status)
+
+				// Refreshing fetches metadata from each account's latest prediction, which must be by the account
+				refreshResp := a.refreshAccounts(context.Background(), apiReqRefreshAccounts{})
+				require.Equal(t, 400, refreshResp.Status)
+
+				cancelledCtx, cancel := context.WithCancel(context.Background())
+				cancel()
+				refreshResp = a.refreshAccounts(cancelledCtx, apiReqRefreshAccounts{Handles: []string{"User0", "User1"}})
+				require.NotEqual(t, 200, refreshResp.Status)
+
+				refreshResp = a.refreshAccounts(context.Background(), apiReqRefreshAccounts{Handles: []string{"User0", "User1"}})
+				require.Equal(t, 200, refreshResp.Status, refreshResp.InternalErrorMessage)
+				require.Len(t, refreshResp.Data.Refreshed, 0)
+				require.Equal(t, map[string]string{
+					"https://twitter.com/user0": "latest prediction's post https://twitter.com/CryptoCapo_/status/1499475622988595206 is by https://twitter.com/CryptoCapo_, not by this account",
+					"https://twitter.com/user1": "account has no predictions to fetch its metadata from",
+				}, refreshResp.Data.Failed)
+				require.Equal(t, 404, a.getAccount(context.Background(), "https://twitter.com/CryptoCapo_").Status)
+
+				// New predictions by a merged account, and refetching it, go to the account it was merged into
+				_, capoAccount := compile(t, sampleRawPrediction)
+				_, err = ctx.store.UpsertAccounts(context.Background(), []*core.Account{capoAccount})
+				require.Nil(t, err)
+				mergeResp = a.mergeAccounts(context.Background(), apiReqMergeAccounts{From: "https://twitter.com/CryptoCapo_", Into: "User1"})
+				require.Equal(t, 200, mergeResp.Status, mergeResp.InternalErrorMessage)
+				require.Equal(t, 0, mergeResp.Data.RepointedPredictions)
+
+				postResp := a.postPrediction(context.Background(), apiReqPostPrediction{Prediction: strings.Replace(string(sampleRawPrediction), "1499475622988595206", "1499475622988595207", 1), Store: true})
+				require.Equal(t, 200, postResp.Status, postResp.InternalErrorMessage)
+				require.Equal(t, "User1", postResp.Data.Prediction.PostAuthor)
+				require.Equal(t, "https://twitter.com/user1", postResp.Data.Prediction.PostAuthorURL)
+
+				refetchResp := a.predictionRefetchAccount(context.Background(), postResp.Data.Prediction.UUID)
+				require.Equal(t, 200, refetchResp.Status, refetchResp.InternalErrorMessage)
+				require.False(t, refetchResp.Data.Stored)
+				require.Equal(t, 404, a.getAccount(context.Background(), "https://twitter.com/CryptoCapo_").Status)
+			},
+		},
+		{
+			name: "get account by escaped url through the router",
+			test: func(t *testing.T, a *API, ctx testContext) {
+				_, account := compile(t, sampleRawPrediction)
+				_, err := ctx.store.UpsertAccounts(context.Background(), []*core.Account{account})
+				require.Nil(t, err)
+
+				for _, ts := range []struct {
+					path           string
+					expectedStatus int
+				}{
+					{"/accounts/" + url.PathEscape(account.URL.String()), 200},
+					{"/accounts/" + url.PathEscape(account.Handle), 200},
+					{"/accounts/" + url.PathEscape("https://twitter.com/unknown"), 404},
+				} {
+					req := httptest.NewRequest(http.MethodGet, ts.path, nil)
+					req.SetBasicAuth("admin", "admin")
+					rec := httptest.NewRecorder()
+					a.mux.ServeHTTP(rec, req)
+
+					// Unmatched routes aren't JSON, so this also checks that the route matched.
+					resp := apiResponse[apiResGetAccount]{}
+					require.Nil(t, json.Unmarshal(rec.Body.Bytes(), &resp), ts.path)
+					require.Equal(t, ts.expectedStatus, resp.Status, ts.path)
+					if ts.expectedStatus == 200 {
+						require.Equal(t, account.URL.String(), resp.Data.Account.URL)
+					}
+				}
+			},
+		},
+		{
+			name: "prediction history",
+			test: func(t *testing.T, a *API, ctx testContext) {
+				samplePred, _ := compile(t, sampleRawPrediction)
+				_, err := ctx.store.UpsertPredictions(context.Background(), []*core.Prediction{&samplePred})
+				require.Nil(t, err)
+
+				ticks := map[string]core.Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: 1641081660, Value: 29000}}
+				for _, change := range []core.PredictionStateValueChange{
+					{PredictionUUID: samplePred.UUID, StateValue: core.ONGOINGPREDICTION.String(), CreatedAt: tpToISO("2022-01-02 00:00:00")},
+					{PredictionUUID: samplePred.UUID, StateValue: core.CORRECT.String(), ConditionName: "a", ConditionValue: core.TRUE.String(), Ticks: ticks, CreatedAt: tpToISO("2022-01-02 00:05:00")},
+				} {
+					require.Nil(t, ctx.store.LogPredictionStateValueChange(context.Background(), change))
+				}
+
+				historyResp := a.getPredictionHistory(context.Background(), samplePred.UUID)
+				require.Equal(t, 200, historyResp.Status, historyResp.InternalErrorMessage)
+				require.Equal(t, []apiPredictionStateValueChange{
+					{StateValue: core.ONGOINGPREDICTION.String(), CreatedAt: tpToISO("2022-01-02 00:00:00")},
+					{StateValue: core.CORRECT.String(), ConditionName: "a", ConditionValue: core.TRUE.String(), Ticks: ticks, CreatedAt: tpToISO("2022-01-02 00:05:00")},
+				}, historyResp.Data.History)
+
+				require.Equal(t, 404, a.getPredictionHistory(context.Background(), uuid.NewString()).Status)
+			},
+		},
+		{
+			name: "get returns the ticks that decided each condition",
+			test: func(t *testing.T, a *API, ctx testContext) {
+				samplePred, _ := compile(t, sampleRawPrediction)
+				cond := samplePred.Given["a"]
+				require.Nil(t, cond.Run(map[string]core.Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: cond.FromTs, Value: 29000}}))
+				samplePred.Evaluate()
+				_, err := ctx.store.UpsertPredictions(context.Background(), []*core.Prediction{&samplePred})
+				require.Nil(t, err)
+
+				getResp := a.getPredictions(context.Background(), apiReqGetPredictions{UUIDs: []string{samplePred.UUID}})
+				require.Equal(t, 200, getResp.Status, getResp.InternalErrorMessage)
+				require.Len(t, getResp.Data.Predictions, 1)
+				require.Equal(t, []compiler.DecidingTick{{
+					Operand:        "COIN:BINANCE:BTC-USDT",
+					Exchange:       "BINANCE",
+					CandleInterval: "1m",
+					Timestamp:      cond.FromTs,
+					Value:          29000,
+				}}, getResp.Data.Predictions[0].Given["a"].State.DecidingTicks)
+			},
+		},
 	}
 
 	for _, ts := range tss {
@@ -388,12 +661,14 @@ func TestAPI(t *testing.T) {
 				store      = setupTestDB(t)
 				testMarket = newTestMarket(map[string][]core.Tick{})
 				mFetcher   = metadatafetcher.NewMetadataFetcher()
-				daemon     = daemon.NewDaemon(testMarket, store, imagebuilder.PredictionImageBuilder{}, false, false, "")
+				daemon     = daemon.NewDaemon(core.NewMarket(testMarket), store, imagebuilder.PredictionImageBuilder{}, false, false, "")
 			)
 			addTestFetcher(mFetcher)
 
-			a := NewAPI(testMarket, store, *mFetcher, imagebuilder.PredictionImageBuilder{}, "admin", "admin")
-			go a.MustBlockinglyListenAndServe("localhost:0")
+			a := NewAPI(core.NewMarket(testMarket), store, *mFetcher, imagebuilder.PredictionImageBuilder{}, "admin", "admin")
+			serveCtx, stopServing := context.WithCancel(context.Background())
+			defer stopServing()
+			go a.MustBlockinglyListenAndServe(serveCtx, "localhost:0", time.Second)
 
 			ts.test(t, a, testContext{store, testMarket, daemon, mFetcher})
 		})
@@ -33,6 +33,8 @@ var (
 	ErrInvalidRequestJSON = errors.New("invalid request JSON")
 	// ErrStorageErrorRetrievingPredictions is returned by the API when something went wrong retrieving a prediction.
 	ErrStorageErrorRetrievingPredictions = errors.New("storage had error retrieving predictions")
+	// ErrStorageErrorRetrievingAccountStats is returned by the API when something went wrong calculating account stats.
+	ErrStorageErrorRetrievingAccountStats = errors.New("storage had error retrieving account stats")
 	// ErrStorageErrorStoringPrediction is returned by the API when something went wrong storing a prediction.
 	ErrStorageErrorStoringPrediction = errors.New("storage had error storing predictions")
 	// ErrStorageErrorStoringAccount is returned by the API when something went wrong storing an account.
@@ -45,6 +47,18 @@ var (
 	ErrFailedToCompilePrediction = errors.New("failed to compile prediction")
 	// ErrPredictionNotFound is returned by the API when an unknown prediction was requested.
 	ErrPredictionNotFound = errors.New("prediction not found")
+	// ErrAccountNotFound is returned by the API when an unknown account was requested.
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrAmbiguousAccountHandle is returned by the API when an account was requested by a handle that many accounts have.
+	ErrAmbiguousAccountHandle = errors.New("more than one account has this handle")
+	// ErrCannotMergeAccountIntoItself is returned by the API when asked to merge an account into itself.
+	ErrCannotMergeAccountIntoItself = errors.New("cannot merge an account into itself")
+	// ErrInvalidAccountStatsFilters is returned by the API when the filters for account stats are invalid.
+	ErrInvalidAccountStatsFilters = errors.New("invalid account stats filters")
+	// ErrInvalidRefreshAccountsRequest is returned by the API when asked to refresh no accounts or too many at once.
+	ErrInvalidRefreshAccountsRequest = errors.New("invalid refresh accounts request")
+	// ErrInvalidAccountID is returned by the API when an account was requested by an id that isn't a valid escaped url.
+	ErrInvalidAccountID = errors.New("invalid account id")
 
 	errToResponse = map[error]ErrorContent{
 		core.ErrUnknownOperandType:                 {StatusCode: 400, ErrorCode: "ErrUnknownOperandType", Message: "unknown value for operandType"},
@@ -55,6 +69,12 @@ var (
 		core.ErrEmptyQuoteAsset:                    {StatusCode: 400, ErrorCode: "ErrEmptyQuoteAsset", Message: "quote asset cannot be empty"},
 		core.ErrNonEmptyQuoteAssetOnNonCoin:        {StatusCode: 400, ErrorCode: "ErrNonEmptyQuoteAssetOnNonCoin", Message: "quote asset must be empty for non-coin operand types"},
 		core.ErrEqualBaseQuoteAssets:               {StatusCode: 400, ErrorCode: "ErrEqualBaseQuoteAssets", Message: "base asset cannot be equal to quote asset"},
+		core.ErrInvalidExchange:                    {StatusCode: 400, ErrorCode: "ErrInvalidExchange", Message: "the only valid exchanges are 'binance', 'coinbase', 'kucoin', 'bitstamp', 'bitfinex' and 'binanceusdmfutures'"},
+		core.ErrInvalidAggregateProvider:           {StatusCode: 400, ErrorCode: "ErrInvalidAggregateProvider", Message: "aggregate providers are ANY or MEDIAN, optionally of two or more different exchanges (e.g. MEDIAN(BINANCE,COINBASE,KUCOIN))"},
+		core.ErrUnknownEvaluationMode:              {StatusCode: 400, ErrorCode: "ErrUnknownEvaluationMode", Message: "the only supported evaluation modes are WICK, CLOSE and SUSTAINED"},
+		core.ErrInvalidCandleInterval:              {StatusCode: 400, ErrorCode: "ErrInvalidCandleInterval", Message: "candle intervals must be one of 1m, 5m, 15m, 30m, 1h, 2h, 4h, 6h, 12h, 1d or 1w, and only for CLOSE or SUSTAINED evaluation modes"},
+		core.ErrInvalidSustainedCandles:            {StatusCode: 400, ErrorCode: "ErrInvalidSustainedCandles", Message: "sustained candles must be at least 1, and only for the SUSTAINED evaluation mode"},
+		core.ErrInvalidSustainedFor:                {StatusCode: 400, ErrorCode: "ErrInvalidSustainedFor", Message: "sustainedFor must be a valid duration that matches the condition's deadline, and cannot be combined with the SUSTAINED evaluation mode"},
 		core.ErrInvalidDuration:                    {StatusCode: 400, ErrorCode: "ErrInvalidDuration", Message: "invalid duration"},
 		core.ErrInvalidFromISO8601:                 {StatusCode: 400, ErrorCode: "ErrInvalidFromISO8601", Message: "invalid FromISO8601"},
 		core.ErrInvalidToISO8601:                   {StatusCode: 400, ErrorCode: "ErrInvalidToISO8601", Message: "invalid ToISO8601"},
@@ -71,6 +91,7 @@ var (
 		core.ErrEmptyPredict:                       {StatusCode: 400, ErrorCode: "ErrEmptyPredict", Message: "main predict clause cannot be empty"},
 		core.ErrMissingRequiredPrePredictPredictIf: {StatusCode: 400, ErrorCode: "ErrMissingRequiredPrePredictPredictIf", Message: "pre-predict clause must have predictIf if it has either wrongIf or annuledIf. Otherwise, add them directly on predict clause"},
 		core.ErrBoolExprSyntaxError:                {StatusCode: 400, ErrorCode: "ErrBoolExprSyntaxError", Message: "syntax error in bool expression"},
+		core.ErrUnknownAPIOrderBy:                  {StatusCode: 400, ErrorCode: "ErrUnknownAPIOrderBy", Message: "unknown value for orderBys"},
 		core.ErrPredictionFinishedAtStartTime:      {StatusCode: 400, ErrorCode: "ErrPredictionFinishedAtStartTime", Message: "prediction is finished at start time"},
 
 		// From Market
@@ -88,11 +109,18 @@ var (
 		ErrInvalidRequestJSON:                  {StatusCode: 400, ErrorCode: "ErrInvalidRequestJSON", Message: "invalid request JSON"},
 		ErrStorageErrorRetrievingPredictions:   {StatusCode: 500, ErrorCode: "ErrStorageErrorRetrievingPredictions", Message: "storage had error retrieving predictions"},
 		core.ErrStorageErrorRetrievingAccounts: {StatusCode: 500, ErrorCode: "ErrStorageErrorRetrievingAccounts", Message: "storage had error retrieving accounts"},
+		ErrStorageErrorRetrievingAccountStats:  {StatusCode: 500, ErrorCode: "ErrStorageErrorRetrievingAccountStats", Message: "storage had error retrieving account stats"},
 		ErrStorageErrorStoringPrediction:       {StatusCode: 500, ErrorCode: "ErrStorageErrorStoringPrediction", Message: "storage had error storing predictions"},
 		ErrStorageErrorStoringAccount:          {StatusCode: 500, ErrorCode: "ErrStorageErrorStoringAccount", Message: "storage had error storing accounts"},
 		ErrFailedToSerializePredictions:        {StatusCode: 500, ErrorCode: "ErrFailedToSerializePredictions", Message: "failed to serialize predictions"},
 		ErrFailedToSerializeAccount:            {StatusCode: 500, ErrorCode: "ErrFailedToSerializeAccount", Message: "failed to serialize account"},
 		ErrFailedToCompilePrediction:           {StatusCode: 500, ErrorCode: "ErrFailedToCompilePrediction", Message: "failed to compile prediction"},
 		ErrPredictionNotFound:                  {StatusCode: 404, ErrorCode: "ErrPredictionNotFound", Message: "prediction not found"},
+		ErrAccountNotFound:                     {StatusCode: 404, ErrorCode: "ErrAccountNotFound", Message: "account not found"},
+		ErrAmbiguousAccountHandle:              {StatusCode: 409, ErrorCode: "ErrAmbiguousAccountHandle", Message: "more than one account has this handle: use the account's url instead"},
+		ErrCannotMergeAccountIntoItself:        {StatusCode: 400, ErrorCode: "ErrCannotMergeAccountIntoItself", Message: "cannot merge an account into itself"},
+		ErrInvalidAccountID:                    {StatusCode: 400, ErrorCode: "ErrInvalidAccountID", Message: "invalid account id: urls must be path-escaped"},
+		ErrInvalidRefreshAccountsRequest:       {StatusCode: 400, ErrorCode: "ErrInvalidRefreshAccountsRequest", Message: "at least one of handles or urls is required, and at most 50 accounts can be refreshed at once"},
+		ErrInvalidAccountStatsFilters:          {StatusCode: 400, ErrorCode: "ErrInvalidAccountStatsFilters", Message: "invalid account stats filters: coins are base assets (e.g. BTC), predictionTypes are PREDICTION_TYPE_* values, postedFrom/postedTo are ISO8601 datetimes and limit is a positive integer"},
 	}
 )
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/marianogappa/predictions/core"
+	"github.com/swaggest/usecase"
+)
+
+type apiAccountStats struct {
+	Handle    string  `json:"handle"`
+	Correct   int     `json:"correct"`
+	Incorrect int     `json:"incorrect"`
+	Annulled  int     `json:"annulled"`
+	Accuracy  float64 `json:"accuracy"`
+	Score     float64 `json:"score"`
+}
+
+type apiResGetAccountsLeaderboard struct {
+	Accounts []apiAccountStats `json:"accounts"`
+	_        struct{}          `query:"_" additionalProperties:"false"`
+}
+
+type apiReqGetAccountsLeaderboard struct {
+	Coins           []string `json:"coins" query:"coins" description:"Only count predictions on any of these coins, e.g. BTC"`
+	PredictionTypes []string `json:"predictionTypes" query:"predictionTypes" description:"Only count predictions of these types, e.g. PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE"`
+	PostedFrom      string   `json:"postedFrom" query:"postedFrom" description:"Only count predictions posted at or after this ISO8601 datetime"`
+	PostedTo        string   `json:"postedTo" query:"postedTo" description:"Only count predictions posted before this ISO8601 datetime"`
+	Limit           string   `json:"limit" query:"limit" description:"How many accounts to return, as a positive integer"`
+	_               struct{} `query:"_" additionalProperties:"false"`
+}
+
+type apiResGetAccountStats struct {
+	Stats apiAccountStats `json:"stats"`
+	_     struct{}        `query:"_" additionalProperties:"false"`
+}
+
+type apiReqGetAccountStats struct {
+	Handle          string   `path:"handle" description:"Handle or path-escaped url of the account, e.g. Datadash"`
+	Coins           []string `json:"coins" query:"coins" description:"Only count predictions on any of these coins, e.g. BTC"`
+	PredictionTypes []string `json:"predictionTypes" query:"predictionTypes" description:"Only count predictions of these types, e.g. PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE"`
+	PostedFrom      string   `json:"postedFrom" query:"postedFrom" description:"Only count predictions posted at or after this ISO8601 datetime"`
+	PostedTo        string   `json:"postedTo" query:"postedTo" description:"Only count predictions posted before this ISO8601 datetime"`
+	_               struct{} `query:"_" additionalProperties:"false"`
+}
+
+func (a *API) getAccountsLeaderboard(ctx context.Context, req apiReqGetAccountsLeaderboard) apiResponse[apiResGetAccountsLeaderboard] {
+	filters, err := parseAccountStatsFilters(req.Coins, req.PredictionTypes, req.PostedFrom, req.PostedTo)
+	if err != nil {
+		return failWith(ErrInvalidAccountStatsFilters, err, apiResGetAccountsLeaderboard{})
+	}
+
+	limit := 0
+	if req.Limit != "" {
+		if limit, err = strconv.Atoi(req.Limit); err != nil || limit <= 0 {
+			return failWith(ErrInvalidAccountStatsFilters, fmt.Errorf("invalid limit %v: must be a positive integer", req.Limit), apiResGetAccountsLeaderboard{})
+		}
+	}
+
+	stats, err := a.store.GetAccountStats(ctx, filters, limit)
+	if err != nil {
+		return failWith(ErrStorageErrorRetrievingAccountStats, err, apiResGetAccountsLeaderboard{})
+	}
+
+	res := []apiAccountStats{}
+	for _, accountStats := range stats {
+		res = append(res, apiAccountStats(accountStats))
+	}
+
+	return apiResponse[apiResGetAccountsLeaderboard]{Status: 200, Data: apiResGetAccountsLeaderboard{Accounts: res}}
+}
+
+func (a *API) getAccountStats(ctx context.Context, req apiReqGetAccountStats) apiResponse[apiResGetAccountStats] {
+	filters, err := parseAccountStatsFilters(req.Coins, req.PredictionTypes, req.PostedFrom, req.PostedTo)
+	if err != nil {
+		return failWith(ErrInvalidAccountStatsFilters, err, apiResGetAccountStats{})
+	}
+
+	id, err := url.PathUnescape(req.Handle)
+	if err != nil {
+		return failWith(ErrInvalidAccountID, fmt.Errorf("%w: %v", ErrInvalidAccountID, err), apiResGetAccountStats{})
+	}
+	account, errResp := getAccountByHandleOrURL(ctx, id, a.store, apiResGetAccountStats{})
+	if errResp != nil {
+		return *errResp
+	}
+
+	// By url rather than by handle, as other accounts may have the same handle.
+	filters.AuthorURLs = []string{account.URL.String()}
+	allStats, err := a.store.GetAccountStats(ctx, filters, 0)
+	if err != nil {
+		return failWith(ErrStorageErrorRetrievingAccountStats, err, apiResGetAccountStats{})
+	}
+
+	stats := apiAccountStats{Handle: account.Handle}
+	for _, accountStats := range allStats {
+		// e.g. predictions posted under a previous handle of the account.
+		stats.Correct += accountStats.Correct
+		stats.Incorrect += accountStats.Incorrect
+		stats.Annulled += accountStats.Annulled
+		stats.Score += accountStats.Score
+	}
+	if stats.Correct+stats.Incorrect > 0 {
+		stats.Accuracy = float64(stats.Correct) / float64(stats.Correct+stats.Incorrect)
+	}
+
+	return apiResponse[apiResGetAccountStats]{Status: 200, Data: apiResGetAccountStats{Stats: stats}}
+}
+
+func parseAccountStatsFilters(coins, predictionTypes []string, postedFrom, postedTo string) (core.AccountStatsFilters, error) {
+	filters := core.AccountStatsFilters{Coins: coins}
+	for _, s := range predictionTypes {
+		predictionType := core.PredictionTypeFromString(s)
+		if predictionType == core.PredictionTypeUnsupported {
+			return filters, fmt.Errorf("unknown prediction type %v", s)
+		}
+		filters.PredictionTypes = append(filters.PredictionTypes, predictionType)
+	}
+	var err error
+	if postedFrom != "" {
+		if filters.PostedFrom, err = core.ISO8601(postedFrom).Time(); err != nil {
+			return filters, fmt.Errorf("invalid postedFrom: %w", err)
+		}
+	}
+	if postedTo != "" {
+		if filters.PostedTo, err = core.ISO8601(postedTo).Time(); err != nil {
+			return filters, fmt.Errorf("invalid postedTo: %w", err)
+		}
+	}
+	return filters, nil
+}
+
+func (a *API) apiGetAccountsLeaderboard() usecase.Interactor {
+	u := usecase.NewInteractor(func(ctx context.Context, input apiReqGetAccountsLeaderboard, output *apiResponse[apiResGetAccountsLeaderboard]) error {
+		out := a.getAccountsLeaderboard(ctx, input)
+		*output = out
+		return nil
+	})
+	u.SetTags("Account")
+	u.SetTitle("Returns the accounts ranked by the score of their finished predictions.")
+	u.SetDescription(`Counts the CORRECT, INCORRECT and ANNULLED predictions of each account, optionally only those on some coins, of some prediction types or posted within a time window.
+
+- accuracy: ratio of CORRECT predictions among the CORRECT and INCORRECT ones (ANNULLED predictions don't count towards it).
+- score: each CORRECT prediction scores 1 plus its difficulty, each INCORRECT one scores -1, and ANNULLED ones score nothing. The difficulty is how far the price goal was from the price at post time, relative to it (e.g. 0.3 for "Bitcoin will reach 39k" when Bitcoin was at 30k). It's calculated once, when the prediction becomes CORRECT. Only PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE and PREDICTION_TYPE_COIN_WILL_REACH_BEFORE_IT_REACHES predictions have a price goal; the difficulty of the others is 0.`)
+	return u
+}
+
+func (a *API) apiGetAccountStats() usecase.Interactor {
+	u := usecase.NewInteractor(func(ctx context.Context, input apiReqGetAccountStats, output *apiResponse[apiResGetAccountStats]) error {
+		out := a.getAccountStats(ctx, input)
+		*output = out
+		return nil
+	})
+	u.SetTags("Account")
+	u.SetTitle("Returns the counts, accuracy and score of an account's finished predictions.")
+	u.SetDescription("Filters, accuracy and score work as in /accounts/leaderboard.")
+	return u
+}
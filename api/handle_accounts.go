@@ -0,0 +1,308 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/serializer"
+	"github.com/marianogappa/predictions/statestorage"
+	"github.com/swaggest/usecase"
+)
+
+type apiResGetAccounts struct {
+	Accounts []serializer.Account `json:"accounts"`
+	_        struct{}             `query:"_" additionalProperties:"false"`
+}
+
+type apiReqGetAccounts struct {
+	Handles  []string `json:"handles" query:"handles" description:"e.g. Datadash"`
+	URLs     []string `json:"urls" query:"urls" description:"e.g. https://twitter.com/CryptoCapo_"`
+	OrderBys []string `json:"orderBys" query:"orderBys" description:"Order in which accounts are returned: ACCOUNT_CREATED_AT_DESC, ACCOUNT_CREATED_AT_ASC or ACCOUNT_FOLLOWER_COUNT_DESC. Defaults to ACCOUNT_FOLLOWER_COUNT_DESC."`
+	Limit    string   `json:"limit" query:"limit" description:"How many accounts to return"`
+	Offset   string   `json:"offset" query:"offset" description:"From which account to return 'limit' accounts"`
+	_        struct{} `query:"_" additionalProperties:"false"`
+}
+
+type apiResGetAccount struct {
+	Account serializer.Account `json:"account"`
+	_       struct{}           `query:"_" additionalProperties:"false"`
+}
+
+type apiReqGetAccount struct {
+	ID string   `path:"id" format:"string" description:"e.g. Datadash, or an escaped url like https%3A%2F%2Ftwitter.com%2FCryptoCapo_"`
+	_  struct{} `query:"_" additionalProperties:"false"`
+}
+
+type apiResRefreshAccounts struct {
+	Refreshed []serializer.Account `json:"refreshed"`
+	Failed    map[string]string    `json:"failed" description:"Error refreshing each account that failed, by account url"`
+	_         struct{}             `query:"_" additionalProperties:"false"`
+}
+
+type apiReqRefreshAccounts struct {
+	Handles []string `json:"handles" description:"e.g. Datadash"`
+	URLs    []string `json:"urls" description:"e.g. https://twitter.com/CryptoCapo_"`
+	_       struct{} `query:"_" additionalProperties:"false"`
+}
+
+type apiResMergeAccounts struct {
+	Account              serializer.Account `json:"account"`
+	RepointedPredictions int                `json:"repointedPredictions"`
+	_                    struct{}           `query:"_" additionalProperties:"false"`
+}
+
+type apiReqMergeAccounts struct {
+	From string   `json:"from" required:"true" description:"Handle or url of the duplicate account, which is deleted, e.g. https://www.youtube.com/channel/UCJgHxpqfhWEEjYH9cLXqhIQ"`
+	Into string   `json:"into" required:"true" description:"Handle or url of the account that is kept, e.g. https://twitter.com/CryptoCapo_"`
+	_    struct{} `query:"_" additionalProperties:"false"`
+}
+
+func (a *API) getAccounts(ctx context.Context, req apiReqGetAccounts) apiResponse[apiResGetAccounts] {
+	for _, orderBy := range req.OrderBys {
+		if _, err := core.APIAccountOrderByFromString(orderBy); err != nil {
+			return failWith(core.ErrUnknownAPIOrderBy, err, apiResGetAccounts{})
+		}
+	}
+
+	limit := 0
+	offset := 0
+	rLimit, err := strconv.Atoi(req.Limit)
+	if err == nil && rLimit > 0 {
+		limit = rLimit
+		rOffset, err := strconv.Atoi(req.Offset)
+		if err == nil && rOffset > 0 {
+			offset = rOffset
+		}
+	}
+
+	accounts, err := a.store.GetAccounts(ctx, core.APIAccountFilters{Handles: req.Handles, URLs: req.URLs}, req.OrderBys, limit, offset)
+	if err != nil {
+		return failWith(core.ErrStorageErrorRetrievingAccounts, err, apiResGetAccounts{})
+	}
+
+	res, err := serializeAccounts(accounts)
+	if err != nil {
+		return failWith(ErrFailedToSerializeAccount, err, apiResGetAccounts{})
+	}
+	return apiResponse[apiResGetAccounts]{Status: 200, Data: apiResGetAccounts{Accounts: res}}
+}
+
+// getAccount returns the account by handle or url. The id comes from the path, so urls are path-escaped.
+func (a *API) getAccount(ctx context.Context, id string) apiResponse[apiResGetAccount] {
+	id, err := url.PathUnescape(id)
+	if err != nil {
+		return failWith(ErrInvalidAccountID, fmt.Errorf("%w: %v", ErrInvalidAccountID, err), apiResGetAccount{})
+	}
+	account, errResp := getAccountByHandleOrURL(ctx, id, a.store, apiResGetAccount{})
+	if errResp != nil {
+		return *errResp
+	}
+
+	res, err := serializer.NewAccountSerializer().PreSerialize(&account)
+	if err != nil {
+		return failWith(ErrFailedToSerializeAccount, err, apiResGetAccount{})
+	}
+	return apiResponse[apiResGetAccount]{Status: 200, Data: apiResGetAccount{Account: res}}
+}
+
+// maxRefreshAccounts is how many accounts can be refreshed at once, as each one is fetched in turn.
+const maxRefreshAccounts = 50
+
+// refreshAccounts refetches the metadata of the accounts through their latest prediction's post, as that's what
+// the MetadataFetcher fetches. Accounts that fail to refresh don't stop the others from refreshing.
+func (a *API) refreshAccounts(ctx context.Context, req apiReqRefreshAccounts) apiResponse[apiResRefreshAccounts] {
+	if len(req.Handles) == 0 && len(req.URLs) == 0 {
+		return failWith(ErrInvalidRefreshAccountsRequest, fmt.Errorf("%w: no handles or urls", ErrInvalidRefreshAccountsRequest), apiResRefreshAccounts{})
+	}
+	accounts, err := a.store.GetAccounts(ctx, core.APIAccountFilters{Handles: req.Handles, URLs: req.URLs}, []string{}, maxRefreshAccounts+1, 0)
+	if err != nil {
+		return failWith(core.ErrStorageErrorRetrievingAccounts, err, apiResRefreshAccounts{})
+	}
+	if len(accounts) > maxRefreshAccounts {
+		return failWith(ErrInvalidRefreshAccountsRequest, fmt.Errorf("%w: more than %v accounts match", ErrInvalidRefreshAccountsRequest, maxRefreshAccounts), apiResRefreshAccounts{})
+	}
+
+	var (
+		refreshed = []*core.Account{}
+		failed    = map[string]string{}
+	)
+	for _, account := range accounts {
+		// e.g. the client went away, so there's no point in fetching the rest.
+		if err := ctx.Err(); err != nil {
+			return failWith(ErrStorageErrorStoringAccount, fmt.Errorf("%w: request cancelled before storing the refreshed accounts: %v", ErrStorageErrorStoringAccount, err), apiResRefreshAccounts{})
+		}
+		accountURL := account.URL.String()
+		preds, err := a.store.GetPredictions(ctx, core.APIFilters{AuthorURLs: []string{accountURL}, IncludeUIUnsupported: true}, []string{core.PredictionsPostedAtDesc.String()}, 1, 0)
+		if err != nil {
+			return failWith(ErrStorageErrorRetrievingPredictions, err, apiResRefreshAccounts{})
+		}
+		if len(preds) == 0 {
+			failed[accountURL] = "account has no predictions to fetch its metadata from"
+			continue
+		}
+		metadata, err := a.mFetcher.Fetch(preds[0].PostURL)
+		if err != nil {
+			failed[accountURL] = fmt.Sprintf("error fetching metadata for url %v: %v", preds[0].PostURL, err)
+			continue
+		}
+		// e.g. the account had a duplicate merged into it, and the latest prediction's post is by the duplicate.
+		if metadata.Author.URL.String() != accountURL {
+			failed[accountURL] = fmt.Sprintf("latest prediction's post %v is by %v, not by this account", preds[0].PostURL, metadata.Author.URL.String())
+			continue
+		}
+		author := metadata.Author
+		refreshed = append(refreshed, &author)
+	}
+
+	if _, err := a.store.UpsertAccounts(ctx, refreshed); err != nil {
+		return failWith(ErrStorageErrorStoringAccount, fmt.Errorf("%w: error storing accounts: %v", ErrStorageErrorStoringAccount, err), apiResRefreshAccounts{})
+	}
+
+	res := []serializer.Account{}
+	for _, account := range refreshed {
+		serialized, err := serializer.NewAccountSerializer().PreSerialize(account)
+		if err != nil {
+			return failWith(ErrFailedToSerializeAccount, err, apiResRefreshAccounts{})
+		}
+		res = append(res, serialized)
+	}
+	return apiResponse[apiResRefreshAccounts]{Status: 200, Data: apiResRefreshAccounts{Refreshed: res, Failed: failed}}
+}
+
+// mergeAccounts re-points the predictions of the duplicate account to the kept one and deletes the duplicate, all at
+// once. The duplicate's url is kept as an alias of the kept account, so new predictions by it go to the kept account
+// and refetching it doesn't bring it back.
+func (a *API) mergeAccounts(ctx context.Context, req apiReqMergeAccounts) apiResponse[apiResMergeAccounts] {
+	from, errResp := getAccountByHandleOrURL(ctx, req.From, a.store, apiResMergeAccounts{})
+	if errResp != nil {
+		return *errResp
+	}
+	into, errResp := getAccountByHandleOrURL(ctx, req.Into, a.store, apiResMergeAccounts{})
+	if errResp != nil {
+		return *errResp
+	}
+	if from.URL.String() == into.URL.String() {
+		return failWith(ErrCannotMergeAccountIntoItself, nil, apiResMergeAccounts{})
+	}
+
+	repointed, err := a.store.MergeAccounts(ctx, from.URL.String(), into)
+	if err != nil {
+		return failWith(ErrStorageErrorStoringAccount, fmt.Errorf("%w: error merging accounts: %v", ErrStorageErrorStoringAccount, err), apiResMergeAccounts{})
+	}
+
+	res, err := serializer.NewAccountSerializer().PreSerialize(&into)
+	if err != nil {
+		return failWith(ErrFailedToSerializeAccount, err, apiResMergeAccounts{})
+	}
+	return apiResponse[apiResMergeAccounts]{Status: 200, Data: apiResMergeAccounts{Account: res, RepointedPredictions: repointed}}
+}
+
+func (a *API) apiGetAccounts() usecase.Interactor {
+	u := usecase.NewInteractor(func(ctx context.Context, input apiReqGetAccounts, output *apiResponse[apiResGetAccounts]) error {
+		out := a.getAccounts(ctx, input)
+		*output = out
+		return nil
+	})
+	u.SetTags("Account")
+	u.SetTitle("Returns an ordered array of accounts based on the configured filters and ordering.")
+	u.SetDescription("If no query string is provided, all accounts are returned, by follower count descending.")
+	return u
+}
+
+func (a *API) apiGetAccount() usecase.Interactor {
+	u := usecase.NewInteractor(func(ctx context.Context, input apiReqGetAccount, output *apiResponse[apiResGetAccount]) error {
+		out := a.getAccount(ctx, input.ID)
+		*output = out
+		return nil
+	})
+	u.SetTags("Account")
+	u.SetTitle("Returns an account by its handle or url.")
+	u.SetDescription("Handles are not unique across social networks, so if more than one account has the handle, use its url instead. Urls must be path-escaped, e.g. https%3A%2F%2Ftwitter.com%2FCryptoCapo_.")
+	return u
+}
+
+func (a *API) apiRefreshAccounts() usecase.Interactor {
+	u := usecase.NewInteractor(func(ctx context.Context, input apiReqRefreshAccounts, output *apiResponse[apiResRefreshAccounts]) error {
+		out := a.refreshAccounts(ctx, input)
+		*output = out
+		return nil
+	})
+	u.SetTags("Account")
+	u.SetTitle("Refetch the metadata (e.g. name, thumbnails, isVerified, followerCount) of many social media Accounts.")
+	u.SetDescription("Refreshes the accounts with any of the handles or urls, which are required, up to 50 accounts at once. Metadata is fetched from each account's latest prediction post, so accounts without predictions can't be refreshed.")
+	return u
+}
+
+func (a *API) apiMergeAccounts() usecase.Interactor {
+	u := usecase.NewInteractor(func(ctx context.Context, input apiReqMergeAccounts, output *apiResponse[apiResMergeAccounts]) error {
+		out := a.mergeAccounts(ctx, input)
+		*output = out
+		return nil
+	})
+	u.SetTags("Account")
+	u.SetTitle("Merge a duplicate account into another one, e.g. the Twitter & YouTube accounts of the same person.")
+	u.SetDescription("The duplicate account's predictions are re-pointed to the kept account (i.e. their postAuthor & postAuthorURL change), and the duplicate account is deleted.")
+	return u
+}
+
+// getAccountByHandleOrURL returns the only account with the id as url if it looks like one, or as handle otherwise.
+func getAccountByHandleOrURL[D any](ctx context.Context, id string, store statestorage.StateStorage, zero D) (core.Account, *apiResponse[D]) {
+	filters := core.APIAccountFilters{Handles: []string{id}}
+	if strings.HasPrefix(id, "http") {
+		filters = core.APIAccountFilters{URLs: []string{id}}
+	}
+	accounts, err := store.GetAccounts(ctx, filters, []string{}, 0, 0)
+	if err != nil {
+		errResp := failWith(core.ErrStorageErrorRetrievingAccounts, err, zero)
+		return core.Account{}, &errResp
+	}
+	if len(accounts) == 0 {
+		errResp := failWith(ErrAccountNotFound, fmt.Errorf("%w: %v", ErrAccountNotFound, id), zero)
+		return core.Account{}, &errResp
+	}
+	if len(accounts) != 1 {
+		errResp := failWith(ErrAmbiguousAccountHandle, fmt.Errorf("%w: %v accounts have handle %v", ErrAmbiguousAccountHandle, len(accounts), id), zero)
+		return core.Account{}, &errResp
+	}
+	return accounts[0], nil
+}
+
+// resolveAccountAlias makes the prediction's author the account that its author was merged into, if it was.
+func resolveAccountAlias(ctx context.Context, pred *core.Prediction, store statestorage.StateStorage) error {
+	if pred.PostAuthorURL == "" {
+		return nil
+	}
+	aliases, err := store.GetAccountAliases(ctx, []string{pred.PostAuthorURL})
+	if err != nil {
+		return err
+	}
+	aliasOf, ok := aliases[pred.PostAuthorURL]
+	if !ok {
+		return nil
+	}
+	accounts, err := store.GetAccounts(ctx, core.APIAccountFilters{URLs: []string{aliasOf}}, []string{}, 0, 0)
+	if err != nil {
+		return err
+	}
+	pred.PostAuthorURL = aliasOf
+	if len(accounts) > 0 {
+		pred.PostAuthor = accounts[0].Handle
+	}
+	return nil
+}
+
+func serializeAccounts(accounts []core.Account) ([]serializer.Account, error) {
+	res := []serializer.Account{}
+	for i := range accounts {
+		account, err := serializer.NewAccountSerializer().PreSerialize(&accounts[i])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, account)
+	}
+	return res, nil
+}
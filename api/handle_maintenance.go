@@ -25,19 +25,22 @@ type apiReqMaintenance struct {
 	_      struct{} `query:"_" additionalProperties:"false"`
 }
 
-func (a *API) maintenance(req apiReqMaintenance) apiResponse[apiResMaintenance] {
+func (a *API) maintenance(ctx context.Context, req apiReqMaintenance) apiResponse[apiResMaintenance] {
 	switch req.Action {
 	case "ensureAllPredictionsHavePostAuthorURL":
-		return a.ensureAllPredictionsHavePostAuthorURL(req)
+		return a.ensureAllPredictionsHavePostAuthorURL(ctx, req)
 	case "recalculatePredictionTypeOnAllPredictions":
-		return a.recalculatePredictionTypeOnAllPredictions(req)
+		return a.recalculatePredictionTypeOnAllPredictions(ctx, req)
+	case "calculateDifficultyOnCorrectPredictions":
+		return a.calculateDifficultyOnCorrectPredictions(ctx, req)
 	default:
 		return apiResponse[apiResMaintenance]{Status: 400, Data: apiResMaintenance{Success: false, Message: "action does not exist"}}
 	}
 }
 
-func (a *API) ensureAllPredictionsHavePostAuthorURL(req apiReqMaintenance) apiResponse[apiResMaintenance] {
+func (a *API) ensureAllPredictionsHavePostAuthorURL(ctx context.Context, req apiReqMaintenance) apiResponse[apiResMaintenance] {
 	preds, err := a.store.GetPredictions(
+		ctx,
 		core.APIFilters{},
 		[]string{},
 		0, 0,
@@ -71,10 +74,14 @@ func (a *API) ensureAllPredictionsHavePostAuthorURL(req apiReqMaintenance) apiRe
 			return failWith(ErrFailedToCompilePrediction, fmt.Errorf("%w: metadata fetcher could not resolve postAuthorURL from postURL: %v", ErrFailedToCompilePrediction, pred.PostURL), apiResMaintenance{})
 		}
 
+		if err := resolveAccountAlias(ctx, &newPred, a.store); err != nil {
+			return failWith(core.ErrStorageErrorRetrievingAccounts, err, apiResMaintenance{})
+		}
+
 		predsToUpdate = append(predsToUpdate, &newPred)
 	}
 
-	_, err = a.store.UpsertPredictions(predsToUpdate)
+	_, err = a.store.UpsertPredictions(ctx, predsToUpdate)
 	if err != nil {
 		return failWith(ErrStorageErrorStoringPrediction, fmt.Errorf("%w: failed to upsert predictions: %v", ErrStorageErrorStoringPrediction, err), apiResMaintenance{})
 	}
@@ -84,8 +91,8 @@ func (a *API) ensureAllPredictionsHavePostAuthorURL(req apiReqMaintenance) apiRe
 	return apiResponse[apiResMaintenance]{Status: 200, Data: apiResMaintenance{Success: true, Message: msg}}
 }
 
-func (a *API) recalculatePredictionTypeOnAllPredictions(req apiReqMaintenance) apiResponse[apiResMaintenance] {
-	scanner := statestorage.NewAllPredictionsScanner(a.store)
+func (a *API) recalculatePredictionTypeOnAllPredictions(ctx context.Context, req apiReqMaintenance) apiResponse[apiResMaintenance] {
+	scanner := statestorage.NewAllPredictionsScanner(ctx, a.store)
 	var fixedCount, totalCount int
 
 	var prediction core.Prediction
@@ -98,7 +105,7 @@ func (a *API) recalculatePredictionTypeOnAllPredictions(req apiReqMaintenance) a
 
 		log.Info().Msgf("Changing prediction %v from %v to %v", prediction.PostURL, prediction.Type, predType)
 		prediction.Type = predType
-		if _, err := a.store.UpsertPredictions([]*core.Prediction{&prediction}); err != nil {
+		if _, err := a.store.UpsertPredictions(ctx, []*core.Prediction{&prediction}); err != nil {
 			return failWith(ErrStorageErrorStoringPrediction, fmt.Errorf("%w: failed to upsert predictions: %v", ErrStorageErrorStoringPrediction, err), apiResMaintenance{})
 		}
 		fixedCount++
@@ -111,9 +118,43 @@ func (a *API) recalculatePredictionTypeOnAllPredictions(req apiReqMaintenance) a
 	return apiResponse[apiResMaintenance]{Status: 200, Data: apiResMaintenance{Success: true, Message: msg}}
 }
 
+// calculateDifficultyOnCorrectPredictions stores the difficulty of the CORRECT predictions that became CORRECT before
+// the Daemon started storing it.
+func (a *API) calculateDifficultyOnCorrectPredictions(ctx context.Context, req apiReqMaintenance) apiResponse[apiResMaintenance] {
+	scanner := statestorage.NewAllPredictionsScanner(ctx, a.store)
+	var fixedCount, totalCount int
+
+	var (
+		prediction core.Prediction
+		priceAt    = core.NewMarketPriceFunc(a.mkt)
+	)
+	for scanner.Scan(&prediction) {
+		if prediction.State.Value != core.CORRECT {
+			continue
+		}
+		totalCount++
+		difficulty := prediction.Difficulty(priceAt)
+		if difficulty == prediction.State.Difficulty {
+			continue
+		}
+
+		prediction.State.Difficulty = difficulty
+		if _, err := a.store.UpsertPredictions(ctx, []*core.Prediction{&prediction}); err != nil {
+			return failWith(ErrStorageErrorStoringPrediction, fmt.Errorf("%w: failed to upsert predictions: %v", ErrStorageErrorStoringPrediction, err), apiResMaintenance{})
+		}
+		fixedCount++
+	}
+	if scanner.Error != nil {
+		return failWith(ErrStorageErrorRetrievingPredictions, fmt.Errorf("%w: failed to retrieve predictions: %v", ErrStorageErrorRetrievingPredictions, scanner.Error), apiResMaintenance{})
+	}
+	msg := fmt.Sprintf("Stored the difficulty of %v out of %v CORRECT predictions!", fixedCount, totalCount)
+
+	return apiResponse[apiResMaintenance]{Status: 200, Data: apiResMaintenance{Success: true, Message: msg}}
+}
+
 func (a *API) apiMaintenance() usecase.Interactor {
 	u := usecase.NewInteractor(func(ctx context.Context, input apiReqMaintenance, output *apiResponse[apiResMaintenance]) error {
-		out := a.maintenance(input)
+		out := a.maintenance(ctx, input)
 		*output = out
 		return nil
 	})
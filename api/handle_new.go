@@ -27,7 +27,7 @@ type apiResPostPrediction struct {
 
 func (a *API) apiPostPrediction() usecase.Interactor {
 	u := usecase.NewInteractor(func(ctx context.Context, input apiReqPostPrediction, output *apiResponse[apiResPostPrediction]) error {
-		out := a.postPrediction(input)
+		out := a.postPrediction(ctx, input)
 		*output = out
 		return nil
 	})
@@ -44,12 +44,12 @@ func (a *API) apiPostPrediction() usecase.Interactor {
 	return u
 }
 
-func (a *API) postPrediction(req apiReqPostPrediction) apiResponse[apiResPostPrediction] {
+func (a *API) postPrediction(ctx context.Context, req apiReqPostPrediction) apiResponse[apiResPostPrediction] {
 	if a.debug {
 		log.Info().Msgf("API.postPrediction: with request: %+v", req)
 	}
 
-	pc := compiler.NewPredictionCompiler(&a.mFetcher, a.NowFunc)
+	pc := compiler.NewPredictionCompiler(&a.mFetcher, a.NowFunc, compiler.WithMarket(a.mkt))
 	pred, account, err := pc.Compile([]byte(req.Prediction))
 	if err != nil {
 		return failWith(ErrInvalidRequestJSON, err, apiResPostPrediction{})
@@ -59,7 +59,7 @@ func (a *API) postPrediction(req apiReqPostPrediction) apiResponse[apiResPostPre
 	if pred.State == (core.PredictionState{}) {
 		predRunner, errs := daemon.NewPredEvolver(&pred, a.mkt, int(a.NowFunc().Unix()))
 		if len(errs) == 0 {
-			predRunnerErrs := predRunner.Run(true)
+			predRunnerErrs := predRunner.Run(ctx, true)
 			for _, err := range predRunnerErrs {
 				if errors.Is(err, common.ErrInvalidMarketPair) {
 					return failWith(common.ErrInvalidMarketPair, err, apiResPostPrediction{})
@@ -75,14 +75,18 @@ func (a *API) postPrediction(req apiReqPostPrediction) apiResponse[apiResPostPre
 	}
 
 	if req.Store {
+		if err := resolveAccountAlias(ctx, &pred, a.store); err != nil {
+			return failWith(core.ErrStorageErrorRetrievingAccounts, err, apiResPostPrediction{})
+		}
+
 		// N.B. as per interface, UpsertPredictions may add UUIDs in-place on predictions
-		_, err = a.store.UpsertPredictions([]*core.Prediction{&pred})
+		_, err = a.store.UpsertPredictions(ctx, []*core.Prediction{&pred})
 		if err != nil {
 			return failWith(ErrStorageErrorStoringPrediction, err, apiResPostPrediction{})
 		}
 
 		if account != nil {
-			_, err := a.store.UpsertAccounts([]*core.Account{account})
+			_, err := a.store.UpsertAccounts(ctx, []*core.Account{account})
 			if err != nil {
 				return failWith(ErrStorageErrorStoringPrediction, err, apiResPostPrediction{})
 			}
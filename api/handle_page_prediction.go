@@ -27,7 +27,7 @@ type apiReqGetPagesPrediction struct {
 	_  struct{} `query:"_" additionalProperties:"false"`
 }
 
-func (a *API) getPagesPrediction(id string) apiResponse[apiResGetPagesPrediction] {
+func (a *API) getPagesPrediction(ctx context.Context, id string) apiResponse[apiResGetPagesPrediction] {
 	// Support both urls and UUIDs as id
 	var url, uuid string
 	if strings.HasPrefix(id, "http") {
@@ -36,7 +36,7 @@ func (a *API) getPagesPrediction(id string) apiResponse[apiResGetPagesPrediction
 		uuid = id
 	}
 
-	pred, errResp := getPredictionByUUIDOrURL(uuid, url, a.store, apiResGetPagesPrediction{})
+	pred, errResp := getPredictionByUUIDOrURL(ctx, uuid, url, a.store, apiResGetPagesPrediction{})
 	if errResp != nil {
 		return *errResp
 	}
@@ -49,21 +49,21 @@ func (a *API) getPagesPrediction(id string) apiResponse[apiResGetPagesPrediction
 	predictionsByUUID[UUID(mainCompilerPred.UUID)] = mainCompilerPred
 
 	// Latest Predictions
-	predictions, err := a.store.GetPredictions(core.APIFilters{}, []string{core.PredictionsPostedAtDesc.String()}, 10, 0)
+	predictions, err := a.store.GetPredictions(ctx, core.APIFilters{}, []string{core.PredictionsPostedAtDesc.String()}, 10, 0)
 	latestPredictionUUIDs, predictionsByUUID, errResp := collectPredictions(predictions, err, predictionsByUUID, mainCompilerPred)
 	if errResp != nil {
 		return *errResp
 	}
 
 	// Latest Predictions by same author URL
-	predictions, err = a.store.GetPredictions(core.APIFilters{AuthorURLs: []string{pred.PostAuthorURL}}, []string{core.PredictionsPostedAtDesc.String()}, 5, 0)
+	predictions, err = a.store.GetPredictions(ctx, core.APIFilters{AuthorURLs: []string{pred.PostAuthorURL}}, []string{core.PredictionsPostedAtDesc.String()}, 5, 0)
 	latestPredictionSameAuthorURL, predictionsByUUID, errResp := collectPredictions(predictions, err, predictionsByUUID, mainCompilerPred)
 	if errResp != nil {
 		return *errResp
 	}
 
 	// Latest Predictions by same coin
-	predictions, err = a.store.GetPredictions(core.APIFilters{Tags: []string{pred.CalculateMainCoin().Str}}, []string{core.PredictionsPostedAtDesc.String()}, 5, 0)
+	predictions, err = a.store.GetPredictions(ctx, core.APIFilters{Tags: []string{pred.CalculateMainCoin().Str}}, []string{core.PredictionsPostedAtDesc.String()}, 5, 0)
 	latestPredictionSameCoinUUID, predictionsByUUID, errResp := collectPredictions(predictions, err, predictionsByUUID, mainCompilerPred)
 	if errResp != nil {
 		return *errResp
@@ -72,7 +72,7 @@ func (a *API) getPagesPrediction(id string) apiResponse[apiResGetPagesPrediction
 	accountURLSet := map[URL]struct{}{}
 
 	// Get the top 10 Accounts by follower count
-	topAccounts, err := a.store.GetAccounts(core.APIAccountFilters{}, []string{core.AccountFollowerCountDesc.String()}, 10, 0)
+	topAccounts, err := a.store.GetAccounts(ctx, core.APIAccountFilters{}, []string{core.AccountFollowerCountDesc.String()}, 10, 0)
 	if err != nil {
 		return failWith(core.ErrStorageErrorRetrievingAccounts, err, apiResGetPagesPrediction{})
 	}
@@ -98,7 +98,7 @@ func (a *API) getPagesPrediction(id string) apiResponse[apiResGetPagesPrediction
 	}
 
 	// Get all accounts from the slice
-	allAccounts, err := a.store.GetAccounts(core.APIAccountFilters{URLs: accountURLs}, []string{}, 0, 0)
+	allAccounts, err := a.store.GetAccounts(ctx, core.APIAccountFilters{URLs: accountURLs}, []string{}, 0, 0)
 	if err != nil {
 		return failWith(core.ErrStorageErrorRetrievingAccounts, err, apiResGetPagesPrediction{})
 	}
@@ -154,7 +154,7 @@ func collectPredictions(
 
 func (a *API) apiGetPagesPrediction() usecase.Interactor {
 	u := usecase.NewInteractor(func(ctx context.Context, input apiReqGetPagesPrediction, output *apiResponse[apiResGetPagesPrediction]) error {
-		out := a.getPagesPrediction(input.ID)
+		out := a.getPagesPrediction(ctx, input.ID)
 		*output = out
 		return nil
 	})
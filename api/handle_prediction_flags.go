@@ -18,21 +18,21 @@ type apiResStored struct {
 	_      struct{} `query:"_" additionalProperties:"false"`
 }
 
-func (a *API) predictionStorageActionWithUUID(uuid string, fn func(string) error) apiResponse[apiResStored] {
-	pred, errResp := getPredictionByUUID(uuid, a.store, apiResStored{})
+func (a *API) predictionStorageActionWithUUID(ctx context.Context, uuid string, fn func(context.Context, string) error) apiResponse[apiResStored] {
+	pred, errResp := getPredictionByUUID(ctx, uuid, a.store, apiResStored{})
 	if errResp != nil {
 		return *errResp
 	}
 
-	if err := fn(pred.UUID); err != nil {
+	if err := fn(ctx, pred.UUID); err != nil {
 		return failWith(ErrFailedToCompilePrediction, err, apiResStored{})
 	}
 	return apiResponse[apiResStored]{Status: 200, Data: apiResStored{Stored: true}}
 }
 
-func (a *API) apiPredictionStorageActionWithUUID(fn func(string) error, title string) usecase.Interactor {
+func (a *API) apiPredictionStorageActionWithUUID(fn func(context.Context, string) error, title string) usecase.Interactor {
 	u := usecase.NewInteractor(func(ctx context.Context, input apiReqUUIDPath, output *apiResponse[apiResStored]) error {
-		out := a.predictionStorageActionWithUUID(input.UUID, fn)
+		out := a.predictionStorageActionWithUUID(ctx, input.UUID, fn)
 		*output = out
 		return nil
 	})
@@ -44,7 +44,7 @@ func (a *API) apiPredictionStorageActionWithUUID(fn func(string) error, title st
 
 func (a *API) apiPredictionRefetchAccount() usecase.Interactor {
 	u := usecase.NewInteractor(func(ctx context.Context, input apiReqUUIDPath, output *apiResponse[apiResStored]) error {
-		out := a.predictionRefetchAccount(input.UUID)
+		out := a.predictionRefetchAccount(ctx, input.UUID)
 		*output = out
 		return nil
 	})
@@ -55,8 +55,8 @@ func (a *API) apiPredictionRefetchAccount() usecase.Interactor {
 	return u
 }
 
-func (a *API) predictionRefetchAccount(uuid string) apiResponse[apiResStored] {
-	pred, errResp := getPredictionByUUID(uuid, a.store, apiResStored{})
+func (a *API) predictionRefetchAccount(ctx context.Context, uuid string) apiResponse[apiResStored] {
+	pred, errResp := getPredictionByUUID(ctx, uuid, a.store, apiResStored{})
 	if errResp != nil {
 		return *errResp
 	}
@@ -66,7 +66,16 @@ func (a *API) predictionRefetchAccount(uuid string) apiResponse[apiResStored] {
 		return failWith(ErrFailedToCompilePrediction, fmt.Errorf("%w: error fetching metadata for url: %v", ErrFailedToCompilePrediction, pred.PostURL), apiResStored{})
 	}
 
-	if _, err := a.store.UpsertAccounts([]*core.Account{&metadata.Author}); err != nil {
+	// Accounts merged into other accounts aren't stored (see mergeAccounts).
+	aliases, err := a.store.GetAccountAliases(ctx, []string{metadata.Author.URL.String()})
+	if err != nil {
+		return failWith(core.ErrStorageErrorRetrievingAccounts, err, apiResStored{})
+	}
+	if _, ok := aliases[metadata.Author.URL.String()]; ok {
+		return apiResponse[apiResStored]{Status: 200, Data: apiResStored{Stored: false}}
+	}
+
+	if _, err := a.store.UpsertAccounts(ctx, []*core.Account{&metadata.Author}); err != nil {
 		return failWith(ErrStorageErrorStoringAccount, fmt.Errorf("%w: error storing account: %v", ErrStorageErrorStoringAccount, err), apiResStored{})
 	}
 
@@ -75,7 +84,7 @@ func (a *API) predictionRefetchAccount(uuid string) apiResponse[apiResStored] {
 
 func (a *API) apiPredictionClearState() usecase.Interactor {
 	u := usecase.NewInteractor(func(ctx context.Context, input apiReqUUIDPath, output *apiResponse[apiResStored]) error {
-		out := a.predictionClearState(input.UUID)
+		out := a.predictionClearState(ctx, input.UUID)
 		*output = out
 		return nil
 	})
@@ -85,32 +94,32 @@ func (a *API) apiPredictionClearState() usecase.Interactor {
 	return u
 }
 
-func (a *API) predictionClearState(uuid string) apiResponse[apiResStored] {
-	pred, errResp := getPredictionByUUID(uuid, a.store, apiResStored{})
+func (a *API) predictionClearState(ctx context.Context, uuid string) apiResponse[apiResStored] {
+	pred, errResp := getPredictionByUUID(ctx, uuid, a.store, apiResStored{})
 	if errResp != nil {
 		return *errResp
 	}
 
 	pred.ClearState()
-	if _, err := a.store.UpsertPredictions([]*core.Prediction{&pred}); err != nil {
+	if _, err := a.store.UpsertPredictions(ctx, []*core.Prediction{&pred}); err != nil {
 		return failWith(ErrStorageErrorStoringPrediction, fmt.Errorf("%w: error storing prediction: %v", ErrStorageErrorStoringPrediction, err), apiResStored{})
 	}
 	return apiResponse[apiResStored]{Status: 200, Data: apiResStored{Stored: true}}
 }
 
-func getPredictionByUUIDOrURL[D any](uuid, url string, store statestorage.StateStorage, zero D) (core.Prediction, *apiResponse[D]) {
+func getPredictionByUUIDOrURL[D any](ctx context.Context, uuid, url string, store statestorage.StateStorage, zero D) (core.Prediction, *apiResponse[D]) {
 	if uuid != "" {
-		return getPredictionByFilter(core.APIFilters{UUIDs: []string{uuid}, URLs: []string{}}, store, zero)
+		return getPredictionByFilter(ctx, core.APIFilters{UUIDs: []string{uuid}, URLs: []string{}}, store, zero)
 	}
-	return getPredictionByFilter(core.APIFilters{UUIDs: []string{}, URLs: []string{url}}, store, zero)
+	return getPredictionByFilter(ctx, core.APIFilters{UUIDs: []string{}, URLs: []string{url}}, store, zero)
 }
 
-func getPredictionByUUID[D any](uuid string, store statestorage.StateStorage, zero D) (core.Prediction, *apiResponse[D]) {
-	return getPredictionByFilter(core.APIFilters{UUIDs: []string{uuid}}, store, zero)
+func getPredictionByUUID[D any](ctx context.Context, uuid string, store statestorage.StateStorage, zero D) (core.Prediction, *apiResponse[D]) {
+	return getPredictionByFilter(ctx, core.APIFilters{UUIDs: []string{uuid}}, store, zero)
 }
 
-func getPredictionByFilter[D any](filter core.APIFilters, store statestorage.StateStorage, zero D) (core.Prediction, *apiResponse[D]) {
-	ps, err := store.GetPredictions(filter, nil, 0, 0)
+func getPredictionByFilter[D any](ctx context.Context, filter core.APIFilters, store statestorage.StateStorage, zero D) (core.Prediction, *apiResponse[D]) {
+	ps, err := store.GetPredictions(ctx, filter, nil, 0, 0)
 	if err != nil {
 		errResp := failWith(ErrPredictionNotFound, err, zero)
 		return core.Prediction{}, &errResp
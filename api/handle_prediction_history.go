@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+
+	"github.com/marianogappa/predictions/core"
+	"github.com/swaggest/usecase"
+)
+
+type apiPredictionStateValueChange struct {
+	StateValue     string               `json:"stateValue"`
+	ConditionName  string               `json:"conditionName,omitempty"`
+	ConditionValue string               `json:"conditionValue,omitempty"`
+	Ticks          map[string]core.Tick `json:"ticks,omitempty"`
+	CreatedAt      core.ISO8601         `json:"createdAt"`
+}
+
+type apiResGetPredictionHistory struct {
+	History []apiPredictionStateValueChange `json:"history"`
+	_       struct{}                        `query:"_" additionalProperties:"false"`
+}
+
+func (a *API) getPredictionHistory(ctx context.Context, uuid string) apiResponse[apiResGetPredictionHistory] {
+	pred, errResp := getPredictionByUUID(ctx, uuid, a.store, apiResGetPredictionHistory{})
+	if errResp != nil {
+		return *errResp
+	}
+
+	changes, err := a.store.GetPredictionStateValueChanges(ctx, pred.UUID)
+	if err != nil {
+		return failWith(ErrStorageErrorRetrievingPredictions, err, apiResGetPredictionHistory{})
+	}
+
+	history := []apiPredictionStateValueChange{}
+	for _, change := range changes {
+		history = append(history, apiPredictionStateValueChange{
+			StateValue:     change.StateValue,
+			ConditionName:  change.ConditionName,
+			ConditionValue: change.ConditionValue,
+			Ticks:          change.Ticks,
+			CreatedAt:      change.CreatedAt,
+		})
+	}
+	return apiResponse[apiResGetPredictionHistory]{Status: 200, Data: apiResGetPredictionHistory{History: history}}
+}
+
+func (a *API) apiGetPredictionHistory() usecase.Interactor {
+	u := usecase.NewInteractor(func(ctx context.Context, input apiReqUUIDPath, output *apiResponse[apiResGetPredictionHistory]) error {
+		out := a.getPredictionHistory(ctx, input.UUID)
+		*output = out
+		return nil
+	})
+	u.SetTags("Prediction")
+	u.SetTitle("Returns every change of value of a prediction, oldest first.")
+	u.SetDescription("Each change has the condition whose change of value triggered it (e.g. main became TRUE) and the ticks it changed on, except for the prediction's initial value. A prediction may change to the same value many times, e.g. when its state is cleared.")
+	return u
+}
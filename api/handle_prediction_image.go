@@ -18,8 +18,9 @@ type apiReqGetPredictionImage struct {
 	_    struct{} `query:"_" additionalProperties:"false"`
 }
 
-func (a *API) getPredictionImage(uuid string) apiResponse[apiResGetPredictionImage] {
+func (a *API) getPredictionImage(ctx context.Context, uuid string) apiResponse[apiResGetPredictionImage] {
 	preds, err := a.store.GetPredictions(
+		ctx,
 		core.APIFilters{UUIDs: []string{uuid}},
 		[]string{},
 		0, 0,
@@ -35,7 +36,7 @@ func (a *API) getPredictionImage(uuid string) apiResponse[apiResGetPredictionIma
 		return failWith(core.ErrStorageErrorRetrievingAccounts, errors.New("prediction has no postAuthorURL"), apiResGetPredictionImage{})
 	}
 
-	accounts, err := a.store.GetAccounts(core.APIAccountFilters{URLs: []string{pred.PostAuthorURL}}, []string{}, 0, 0)
+	accounts, err := a.store.GetAccounts(ctx, core.APIAccountFilters{URLs: []string{pred.PostAuthorURL}}, []string{}, 0, 0)
 	if err != nil {
 		return failWith(core.ErrStorageErrorRetrievingAccounts, err, apiResGetPredictionImage{})
 	}
@@ -56,7 +57,7 @@ func (a *API) getPredictionImage(uuid string) apiResponse[apiResGetPredictionIma
 
 func (a *API) apiGetPredictionImage() usecase.Interactor {
 	u := usecase.NewInteractor(func(ctx context.Context, input apiReqGetPredictionImage, output *apiResponse[apiResGetPredictionImage]) error {
-		out := a.getPredictionImage(input.UUID)
+		out := a.getPredictionImage(ctx, input.UUID)
 		*output = out
 		return nil
 	})
@@ -19,7 +19,7 @@ import (
 )
 
 var (
-	store         statestorage.StateStorage
+	store         *statestorage.PostgresDBStateStorage
 	mainTestStore = "test_predictions"
 	apiTestStore  = "test_predictions_api"
 )
@@ -34,7 +34,7 @@ func setupTestDB(t *testing.T) statestorage.StateStorage {
 	return store
 }
 
-func connectToTestStore(t *testing.T, databaseName string) statestorage.StateStorage {
+func connectToTestStore(t *testing.T, databaseName string) *statestorage.PostgresDBStateStorage {
 	if !strings.Contains(databaseName, "test_") {
 		log.Error().Msgf("I'm not gonna let you connect to a non-test database!")
 		return nil
@@ -62,11 +62,7 @@ func connectToTestStore(t *testing.T, databaseName string) statestorage.StateSto
 			return nil
 		}
 	}
-	return castStore(&_store)
-}
-
-func castStore(s *statestorage.PostgresDBStateStorage) statestorage.StateStorage {
-	return s
+	return &_store
 }
 
 func mustTruncateTables(t *testing.T, db *sql.DB) {
@@ -75,6 +71,8 @@ func mustTruncateTables(t *testing.T, db *sql.DB) {
 		"prediction_state_value_change",
 		"accounts",
 		"prediction_interactions",
+		"exchange_failovers",
+		"account_aliases",
 	}
 	for _, table := range tables {
 		if _, err := db.Exec(fmt.Sprintf("TRUNCATE TABLE %v", table)); err != nil {
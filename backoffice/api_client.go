@@ -98,6 +98,22 @@ func (c apiClient) predictionImage(body predictionImageBody) parsedResponse {
 	return request.MakeRequest(reqData, c.debug)
 }
 
+func (c apiClient) predictionHistory(uuid string) parsedResponse {
+	reqData := request.Request[response, parsedResponse]{
+		HTTPMethod:    "GET",
+		BaseURL:       c.apiURL,
+		Path:          fmt.Sprintf("predictions/%v/history", uuid),
+		QueryString:   nil,
+		Body:          nil,
+		ParseResponse: parseResponse,
+		ParseError:    parseError,
+		BasicAuthUser: c.basicAuthUser,
+		BasicAuthPass: c.basicAuthPass,
+	}
+
+	return request.MakeRequest(reqData, c.debug)
+}
+
 func (c apiClient) pausePrediction(uuid string) parsedResponse {
 	reqData := request.Request[response, parsedResponse]{
 		HTTPMethod:    "POST",
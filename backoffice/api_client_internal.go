@@ -75,11 +75,12 @@ type response struct {
 }
 
 type responseData struct {
-	Prediction        *json.RawMessage   `json:"prediction,omitempty"`
-	Predictions       *[]json.RawMessage `json:"predictions,omitempty"`
-	PredictionSummary *json.RawMessage   `json:"predictionSummary,omitempty"`
-	Stored            *bool              `json:"stored,omitempty"`
-	Base64Image       *string            `json:"base64Image,omitempty"`
+	Prediction        *json.RawMessage    `json:"prediction,omitempty"`
+	Predictions       *[]json.RawMessage  `json:"predictions,omitempty"`
+	PredictionSummary *json.RawMessage    `json:"predictionSummary,omitempty"`
+	Stored            *bool               `json:"stored,omitempty"`
+	Base64Image       *string             `json:"base64Image,omitempty"`
+	History           *[]stateValueChange `json:"history,omitempty"`
 }
 
 func (r response) parse() parsedResponse {
@@ -123,6 +124,7 @@ func (r response) parse() parsedResponse {
 		PredictionSummary:    predSummary,
 		Stored:               stored,
 		Base64Image:          base64Image,
+		History:              r.Data.History,
 	}
 }
 
@@ -133,6 +135,15 @@ type predictionSummary struct {
 	Operator string                 `json:"operator"`
 	Deadline core.ISO8601           `json:"deadline"`
 }
+
+type stateValueChange struct {
+	StateValue     string               `json:"stateValue"`
+	ConditionName  string               `json:"conditionName"`
+	ConditionValue string               `json:"conditionValue"`
+	Ticks          map[string]core.Tick `json:"ticks"`
+	CreatedAt      core.ISO8601         `json:"createdAt"`
+}
+
 type parsedResponse struct {
 	Status               int
 	ErrorMessage         string
@@ -143,6 +154,7 @@ type parsedResponse struct {
 	PredictionSummary    *predictionSummary
 	Stored               *bool
 	Base64Image          *string
+	History              *[]stateValueChange
 }
 
 func (r parsedResponse) String() string {
@@ -78,11 +78,23 @@ func mapifyCondition(c *core.Condition) map[string]interface{} {
 		lastTicks[k] = mapifyLastTick(lt)
 	}
 
+	decidingTicks := []map[string]interface{}{}
+	for _, dt := range c.State.DecidingTicks {
+		decidingTicks = append(decidingTicks, mapifyDecidingTick(dt))
+	}
+
 	state := map[string]interface{}{
-		"Status":    c.State.Status.String(),
-		"LastTs":    c.State.LastTs,
-		"LastTicks": lastTicks,
-		"Value":     c.State.Value.String(),
+		"Status":        c.State.Status.String(),
+		"LastTs":        c.State.LastTs,
+		"LastTicks":     lastTicks,
+		"Value":         c.State.Value.String(),
+		"StalledRuns":   c.State.StalledRuns,
+		"DecidingTicks": decidingTicks,
+	}
+
+	failovers := []map[string]interface{}{}
+	for _, f := range c.Failovers {
+		failovers = append(failovers, mapifyExchangeFailover(f))
 	}
 
 	return map[string]interface{}{
@@ -95,6 +107,53 @@ func mapifyCondition(c *core.Condition) map[string]interface{} {
 		"Assumed":          c.Assumed,
 		"State":            state,
 		"ErrorMarginRatio": fmt.Sprintf("%v", c.ErrorMarginRatio),
+		"Baseline":         fmt.Sprintf("%v", c.Baseline),
+		"Failovers":        failovers,
+	}
+}
+
+func mapifyExchangeFailover(f core.ExchangeFailover) map[string]interface{} {
+	createdAt := string(f.CreatedAt)
+	if t, err := f.CreatedAt.Time(); err == nil {
+		createdAt = t.Format(time.RFC850)
+	}
+	return map[string]interface{}{
+		"From":      f.FromOperand,
+		"To":        f.ToOperand,
+		"Reason":    f.Reason,
+		"CreatedAt": createdAt,
+	}
+}
+
+func historyToMaps(history []stateValueChange) []map[string]interface{} {
+	res := []map[string]interface{}{}
+	for _, c := range history {
+		createdAt := string(c.CreatedAt)
+		if t, err := c.CreatedAt.Time(); err == nil {
+			createdAt = t.Format(time.RFC850)
+		}
+		ticks := map[string]map[string]interface{}{}
+		for k, t := range c.Ticks {
+			ticks[k] = mapifyLastTick(t)
+		}
+		res = append(res, map[string]interface{}{
+			"StateValue":     c.StateValue,
+			"ConditionName":  c.ConditionName,
+			"ConditionValue": c.ConditionValue,
+			"Ticks":          ticks,
+			"CreatedAt":      createdAt,
+		})
+	}
+	return res
+}
+
+func mapifyDecidingTick(t core.DecidingTick) map[string]interface{} {
+	return map[string]interface{}{
+		"Operand":        t.Operand,
+		"Exchange":       t.Exchange,
+		"CandleInterval": t.CandleInterval,
+		"Timestamp":      time.Unix(int64(t.Timestamp), 0).Format(time.RFC850),
+		"Value":          t.Value,
 	}
 }
 
@@ -103,6 +162,7 @@ func mapifyLastTick(t core.Tick) map[string]interface{} {
 	return map[string]interface{}{
 		"Timestamp": timestamp,
 		"Value":     t.Value,
+		"Source":    t.Source,
 	}
 }
 
@@ -1,7 +1,9 @@
 package backoffice
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net"
@@ -69,8 +71,9 @@ func (s *UI) SetDebug(b bool) {
 	s.debug = b
 }
 
-// MustBlockinglyServe serves the BackOffice component.
-func (s UI) MustBlockinglyServe(port int, apiURL string) {
+// MustBlockinglyServe serves the BackOffice component until ctx is cancelled. Then it stops accepting connections and
+// waits up to shutdownTimeout for in-flight requests to finish before returning.
+func (s UI) MustBlockinglyServe(ctx context.Context, port int, apiURL string, shutdownTimeout time.Duration) {
 	s.apiClient = newAPIClient(apiURL, s.basicAuthUser, s.basicAuthPass)
 
 	if s.debug {
@@ -87,9 +90,29 @@ func (s UI) MustBlockinglyServe(port int, apiURL string) {
 	s.handleFunc("/predictionPage", s.predictionPageHandler)
 	// s.handleFunc("/reRunAll", s.reRunAllHandler)
 
-	addr := fmt.Sprintf(":%v", port)
+	var (
+		addr         = fmt.Sprintf(":%v", port)
+		server       = &http.Server{Addr: addr}
+		shutdownDone = make(chan struct{})
+	)
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		log.Info().Msg("BackOffice shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("BackOffice: in-flight requests didn't finish in time; closing their connections.")
+			server.Close()
+		}
+	}()
+
 	log.Info().Msgf("BackOffice listening on %v", addr)
-	log.Fatal().Err(http.ListenAndServe(addr, nil)).Msg("")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal().Err(err).Msg("")
+	}
+	<-shutdownDone
+	log.Info().Msg("BackOffice shut down.")
 }
 
 func trim(ss []string) []string {
@@ -256,12 +279,16 @@ func (s UI) predictionHandler(w http.ResponseWriter, r *http.Request) {
 	base64ImageRes := s.apiClient.predictionImage(predictionImageBody{
 		UUID: uuid,
 	})
+	historyRes := s.apiClient.predictionHistory(uuid)
 
 	data := make(map[string]interface{})
 	if res.Predictions != nil && len(*res.Predictions) == 1 {
 		pred := (*res.Predictions)[0]
 		data["prediction"] = predictionToMap(pred)
 	}
+	if historyRes.History != nil {
+		data["history"] = historyToMaps(*historyRes.History)
+	}
 
 	data["GetPredictionsErr"] = res.ErrorMessage
 	data["GetPredictionsStatus"] = res.Status
@@ -0,0 +1,138 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/marianogappa/predictions/compiler/arithunmarshal"
+	"github.com/marianogappa/predictions/core"
+)
+
+// parseOperand parses one side of a condition's operator, e.g. "COIN:BINANCE:BTC-USDT", "1.2 * 30000", "-15%" or
+// "SMA(COIN:BINANCE:BTC-USDT,200D)".
+//
+// Arithmetic expressions that only have NUMBERs are resolved to a NUMBER, and a market times a number, in either order
+// (e.g. "COIN:BINANCE:BTC-USDT * 0.1" or "0.1 * COIN:BINANCE:BTC-USDT"), is a multiplied market, so that these are
+// still recognised as prediction types. Any other arithmetic expression is an EXPR. The Operand's Str is the operand
+// as written, except for multiplied markets, whose Str is the market's (see Operand.ConditionStr).
+func parseOperand(s string) (core.Operand, error) {
+	n, err := arithunmarshal.NewExprParser(s).Parse()
+	if err != nil {
+		return core.Operand{}, fmt.Errorf("%w: %v", core.ErrInvalidOperand, err)
+	}
+	switch n.TT {
+	case arithunmarshal.VARIABLE:
+		return mapVariable(n.Token)
+	case arithunmarshal.FUNCTION:
+		return mapIndicator(n)
+	case arithunmarshal.PERCENT:
+		percent, _ := strconv.ParseFloat(n.Token, 64)
+		return core.Operand{Type: core.PERCENT, Number: core.JSONFloat64(percent), Str: fmt.Sprintf("%v%%", n.Token)}, nil
+	case arithunmarshal.TIMES:
+		if n.Nodes[0].TT == arithunmarshal.VARIABLE && n.Nodes[1].TT == arithunmarshal.NUMBER {
+			return mapMultipliedVariable(n.Nodes[0].Token, n.Nodes[1].Token)
+		}
+		if n.Nodes[0].TT == arithunmarshal.NUMBER && n.Nodes[1].TT == arithunmarshal.VARIABLE {
+			return mapMultipliedVariable(n.Nodes[1].Token, n.Nodes[0].Token)
+		}
+	}
+
+	expr, err := nodeToExpr(n)
+	if err != nil {
+		return core.Operand{}, err
+	}
+	if expr.Operator == "" {
+		return *expr.Literal, nil
+	}
+	if len(expr.NonNumberOperands()) > 0 {
+		return core.Operand{Type: core.EXPR, Expr: expr, Str: expr.String()}, nil
+	}
+	number, _ := expr.Value(nil)
+	if math.IsInf(number, 0) || math.IsNaN(number) {
+		return core.Operand{}, fmt.Errorf("%w: %v divides by zero", core.ErrInvalidOperand, expr)
+	}
+	return core.Operand{Type: core.NUMBER, Number: core.JSONFloat64(number), Str: expr.String()}, nil
+}
+
+// mapMultipliedVariable maps an operand like "COIN:BINANCE:BTC-USDT * 0.1" or "0.1 * COIN:BINANCE:BTC-USDT".
+func mapMultipliedVariable(v, multiplier string) (core.Operand, error) {
+	operand, err := mapVariable(v)
+	if err != nil {
+		return core.Operand{}, err
+	}
+	m, _ := strconv.ParseFloat(multiplier, 64)
+	if m == 0 {
+		return core.Operand{}, fmt.Errorf("%w: operand %v can't be multiplied by zero", core.ErrInvalidOperand, v)
+	}
+	operand.Multiplier = core.JSONFloat64(m)
+	return operand, nil
+}
+
+// mapIndicator maps an operand like "SMA(COIN:BINANCE:BTC-USDT,200D)".
+func mapIndicator(n arithunmarshal.Node) (core.Operand, error) {
+	if !core.IsIndicator(n.Token) {
+		return core.Operand{}, fmt.Errorf("%w: %v", core.ErrUnknownIndicator, n.Token)
+	}
+	if len(n.Nodes) != 2 {
+		return core.Operand{}, fmt.Errorf("%w: %v needs a coin and a window, e.g. %v(COIN:BINANCE:BTC-USDT,200d)", core.ErrInvalidOperand, n.Token, n.Token)
+	}
+	operand, err := mapVariable(n.Nodes[0].Token)
+	if err != nil {
+		return core.Operand{}, err
+	}
+	if operand.Type != core.COIN {
+		return core.Operand{}, fmt.Errorf("%w: %v is only supported on COIN operands", core.ErrInvalidOperand, n.Token)
+	}
+	// The Daemon's market only has traded volumes of some exchanges (see indicator.WithVolumeDataSource), so VOLUME
+	// predictions on other ones would never evolve.
+	if n.Token == "VOLUME" && !core.HasVolume(operand.Provider) {
+		return core.Operand{}, fmt.Errorf("%w: %v", core.ErrVolumeIndicatorUnavailable, operand.Str)
+	}
+	window := strings.ToLower(n.Nodes[1].Token)
+	if _, _, err := core.ParseIndicatorWindow(window); err != nil {
+		return core.Operand{}, err
+	}
+	operand.Type = core.INDICATOR
+	operand.Indicator = n.Token
+	operand.Window = window
+	operand.Str = fmt.Sprintf("%v(%v,%v)", n.Token, operand.Str, window)
+	return operand, nil
+}
+
+func nodeToExpr(n arithunmarshal.Node) (*core.Expr, error) {
+	switch n.TT {
+	case arithunmarshal.NUMBER:
+		number, err := strconv.ParseFloat(n.Token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %v", core.ErrInvalidOperand, n.Token)
+		}
+		return &core.Expr{Literal: &core.Operand{Type: core.NUMBER, Number: core.JSONFloat64(number), Str: n.Token}}, nil
+	case arithunmarshal.VARIABLE:
+		operand, err := mapVariable(n.Token)
+		if err != nil {
+			return nil, err
+		}
+		return &core.Expr{Literal: &operand}, nil
+	case arithunmarshal.FUNCTION:
+		operand, err := mapIndicator(n)
+		if err != nil {
+			return nil, err
+		}
+		return &core.Expr{Literal: &operand}, nil
+	case arithunmarshal.PERCENT:
+		return nil, fmt.Errorf("%w: percentages like %v%% cannot be part of an arithmetic expression", core.ErrInvalidOperand, n.Token)
+	case arithunmarshal.PLUS, arithunmarshal.MINUS, arithunmarshal.TIMES, arithunmarshal.DIVIDE:
+		e := core.Expr{Operator: n.Token}
+		for _, node := range n.Nodes {
+			operand, err := nodeToExpr(node)
+			if err != nil {
+				return nil, err
+			}
+			e.Operands = append(e.Operands, operand)
+		}
+		return &e, nil
+	}
+	return nil, fmt.Errorf("%w: unknown token %v", core.ErrInvalidOperand, n.Token)
+}
@@ -0,0 +1,335 @@
+package arithunmarshal
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenType int
+
+func (t tokenType) String() string {
+	switch t {
+	case EOF:
+		return "EOF"
+	case NUMBER:
+		return "NUMBER"
+	case VARIABLE:
+		return "VARIABLE"
+	case PERCENT:
+		return "PERCENT"
+	case PLUS:
+		return "PLUS"
+	case MINUS:
+		return "MINUS"
+	case TIMES:
+		return "TIMES"
+	case DIVIDE:
+		return "DIVIDE"
+	case OPEN:
+		return "OPEN"
+	case CLOSE:
+		return "CLOSE"
+	case FUNCTION:
+		return "FUNCTION"
+	case ARGUMENT:
+		return "ARGUMENT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+const (
+	// UNKNOWN is a token type
+	UNKNOWN tokenType = iota
+	// EOF is a token type
+	EOF
+	// NUMBER is a token type, e.g. "1.2"
+	NUMBER
+	// VARIABLE is a token type, e.g. "COIN:BINANCE:BTC-USDT"
+	VARIABLE
+	// PERCENT is a token type, e.g. "-15%" (its Token is "-15")
+	PERCENT
+	// PLUS is a token type
+	PLUS
+	// MINUS is a token type (its Node has one operand when it's a negation)
+	MINUS
+	// TIMES is a token type
+	TIMES
+	// DIVIDE is a token type
+	DIVIDE
+	// OPEN is a token type, i.e. an opening parenthesis
+	OPEN
+	// CLOSE is a token type, i.e. a closing parenthesis
+	CLOSE
+	// FUNCTION is a token type, e.g. "SMA(COIN:BINANCE:BTC-USDT,200D)" (its Token is "SMA" & its Nodes are ARGUMENTs)
+	FUNCTION
+	// ARGUMENT is a token type, i.e. an argument of a FUNCTION as written, e.g. "200D"
+	ARGUMENT
+)
+
+var operatorTokens = map[byte]tokenType{'+': PLUS, '-': MINUS, '*': TIMES, '/': DIVIDE, '(': OPEN, ')': CLOSE}
+
+// Node is a node in the arithmetic expression tree.
+type Node struct {
+	TT    tokenType
+	Token string
+	Nodes []Node
+}
+
+// ArithExprParser is the main struct for parsing arithmetic expressions.
+type ArithExprParser struct {
+	s string
+	i int
+}
+
+// NewExprParser is the constructor for parsing arithmetic expressions.
+func NewExprParser(s string) *ArithExprParser {
+	return &ArithExprParser{s: s, i: 0}
+}
+
+func (p *ArithExprParser) error(message string, args ...interface{}) error {
+	if len(p.s) == 0 || p.i >= len(p.s) {
+		return fmt.Errorf(fmt.Sprintf("parsing '%v': %v", p.s, message), args...)
+	}
+	return fmt.Errorf(fmt.Sprintf("parsing '%v[%v]%v': %v", p.s[:p.i], string(p.s[p.i]), p.s[p.i+1:], message), args...)
+}
+
+// Parse parses an arithmetic expression, e.g. "(COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT) / 2".
+//
+// The usual precedence rules apply, i.e. "*" and "/" bind tighter than "+" and "-", and operators of the same
+// precedence are left-associative.
+func (p *ArithExprParser) Parse() (Node, error) {
+	p.s = strings.ToUpper(p.s)
+	p.i = 0
+
+	node, err := p.popSum()
+	if err != nil {
+		return Node{}, err
+	}
+	if next := p.pop(); next.TT != EOF {
+		return Node{}, p.error("expected EOF but found '%v'", next.Token)
+	}
+	return node, nil
+}
+
+// popSum pops a chain of "+" & "-" operations, e.g. "1 + 2 * 3 - 4".
+func (p *ArithExprParser) popSum() (Node, error) {
+	node, err := p.popProduct()
+	if err != nil {
+		return Node{}, err
+	}
+	for {
+		next := p.peek()
+		if next.TT != PLUS && next.TT != MINUS {
+			return node, nil
+		}
+		p.pop()
+		right, err := p.popProduct()
+		if err != nil {
+			return Node{}, err
+		}
+		node = Node{TT: next.TT, Token: next.Token, Nodes: []Node{node, right}}
+	}
+}
+
+// popProduct pops a chain of "*" & "/" operations, e.g. "2 * 3 / 4".
+func (p *ArithExprParser) popProduct() (Node, error) {
+	node, err := p.popUnary()
+	if err != nil {
+		return Node{}, err
+	}
+	for {
+		next := p.peek()
+		if next.TT != TIMES && next.TT != DIVIDE {
+			return node, nil
+		}
+		p.pop()
+		right, err := p.popUnary()
+		if err != nil {
+			return Node{}, err
+		}
+		node = Node{TT: next.TT, Token: next.Token, Nodes: []Node{node, right}}
+	}
+}
+
+// popUnary pops an operand, optionally signed. A sign right before a percentage is part of it, e.g. "-15%".
+func (p *ArithExprParser) popUnary() (Node, error) {
+	next := p.peek()
+	if next.TT != PLUS && next.TT != MINUS {
+		return p.popOperand()
+	}
+	p.pop()
+	if afterSign := p.peek(); afterSign.TT == PERCENT {
+		p.pop()
+		return Node{TT: PERCENT, Token: next.Token + afterSign.Token}, nil
+	}
+	node, err := p.popUnary()
+	if err != nil {
+		return Node{}, err
+	}
+	if next.TT == PLUS {
+		return node, nil
+	}
+	return Node{TT: MINUS, Token: "-", Nodes: []Node{node}}, nil
+}
+
+// popOperand pops a number, a percentage, a variable, a function or a parenthesised expression.
+func (p *ArithExprParser) popOperand() (Node, error) {
+	node := p.pop()
+	switch node.TT {
+	case NUMBER, PERCENT, VARIABLE, FUNCTION:
+		return node, nil
+	case OPEN:
+		inner, err := p.popSum()
+		if err != nil {
+			return Node{}, err
+		}
+		if closing := p.pop(); closing.TT != CLOSE {
+			return Node{}, p.error("expected ')' but found '%v'", closing.Token)
+		}
+		return inner, nil
+	case EOF:
+		return Node{}, p.error("expected an operand but reached EOF")
+	default:
+		return Node{}, p.error("expected an operand but found '%v'", node.Token)
+	}
+}
+
+func (p *ArithExprParser) peek() Node {
+	i := p.i
+	node := p.pop()
+	p.i = i
+	return node
+}
+
+func (p *ArithExprParser) pop() Node {
+	p.popSpaces()
+	if p.i >= len(p.s) {
+		return Node{TT: EOF, Token: ""}
+	}
+	c := p.s[p.i]
+	switch {
+	case operatorTokens[c] != UNKNOWN:
+		p.i++
+		return Node{TT: operatorTokens[c], Token: string(c)}
+	case isDigit(c):
+		number := p.popWhile(func(c byte) bool { return isDigit(c) })
+		if p.i < len(p.s) && p.s[p.i] == '.' {
+			p.i++
+			number += "." + p.popWhile(func(c byte) bool { return isDigit(c) })
+		}
+		if p.i < len(p.s) && p.s[p.i] == '%' {
+			p.i++
+			return Node{TT: PERCENT, Token: number}
+		}
+		return Node{TT: NUMBER, Token: number}
+	case isLetter(c):
+		// Variables look like "COIN:BINANCE:BTC-USDT". The "-" is only part of the variable when followed by a letter,
+		// and only once, so that "COIN:BINANCE:BTC-USDT-COIN:KUCOIN:BTC-USDT" is a subtraction.
+		variable := p.popWhile(isVariableChar)
+		if strings.Contains(variable, ":") && p.i < len(p.s) && p.s[p.i] == '(' {
+			// An aggregate provider lists its exchanges, e.g. "COIN:MEDIAN(BINANCE,COINBASE):BTC-USDT".
+			exchanges := p.popParenthesised()
+			if !strings.HasSuffix(exchanges, ")") {
+				return Node{TT: UNKNOWN, Token: variable + exchanges}
+			}
+			variable += exchanges + p.popWhile(isVariableChar)
+		}
+		if p.i+1 < len(p.s) && p.s[p.i] == '-' && isLetter(p.s[p.i+1]) {
+			p.i++
+			variable += "-" + p.popWhile(func(c byte) bool { return isLetter(c) || isDigit(c) })
+		}
+		if p.i < len(p.s) && p.s[p.i] == '(' {
+			return p.popFunction(variable)
+		}
+		return Node{TT: VARIABLE, Token: variable}
+	default:
+		p.i++
+		return Node{TT: UNKNOWN, Token: string(c)}
+	}
+}
+
+// popFunction pops the arguments of a function right after its name, e.g. "(COIN:BINANCE:BTC-USDT,200D)" after
+// "SMA". Arguments are kept as written, and only commas outside of parentheses separate them, so that a variable with
+// an aggregate provider is a single argument.
+func (p *ArithExprParser) popFunction(name string) Node {
+	args := p.popParenthesised()
+	if !strings.HasSuffix(args, ")") {
+		return Node{TT: UNKNOWN, Token: name + args}
+	}
+	node := Node{TT: FUNCTION, Token: name}
+	for _, arg := range splitTopLevel(args[1 : len(args)-1]) {
+		node.Nodes = append(node.Nodes, Node{TT: ARGUMENT, Token: strings.TrimSpace(arg)})
+	}
+	return node
+}
+
+// popParenthesised pops from an opening parenthesis up to its matching closing one, both included, without spaces.
+// If the parenthesis is never closed, it pops until the end.
+func (p *ArithExprParser) popParenthesised() string {
+	var (
+		s     = []byte{}
+		depth = 0
+	)
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		p.i++
+		if c == ' ' {
+			continue
+		}
+		s = append(s, c)
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	return string(s)
+}
+
+// splitTopLevel splits s by the commas that are outside of parentheses.
+func splitTopLevel(s string) []string {
+	var (
+		parts = []string{}
+		depth = 0
+		start = 0
+	)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func (p *ArithExprParser) popWhile(accept func(byte) bool) string {
+	start := p.i
+	for p.i < len(p.s) && accept(p.s[p.i]) {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+func (p *ArithExprParser) popSpaces() {
+	for p.i < len(p.s) && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
+
+func isVariableChar(c byte) bool { return isLetter(c) || isDigit(c) || c == ':' }
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isLetter(c byte) bool { return c >= 'A' && c <= 'Z' }
@@ -0,0 +1,181 @@
+package arithunmarshal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	var (
+		anyError = errors.New("any error for now... ")
+		btc      = Node{TT: VARIABLE, Token: "COIN:BINANCE:BTC-USDT"}
+		kucoin   = Node{TT: VARIABLE, Token: "COIN:KUCOIN:BTC-USDT"}
+	)
+
+	tss := []struct {
+		name     string
+		s        string
+		err      error
+		expected Node
+	}{
+		{
+			name: "Empty string cannot be parsed",
+			s:    "",
+			err:  anyError,
+		},
+		{
+			name:     "A number",
+			s:        "1.2",
+			expected: Node{TT: NUMBER, Token: "1.2"},
+		},
+		{
+			name:     "A variable",
+			s:        "coin:binance:btc-usdt",
+			expected: btc,
+		},
+		{
+			name:     "A percentage",
+			s:        "15%",
+			expected: Node{TT: PERCENT, Token: "15"},
+		},
+		{
+			name:     "A negative percentage",
+			s:        "-15%",
+			expected: Node{TT: PERCENT, Token: "-15"},
+		},
+		{
+			name:     "A positive percentage",
+			s:        " + 30% ",
+			expected: Node{TT: PERCENT, Token: "+30"},
+		},
+		{
+			name:     "A negation",
+			s:        "-1.2",
+			expected: Node{TT: MINUS, Token: "-", Nodes: []Node{{TT: NUMBER, Token: "1.2"}}},
+		},
+		{
+			name:     "A multiplication",
+			s:        "1.2 * 30000",
+			expected: Node{TT: TIMES, Token: "*", Nodes: []Node{{TT: NUMBER, Token: "1.2"}, {TT: NUMBER, Token: "30000"}}},
+		},
+		{
+			name:     "A spread without spaces",
+			s:        "COIN:BINANCE:BTC-USDT-COIN:KUCOIN:BTC-USDT",
+			expected: Node{TT: MINUS, Token: "-", Nodes: []Node{btc, kucoin}},
+		},
+		{
+			name: "Products bind tighter than sums",
+			s:    "COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT / 2",
+			expected: Node{TT: PLUS, Token: "+", Nodes: []Node{
+				btc,
+				{TT: DIVIDE, Token: "/", Nodes: []Node{kucoin, {TT: NUMBER, Token: "2"}}},
+			}},
+		},
+		{
+			name: "Parentheses override precedence",
+			s:    "(COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT) / 2",
+			expected: Node{TT: DIVIDE, Token: "/", Nodes: []Node{
+				{TT: PLUS, Token: "+", Nodes: []Node{btc, kucoin}},
+				{TT: NUMBER, Token: "2"},
+			}},
+		},
+		{
+			name: "Operators are left-associative",
+			s:    "10 - 2 - 3",
+			expected: Node{TT: MINUS, Token: "-", Nodes: []Node{
+				{TT: MINUS, Token: "-", Nodes: []Node{{TT: NUMBER, Token: "10"}, {TT: NUMBER, Token: "2"}}},
+				{TT: NUMBER, Token: "3"},
+			}},
+		},
+		{
+			name: "A function",
+			s:    "sma(coin:binance:btc-usdt, 200d)",
+			expected: Node{TT: FUNCTION, Token: "SMA", Nodes: []Node{
+				{TT: ARGUMENT, Token: "COIN:BINANCE:BTC-USDT"},
+				{TT: ARGUMENT, Token: "200D"},
+			}},
+		},
+		{
+			name: "A function in an expression",
+			s:    "COIN:BINANCE:BTC-USDT - SMA(COIN:BINANCE:BTC-USDT,200D)",
+			expected: Node{TT: MINUS, Token: "-", Nodes: []Node{
+				btc,
+				{TT: FUNCTION, Token: "SMA", Nodes: []Node{{TT: ARGUMENT, Token: "COIN:BINANCE:BTC-USDT"}, {TT: ARGUMENT, Token: "200D"}}},
+			}},
+		},
+		{
+			name:     "A variable with an aggregate provider",
+			s:        "coin:median(binance, coinbase):btc-usdt",
+			expected: Node{TT: VARIABLE, Token: "COIN:MEDIAN(BINANCE,COINBASE):BTC-USDT"},
+		},
+		{
+			name: "A variable with an aggregate provider in an expression",
+			s:    "COIN:ANY(BINANCE,KUCOIN):BTC-USDT-COIN:KUCOIN:BTC-USDT",
+			expected: Node{TT: MINUS, Token: "-", Nodes: []Node{
+				{TT: VARIABLE, Token: "COIN:ANY(BINANCE,KUCOIN):BTC-USDT"},
+				kucoin,
+			}},
+		},
+		{
+			name: "A function of a variable with an aggregate provider",
+			s:    "SMA(COIN:MEDIAN(BINANCE,COINBASE):BTC-USDT,200D)",
+			expected: Node{TT: FUNCTION, Token: "SMA", Nodes: []Node{
+				{TT: ARGUMENT, Token: "COIN:MEDIAN(BINANCE,COINBASE):BTC-USDT"},
+				{TT: ARGUMENT, Token: "200D"},
+			}},
+		},
+		{
+			name: "Unclosed aggregate provider",
+			s:    "COIN:MEDIAN(BINANCE,COINBASE:BTC-USDT + 1",
+			err:  anyError,
+		},
+		{
+			name: "Unclosed function",
+			s:    "SMA(COIN:BINANCE:BTC-USDT,200D",
+			err:  anyError,
+		},
+		{
+			name: "Unclosed parenthesis",
+			s:    "(1 + 2",
+			err:  anyError,
+		},
+		{
+			name: "Unopened parenthesis",
+			s:    "1 + 2)",
+			err:  anyError,
+		},
+		{
+			name: "Missing operand",
+			s:    "1 +",
+			err:  anyError,
+		},
+		{
+			name: "Two operands in a row",
+			s:    "1 2",
+			err:  anyError,
+		},
+		{
+			name: "Unknown character",
+			s:    "1 ^ 2",
+			err:  anyError,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			actual, actualErr := NewExprParser(ts.s).Parse()
+			if actualErr != nil && ts.err == nil {
+				t.Logf("expected no error but had '%v'", actualErr)
+				t.FailNow()
+			}
+			if actualErr == nil && ts.err != nil {
+				t.Logf("expected error '%v' but had no error", ts.err)
+				t.FailNow()
+			}
+			if !reflect.DeepEqual(actual, ts.expected) {
+				t.Logf("expected %v but got %v", ts.expected, actual)
+				t.FailNow()
+			}
+		})
+	}
+}
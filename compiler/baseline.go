@@ -0,0 +1,57 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+)
+
+// resolveBaselines resolves the Baseline of every Condition with PERCENT Operands (e.g. "COIN:BINANCE:BTC-USDT >=
+// +30%") to the value of its first Operand at post time, so that it's stored with the prediction and stays stable.
+//
+// Baselines that were already resolved are kept. If the market has no data for post time yet, the Baseline is left
+// unresolved, and the Condition resolves it when it starts.
+func resolveBaselines(prediction *core.Prediction, market core.IMarket) error {
+	postedAt, err := prediction.PostedAt.Time()
+	if err != nil {
+		return fmt.Errorf("%w: %v", core.ErrInvalidPostedAt, err)
+	}
+	for name, cond := range prediction.Given {
+		if cond.Baseline != 0 || !cond.HasPercentOperands() {
+			continue
+		}
+		baseline, ok, err := valueAt(cond.Operands[0], market, postedAt)
+		if err != nil {
+			return fmt.Errorf("while resolving the baseline of condition %v: %w", name, err)
+		}
+		if ok {
+			cond.Baseline = baseline
+		}
+	}
+	return nil
+}
+
+// valueAt returns the value of an Operand at the opening of the 1 minute candlestick of the given time, or false if the
+// market doesn't have that candlestick yet.
+func valueAt(operand core.Operand, market core.IMarket, tm time.Time) (float64, bool, error) {
+	ticks := map[string]core.Tick{}
+	for _, op := range operand.NonNumberOperands() {
+		it, err := market.Iterator(op, tm, time.Minute)
+		if err != nil {
+			return 0, false, err
+		}
+		candlestick, err := it.Next()
+		if errors.Is(err, common.ErrNoNewTicksYet) || errors.Is(err, common.ErrOutOfTicks) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		ticks[op.Str] = core.Tick{Timestamp: candlestick.Timestamp, Value: candlestick.OpenPrice}
+	}
+	value, err := operand.Value(ticks, 0)
+	return value, err == nil, err
+}
@@ -0,0 +1,122 @@
+package compiler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/metadatafetcher"
+	mfTypes "github.com/marianogappa/predictions/metadatafetcher/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileResolvesBaselines(t *testing.T) {
+	var (
+		anyError = errors.New("any error for now... ")
+		postedAt = tp("2022-02-09 10:25:00")
+		opens    = map[string]float64{"BINANCE:BTC-USDT": 30000, "KUCOIN:BTC-USDT": 30100}
+	)
+
+	tss := []struct {
+		name      string
+		condition string
+		baseline  string
+		marketErr error
+		err       error
+		expected  float64
+	}{
+		{
+			name:      "Resolves the baseline at post time",
+			condition: "COIN:BINANCE:BTC-USDT >= +30%",
+			expected:  30000,
+		},
+		{
+			name:      "Resolves the baseline of an arithmetic expression",
+			condition: "(COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT) / 2 <= -15%",
+			expected:  30050,
+		},
+		{
+			name:      "Keeps a known baseline",
+			condition: "COIN:BINANCE:BTC-USDT >= +30%",
+			baseline:  `, "baseline": 29000`,
+			expected:  29000,
+		},
+		{
+			name:      "Ignores conditions without percentages",
+			condition: "COIN:BINANCE:BTC-USDT >= 39000",
+			expected:  0,
+		},
+		{
+			name:      "Leaves the baseline unresolved if the market has no data for post time yet",
+			condition: "COIN:BINANCE:BTC-USDT >= +30%",
+			marketErr: common.ErrNoNewTicksYet,
+			expected:  0,
+		},
+		{
+			name:      "Fails if the market doesn't exist",
+			condition: "COIN:BINANCE:BTC-USDT >= +30%",
+			marketErr: common.ErrInvalidMarketPair,
+			err:       anyError,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			market := baselineMarket{opens: opens, tm: postedAt, err: ts.marketErr}
+			pc := NewPredictionCompiler(metadatafetcher.NewMetadataFetcher(), nil, WithMarket(core.NewMarket(market)))
+			pc.metadataFetcher.Fetchers = []metadatafetcher.SpecificFetcher{
+				newTestMetadataFetcher(mfTypes.PostMetadata{
+					Author:        core.Account{Handle: "CryptoCapo_"},
+					PostCreatedAt: core.ISO8601(postedAt.Format(time.RFC3339)),
+				}, nil),
+			}
+			pred, _, err := pc.Compile([]byte(`{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+				"given": {"main": {"condition": "` + ts.condition + `", "toDuration": "eom"` + ts.baseline + `}},
+				"predict": {"predict": "main"}
+			}`))
+			if ts.err != nil {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, ts.expected, pred.Given["main"].Baseline)
+		})
+	}
+}
+
+type baselineMarket struct {
+	opens map[string]float64
+	tm    time.Time
+	err   error
+}
+
+func (m baselineMarket) Iterator(marketSource common.MarketSource, tm time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	if !tm.Equal(m.tm) || candlestickInterval != time.Minute {
+		return nil, errors.New("baselines should be resolved with the 1 minute candlestick at post time")
+	}
+	if errors.Is(m.err, common.ErrInvalidMarketPair) {
+		return nil, m.err
+	}
+	open := m.opens[marketSource.Provider+":"+marketSource.BaseAsset+"-"+marketSource.QuoteAsset]
+	return &baselineIterator{candlestick: common.Candlestick{Timestamp: int(tm.Unix()), OpenPrice: common.JSONFloat64(open)}, err: m.err}, nil
+}
+
+type baselineIterator struct {
+	candlestick common.Candlestick
+	err         error
+}
+
+func (i *baselineIterator) Next() (common.Candlestick, error) { return i.candlestick, i.err }
+
+// Not using Scanner interface
+func (i *baselineIterator) Scan(*common.Candlestick) bool { return false }
+
+// Not using Scanner interface
+func (i *baselineIterator) Error() error { return nil }
+
+func (i *baselineIterator) SetStartFromNext(bool)           {}
+func (i *baselineIterator) SetTimeNowFunc(func() time.Time) {}
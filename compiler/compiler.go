@@ -13,15 +13,29 @@ import (
 
 // PredictionCompiler is the component that takes a JSON string representing a prediction and compiles it into a
 // prediction that can be used throughout the engine. It may use a MetadataFetcher to fetch metadata from the
-// Twitter / Youtube post, e.g. when it was posted & by whom.
+// Twitter / Youtube post, e.g. when it was posted & by whom. It may also use a market to resolve relative conditions
+// (e.g. "COIN:BINANCE:BTC-USDT >= +30%") against the price at post time.
 type PredictionCompiler struct {
 	metadataFetcher *metadatafetcher.MetadataFetcher
 	timeNow         func() time.Time
+	market          core.IMarket
 }
 
 // NewPredictionCompiler constructs a PredictionCompiler.
-func NewPredictionCompiler(fetcher *metadatafetcher.MetadataFetcher, timeNow func() time.Time) PredictionCompiler {
-	return PredictionCompiler{metadataFetcher: fetcher, timeNow: timeNow}
+func NewPredictionCompiler(fetcher *metadatafetcher.MetadataFetcher, timeNow func() time.Time, options ...func(*PredictionCompiler)) PredictionCompiler {
+	c := PredictionCompiler{metadataFetcher: fetcher, timeNow: timeNow}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithMarket makes the PredictionCompiler resolve the baselines of conditions with percentages (e.g.
+// "COIN:BINANCE:BTC-USDT >= +30%") to the market value at post time.
+func WithMarket(market core.IMarket) func(*PredictionCompiler) {
+	return func(c *PredictionCompiler) {
+		c.market = market
+	}
 }
 
 type partiallyCompile = func(Prediction, *core.Prediction, *core.Account, *metadatafetcher.MetadataFetcher, func() time.Time) error
@@ -79,6 +93,12 @@ func (c PredictionCompiler) Compile(rawPredictionBs []byte) (core.Prediction, *c
 		}
 	}
 
+	if c.market != nil {
+		if err := resolveBaselines(&prediction, c.market); err != nil {
+			return prediction, account, err
+		}
+	}
+
 	return prediction, account, nil
 }
 
@@ -185,6 +205,9 @@ func compileGiven(raw Prediction, prediction *core.Prediction, account *core.Acc
 		if err != nil {
 			return err
 		}
+		for i := range c.Failovers {
+			c.Failovers[i].PredictionUUID = prediction.UUID
+		}
 		prediction.Given[name] = &c
 	}
 	return nil
@@ -192,6 +215,10 @@ func compileGiven(raw Prediction, prediction *core.Prediction, account *core.Acc
 
 func compilePredictionType(raw Prediction, prediction *core.Prediction, account *core.Account, mf *metadatafetcher.MetadataFetcher, timeNow func() time.Time) error {
 	prediction.Type = core.PredictionTypeFromString(raw.Type)
+	// Predictions with a BETWEEN condition used to be PREDICTION_TYPE_COIN_WILL_RANGE before ranges had to be sustained.
+	if prediction.Type == core.PredictionTypeCoinWillRange && !predictionTypes[core.PredictionTypeCoinWillRange](*prediction) {
+		prediction.Type = core.PredictionTypeUnsupported
+	}
 	if prediction.Type == core.PredictionTypeUnsupported {
 		prediction.Type = CalculatePredictionType(*prediction)
 	}
@@ -208,9 +235,10 @@ func compilePredictionState(raw Prediction, prediction *core.Prediction, account
 		return err
 	}
 	prediction.State = core.PredictionState{
-		Status: status,
-		LastTs: raw.PredictionState.LastTs,
-		Value:  value,
+		Status:     status,
+		LastTs:     raw.PredictionState.LastTs,
+		Value:      value,
+		Difficulty: raw.PredictionState.Difficulty,
 	}
 	return nil
 }
@@ -11,15 +11,14 @@ import (
 )
 
 var (
-	strCondition        = fmt.Sprintf(` *%v *%v *%v *`, strFloatOrVariable, strOperator, strFloatOrVariable)
-	strBetweenCondition = fmt.Sprintf(` *%v +BETWEEN +%v +AND +%v *`, strFloatOrVariable, strFloatOrVariable, strFloatOrVariable)
-	strFloatOrVariable  = fmt.Sprintf(`(%v|%v)`, strFloat, strVariable)
+	strCondition        = fmt.Sprintf(` *%v *%v *%v *`, strOperand, strOperator, strOperand)
+	strBetweenCondition = fmt.Sprintf(` *%v +BETWEEN +%v +AND +%v *`, strOperand, strOperand, strOperand)
+	strOperand          = `([^>=!<]+?)`
 	strOperator         = `([>=!<]+)`
-	strFloat            = `[0-9]+(.[0-9]*)?`
-	strVariable         = `(COIN|MARKETCAP):([A-Z]+):([A-Z]+)(-([A-Z]+))?`
+	strVariable         = `(COIN|MARKETCAP):([A-Z]+(?:\([A-Z,]*\))?):([A-Z]+)(-([A-Z]+))?`
 	rxVariable          = regexp.MustCompile(fmt.Sprintf("^%v$", strVariable))
-	rxCondition         = regexp.MustCompile(strCondition)
-	rxBetweenCondition  = regexp.MustCompile(strBetweenCondition)
+	rxCondition         = regexp.MustCompile(fmt.Sprintf("^%v$", strCondition))
+	rxBetweenCondition  = regexp.MustCompile(fmt.Sprintf("^%v$", strBetweenCondition))
 	rxDurationWeeks     = regexp.MustCompile(`([0-9]+)w`)
 	rxDurationDays      = regexp.MustCompile(`([0-9]+)d`)
 	rxDurationMonths    = regexp.MustCompile(`([0-9]+)m`)
@@ -31,11 +30,10 @@ func mapOperandForTests(v string) (core.Operand, error) {
 }
 
 func mapOperand(v string) (core.Operand, error) {
-	v = strings.ToUpper(v)
-	f, err := strconv.ParseFloat(v, 64)
-	if err == nil {
-		return core.Operand{Type: core.NUMBER, Number: core.JSONFloat64(f), Str: v}, nil
-	}
+	return parseOperand(strings.ToUpper(v))
+}
+
+func mapVariable(v string) (core.Operand, error) {
 	matches := rxVariable.FindStringSubmatch(v)
 	if len(matches) == 0 {
 		return core.Operand{}, fmt.Errorf("%w: operand %v doesn't parse to float nor match the regex %v", core.ErrInvalidOperand, v, strVariable)
@@ -50,6 +48,14 @@ func mapOperand(v string) (core.Operand, error) {
 	if matches[3] == matches[5] {
 		return core.Operand{}, core.ErrEqualBaseQuoteAssets
 	}
+	if core.IsAggregateProvider(matches[2]) {
+		if operandType != core.COIN {
+			return core.Operand{}, fmt.Errorf("%w: aggregate providers are only supported on COIN operands", core.ErrInvalidOperand)
+		}
+		if _, _, err := core.ParseAggregateProvider(matches[2]); err != nil {
+			return core.Operand{}, err
+		}
+	}
 	return core.Operand{
 		Type:       operandType,
 		Provider:   matches[2],
@@ -138,6 +144,9 @@ func mapFromTs(c Condition, postedAt core.ISO8601) (int, error) {
 }
 
 func mapToTs(c Condition, fromTs int) (int, error) {
+	if c.SustainedFor != "" {
+		return mapSustainedForToTs(c, fromTs)
+	}
 	s, err := c.ToISO8601.Seconds()
 	if err == nil {
 		return s, nil
@@ -156,6 +165,56 @@ func mapToTs(c Condition, fromTs int) (int, error) {
 	return int(fromTime.Add(duration).Unix()), nil
 }
 
+// mapEvaluationMode parses the condition's evaluationMode, and validates that it has a candleInterval only if it's
+// evaluated on candle closes, and sustainedCandles only if it's SUSTAINED.
+func mapEvaluationMode(c Condition) (core.EvaluationMode, error) {
+	evaluationMode, err := core.EvaluationModeFromString(c.EvaluationMode)
+	if err != nil {
+		return 0, err
+	}
+	if evaluationMode == core.WICK && c.CandleInterval != "" {
+		return 0, fmt.Errorf("%w, but was %v for a WICK evaluation mode", core.ErrInvalidCandleInterval, c.CandleInterval)
+	}
+	if evaluationMode != core.WICK {
+		if _, err := core.ParseCandleInterval(c.CandleInterval); err != nil {
+			return 0, err
+		}
+	}
+	if (evaluationMode == core.SUSTAINED) != (c.SustainedCandles >= 1) {
+		return 0, fmt.Errorf("%w, but was %v for a %v evaluation mode", core.ErrInvalidSustainedCandles, c.SustainedCandles, evaluationMode)
+	}
+	if evaluationMode == core.SUSTAINED && c.SustainedFor != "" {
+		return 0, fmt.Errorf("%w, but it was %v for a SUSTAINED evaluation mode", core.ErrInvalidSustainedFor, c.SustainedFor)
+	}
+	return evaluationMode, nil
+}
+
+// mapSustainedForToTs resolves the deadline of a condition that must be sustained, which is when it has held for
+// its sustainedFor duration. If the condition also has a toISO8601 or toDuration (e.g. it was serialized), it must
+// resolve to the same deadline.
+func mapSustainedForToTs(c Condition, fromTs int) (int, error) {
+	fromTime := time.Unix(int64(fromTs), 0)
+	duration, err := parseDuration(c.SustainedFor, fromTime)
+	if err != nil {
+		return 0, fmt.Errorf("%w for condition: %v, error: %v", core.ErrInvalidSustainedFor, c.SustainedFor, err)
+	}
+	toTs := int(fromTime.Add(duration).Unix())
+
+	if c.ToISO8601 == "" && c.ToDuration == "" {
+		return toTs, nil
+	}
+	sustainedFor := c.SustainedFor
+	c.SustainedFor = ""
+	otherToTs, err := mapToTs(c, fromTs)
+	if err != nil {
+		return 0, err
+	}
+	if otherToTs != toTs {
+		return 0, fmt.Errorf("%w, but it's sustained for %v until %v and the deadline is %v", core.ErrInvalidSustainedFor, sustainedFor, toTs, otherToTs)
+	}
+	return toTs, nil
+}
+
 func mapCondition(c Condition, name string, postedAt core.ISO8601) (core.Condition, error) {
 	var (
 		operator    string
@@ -168,10 +227,10 @@ func mapCondition(c Condition, name string, postedAt core.ISO8601) (core.Conditi
 			return core.Condition{}, fmt.Errorf("%w; expecting regex match for '%v' or '%v' but got '%v'", core.ErrInvalidConditionSyntax, rxCondition, rxBetweenCondition, c.Condition)
 		}
 		operator = "BETWEEN"
-		strOperands = []string{matchCondition[1], matchCondition[8], matchCondition[15]}
+		strOperands = []string{matchCondition[1], matchCondition[2], matchCondition[3]}
 	} else {
-		operator = matchCondition[8]
-		strOperands = []string{matchCondition[1], matchCondition[9]}
+		operator = matchCondition[2]
+		strOperands = []string{matchCondition[1], matchCondition[3]}
 	}
 
 	operands, err := mapOperands(strOperands)
@@ -179,6 +238,11 @@ func mapCondition(c Condition, name string, postedAt core.ISO8601) (core.Conditi
 		return core.Condition{}, fmt.Errorf("while parsing condition's operands: %w", err)
 	}
 
+	// Percentages are relative to the first operand's value, so it cannot be one.
+	if operands[0].Type == core.PERCENT {
+		return core.Condition{}, fmt.Errorf("while parsing condition's operands: %w: the first operand cannot be a percentage, as percentages are relative to it", core.ErrInvalidOperand)
+	}
+
 	if operator != ">" && operator != "<" && operator != ">=" && operator != "<=" && operator != "BETWEEN" {
 		return core.Condition{}, fmt.Errorf("%w %v", core.ErrUnknownConditionOperator, operator)
 	}
@@ -187,6 +251,11 @@ func mapCondition(c Condition, name string, postedAt core.ISO8601) (core.Conditi
 		return core.Condition{}, fmt.Errorf("%w, but was %v", core.ErrErrorMarginRatioAbove30, c.ErrorMarginRatio)
 	}
 
+	evaluationMode, err := mapEvaluationMode(c)
+	if err != nil {
+		return core.Condition{}, fmt.Errorf("while parsing condition's evaluationMode: %w", err)
+	}
+
 	stateValue, err := core.ConditionStateValueFromString(c.State.Value)
 	if err != nil {
 		return core.Condition{}, fmt.Errorf("while parsing condition's stateValue: %w", err)
@@ -212,19 +281,62 @@ func mapCondition(c Condition, name string, postedAt core.ISO8601) (core.Conditi
 		Operator:         operator,
 		Operands:         operands,
 		ErrorMarginRatio: c.ErrorMarginRatio,
+		Baseline:         c.Baseline,
 		FromTs:           fromTs,
 		ToTs:             toTs,
 		ToDuration:       c.ToDuration,
 		Assumed:          c.Assumed,
+		EvaluationMode:   evaluationMode,
+		CandleInterval:   c.CandleInterval,
+		SustainedCandles: c.SustainedCandles,
+		SustainedFor:     c.SustainedFor,
+		Failovers:        mapFailovers(c.Failovers, name),
 		State: core.ConditionState{
-			Status:    stateStatus,
-			LastTs:    c.State.LastTs,
-			LastTicks: c.State.LastTicks,
-			Value:     stateValue,
+			Status:        stateStatus,
+			LastTs:        c.State.LastTs,
+			LastTicks:     c.State.LastTicks,
+			Value:         stateValue,
+			Streak:        c.State.Streak,
+			StalledRuns:   c.State.StalledRuns,
+			DecidingTicks: mapDecidingTicks(c.State.DecidingTicks),
 		},
 	}, nil
 }
 
+func mapDecidingTicks(ticks []DecidingTick) []core.DecidingTick {
+	if len(ticks) == 0 {
+		return nil
+	}
+	result := []core.DecidingTick{}
+	for _, tick := range ticks {
+		result = append(result, core.DecidingTick{
+			Operand:        tick.Operand,
+			Exchange:       tick.Exchange,
+			CandleInterval: tick.CandleInterval,
+			Timestamp:      tick.Timestamp,
+			Value:          tick.Value,
+		})
+	}
+	return result
+}
+
+func mapFailovers(failovers []ExchangeFailover, name string) []core.ExchangeFailover {
+	if len(failovers) == 0 {
+		return nil
+	}
+	result := []core.ExchangeFailover{}
+	for _, failover := range failovers {
+		result = append(result, core.ExchangeFailover{
+			ConditionName: name,
+			FromOperand:   failover.From,
+			ToOperand:     failover.To,
+			Reason:        failover.Reason,
+			CreatedAt:     failover.CreatedAt,
+		})
+	}
+	return result
+}
+
 func mapBoolExpr(expr *string, def map[string]*core.Condition) (*core.BoolExpr, error) {
 	if expr == nil {
 		return nil, nil
@@ -195,6 +195,260 @@ func TestMapOperand(t *testing.T) {
 				Str:       "MARKETCAP:MESSARI:BTC",
 			},
 		},
+		{
+			raw: "COIN:BINANCE:BTC-USDT * 0.1",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.COIN,
+				Provider:   "BINANCE",
+				BaseAsset:  "BTC",
+				QuoteAsset: "USDT",
+				Str:        "COIN:BINANCE:BTC-USDT",
+				Multiplier: 0.1,
+			},
+		},
+		{
+			raw: "0.1 * COIN:BINANCE:BTC-USDT",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.COIN,
+				Provider:   "BINANCE",
+				BaseAsset:  "BTC",
+				QuoteAsset: "USDT",
+				Str:        "COIN:BINANCE:BTC-USDT",
+				Multiplier: 0.1,
+			},
+		},
+		{
+			raw: "COIN:BINANCE:BTC-USDT * 0",
+			err: core.ErrInvalidOperand,
+		},
+		{
+			raw: "0 * COIN:BINANCE:BTC-USDT",
+			err: core.ErrInvalidOperand,
+		},
+		{
+			raw: "60000 * 0.1",
+			err: nil,
+			expected: core.Operand{
+				Type:   core.NUMBER,
+				Number: 6000,
+				Str:    "60000 * 0.1",
+			},
+		},
+		{
+			raw: "COIN:BINANCE:BTC * 0.1",
+			err: core.ErrEmptyQuoteAsset,
+		},
+		{
+			raw: "1.2 * 30000",
+			err: nil,
+			expected: core.Operand{
+				Type:   core.NUMBER,
+				Number: 36000,
+				Str:    "1.2 * 30000",
+			},
+		},
+		{
+			raw: "-5",
+			err: nil,
+			expected: core.Operand{
+				Type:   core.NUMBER,
+				Number: -5,
+				Str:    "-5",
+			},
+		},
+		{
+			raw: "1 / (2 - 2)",
+			err: core.ErrInvalidOperand,
+		},
+		{
+			raw: "-15%",
+			err: nil,
+			expected: core.Operand{
+				Type:   core.PERCENT,
+				Number: -15,
+				Str:    "-15%",
+			},
+		},
+		{
+			raw: "+30%",
+			err: nil,
+			expected: core.Operand{
+				Type:   core.PERCENT,
+				Number: 30,
+				Str:    "+30%",
+			},
+		},
+		{
+			raw: "COIN:BINANCE:BTC-USDT * 110%",
+			err: core.ErrInvalidOperand,
+		},
+		{
+			raw: "(coin:binance:btc-usdt+coin:kucoin:btc-usdt)/2",
+			err: nil,
+			expected: core.Operand{
+				Type: core.EXPR,
+				Str:  "(COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT) / 2",
+				Expr: &core.Expr{
+					Operator: "/",
+					Operands: []*core.Expr{
+						{
+							Operator: "+",
+							Operands: []*core.Expr{
+								{Literal: &core.Operand{Type: core.COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:BINANCE:BTC-USDT"}},
+								{Literal: &core.Operand{Type: core.COIN, Provider: "KUCOIN", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:KUCOIN:BTC-USDT"}},
+							},
+						},
+						{Literal: &core.Operand{Type: core.NUMBER, Number: 2, Str: "2"}},
+					},
+				},
+			},
+		},
+		{
+			raw: "COIN:BINANCE:BTC-USDT - COIN:KUCOIN:BTC",
+			err: core.ErrEmptyQuoteAsset,
+		},
+		{
+			raw: "sma(coin:binance:btc-usdt,200d)",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.INDICATOR,
+				Provider:   "BINANCE",
+				BaseAsset:  "BTC",
+				QuoteAsset: "USDT",
+				Indicator:  "SMA",
+				Window:     "200d",
+				Str:        "SMA(COIN:BINANCE:BTC-USDT,200d)",
+			},
+		},
+		{
+			raw: "RSI(COIN:BINANCE:ETH-USDT, 14h)",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.INDICATOR,
+				Provider:   "BINANCE",
+				BaseAsset:  "ETH",
+				QuoteAsset: "USDT",
+				Indicator:  "RSI",
+				Window:     "14h",
+				Str:        "RSI(COIN:BINANCE:ETH-USDT,14h)",
+			},
+		},
+		{
+			raw: "COIN:BINANCE:BTC-USDT - EMA(COIN:BINANCE:BTC-USDT,50d)",
+			err: nil,
+			expected: core.Operand{
+				Type: core.EXPR,
+				Str:  "COIN:BINANCE:BTC-USDT - EMA(COIN:BINANCE:BTC-USDT,50d)",
+				Expr: &core.Expr{
+					Operator: "-",
+					Operands: []*core.Expr{
+						{Literal: &core.Operand{Type: core.COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:BINANCE:BTC-USDT"}},
+						{Literal: &core.Operand{Type: core.INDICATOR, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Indicator: "EMA", Window: "50d", Str: "EMA(COIN:BINANCE:BTC-USDT,50d)"}},
+					},
+				},
+			},
+		},
+		{
+			raw: "MACD(COIN:BINANCE:BTC-USDT,200d)",
+			err: core.ErrUnknownIndicator,
+		},
+		{
+			raw: "VOLUME(COIN:BINANCE:BTC-USDT,1d)",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.INDICATOR,
+				Provider:   "BINANCE",
+				BaseAsset:  "BTC",
+				QuoteAsset: "USDT",
+				Indicator:  "VOLUME",
+				Window:     "1d",
+				Str:        "VOLUME(COIN:BINANCE:BTC-USDT,1d)",
+			},
+		},
+		{
+			raw: "VOLUME(COIN:KUCOIN:BTC-USDT,1d)",
+			err: core.ErrVolumeIndicatorUnavailable,
+		},
+		{
+			raw: "SMA(COIN:BINANCE:BTC-USDT)",
+			err: core.ErrInvalidOperand,
+		},
+		{
+			raw: "SMA(MARKETCAP:MESSARI:BTC,200d)",
+			err: core.ErrInvalidOperand,
+		},
+		{
+			raw: "SMA(COIN:BINANCE:BTC-USDT,2y)",
+			err: core.ErrInvalidIndicatorWindow,
+		},
+		{
+			raw: "SMA(COIN:BINANCE:BTC-USDT,0d)",
+			err: core.ErrInvalidIndicatorWindow,
+		},
+		{
+			raw: "COIN:ANY:BTC-USDT",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.COIN,
+				Provider:   "ANY",
+				BaseAsset:  "BTC",
+				QuoteAsset: "USDT",
+				Str:        "COIN:ANY:BTC-USDT",
+			},
+		},
+		{
+			raw: "coin:median(binance, coinbase, kucoin):btc-usdt",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.COIN,
+				Provider:   "MEDIAN(BINANCE,COINBASE,KUCOIN)",
+				BaseAsset:  "BTC",
+				QuoteAsset: "USDT",
+				Str:        "COIN:MEDIAN(BINANCE,COINBASE,KUCOIN):BTC-USDT",
+			},
+		},
+		{
+			raw: "SMA(COIN:MEDIAN(BINANCE,COINBASE):BTC-USDT,200d)",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.INDICATOR,
+				Provider:   "MEDIAN(BINANCE,COINBASE)",
+				BaseAsset:  "BTC",
+				QuoteAsset: "USDT",
+				Indicator:  "SMA",
+				Window:     "200d",
+				Str:        "SMA(COIN:MEDIAN(BINANCE,COINBASE):BTC-USDT,200d)",
+			},
+		},
+		{
+			raw: "COIN:MEDIAN(BINANCE,COINBASE,KRAKEN):BTC-USDT",
+			err: nil,
+			expected: core.Operand{
+				Type:       core.COIN,
+				Provider:   "MEDIAN(BINANCE,COINBASE,KRAKEN)",
+				BaseAsset:  "BTC",
+				QuoteAsset: "USDT",
+				Str:        "COIN:MEDIAN(BINANCE,COINBASE,KRAKEN):BTC-USDT",
+			},
+		},
+		{
+			raw: "COIN:MEDIAN(KRAKEN,FTX):BTC-USDT",
+			err: core.ErrInvalidExchange,
+		},
+		{
+			raw: "COIN:MEDIAN(BINANCE):BTC-USDT",
+			err: core.ErrInvalidAggregateProvider,
+		},
+		{
+			raw: "COIN:ANY(BINANCE,BINANCE):BTC-USDT",
+			err: core.ErrInvalidAggregateProvider,
+		},
+		{
+			raw: "MARKETCAP:ANY:BTC",
+			err: core.ErrInvalidOperand,
+		},
 	}
 	for _, ts := range tss {
 		t.Run(ts.raw, func(t *testing.T) {
@@ -212,7 +466,7 @@ func TestMapOperand(t *testing.T) {
 				t.Logf("expected error '%v' but had error '%v'", ts.err, actualErr)
 				t.FailNow()
 			}
-			if actual != ts.expected {
+			if !reflect.DeepEqual(actual, ts.expected) {
 				t.Logf("expected %v but got %v", ts.expected, actual)
 				t.FailNow()
 			}
@@ -410,6 +664,69 @@ func TestMapCondition(t *testing.T) {
 			postedAt: tpToISO("2020-01-02 00:00:00"),
 			err:      core.ErrErrorMarginRatioAbove30,
 		},
+		{
+			name:     "Unknown evaluation mode",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", EvaluationMode: "OPEN"},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrUnknownEvaluationMode,
+		},
+		{
+			name:     "Close evaluation mode without candle interval",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", EvaluationMode: "CLOSE"},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidCandleInterval,
+		},
+		{
+			name:     "Close evaluation mode with unsupported candle interval",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", EvaluationMode: "CLOSE", CandleInterval: "3d"},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidCandleInterval,
+		},
+		{
+			name:     "Wick evaluation mode with candle interval",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", CandleInterval: "1d"},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidCandleInterval,
+		},
+		{
+			name:     "Sustained evaluation mode without sustained candles",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", EvaluationMode: "SUSTAINED", CandleInterval: "1d"},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidSustainedCandles,
+		},
+		{
+			name:     "Close evaluation mode with sustained candles",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", EvaluationMode: "CLOSE", CandleInterval: "1d", SustainedCandles: 3},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidSustainedCandles,
+		},
+		{
+			name:     "Invalid sustained for",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", SustainedFor: "forever"},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidSustainedFor,
+		},
+		{
+			name:     "Sustained for mismatching the deadline",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", SustainedFor: "2w", ToDuration: "1w"},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidSustainedFor,
+		},
+		{
+			name:     "Sustained for with sustained evaluation mode",
+			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", SustainedFor: "2w", EvaluationMode: "SUSTAINED", CandleInterval: "1d", SustainedCandles: 3},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidSustainedFor,
+		},
 		{
 			name:     "Unknown condition state value",
 			cond:     Condition{Condition: "COIN:BINANCE:BTC-USDT >= 60000", State: ConditionState{Value: "???"}},
@@ -486,6 +803,196 @@ func TestMapCondition(t *testing.T) {
 				ErrorMarginRatio: 0,
 			},
 		},
+		{
+			name:     "Percentage as the first operand",
+			cond:     Condition{Condition: "-15% >= COIN:BINANCE:BTC-USDT", ToISO8601: tpToISO("2020-01-03 00:00:00")},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      core.ErrInvalidOperand,
+		},
+		{
+			name: "Percentage with known baseline",
+			cond: Condition{
+				Condition: "COIN:BINANCE:BTC-USDT <= -15%",
+				State:     ConditionState{Value: "UNDECIDED", Status: "STARTED"},
+				ToISO8601: tpToISO("2020-01-03 00:00:00"),
+				Baseline:  29000,
+			},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      nil,
+			expected: core.Condition{
+				Name:     "main",
+				Operator: "<=",
+				Operands: []core.Operand{
+					{
+						Type:       core.COIN,
+						Provider:   "BINANCE",
+						QuoteAsset: "USDT",
+						BaseAsset:  "BTC",
+						Str:        "COIN:BINANCE:BTC-USDT",
+					},
+					{
+						Type:   core.PERCENT,
+						Number: -15,
+						Str:    "-15%",
+					},
+				},
+				FromTs:   int(tp("2020-01-02 00:00:00").Unix()),
+				ToTs:     int(tp("2020-01-03 00:00:00").Unix()),
+				State:    core.ConditionState{Value: core.UNDECIDED, Status: core.STARTED},
+				Baseline: 29000,
+			},
+		},
+		{
+			name: "Indicator operand",
+			cond: Condition{
+				Condition: "COIN:BINANCE:BTC-USDT > SMA(COIN:BINANCE:BTC-USDT, 200d)",
+				State:     ConditionState{Value: "UNDECIDED", Status: "STARTED"},
+				ToISO8601: tpToISO("2020-01-03 00:00:00"),
+			},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      nil,
+			expected: core.Condition{
+				Name:     "main",
+				Operator: ">",
+				Operands: []core.Operand{
+					{
+						Type:       core.COIN,
+						Provider:   "BINANCE",
+						QuoteAsset: "USDT",
+						BaseAsset:  "BTC",
+						Str:        "COIN:BINANCE:BTC-USDT",
+					},
+					{
+						Type:       core.INDICATOR,
+						Provider:   "BINANCE",
+						QuoteAsset: "USDT",
+						BaseAsset:  "BTC",
+						Indicator:  "SMA",
+						Window:     "200d",
+						Str:        "SMA(COIN:BINANCE:BTC-USDT,200d)",
+					},
+				},
+				FromTs: int(tp("2020-01-02 00:00:00").Unix()),
+				ToTs:   int(tp("2020-01-03 00:00:00").Unix()),
+				State:  core.ConditionState{Value: core.UNDECIDED, Status: core.STARTED},
+			},
+		},
+		{
+			name: "Multiplied coin operand",
+			cond: Condition{
+				Condition: "COIN:BINANCE:ETH-USDT > COIN:BINANCE:BTC-USDT * 0.1",
+				State:     ConditionState{Value: "UNDECIDED", Status: "STARTED"},
+				ToISO8601: tpToISO("2020-01-03 00:00:00"),
+			},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      nil,
+			expected: core.Condition{
+				Name:     "main",
+				Operator: ">",
+				Operands: []core.Operand{
+					{
+						Type:       core.COIN,
+						Provider:   "BINANCE",
+						QuoteAsset: "USDT",
+						BaseAsset:  "ETH",
+						Str:        "COIN:BINANCE:ETH-USDT",
+					},
+					{
+						Type:       core.COIN,
+						Provider:   "BINANCE",
+						QuoteAsset: "USDT",
+						BaseAsset:  "BTC",
+						Str:        "COIN:BINANCE:BTC-USDT",
+						Multiplier: 0.1,
+					},
+				},
+				FromTs: int(tp("2020-01-02 00:00:00").Unix()),
+				ToTs:   int(tp("2020-01-03 00:00:00").Unix()),
+				State:  core.ConditionState{Value: core.UNDECIDED, Status: core.STARTED},
+			},
+		},
+		{
+			name: "Sustained evaluation mode",
+			cond: Condition{
+				Condition:        "COIN:BINANCE:BTC-USDT >= 60000",
+				EvaluationMode:   "SUSTAINED",
+				CandleInterval:   "1d",
+				SustainedCandles: 3,
+				State:            ConditionState{Value: "UNDECIDED", Status: "STARTED", Streak: 2},
+				ToDuration:       "1w",
+			},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      nil,
+			expected: core.Condition{
+				Name:     "main",
+				Operator: ">=",
+				Operands: []core.Operand{
+					{
+						Type:       core.COIN,
+						Provider:   "BINANCE",
+						QuoteAsset: "USDT",
+						BaseAsset:  "BTC",
+						Str:        "COIN:BINANCE:BTC-USDT",
+					},
+					{
+						Type:   core.NUMBER,
+						Number: 60000,
+						Str:    "60000",
+					},
+				},
+				FromTs:           int(tp("2020-01-02 00:00:00").Unix()),
+				ToTs:             int(tp("2020-01-09 00:00:00").Unix()),
+				ToDuration:       "1w",
+				EvaluationMode:   core.SUSTAINED,
+				CandleInterval:   "1d",
+				SustainedCandles: 3,
+				State:            core.ConditionState{Value: core.UNDECIDED, Status: core.STARTED, Streak: 2},
+			},
+		},
+		{
+			name: "Sustained for",
+			cond: Condition{
+				Condition:    "COIN:BINANCE:BTC-USDT BETWEEN 17000 AND 20000",
+				SustainedFor: "2w",
+				ToISO8601:    tpToISO("2020-01-16 00:00:00"),
+				State:        ConditionState{Value: "UNDECIDED", Status: "STARTED"},
+			},
+			condName: "main",
+			postedAt: tpToISO("2020-01-02 00:00:00"),
+			err:      nil,
+			expected: core.Condition{
+				Name:     "main",
+				Operator: "BETWEEN",
+				Operands: []core.Operand{
+					{
+						Type:       core.COIN,
+						Provider:   "BINANCE",
+						QuoteAsset: "USDT",
+						BaseAsset:  "BTC",
+						Str:        "COIN:BINANCE:BTC-USDT",
+					},
+					{
+						Type:   core.NUMBER,
+						Number: 17000,
+						Str:    "17000",
+					},
+					{
+						Type:   core.NUMBER,
+						Number: 20000,
+						Str:    "20000",
+					},
+				},
+				FromTs:       int(tp("2020-01-02 00:00:00").Unix()),
+				ToTs:         int(tp("2020-01-16 00:00:00").Unix()),
+				SustainedFor: "2w",
+				State:        core.ConditionState{Value: core.UNDECIDED, Status: core.STARTED},
+			},
+		},
 	}
 	for _, ts := range tss {
 		t.Run(ts.name, func(t *testing.T) {
@@ -8,10 +8,22 @@ import (
 
 // ConditionState holds the state of evolving a condition using market data.
 type ConditionState struct {
-	Status    string               `json:"status" enum:"UNSTARTED,STARTED,FINISHED" example:"STARTED"`
-	LastTs    int                  `json:"lastTs" example:"1649594376"`
-	LastTicks map[string]core.Tick `json:"lastTicks"`
-	Value     string               `json:"value" enum:"UNDECIDED,TRUE,FALSE" example:"UNDECIDED"`
+	Status        string               `json:"status" enum:"UNSTARTED,STARTED,FINISHED" example:"STARTED"`
+	LastTs        int                  `json:"lastTs" example:"1649594376"`
+	LastTicks     map[string]core.Tick `json:"lastTicks"`
+	Value         string               `json:"value" enum:"UNDECIDED,TRUE,FALSE" example:"UNDECIDED"`
+	Streak        int                  `json:"streak,omitempty" example:"2"`
+	StalledRuns   int                  `json:"stalledRuns,omitempty" example:"0"`
+	DecidingTicks []DecidingTick       `json:"decidingTicks,omitempty"`
+}
+
+// DecidingTick is the tick of one of a condition's operands that moved it to TRUE or FALSE.
+type DecidingTick struct {
+	Operand        string             `json:"operand" example:"COIN:BINANCE:BTC-USDT"`
+	Exchange       string             `json:"exchange" example:"BINANCE"`
+	CandleInterval string             `json:"candleInterval" example:"1m"`
+	Timestamp      int                `json:"timestamp" example:"1649594376"`
+	Value          common.JSONFloat64 `json:"value" example:"45000.5"`
 }
 
 // PredictionState holds the state of evolving a prediction using market data.
@@ -19,17 +31,34 @@ type PredictionState struct {
 	Status string `json:"status" enum:"UNSTARTED,STARTED,FINISHED" example:"FINISHED"`
 	LastTs int    `json:"lastTs" example:"1649594376"`
 	Value  string `json:"value" enum:"ONGOING_PRE_PREDICTION,ONGOING_PREDICTION,CORRECT,INCORRECT,ANNULLED" example:"CORRECT"`
+	// Difficulty is only set on CORRECT predictions (see core.Prediction.Difficulty).
+	Difficulty float64 `json:"difficulty,omitempty" example:"0.3"`
 }
 
 // Condition is each of the conditions that form a prediction, e.g. "COIN:BINANCE:BTC-USDT <= 29000 within 3 weeks".
 type Condition struct {
-	Condition        string         `json:"condition" required:"true" example:"COIN:BINANCE:BTC-USDT <= 29000"`
-	FromISO8601      core.ISO8601   `json:"fromISO8601" format:"date-time" example:"2022-01-26T22:35:43Z"`
-	ToISO8601        core.ISO8601   `json:"toISO8601" format:"date-time" example:"2023-01-01T00:00:00Z"`
-	ToDuration       string         `json:"toDuration" example:"eoy"`
-	Assumed          []string       `json:"assumed" example:"[\"toDuration\"]"`
-	State            ConditionState `json:"state"`
-	ErrorMarginRatio float64        `json:"errorMarginRatio" example:"0.03"`
+	Condition        string             `json:"condition" required:"true" example:"COIN:BINANCE:BTC-USDT <= 29000"`
+	FromISO8601      core.ISO8601       `json:"fromISO8601" format:"date-time" example:"2022-01-26T22:35:43Z"`
+	ToISO8601        core.ISO8601       `json:"toISO8601" format:"date-time" example:"2023-01-01T00:00:00Z"`
+	ToDuration       string             `json:"toDuration" example:"eoy"`
+	Assumed          []string           `json:"assumed" example:"[\"toDuration\"]"`
+	State            ConditionState     `json:"state"`
+	ErrorMarginRatio float64            `json:"errorMarginRatio" example:"0.03"`
+	Baseline         float64            `json:"baseline,omitempty" example:"29000"`
+	EvaluationMode   string             `json:"evaluationMode,omitempty" enum:"WICK,CLOSE,SUSTAINED" example:"CLOSE"`
+	CandleInterval   string             `json:"candleInterval,omitempty" enum:"1m,5m,15m,30m,1h,2h,4h,6h,12h,1d,1w" example:"1d"`
+	SustainedCandles int                `json:"sustainedCandles,omitempty" example:"3"`
+	SustainedFor     string             `json:"sustainedFor,omitempty" example:"2w"`
+	Failovers        []ExchangeFailover `json:"failovers,omitempty"`
+}
+
+// ExchangeFailover is the switch of a condition's operand to the same market on a fallback exchange, because its
+// exchange stopped providing market data.
+type ExchangeFailover struct {
+	From      string       `json:"from" example:"COIN:BINANCE:BTC-USDT"`
+	To        string       `json:"to" example:"COIN:KUCOIN:BTC-USDT"`
+	Reason    string       `json:"reason" example:"no new ticks since 2022-07-01T00:00:00Z"`
+	CreatedAt core.ISO8601 `json:"createdAt" format:"date-time" example:"2022-07-02T00:00:00Z"`
 }
 
 // PrePredict is a subpart of a Prediction that represents an initial step that is required for a two-step prediction.
@@ -64,7 +93,7 @@ type Prediction struct {
 	PrePredict      *PrePredict          `json:"prePredict,omitempty"`
 	Predict         Predict              `json:"predict,omitempty" required:"true"`
 	PredictionState PredictionState      `json:"state"`
-	Type            string               `json:"type" description:"This is automatically calculated based on the prediction's structure." enum:"PREDICTION_TYPE_UNSUPPORTED,PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE,PREDICTION_TYPE_COIN_WILL_RANGE,PREDICTION_TYPE_COIN_WILL_REACH_BEFORE_IT_REACHES,PREDICTION_TYPE_THE_FLIPPENING" example:"PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE"`
+	Type            string               `json:"type" description:"This is automatically calculated based on the prediction's structure." enum:"PREDICTION_TYPE_UNSUPPORTED,PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE,PREDICTION_TYPE_COIN_WILL_RANGE,PREDICTION_TYPE_COIN_WILL_REACH_BEFORE_IT_REACHES,PREDICTION_TYPE_THE_FLIPPENING,PREDICTION_TYPE_COIN_OPERATOR_COIN_DEADLINE" example:"PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE"`
 
 	// extra fields for API, but not for Postgres
 	PredictionText string            `json:"predictionText,omitempty" example:"COIN:BINANCE:BTC-USDT will hit 29000 by end of year"`
@@ -76,12 +105,14 @@ type Prediction struct {
 
 // PredictionSummary contains all necessary information about the prediction to make a candlestick chart of it.
 type PredictionSummary struct {
-	// Only in "PredictionTheFlippening" type
-	OtherCoin string `json:"otherCoin,omitempty"`
+	// Only in "PredictionTheFlippening" & "PredictionTypeCoinOperatorCoinDeadline" types
+	OtherCoin           string           `json:"otherCoin,omitempty"`
+	OtherCoinMultiplier core.JSONFloat64 `json:"otherCoinMultiplier,omitempty"`
 
 	// Only in "PredictionTypeCoinOperatorFloatDeadline" type
 	Goal                                    core.JSONFloat64 `json:"goal,omitempty"`
 	GoalWithError                           core.JSONFloat64 `json:"goalWithError,omitempty"`
+	GoalPercent                             core.JSONFloat64 `json:"goalPercent,omitempty"` // only if the goal is relative to the price at post time, e.g. 30 for "+30%"
 	EndedAtTruncatedDueToResultInvalidation core.ISO8601     `json:"endedAtTruncatedDueToResultInvalidation,omitempty"`
 
 	// Only in "PredictionTypeCoinWillReachInvalidatedIfItReaches"
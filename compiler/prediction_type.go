@@ -18,8 +18,9 @@ var (
 			return pred.PrePredict.Predict == nil && pred.PrePredict.AnnulledIf == nil &&
 				pred.PrePredict.WrongIf == nil && pred.Predict.AnnulledIf == nil && pred.Predict.WrongIf == nil &&
 				pred.Predict.Predict.Operator == core.LITERAL && len(pred.Predict.Predict.Literal.Operands) == 2 &&
+				pred.Predict.Predict.Literal.SustainedFor == "" &&
 				pred.Predict.Predict.Literal.Operands[0].Type == core.COIN &&
-				pred.Predict.Predict.Literal.Operands[1].Type == core.NUMBER
+				(pred.Predict.Predict.Literal.Operands[1].Type == core.NUMBER || pred.Predict.Predict.Literal.Operands[1].Type == core.PERCENT)
 		},
 		core.PredictionTypeCoinWillReachInvalidatedIfItReaches: func(pred core.Prediction) bool {
 			return pred.PrePredict.Predict == nil && pred.PrePredict.AnnulledIf == nil &&
@@ -27,6 +28,7 @@ var (
 
 				// Predict section
 				pred.Predict.Predict.Operator == core.LITERAL && len(pred.Predict.Predict.Literal.Operands) == 2 &&
+				pred.Predict.Predict.Literal.SustainedFor == "" &&
 				pred.Predict.Predict.Literal.Operands[0].Type == core.COIN &&
 				pred.Predict.Predict.Literal.Operands[1].Type == core.NUMBER &&
 
@@ -42,7 +44,7 @@ var (
 			return pred.PrePredict.Predict == nil && pred.PrePredict.AnnulledIf == nil &&
 				pred.PrePredict.WrongIf == nil && pred.Predict.AnnulledIf == nil && pred.Predict.WrongIf == nil &&
 				pred.Predict.Predict.Operator == core.LITERAL && len(pred.Predict.Predict.Literal.Operands) == 3 &&
-				pred.Predict.Predict.Literal.Operator == "BETWEEN" &&
+				pred.Predict.Predict.Literal.Operator == "BETWEEN" && pred.Predict.Predict.Literal.SustainedFor != "" &&
 				pred.Predict.Predict.Literal.Operands[0].Type == core.COIN &&
 				pred.Predict.Predict.Literal.Operands[1].Type == core.NUMBER &&
 				pred.Predict.Predict.Literal.Operands[2].Type == core.NUMBER
@@ -68,6 +70,23 @@ var (
 				pred.Predict.Predict.Operands[0].Literal.Operands[0] == pred.Predict.Predict.Operands[1].Operands[0].Literal.Operands[0] &&
 				pred.Predict.Predict.Operands[0].Literal.Operator != pred.Predict.Predict.Operands[1].Operands[0].Literal.Operator
 		},
+		core.PredictionTypeTheFlippening: func(pred core.Prediction) bool {
+			return pred.PrePredict.Predict == nil && pred.PrePredict.AnnulledIf == nil &&
+				pred.PrePredict.WrongIf == nil && pred.Predict.AnnulledIf == nil && pred.Predict.WrongIf == nil &&
+				pred.Predict.Predict.Operator == core.LITERAL && len(pred.Predict.Predict.Literal.Operands) == 2 &&
+				pred.Predict.Predict.Literal.SustainedFor == "" &&
+				(pred.Predict.Predict.Literal.Operator == ">" || pred.Predict.Predict.Literal.Operator == ">=") &&
+				pred.Predict.Predict.Literal.Operands[0].Type == core.MARKETCAP &&
+				pred.Predict.Predict.Literal.Operands[1].Type == core.MARKETCAP
+		},
+		core.PredictionTypeCoinOperatorCoinDeadline: func(pred core.Prediction) bool {
+			return pred.PrePredict.Predict == nil && pred.PrePredict.AnnulledIf == nil &&
+				pred.PrePredict.WrongIf == nil && pred.Predict.AnnulledIf == nil && pred.Predict.WrongIf == nil &&
+				pred.Predict.Predict.Operator == core.LITERAL && len(pred.Predict.Predict.Literal.Operands) == 2 &&
+				pred.Predict.Predict.Literal.SustainedFor == "" &&
+				pred.Predict.Predict.Literal.Operands[0].Type == core.COIN &&
+				pred.Predict.Predict.Literal.Operands[1].Type == core.COIN
+		},
 	}
 )
 
@@ -33,6 +33,24 @@ func TestPredictionType(t *testing.T) {
 			}`,
 			expected: core.PredictionTypeCoinOperatorFloatDeadline,
 		},
+		{
+			name: "PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE relative to the price at post time",
+			pred: `{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+				"postedAt": "2022-02-09T10:25:26.000Z",
+				"given": {
+					"main": {
+						"condition": "COIN:BINANCE:BTC-USDT >= +30%",
+						"toDuration": "eom"
+					}
+				},
+				"predict": {
+					"predict": "main"
+				}
+			}`,
+			expected: core.PredictionTypeCoinOperatorFloatDeadline,
+		},
 		{
 			name: "Basic PREDICTION_TYPE_COIN_WILL_REACH_INVALIDATED_IF_IT_REACHES",
 			pred: `{
@@ -66,7 +84,7 @@ func TestPredictionType(t *testing.T) {
 				"given": {
 					"main": {
 						"condition": "COIN:BINANCE:ADA-USDT BETWEEN 0.845 AND 0.1",
-						"toDuration": "2d"
+						"sustainedFor": "2d"
 					}
 				},
 				"predict": {
@@ -75,6 +93,114 @@ func TestPredictionType(t *testing.T) {
 			}`,
 			expected: core.PredictionTypeCoinWillRange,
 		},
+		{
+			name: "BETWEEN that isn't sustained is not PREDICTION_TYPE_COIN_WILL_RANGE",
+			pred: `{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+				"postedAt": "2022-02-09T10:25:26.000Z",
+				"given": {
+					"main": {
+						"condition": "COIN:BINANCE:ADA-USDT BETWEEN 0.845 AND 0.1",
+						"toDuration": "2d"
+					}
+				},
+				"predict": {
+					"predict": "main"
+				}
+			}`,
+			expected: core.PredictionTypeUnsupported,
+		},
+		{
+			name: "Sustained condition is not PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE",
+			pred: `{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+				"postedAt": "2022-02-09T10:25:26.000Z",
+				"given": {
+					"main": {
+						"condition": "COIN:BINANCE:ADA-USDT >= 0.845",
+						"sustainedFor": "2d"
+					}
+				},
+				"predict": {
+					"predict": "main"
+				}
+			}`,
+			expected: core.PredictionTypeUnsupported,
+		},
+		{
+			name: "Basic PREDICTION_TYPE_THE_FLIPPENING",
+			pred: `{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/Nicholas_Merten/status/1467462765958807556",
+				"postedAt": "2021-12-05T11:56:02.000Z",
+				"given": {
+					"main": {
+						"condition": "MARKETCAP:MESSARI:ETH > MARKETCAP:MESSARI:BTC",
+						"toDuration": "eoy"
+					}
+				},
+				"predict": {
+					"predict": "main"
+				}
+			}`,
+			expected: core.PredictionTypeTheFlippening,
+		},
+		{
+			name: "A marketcap exceeding a number is not PREDICTION_TYPE_THE_FLIPPENING",
+			pred: `{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/Nicholas_Merten/status/1467462765958807556",
+				"postedAt": "2021-12-05T11:56:02.000Z",
+				"given": {
+					"main": {
+						"condition": "MARKETCAP:MESSARI:ETH > 1000000000000",
+						"toDuration": "eoy"
+					}
+				},
+				"predict": {
+					"predict": "main"
+				}
+			}`,
+			expected: core.PredictionTypeUnsupported,
+		},
+		{
+			name: "Basic PREDICTION_TYPE_COIN_OPERATOR_COIN_DEADLINE",
+			pred: `{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+				"postedAt": "2022-02-09T10:25:26.000Z",
+				"given": {
+					"main": {
+						"condition": "COIN:BINANCE:ETH-USDT > COIN:BINANCE:BTC-USDT * 0.1",
+						"toDuration": "eoy"
+					}
+				},
+				"predict": {
+					"predict": "main"
+				}
+			}`,
+			expected: core.PredictionTypeCoinOperatorCoinDeadline,
+		},
+		{
+			name: "PREDICTION_TYPE_COIN_OPERATOR_COIN_DEADLINE with the multiplier first",
+			pred: `{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+				"postedAt": "2022-02-09T10:25:26.000Z",
+				"given": {
+					"main": {
+						"condition": "COIN:BINANCE:ETH-USDT > 0.1 * COIN:BINANCE:BTC-USDT",
+						"toDuration": "eoy"
+					}
+				},
+				"predict": {
+					"predict": "main"
+				}
+			}`,
+			expected: core.PredictionTypeCoinOperatorCoinDeadline,
+		},
 		{
 			name: "Basic PREDICTION_TYPE_COIN_WILL_REACH_BEFORE_IT_REACHES",
 			pred: `{
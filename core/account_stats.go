@@ -0,0 +1,199 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AccountStats are the results of an account's finished predictions.
+//
+// Accuracy is the ratio of CORRECT predictions among the CORRECT & INCORRECT ones, i.e. ANNULLED predictions don't
+// count towards it. Score weighs each prediction by its Difficulty: a CORRECT prediction scores 1 plus its Difficulty,
+// an INCORRECT one scores -1, and an ANNULLED one scores nothing. The Difficulty is the one stored in the CORRECT
+// prediction's state, rather than calculated on the fly, as that takes market data.
+type AccountStats struct {
+	Handle    string
+	Correct   int
+	Incorrect int
+	Annulled  int
+	Accuracy  float64
+	Score     float64
+}
+
+// AccountStatsFilters is the set of filters for the predictions that count towards AccountStats. Empty filters match
+// every prediction.
+type AccountStatsFilters struct {
+	AuthorHandles   []string         // e.g. "Datadash"
+	AuthorURLs      []string         // e.g. "https://twitter.com/Datadash"
+	Coins           []string         // base assets, e.g. "BTC", matching predictions with any operand on any of them
+	PredictionTypes []PredictionType // e.g. PredictionTypeCoinOperatorFloatDeadline
+	PostedFrom      time.Time        // inclusive
+	PostedTo        time.Time        // exclusive
+}
+
+// PriceFunc returns the price of a COIN Operand at the given time, or false if it's unknown.
+type PriceFunc func(operand Operand, tm time.Time) (float64, bool)
+
+// Matches returns whether the prediction passes the filters.
+func (f AccountStatsFilters) Matches(p Prediction) bool {
+	if len(f.AuthorHandles) > 0 && !containsString(f.AuthorHandles, p.PostAuthor) {
+		return false
+	}
+	if len(f.AuthorURLs) > 0 && !containsString(f.AuthorURLs, p.PostAuthorURL) {
+		return false
+	}
+	if len(f.PredictionTypes) > 0 && !containsPredictionType(f.PredictionTypes, p.Type) {
+		return false
+	}
+	if !f.PostedFrom.IsZero() || !f.PostedTo.IsZero() {
+		postedAt, err := p.PostedAt.Time()
+		if err != nil {
+			return false
+		}
+		if (!f.PostedFrom.IsZero() && postedAt.Before(f.PostedFrom)) || (!f.PostedTo.IsZero() && !postedAt.Before(f.PostedTo)) {
+			return false
+		}
+	}
+	if len(f.Coins) == 0 {
+		return true
+	}
+	for _, cond := range p.Given {
+		for _, operand := range cond.NonNumberOperands() {
+			for _, coin := range f.Coins {
+				if strings.EqualFold(operand.BaseAsset, coin) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Difficulty is how far the prediction's price goal was from the coin's price at post time, relative to that price,
+// e.g. 0.3 for "Bitcoin will reach 39k" when Bitcoin was at 30k.
+//
+// Only PREDICTION_TYPE_COIN_OPERATOR_FLOAT_DEADLINE & PREDICTION_TYPE_COIN_WILL_REACH_BEFORE_IT_REACHES predictions have
+// a price goal. It's 0 for the other types, and when the price at post time is unknown.
+func (p Prediction) Difficulty(priceAt PriceFunc) float64 {
+	var (
+		coin Operand
+		goal float64
+	)
+	switch p.Type {
+	case PredictionTypeCoinOperatorFloatDeadline:
+		typedPred := PredictionTypeCoinOperatorFloatDeadlineWrapper{P: p}
+		if goalPercent := typedPred.GoalPercent(); goalPercent != 0 {
+			return math.Abs(float64(goalPercent)) / 100
+		}
+		coin, goal = typedPred.Coin(), float64(typedPred.Goal())
+	case PredictionTypeCoinWillReachBeforeItReaches:
+		typedPred := PredictionTypeCoinWillReachBeforeItReachesWrapper{P: p}
+		coin, goal = typedPred.Coin(), float64(typedPred.WillReach())
+	default:
+		return 0
+	}
+
+	postedAt, err := p.PostedAt.Time()
+	if err != nil {
+		return 0
+	}
+	price, ok := priceAt(coin, postedAt)
+	if !ok || price == 0 {
+		return 0
+	}
+	return math.Abs(goal-price) / price
+}
+
+// NewMarketPriceFunc returns a PriceFunc that reads the price at the opening of the 1 minute candlestick of the given
+// time from the market. Prices are cached for the lifetime of the function, as many predictions are posted on the
+// same coins. Market errors are treated as unknown prices.
+func NewMarketPriceFunc(market IMarket) PriceFunc {
+	cache := map[string]float64{}
+	return func(operand Operand, tm time.Time) (float64, bool) {
+		key := fmt.Sprintf("%v@%v", operand.Str, tm.Truncate(time.Minute).Unix())
+		if price, ok := cache[key]; ok {
+			return price, price != 0
+		}
+		var price float64
+		if it, err := market.Iterator(operand, tm, time.Minute); err == nil {
+			if candlestick, err := it.Next(); err == nil {
+				price = float64(candlestick.OpenPrice)
+			}
+		}
+		cache[key] = price
+		return price, price != 0
+	}
+}
+
+// CalculateAccountStats calculates the AccountStats of the authors of the predictions that match the filters, from the
+// highest Score to the lowest. Predictions that aren't CORRECT, INCORRECT or ANNULLED yet are ignored.
+func CalculateAccountStats(predictions []Prediction, filters AccountStatsFilters) []AccountStats {
+	statsByHandle := map[string]*AccountStats{}
+	for _, p := range predictions {
+		if (p.State.Value != CORRECT && p.State.Value != INCORRECT && p.State.Value != ANNULLED) || !filters.Matches(p) {
+			continue
+		}
+		stats, ok := statsByHandle[p.PostAuthor]
+		if !ok {
+			stats = &AccountStats{Handle: p.PostAuthor}
+			statsByHandle[p.PostAuthor] = stats
+		}
+		switch p.State.Value {
+		case CORRECT:
+			stats.Correct++
+			stats.Score += 1 + p.State.Difficulty
+		case INCORRECT:
+			stats.Incorrect++
+			stats.Score--
+		case ANNULLED:
+			stats.Annulled++
+		}
+	}
+
+	res := []AccountStats{}
+	for _, stats := range statsByHandle {
+		res = append(res, *stats)
+	}
+	return RankAccountStats(res)
+}
+
+// RankAccountStats calculates the Accuracy of each of the stats from their counts, and sorts them from the highest
+// Score to the lowest.
+func RankAccountStats(res []AccountStats) []AccountStats {
+	for i := range res {
+		if res[i].Correct+res[i].Incorrect > 0 {
+			res[i].Accuracy = float64(res[i].Correct) / float64(res[i].Correct+res[i].Incorrect)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		if res[i].Accuracy != res[j].Accuracy {
+			return res[i].Accuracy > res[j].Accuracy
+		}
+		return res[i].Handle < res[j].Handle
+	})
+	return res
+}
+
+func containsPredictionType(predictionTypes []PredictionType, predictionType PredictionType) bool {
+	for _, pt := range predictionTypes {
+		if pt == predictionType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, candidate := range ss {
+		if candidate == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateAccountStats(t *testing.T) {
+	var (
+		btc = operand("COIN:BINANCE:BTC-USDT")
+		eth = operand("COIN:BINANCE:ETH-USDT")
+	)
+	goalPrediction := func(author string, value PredictionStateValue, coin Operand, goal string, postedAt ISO8601) Prediction {
+		cond := &Condition{Name: "main", Operator: ">=", Operands: []Operand{coin, operand(goal)}}
+		return Prediction{
+			PostAuthor: author,
+			PostedAt:   postedAt,
+			Type:       PredictionTypeCoinOperatorFloatDeadline,
+			Given:      map[string]*Condition{"main": cond},
+			Predict:    Predict{Predict: BoolExpr{Operator: LITERAL, Literal: cond}},
+			State:      PredictionState{Value: value},
+		}
+	}
+	percentPrediction := goalPrediction("Datadash", CORRECT, btc, "0", "2022-01-01T00:00:00Z")
+	percentPrediction.Given["main"].Operands[1] = Operand{Type: PERCENT, Number: -20, Str: "-20%"}
+
+	willReach := &Condition{Name: "a", Operator: ">=", Operands: []Operand{eth, operand("4000")}}
+	beforeItReaches := &Condition{Name: "b", Operator: "<=", Operands: []Operand{eth, operand("2000")}}
+	willReachPrediction := Prediction{
+		PostAuthor: "rovercrc",
+		PostedAt:   "2022-01-01T00:00:00Z",
+		Type:       PredictionTypeCoinWillReachBeforeItReaches,
+		Given:      map[string]*Condition{"a": willReach, "b": beforeItReaches},
+		Predict: Predict{Predict: BoolExpr{Operator: AND, Operands: []*BoolExpr{
+			{Operator: LITERAL, Literal: willReach},
+			{Operator: NOT, Operands: []*BoolExpr{{Operator: LITERAL, Literal: beforeItReaches}}},
+		}}},
+		State: PredictionState{Value: CORRECT},
+	}
+
+	var (
+		predictions = []Prediction{
+			goalPrediction("Datadash", CORRECT, btc, "60000", "2022-01-01T00:00:00Z"),
+			goalPrediction("Datadash", INCORRECT, btc, "33000", "2022-02-01T00:00:00Z"),
+			goalPrediction("Datadash", ANNULLED, btc, "90000", "2022-02-01T00:00:00Z"),
+			percentPrediction,
+			willReachPrediction,
+			goalPrediction("CryptoCapo_", INCORRECT, eth, "5000", "2022-01-01T00:00:00Z"),
+			goalPrediction("CryptoCapo_", ONGOINGPREDICTION, btc, "60000", "2022-01-01T00:00:00Z"),
+		}
+		prices = map[string]float64{"COIN:BINANCE:BTC-USDT": 30000, "COIN:BINANCE:ETH-USDT": 3200}
+	)
+	priceAt := func(operand Operand, tm time.Time) (float64, bool) {
+		price, ok := prices[operand.Str]
+		return price, ok
+	}
+
+	for i := range predictions {
+		if predictions[i].State.Value == CORRECT {
+			predictions[i].State.Difficulty = predictions[i].Difficulty(priceAt)
+		}
+	}
+
+	tss := []struct {
+		name     string
+		filters  AccountStatsFilters
+		expected []AccountStats
+	}{
+		{
+			name:    "Without filters, every finished prediction counts",
+			filters: AccountStatsFilters{},
+			expected: []AccountStats{
+				{Handle: "Datadash", Correct: 2, Incorrect: 1, Annulled: 1, Accuracy: 2.0 / 3, Score: 2 + 1 + 0.2 - 1},
+				{Handle: "rovercrc", Correct: 1, Accuracy: 1, Score: 1.25},
+				{Handle: "CryptoCapo_", Incorrect: 1, Accuracy: 0, Score: -1},
+			},
+		},
+		{
+			name:    "Filtering by coin",
+			filters: AccountStatsFilters{Coins: []string{"eth"}},
+			expected: []AccountStats{
+				{Handle: "rovercrc", Correct: 1, Accuracy: 1, Score: 1.25},
+				{Handle: "CryptoCapo_", Incorrect: 1, Accuracy: 0, Score: -1},
+			},
+		},
+		{
+			name:    "Filtering by author",
+			filters: AccountStatsFilters{AuthorHandles: []string{"rovercrc"}},
+			expected: []AccountStats{
+				{Handle: "rovercrc", Correct: 1, Accuracy: 1, Score: 1.25},
+			},
+		},
+		{
+			name:    "Filtering by prediction type and time window",
+			filters: AccountStatsFilters{PredictionTypes: []PredictionType{PredictionTypeCoinOperatorFloatDeadline}, PostedFrom: tp("2022-01-15 00:00:00"), PostedTo: tp("2022-03-01 00:00:00")},
+			expected: []AccountStats{
+				{Handle: "Datadash", Incorrect: 1, Annulled: 1, Accuracy: 0, Score: -1},
+			},
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			actual := CalculateAccountStats(predictions, ts.filters)
+			require.Len(t, actual, len(ts.expected))
+			for i := range ts.expected {
+				require.InDelta(t, ts.expected[i].Score, actual[i].Score, 0.0001)
+				require.InDelta(t, ts.expected[i].Accuracy, actual[i].Accuracy, 0.0001)
+				actual[i].Score, actual[i].Accuracy = ts.expected[i].Score, ts.expected[i].Accuracy
+			}
+			require.Equal(t, ts.expected, actual)
+		})
+	}
+}
+
+func TestPredictionDifficultyWithUnknownPrice(t *testing.T) {
+	cond := &Condition{Operator: ">=", Operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")}}
+	prediction := Prediction{
+		PostedAt: "2022-01-01T00:00:00Z",
+		Type:     PredictionTypeCoinOperatorFloatDeadline,
+		Predict:  Predict{Predict: BoolExpr{Operator: LITERAL, Literal: cond}},
+	}
+	require.Equal(t, 0.0, prediction.Difficulty(func(Operand, time.Time) (float64, bool) { return 0, false }))
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Aggregate providers combine the markets of several exchanges into one COIN operand, so that a prediction doesn't
+// stall when one exchange delists the market pair or goes down, e.g.:
+//
+// - COIN:ANY:BTC-USDT is the price on the first of the default exchanges that has it, in order of preference.
+//
+// - COIN:MEDIAN(BINANCE,COINBASE,KUCOIN):BTC-USDT is the median of the prices on the listed exchanges that have it.
+//
+// Both ANY & MEDIAN may list their exchanges or use the default ones.
+const (
+	// AggregateAny is an aggregate provider that takes the price from the first exchange that has it.
+	AggregateAny = "ANY"
+	// AggregateMedian is an aggregate provider that takes the median of the prices of all exchanges that have it.
+	AggregateMedian = "MEDIAN"
+)
+
+var (
+	// DefaultAggregateExchanges are the exchanges of aggregate providers that don't list them, in order of preference.
+	DefaultAggregateExchanges = []string{"BINANCE", "COINBASE", "KUCOIN", "BITSTAMP", "BITFINEX"}
+
+	// validExchanges are the exchanges that crypto-candles provides market data for. Aggregate providers may list
+	// others (e.g. KRAKEN in MEDIAN(BINANCE,COINBASE,KRAKEN)), which are left out until crypto-candles supports them.
+	validExchanges      = map[string]bool{"BINANCE": true, "COINBASE": true, "KUCOIN": true, "BITSTAMP": true, "BITFINEX": true, "BINANCEUSDMFUTURES": true}
+	rxAggregateProvider = regexp.MustCompile(`^(ANY|MEDIAN)(\(([A-Z,]*)\))?$`)
+)
+
+// IsAggregateProvider returns whether the provider of an operand is an aggregate provider, e.g. ANY or
+// MEDIAN(BINANCE,COINBASE). It doesn't validate the listed exchanges (see ParseAggregateProvider).
+func IsAggregateProvider(provider string) bool {
+	return provider == AggregateAny || provider == AggregateMedian ||
+		strings.HasPrefix(provider, AggregateAny+"(") || strings.HasPrefix(provider, AggregateMedian+"(")
+}
+
+// ParseAggregateProvider parses an aggregate provider into its aggregation (i.e. ANY or MEDIAN) and its exchanges in
+// order of preference, e.g. "MEDIAN" and [BINANCE COINBASE] for MEDIAN(BINANCE,COINBASE).
+//
+// Exchanges without market data are accepted, as long as one of the listed exchanges has it.
+func ParseAggregateProvider(provider string) (string, []string, error) {
+	matches := rxAggregateProvider.FindStringSubmatch(provider)
+	if len(matches) == 0 {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidAggregateProvider, provider)
+	}
+	if matches[2] == "" {
+		return matches[1], DefaultAggregateExchanges, nil
+	}
+	exchanges := strings.Split(matches[3], ",")
+	if len(exchanges) < 2 {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidAggregateProvider, provider)
+	}
+	var (
+		seen   = map[string]bool{}
+		usable = false
+	)
+	for _, exchange := range exchanges {
+		if seen[exchange] {
+			return "", nil, fmt.Errorf("%w: %v is listed twice in %v", ErrInvalidAggregateProvider, exchange, provider)
+		}
+		seen[exchange] = true
+		usable = usable || validExchanges[exchange]
+	}
+	if !usable {
+		return "", nil, fmt.Errorf("%w: none of the exchanges of %v is one of them, because there's no market data for them (i.e. crypto-candles doesn't support them)", ErrInvalidExchange, provider)
+	}
+	return matches[1], exchanges, nil
+}
+
+// Exchanges returns the exchanges a non-NUMBER Operand reads market data from, i.e. its provider, or all the exchanges
+// of its aggregate provider.
+func (o Operand) Exchanges() []string {
+	if IsAggregateProvider(o.Provider) {
+		if _, exchanges, err := ParseAggregateProvider(o.Provider); err == nil {
+			return exchanges
+		}
+	}
+	return []string{o.Provider}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
 )
 
 // Condition represents a boolean condition that looks like "BTC/USDT >= 45000 within 3 weeks".
@@ -19,10 +21,27 @@ import (
 // ToTs still computes the timestamp as a result of adding the period to FromTs. Review compiler.parseDuration for an
 // exhaustive definition of valid values of ToDuration.
 //
+// Some people predict relative moves, e.g. "BTC will drop 15% from here", which is represented by PERCENT Operands
+// (e.g. "COIN:BINANCE:BTC-USDT <= -15%"). These are relative to the Baseline, which is the value of the first
+// Operand at post time. The compiler resolves it when it has a market, or otherwise Condition.Run does when the
+// Condition starts (i.e. normally at post time).
+//
 // Often, people consider predictions that _almost_ became true to be true nonetheless. Because of this reason,
 // ErrorMarginRatio allows a Condition to become CORRECT when the literal number in the boolean condition evaluated is
 // not yet satisfying the condition, but it's ErrorMarginRatio% away from satisfying it.
 //
+// A Condition's EvaluationMode determines whether any price satisfying it makes it true (i.e. a wick), or only the
+// close prices of candles of its CandleInterval (e.g. "BTC closes the week above 50k"), optionally for a number of
+// SustainedCandles in a row (see EvaluationMode).
+//
+// Some people predict that something will hold for a while, e.g. "BTC will range between 17k and 20k for 2 weeks",
+// which is represented by a SustainedFor duration (e.g. "2w"). These Conditions must hold at every tick from FromTs to
+// ToTs (i.e. FromTs plus SustainedFor), so they become FALSE on the first tick that violates them, and TRUE once they
+// have held until ToTs.
+//
+// If a Condition's market data stops (e.g. its exchange delists the market pair), the Daemon may switch its operands
+// to a fallback exchange, which is recorded in Failovers (see Condition.FailOver).
+//
 // Conditions are evolved by calling Condition.Run and supplying market Ticks, which are the closing prices of market
 // candlesticks at 1 minute intervals. The Condition's State contains the results of that evolution, that is, whether
 // the Condition has finished evolving and reached a final state, what were the latest ticks supplied, etc.
@@ -40,8 +59,17 @@ type Condition struct {
 	Assumed          []string // unused for now
 	State            ConditionState
 	ErrorMarginRatio float64
+	Baseline         float64 // the first Operand's value at post time, only if the Condition has PERCENT Operands
+	EvaluationMode   EvaluationMode
+	CandleInterval   string // e.g. "1d", only for CLOSE & SUSTAINED EvaluationModes
+	SustainedCandles int    // only for the SUSTAINED EvaluationMode
+	SustainedFor     string // e.g. "2w", only if the Condition must hold for the whole time between FromTs & ToTs
+	Failovers        []ExchangeFailover
 }
 
+// tickIntervalSecs is the interval between the ticks Conditions are evolved with, i.e. 1 minute candlesticks.
+const tickIntervalSecs = 60
+
 var (
 	conditionOpFuncs = map[string]func(ops []float64, errRatio float64) bool{
 		">=": func(ops []float64, errRatio float64) bool { return ops[0] >= ops[1]*(1.0-errRatio) },
@@ -105,26 +133,34 @@ func (c *Condition) Run(ticks map[string]Tick) error {
 	}
 
 	// Considering we already know this condition is not in a final state, and if the supplied ticks are newer than
-	// the finish timestamp of this condition, then finish the condition with a FALSE value.
+	// the finish timestamp of this condition, then finish the condition with a FALSE value. Unless it's a sustained
+	// condition that has been evolving, because then it was never violated. Either way, the supplied ticks are past
+	// the deadline, so the evidence is the last ticks within it (if any).
 	if timestamp > c.ToTs {
-		c.State.Status = FINISHED
-		c.State.Value = FALSE
+		value := FALSE
+		if c.SustainedFor != "" && c.State.Status == STARTED {
+			value = TRUE
+		}
+		c.finish(value, c.State.LastTicks)
 		return nil
 	}
 
 	// Resolve all operands to numbers. All non-literal operands must have associated ticks, or else this condition
-	// cannot be evolved.
-	operandValues := []float64{}
-	for _, operand := range c.Operands {
-		if operand.Type == NUMBER {
-			operandValues = append(operandValues, float64(operand.Number))
-		} else {
-			tick, ok := ticks[operand.Str]
-			if !ok {
-				return fmt.Errorf("internal error: ticker for operand %v was not supplied", operand.Str)
-			}
-			operandValues = append(operandValues, float64(tick.Value))
+	// cannot be evolved. If the baseline is not known yet, these are the ticks of when the condition starts, so the
+	// first operand's value is the baseline.
+	var (
+		operandValues = []float64{}
+		baseline      = c.Baseline
+	)
+	for i, operand := range c.Operands {
+		value, err := operand.Value(ticks, baseline)
+		if err != nil {
+			return err
+		}
+		if i == 0 && baseline == 0 {
+			baseline = value
 		}
+		operandValues = append(operandValues, value)
 	}
 
 	// Since no more errors are possible at this point, state is ready to be updated, and condition can officially be
@@ -133,22 +169,144 @@ func (c *Condition) Run(ticks map[string]Tick) error {
 	c.State.LastTs = timestamp
 	c.State.LastTicks = ticks
 	c.State.Status = STARTED
+	if c.Baseline == 0 && c.HasPercentOperands() {
+		c.Baseline = baseline
+	}
 
 	if opFunc, ok := conditionOpFuncs[c.Operator]; ok {
 		// Finally, run the actual condition expression!
-		if opFunc(operandValues, c.ErrorMarginRatio) {
-			c.State.Status = FINISHED
-			c.State.Value = TRUE
+		holds := opFunc(operandValues, c.ErrorMarginRatio)
+		if c.SustainedFor != "" {
+			c.runSustained(holds, timestamp, ticks)
+		} else if c.isSatisfied(holds, timestamp) {
+			c.finish(TRUE, ticks)
 		} else if timestamp >= c.ToTs {
 			// If we're evolving with the very last ticks, and considering it didn't evolve to TRUE, then it must
 			// evolve to FALSE.
-			c.State.Status = FINISHED
-			c.State.Value = FALSE
+			c.finish(FALSE, ticks)
 		}
 	}
 	return nil
 }
 
+// runSustained evolves a Condition with a SustainedFor duration, given whether the ticks at timestamp satisfy its
+// boolean condition: it becomes FALSE on the first evaluated tick that doesn't, and TRUE once it has held until ToTs.
+func (c *Condition) runSustained(holds bool, timestamp int, ticks map[string]Tick) {
+	if !holds && c.isEvaluated(timestamp) {
+		c.finish(FALSE, ticks)
+		return
+	}
+	if timestamp >= c.ToTs {
+		c.finish(TRUE, ticks)
+	}
+}
+
+// DecidingTick is the Tick of one of a Condition's operands that moved it to TRUE or FALSE, with where it comes from:
+// the Operand (e.g. COIN:BINANCE:BTC-USDT), its Exchange (for aggregate providers, the one it was resolved from), and
+// the CandleInterval of the candle whose price it is (i.e. 1 minute candles, or those of the Condition's
+// CandleInterval for CLOSE & SUSTAINED EvaluationModes).
+type DecidingTick struct {
+	Operand        string
+	Exchange       string
+	CandleInterval string
+	Timestamp      int
+	Value          common.JSONFloat64
+}
+
+// finish finishes the Condition with value, keeping the ticks that decided it as evidence in its DecidingTicks.
+func (c *Condition) finish(value ConditionStateValue, ticks map[string]Tick) {
+	c.State.Status = FINISHED
+	c.State.Value = value
+
+	candleInterval := "1m"
+	if c.EvaluationMode != WICK && c.CandleInterval != "" {
+		candleInterval = c.CandleInterval
+	}
+	c.State.DecidingTicks = []DecidingTick{}
+	for _, operand := range c.NonNumberOperands() {
+		tick, ok := ticks[operand.Str]
+		if !ok {
+			continue
+		}
+		exchange := operand.Provider
+		if tick.Source != "" {
+			exchange = tick.Source
+		}
+		c.State.DecidingTicks = append(c.State.DecidingTicks, DecidingTick{
+			Operand:        operand.Str,
+			Exchange:       exchange,
+			CandleInterval: candleInterval,
+			Timestamp:      tick.Timestamp,
+			Value:          tick.Value,
+		})
+	}
+}
+
+// CanBeDecidedWithin returns whether the condition could be decided with any value of its non-literal operand between
+// the supplied lowest and highest ticks (e.g. those of a daily candlestick), i.e. if it could become true, or be
+// violated if it must be sustained. This allows skipping long periods of market data without evaluating every minute
+// in them. When it cannot be sure (e.g. the condition has more than one non-literal operand), it returns true.
+func (c *Condition) CanBeDecidedWithin(lowestTicks, highestTicks map[string]Tick) bool {
+	opFunc, ok := conditionOpFuncs[c.Operator]
+	if !ok || len(c.NonNumberOperands()) != 1 {
+		return true
+	}
+
+	var (
+		lowestValues  = []float64{}
+		highestValues = []float64{}
+		operandIndex  int
+	)
+	for i, operand := range c.Operands {
+		// Arithmetic expressions could go in any direction, and percentages are unknown without a baseline.
+		if operand.Type == EXPR || (operand.Type == PERCENT && c.Baseline == 0) {
+			return true
+		}
+		if operand.Type == NUMBER || operand.Type == PERCENT {
+			value, _ := operand.Value(nil, c.Baseline)
+			lowestValues = append(lowestValues, value)
+			highestValues = append(highestValues, value)
+			continue
+		}
+		lowest, okLowest := lowestTicks[operand.Str]
+		highest, okHighest := highestTicks[operand.Str]
+		if !okLowest || !okHighest {
+			return true
+		}
+		operandIndex = i
+		lowestValues = append(lowestValues, operand.valueOf(float64(lowest.Value)))
+		highestValues = append(highestValues, operand.valueOf(float64(highest.Value)))
+	}
+
+	// Every operator is true for a single range of values of the operand, so if it's true at both the lowest and
+	// highest values, it's true in between, and thus a sustained condition cannot be violated.
+	if c.SustainedFor != "" {
+		return !opFunc(lowestValues, c.ErrorMarginRatio) || !opFunc(highestValues, c.ErrorMarginRatio)
+	}
+
+	if opFunc(lowestValues, c.ErrorMarginRatio) || opFunc(highestValues, c.ErrorMarginRatio) {
+		return true
+	}
+
+	// Conversely, if it's false at both the lowest and highest values, it's false in between. The exception is when the
+	// range of the operand contains the whole range of a BETWEEN.
+	if c.Operator == "BETWEEN" && operandIndex == 0 {
+		return lowestValues[0] < lowestValues[1]*(1.0-c.ErrorMarginRatio/2) && highestValues[0] > highestValues[2]*(1.0+c.ErrorMarginRatio/2)
+	}
+	return false
+}
+
+// nextTickTs returns the timestamp of the next tick this Condition needs to keep evolving.
+func (c Condition) nextTickTs() int {
+	if c.State.LastTs > 0 {
+		return c.State.LastTs + tickIntervalSecs
+	}
+	if c.FromTs%tickIntervalSecs == 0 {
+		return c.FromTs
+	}
+	return c.FromTs - c.FromTs%tickIntervalSecs + tickIntervalSecs
+}
+
 // Evaluate is a non-mutating function that returns the Value of a Condition, that is, if the Condition has reached
 // a final value (i.e. TRUE, FALSE) or not (i.e. UNDECIDED).
 func (c Condition) Evaluate() ConditionStateValue {
@@ -162,22 +320,52 @@ func (c *Condition) ClearState() {
 	c.State = ConditionState{}
 }
 
-// NonNumberOperands returns the slice of Operands of a Condition that are either COINs or MARKETCAPs, but not NUMBERs.
+// NonNumberOperands returns the slice of Operands of a Condition that are either COINs or MARKETCAPs, but not NUMBERs,
+// including those within EXPRs. Each market is only returned once.
 func (c *Condition) NonNumberOperands() []Operand {
-	ops := []Operand{}
+	var (
+		ops  = []Operand{}
+		seen = map[operandMarketKey]bool{}
+	)
 	for _, op := range c.Operands {
-		if op.Type == NUMBER {
-			continue
+		for _, candidate := range op.NonNumberOperands() {
+			key := operandMarketKey{candidate.Type, candidate.ToMarketSource(), candidate.Indicator, candidate.Window}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			ops = append(ops, candidate)
 		}
-		ops = append(ops, op)
 	}
 	return ops
 }
 
+// operandMarketKey identifies the market data of an Operand, e.g. SMA(COIN:BINANCE:BTC-USDT,200d) and
+// COIN:BINANCE:BTC-USDT have the same market source, but not the same market data.
+type operandMarketKey struct {
+	operandType  OperandType
+	marketSource common.MarketSource
+	indicator    string
+	window       string
+}
+
+// HasPercentOperands returns whether the Condition has PERCENT Operands, which are relative to its Baseline.
+func (c *Condition) HasPercentOperands() bool {
+	for _, op := range c.Operands {
+		if op.Type == PERCENT {
+			return true
+		}
+	}
+	return false
+}
+
 // Clone returns a deep copy of Condition that does not share any memory with the original struct.
 func (c Condition) Clone() Condition {
 	clonedOperands := make([]Operand, len(c.Operands))
 	copy(clonedOperands, c.Operands)
+	for i := range clonedOperands {
+		clonedOperands[i].Expr = clonedOperands[i].Expr.Clone()
+	}
 
 	return Condition{
 		Name:             c.Name,
@@ -189,5 +377,11 @@ func (c Condition) Clone() Condition {
 		Assumed:          c.Assumed,
 		State:            c.State.Clone(),
 		ErrorMarginRatio: c.ErrorMarginRatio,
+		Baseline:         c.Baseline,
+		EvaluationMode:   c.EvaluationMode,
+		CandleInterval:   c.CandleInterval,
+		SustainedCandles: c.SustainedCandles,
+		SustainedFor:     c.SustainedFor,
+		Failovers:        append([]ExchangeFailover(nil), c.Failovers...),
 	}
 }
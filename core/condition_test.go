@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/marianogappa/crypto-candles/candles/common"
 	"github.com/stretchr/testify/require"
 )
 
@@ -59,6 +60,9 @@ func TestCondition(t *testing.T) {
 	var (
 		anyError = errors.New("any error for now... ")
 		times    = []int{tInt("2022-01-01 00:00:00"), tInt("2022-01-02 00:00:00"), tInt("2022-01-03 00:00:00")}
+		spreadEx = &Expr{Operator: "-", Operands: []*Expr{literal("COIN:KUCOIN:BTC-USDT"), literal("COIN:BINANCE:BTC-USDT")}}
+		spread   = Operand{Type: EXPR, Expr: spreadEx, Str: spreadEx.String()}
+		sma      = Operand{Type: INDICATOR, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Indicator: "SMA", Window: "200d", Str: "SMA(COIN:BINANCE:BTC-USDT,200d)"}
 	)
 
 	tss := []struct {
@@ -229,6 +233,34 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 59000}},
 				Value:     FALSE,
+				// The tick past the deadline didn't decide anything, so the evidence is the last one within it.
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 59000},
+				},
+			},
+		},
+		{
+			name: "sets to false without evidence if the first tick is past the deadline",
+			cond: &Condition{
+				Name:     "main",
+				Operator: ">",
+				Operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")},
+				FromTs:   times[0],
+				ToTs:     times[1],
+				State: ConditionState{
+					Status:    UNSTARTED,
+					LastTicks: map[string]Tick{},
+					Value:     UNDECIDED,
+				},
+				ErrorMarginRatio: 0.0,
+			},
+			ticks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[2], Value: 59500}},
+			err:   nil,
+			expected: ConditionState{
+				Status:        FINISHED,
+				LastTicks:     map[string]Tick{},
+				Value:         FALSE,
+				DecidingTicks: []DecidingTick{},
 			},
 		},
 		{
@@ -254,6 +286,10 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 60000}, "COIN:BINANCE:ETH-USDT": {Timestamp: times[0], Value: 4000}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 60000},
+					{Operand: "COIN:BINANCE:ETH-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 4000},
+				},
 			},
 		},
 		{
@@ -304,6 +340,37 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 61000}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 61000},
+				},
+			},
+		},
+		{
+			name: ">= with marketcap and number works for true",
+			cond: &Condition{
+				Name:     "main",
+				Operator: ">=",
+				Operands: []Operand{operand("MARKETCAP:MESSARI:BTC"), operand("1000000000000")},
+				FromTs:   times[0],
+				ToTs:     times[1],
+				State: ConditionState{
+					Status:    UNSTARTED,
+					LastTs:    0,
+					LastTicks: nil,
+					Value:     UNDECIDED,
+				},
+				ErrorMarginRatio: 0.0,
+			},
+			ticks: map[string]Tick{"MARKETCAP:MESSARI:BTC": {Timestamp: times[0], Value: 1000000000000}},
+			err:   nil,
+			expected: ConditionState{
+				Status:    FINISHED,
+				LastTs:    times[0],
+				LastTicks: map[string]Tick{"MARKETCAP:MESSARI:BTC": {Timestamp: times[0], Value: 1000000000000}},
+				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "MARKETCAP:MESSARI:BTC", Exchange: "MESSARI", CandleInterval: "1m", Timestamp: times[0], Value: 1000000000000},
+				},
 			},
 		},
 		{
@@ -329,6 +396,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[1], Value: 59000}},
 				Value:     FALSE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 59000},
+				},
 			},
 		},
 		{
@@ -354,6 +424,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:FTM-USDT": {Timestamp: times[1], Value: 950}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:FTM-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 950},
+				},
 			},
 		},
 		{
@@ -404,6 +477,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 61000}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 61000},
+				},
 			},
 		},
 		{
@@ -429,6 +505,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[1], Value: 59000}},
 				Value:     FALSE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 59000},
+				},
 			},
 		},
 		{
@@ -454,6 +533,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:FTM-USDT": {Timestamp: times[1], Value: 900}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:FTM-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 900},
+				},
 			},
 		},
 		{
@@ -504,6 +586,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 59000}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 59000},
+				},
 			},
 		},
 		{
@@ -529,6 +614,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[1], Value: 61000}},
 				Value:     FALSE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 61000},
+				},
 			},
 		},
 		{
@@ -554,6 +642,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:FTM-USDT": {Timestamp: times[1], Value: 1050}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:FTM-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 1050},
+				},
 			},
 		},
 		{
@@ -604,6 +695,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 60000}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 60000},
+				},
 			},
 		},
 		{
@@ -629,6 +723,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[1], Value: 61000}},
 				Value:     FALSE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 61000},
+				},
 			},
 		},
 		{
@@ -654,6 +751,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:FTM-USDT": {Timestamp: times[1], Value: 1100}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:FTM-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 1100},
+				},
 			},
 		},
 		{
@@ -704,6 +804,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 60000}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 60000},
+				},
 			},
 		},
 		{
@@ -729,6 +832,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 60500}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 60500},
+				},
 			},
 		},
 		{
@@ -754,6 +860,9 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[0],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 61000}},
 				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 61000},
+				},
 			},
 		},
 		{
@@ -779,6 +888,173 @@ func TestCondition(t *testing.T) {
 				LastTs:    times[1],
 				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[1], Value: 59000}},
 				Value:     FALSE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 59000},
+				},
+			},
+		},
+		{
+			name: "coin exceeding multiplied coin works for true",
+			cond: &Condition{
+				Name:     "main",
+				Operator: ">",
+				Operands: []Operand{operand("COIN:BINANCE:ETH-USDT"), {Type: COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:BINANCE:BTC-USDT", Multiplier: 0.1}},
+				FromTs:   times[0],
+				ToTs:     times[1],
+			},
+			ticks: map[string]Tick{
+				"COIN:BINANCE:ETH-USDT": {Timestamp: times[0], Value: 7000},
+				"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 61000},
+			},
+			err: nil,
+			expected: ConditionState{
+				Status: FINISHED,
+				LastTs: times[0],
+				LastTicks: map[string]Tick{
+					"COIN:BINANCE:ETH-USDT": {Timestamp: times[0], Value: 7000},
+					"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 61000},
+				},
+				Value: TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:ETH-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 7000},
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 61000},
+				},
+			},
+		},
+		{
+			name: "coin exceeding multiplied coin works for false",
+			cond: &Condition{
+				Name:     "main",
+				Operator: ">",
+				Operands: []Operand{operand("COIN:BINANCE:ETH-USDT"), {Type: COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:BINANCE:BTC-USDT", Multiplier: 0.1}},
+				FromTs:   times[0],
+				ToTs:     times[1],
+			},
+			ticks: map[string]Tick{
+				"COIN:BINANCE:ETH-USDT": {Timestamp: times[1], Value: 4000},
+				"COIN:BINANCE:BTC-USDT": {Timestamp: times[1], Value: 61000},
+			},
+			err: nil,
+			expected: ConditionState{
+				Status: FINISHED,
+				LastTs: times[1],
+				LastTicks: map[string]Tick{
+					"COIN:BINANCE:ETH-USDT": {Timestamp: times[1], Value: 4000},
+					"COIN:BINANCE:BTC-USDT": {Timestamp: times[1], Value: 61000},
+				},
+				Value: FALSE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:ETH-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 4000},
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[1], Value: 61000},
+				},
+			},
+		},
+		{
+			name: "spread between exchanges works for true",
+			cond: &Condition{
+				Name:     "main",
+				Operator: ">=",
+				Operands: []Operand{spread, operand("100")},
+				FromTs:   times[0],
+				ToTs:     times[1],
+			},
+			ticks: map[string]Tick{
+				"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 30000},
+				"COIN:KUCOIN:BTC-USDT":  {Timestamp: times[0], Value: 30100},
+			},
+			err: nil,
+			expected: ConditionState{
+				Status: FINISHED,
+				LastTs: times[0],
+				LastTicks: map[string]Tick{
+					"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 30000},
+					"COIN:KUCOIN:BTC-USDT":  {Timestamp: times[0], Value: 30100},
+				},
+				Value: TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:KUCOIN:BTC-USDT", Exchange: "KUCOIN", CandleInterval: "1m", Timestamp: times[0], Value: 30100},
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 30000},
+				},
+			},
+		},
+		{
+			name: "indicator works like any other ticked operand",
+			cond: &Condition{
+				Name:     "main",
+				Operator: ">",
+				Operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), sma},
+				FromTs:   times[0],
+				ToTs:     times[1],
+			},
+			ticks: map[string]Tick{
+				"COIN:BINANCE:BTC-USDT":           {Timestamp: times[0], Value: 30000},
+				"SMA(COIN:BINANCE:BTC-USDT,200d)": {Timestamp: times[0], Value: 29000},
+			},
+			err: nil,
+			expected: ConditionState{
+				Status: FINISHED,
+				LastTs: times[0],
+				LastTicks: map[string]Tick{
+					"COIN:BINANCE:BTC-USDT":           {Timestamp: times[0], Value: 30000},
+					"SMA(COIN:BINANCE:BTC-USDT,200d)": {Timestamp: times[0], Value: 29000},
+				},
+				Value: TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 30000},
+					{Operand: "SMA(COIN:BINANCE:BTC-USDT,200d)", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 29000},
+				},
+			},
+		},
+		{
+			name: "spread between exchanges errors if a tick is missing",
+			cond: &Condition{
+				Name:     "main",
+				Operator: ">=",
+				Operands: []Operand{spread, operand("100")},
+				FromTs:   times[0],
+				ToTs:     times[1],
+			},
+			ticks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 30000}},
+			err:   anyError,
+		},
+		{
+			name: "percentage is relative to the first operand's value when the condition starts",
+			cond: &Condition{
+				Name:     "main",
+				Operator: "<=",
+				Operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), {Type: PERCENT, Number: -15, Str: "-15%"}},
+				FromTs:   times[0],
+				ToTs:     times[1],
+			},
+			ticks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 30000}},
+			err:   nil,
+			expected: ConditionState{
+				Status:    STARTED,
+				LastTs:    times[0],
+				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 30000}},
+				Value:     UNDECIDED,
+			},
+		},
+		{
+			name: "percentage works for true with a known baseline",
+			cond: &Condition{
+				Name:     "main",
+				Operator: "<=",
+				Operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), {Type: PERCENT, Number: -15, Str: "-15%"}},
+				FromTs:   times[0],
+				ToTs:     times[1],
+				Baseline: 30000,
+			},
+			ticks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 25500}},
+			err:   nil,
+			expected: ConditionState{
+				Status:    FINISHED,
+				LastTs:    times[0],
+				LastTicks: map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: times[0], Value: 25500}},
+				Value:     TRUE,
+				DecidingTicks: []DecidingTick{
+					{Operand: "COIN:BINANCE:BTC-USDT", Exchange: "BINANCE", CandleInterval: "1m", Timestamp: times[0], Value: 25500},
+				},
 			},
 		},
 	}
@@ -801,6 +1077,201 @@ func TestCondition(t *testing.T) {
 	}
 }
 
+func TestOperandToMarketSource(t *testing.T) {
+	require.Equal(t, common.MarketSource{Type: common.COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT"}, operand("COIN:BINANCE:BTC-USDT").ToMarketSource())
+	require.Equal(t, common.MarketSource{Type: common.UNSUPPORTED, Provider: "MESSARI", BaseAsset: "BTC"}, operand("MARKETCAP:MESSARI:BTC").ToMarketSource())
+	require.Equal(t, common.MarketSource{Type: common.COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT"}, Operand{Type: INDICATOR, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Indicator: "SMA", Window: "200d"}.ToMarketSource())
+}
+
+func TestOperandConditionStr(t *testing.T) {
+	require.Equal(t, "COIN:BINANCE:BTC-USDT", operand("COIN:BINANCE:BTC-USDT").ConditionStr())
+	require.Equal(t, "COIN:BINANCE:BTC-USDT * 0.1", Operand{Type: COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:BINANCE:BTC-USDT", Multiplier: 0.1}.ConditionStr())
+}
+
+func TestConditionBaseline(t *testing.T) {
+	c := &Condition{
+		Name:     "main",
+		Operator: "<=",
+		Operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), {Type: PERCENT, Number: -15, Str: "-15%"}},
+		FromTs:   tInt("2022-01-01 00:01:00"),
+		ToTs:     tInt("2022-01-02 00:00:00"),
+	}
+
+	// Ticks before the condition starts don't set the baseline.
+	require.Nil(t, c.Run(map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: tInt("2022-01-01 00:00:00"), Value: 10000}}))
+	require.Equal(t, 0.0, c.Baseline)
+
+	require.Nil(t, c.Run(map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: tInt("2022-01-01 00:01:00"), Value: 30000}}))
+	require.Equal(t, 30000.0, c.Baseline)
+
+	require.Nil(t, c.Run(map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: tInt("2022-01-01 00:02:00"), Value: 26000}}))
+	require.Equal(t, 30000.0, c.Baseline)
+	require.Equal(t, UNDECIDED, c.State.Value)
+
+	require.Nil(t, c.Run(map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: tInt("2022-01-01 00:03:00"), Value: 25400}}))
+	require.Equal(t, TRUE, c.State.Value)
+
+	// Conditions without percentages don't have a baseline.
+	c = &Condition{
+		Name:     "main",
+		Operator: "<=",
+		Operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("25000")},
+		FromTs:   tInt("2022-01-01 00:00:00"),
+		ToTs:     tInt("2022-01-02 00:00:00"),
+	}
+	require.Nil(t, c.Run(map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: tInt("2022-01-01 00:00:00"), Value: 30000}}))
+	require.Equal(t, 0.0, c.Baseline)
+}
+
+func TestConditionEvaluationModes(t *testing.T) {
+	run := func(c *Condition, ts string, value float64) {
+		require.Nil(t, c.Run(map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: tInt(ts), Value: common.JSONFloat64(value)}}))
+	}
+	newCondition := func(evaluationMode EvaluationMode, candleInterval string, sustainedCandles int) *Condition {
+		return &Condition{
+			Name:             "main",
+			Operator:         ">=",
+			Operands:         []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("50000")},
+			FromTs:           tInt("2022-01-03 00:00:00"),
+			ToTs:             tInt("2022-01-31 00:00:00"),
+			EvaluationMode:   evaluationMode,
+			CandleInterval:   candleInterval,
+			SustainedCandles: sustainedCandles,
+		}
+	}
+
+	// A wick is enough for WICK.
+	c := newCondition(WICK, "", 0)
+	run(c, "2022-01-03 12:00:00", 51000)
+	require.Equal(t, TRUE, c.State.Value)
+
+	// But not for CLOSE, which only evaluates the last minute of each candle.
+	c = newCondition(CLOSE, "1d", 0)
+	run(c, "2022-01-03 12:00:00", 51000)
+	run(c, "2022-01-03 23:59:00", 49000)
+	require.Equal(t, UNDECIDED, c.State.Value)
+	run(c, "2022-01-04 23:59:00", 50000)
+	require.Equal(t, TRUE, c.State.Value)
+
+	// Weekly candles close on Sunday nights.
+	c = newCondition(CLOSE, "1w", 0)
+	run(c, "2022-01-08 23:59:00", 51000)
+	require.Equal(t, UNDECIDED, c.State.Value)
+	run(c, "2022-01-09 23:59:00", 51000)
+	require.Equal(t, TRUE, c.State.Value)
+
+	// SUSTAINED needs consecutive closes, and a failing one breaks the streak.
+	c = newCondition(SUSTAINED, "1d", 3)
+	run(c, "2022-01-03 23:59:00", 51000)
+	run(c, "2022-01-04 23:59:00", 51000)
+	run(c, "2022-01-05 23:59:00", 49000)
+	require.Equal(t, 0, c.State.Streak)
+	run(c, "2022-01-06 23:59:00", 51000)
+	run(c, "2022-01-07 12:00:00", 49000)
+	run(c, "2022-01-07 23:59:00", 51000)
+	require.Equal(t, UNDECIDED, c.State.Value)
+	require.Equal(t, 2, c.State.Streak)
+
+	// Skipped market data also breaks the streak, but only if a candle closed within it.
+	c.Skip(tInt("2022-01-08 00:00:00"), tInt("2022-01-08 12:00:00"))
+	require.Equal(t, 2, c.State.Streak)
+	run(c, "2022-01-08 23:59:00", 51000)
+	require.Equal(t, TRUE, c.State.Value)
+
+	c = newCondition(SUSTAINED, "1d", 3)
+	run(c, "2022-01-03 23:59:00", 51000)
+	c.Skip(tInt("2022-01-04 00:00:00"), tInt("2022-01-05 00:00:00"))
+	require.Equal(t, 0, c.State.Streak)
+
+	// CLOSE conditions still finish as FALSE at the deadline.
+	c = newCondition(CLOSE, "1w", 0)
+	run(c, "2022-01-30 23:59:00", 49000)
+	require.Equal(t, UNDECIDED, c.State.Value)
+	run(c, "2022-01-31 00:00:00", 51000)
+	require.Equal(t, FALSE, c.State.Value)
+}
+
+func TestConditionSustainedFor(t *testing.T) {
+	run := func(c *Condition, ts string, value float64) {
+		require.Nil(t, c.Run(map[string]Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: tInt(ts), Value: common.JSONFloat64(value)}}))
+	}
+	newCondition := func() *Condition {
+		return &Condition{
+			Name:         "main",
+			Operator:     "BETWEEN",
+			Operands:     []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("17000"), operand("20000")},
+			FromTs:       tInt("2022-01-01 00:00:00"),
+			ToTs:         tInt("2022-01-15 00:00:00"),
+			SustainedFor: "2w",
+		}
+	}
+
+	// Being within the range is not enough, as it must range until the deadline.
+	c := newCondition()
+	run(c, "2022-01-01 00:00:00", 18000)
+	run(c, "2022-01-14 23:59:00", 19000)
+	require.Equal(t, UNDECIDED, c.State.Value)
+	run(c, "2022-01-15 00:00:00", 19000)
+	require.Equal(t, TRUE, c.State.Value)
+
+	// It fails on the first tick outside of the range.
+	c = newCondition()
+	run(c, "2022-01-01 00:00:00", 18000)
+	run(c, "2022-01-07 00:00:00", 21000)
+	require.Equal(t, FALSE, c.State.Value)
+	require.Equal(t, tInt("2022-01-07 00:00:00"), c.State.LastTs)
+
+	// If ticks go past the deadline without violating the range, it ranged.
+	c = newCondition()
+	run(c, "2022-01-14 23:59:00", 18000)
+	run(c, "2022-01-15 00:01:00", 21000)
+	require.Equal(t, TRUE, c.State.Value)
+
+	// But not if it never started ranging.
+	c = newCondition()
+	run(c, "2022-01-15 00:01:00", 18000)
+	require.Equal(t, FALSE, c.State.Value)
+
+	// Evaluated on daily closes, intraday wicks outside of the range don't matter.
+	c = newCondition()
+	c.EvaluationMode, c.CandleInterval = CLOSE, "1d"
+	run(c, "2022-01-01 12:00:00", 21000)
+	require.Equal(t, UNDECIDED, c.State.Value)
+	run(c, "2022-01-01 23:59:00", 21000)
+	require.Equal(t, FALSE, c.State.Value)
+}
+
+func TestConditionDecidingTicks(t *testing.T) {
+	c := &Condition{
+		Name:           "main",
+		Operator:       ">=",
+		Operands:       []Operand{operand("COIN:ANY:BTC-USDT"), operand("50000")},
+		FromTs:         tInt("2022-01-03 00:00:00"),
+		ToTs:           tInt("2022-01-31 00:00:00"),
+		EvaluationMode: CLOSE,
+		CandleInterval: "1d",
+	}
+
+	// There's no evidence until the condition is decided.
+	require.Nil(t, c.Run(map[string]Tick{"COIN:ANY:BTC-USDT": {Timestamp: tInt("2022-01-03 12:00:00"), Value: 51000, Source: "BINANCE"}}))
+	require.Len(t, c.State.DecidingTicks, 0)
+
+	// The deciding tick is the daily close, from the exchange the aggregate provider resolved it from.
+	require.Nil(t, c.Run(map[string]Tick{"COIN:ANY:BTC-USDT": {Timestamp: tInt("2022-01-03 23:59:00"), Value: 50500, Source: "KUCOIN"}}))
+	require.Equal(t, TRUE, c.State.Value)
+	require.Equal(t, []DecidingTick{{
+		Operand:        "COIN:ANY:BTC-USDT",
+		Exchange:       "KUCOIN",
+		CandleInterval: "1d",
+		Timestamp:      tInt("2022-01-03 23:59:00"),
+		Value:          50500,
+	}}, c.State.DecidingTicks)
+
+	// Clearing the state clears the evidence.
+	c.ClearState()
+	require.Len(t, c.State.DecidingTicks, 0)
+}
+
 func TestConditionClearState(t *testing.T) {
 	expected := ConditionState{
 		Status:    UNSTARTED,
@@ -858,4 +1329,59 @@ func TestConditionNonNumberOperands(t *testing.T) {
 		},
 	}
 	require.Equal(t, expected, c.NonNumberOperands())
+
+	c = &Condition{
+		Operands: []Operand{
+			operand("COIN:BINANCE:BTC-USDT"),
+			{Type: EXPR, Expr: &Expr{Operator: "-", Operands: []*Expr{literal("COIN:KUCOIN:BTC-USDT"), literal("COIN:BINANCE:BTC-USDT")}}},
+			{Type: PERCENT, Number: 10, Str: "10%"},
+		},
+	}
+	require.Equal(t, []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("COIN:KUCOIN:BTC-USDT")}, c.NonNumberOperands())
+}
+
+func TestConditionCanBeDecidedWithin(t *testing.T) {
+	percent := Operand{Type: PERCENT, Number: -15, Str: "-15%"}
+
+	tss := []struct {
+		name         string
+		operator     string
+		operands     []Operand
+		baseline     float64
+		sustainedFor string
+		lowest       float64
+		highest      float64
+		expected     bool
+	}{
+		{name: ">= below", operator: ">=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")}, lowest: 50000, highest: 59000, expected: false},
+		{name: ">= reached by the highest", operator: ">=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")}, lowest: 50000, highest: 60000, expected: true},
+		{name: "<= above", operator: "<=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("30000")}, lowest: 31000, highest: 40000, expected: false},
+		{name: "<= reached by the lowest", operator: "<=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("30000")}, lowest: 29000, highest: 40000, expected: true},
+		{name: "< with number first", operator: "<", operands: []Operand{operand("30000"), operand("COIN:BINANCE:BTC-USDT")}, lowest: 20000, highest: 29000, expected: false},
+		{name: "BETWEEN below", operator: "BETWEEN", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("30000"), operand("40000")}, lowest: 20000, highest: 29000, expected: false},
+		{name: "BETWEEN around", operator: "BETWEEN", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("30000"), operand("40000")}, lowest: 20000, highest: 50000, expected: true},
+		{name: "unknown operator", operator: "", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")}, lowest: 50000, highest: 59000, expected: true},
+		{name: "two non-literal operands", operator: ">=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("COIN:BINANCE:ETH-USDT")}, lowest: 50000, highest: 59000, expected: true},
+		{name: "percentage without baseline", operator: "<=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), percent}, lowest: 50000, highest: 59000, expected: true},
+		{name: "percentage above", operator: "<=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), percent}, baseline: 60000, lowest: 52000, highest: 59000, expected: false},
+		{name: "percentage reached by the lowest", operator: "<=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), percent}, baseline: 60000, lowest: 50000, highest: 59000, expected: true},
+		{name: "sustained BETWEEN within", operator: "BETWEEN", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("30000"), operand("40000")}, sustainedFor: "2w", lowest: 31000, highest: 39000, expected: false},
+		{name: "sustained BETWEEN violated by the highest", operator: "BETWEEN", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("30000"), operand("40000")}, sustainedFor: "2w", lowest: 31000, highest: 41000, expected: true},
+		{name: "sustained >= violated by the lowest", operator: ">=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("30000")}, sustainedFor: "2w", lowest: 29000, highest: 41000, expected: true},
+		{name: "arithmetic expression", operator: ">=", operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), {Type: EXPR, Expr: &Expr{Operator: "-", Operands: []*Expr{literal("COIN:BINANCE:BTC-USDT")}}}}, lowest: 50000, highest: 59000, expected: true},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			c := &Condition{Operator: ts.operator, Operands: ts.operands, Baseline: ts.baseline, SustainedFor: ts.sustainedFor}
+			var (
+				lowest  = map[string]Tick{}
+				highest = map[string]Tick{}
+			)
+			for _, operand := range c.NonNumberOperands() {
+				lowest[operand.Str] = Tick{Timestamp: tInt("2022-01-01 00:00:00"), Value: common.JSONFloat64(ts.lowest)}
+				highest[operand.Str] = Tick{Timestamp: tInt("2022-01-01 00:00:00"), Value: common.JSONFloat64(ts.highest)}
+			}
+			require.Equal(t, ts.expected, c.CanBeDecidedWithin(lowest, highest))
+		})
+	}
 }
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// EvaluationMode is how market data is evaluated against a Condition, i.e.: WICK|CLOSE|SUSTAINED.
+//
+// WICK Conditions become true as soon as any price satisfies them, even if only for a minute. CLOSE Conditions only
+// become true when a candle of their CandleInterval closes at a price that satisfies them (e.g. "BTC closes the week
+// above 50k"), and SUSTAINED ones when SustainedCandles consecutive candles do (e.g. "BTC closes above 50k for 3 days
+// in a row").
+type EvaluationMode int
+
+// EvaluationModeFromString constructs an EvaluationMode from a string.
+func EvaluationModeFromString(s string) (EvaluationMode, error) {
+	switch s {
+	case "WICK", "":
+		return WICK, nil
+	case "CLOSE":
+		return CLOSE, nil
+	case "SUSTAINED":
+		return SUSTAINED, nil
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrUnknownEvaluationMode, s)
+	}
+}
+func (v EvaluationMode) String() string {
+	switch v {
+	case WICK:
+		return "WICK"
+	case CLOSE:
+		return "CLOSE"
+	case SUSTAINED:
+		return "SUSTAINED"
+	default:
+		return ""
+	}
+}
+
+const (
+	// WICK is an EvaluationMode
+	WICK EvaluationMode = iota
+	// CLOSE is an EvaluationMode
+	CLOSE
+	// SUSTAINED is an EvaluationMode
+	SUSTAINED
+)
+
+// weeklyCandleOffset is where weekly candles start relative to the Unix epoch, which was a Thursday, because
+// exchanges start weekly candles on Mondays.
+const weeklyCandleOffset = 4 * 24 * 60 * 60
+
+var candleIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// ParseCandleInterval parses the CandleInterval of a Condition, e.g. "1d" is a daily candle. Only the intervals that
+// exchanges commonly provide are supported, so that candles close at the same time as on the exchanges' charts.
+func ParseCandleInterval(candleInterval string) (time.Duration, error) {
+	interval, ok := candleIntervals[candleInterval]
+	if !ok {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidCandleInterval, candleInterval)
+	}
+	return interval, nil
+}
+
+// closesCandle returns whether the 1 minute tick at timestamp is the last one of a candle of the Condition's
+// CandleInterval, i.e. if its price is the candle's close price.
+func (c Condition) closesCandle(timestamp int) bool {
+	return c.closesCandleWithin(timestamp, timestamp+tickIntervalSecs)
+}
+
+// closesCandleWithin returns whether a candle of the Condition's CandleInterval closes between fromTs & toTs, i.e.
+// whether the last 1 minute tick of a candle is at or after fromTs, and before toTs.
+func (c Condition) closesCandleWithin(fromTs, toTs int) bool {
+	interval, err := ParseCandleInterval(c.CandleInterval)
+	if err != nil {
+		return false
+	}
+	var (
+		secs   = int(interval / time.Second)
+		offset = 0
+	)
+	if c.CandleInterval == "1w" {
+		offset = weeklyCandleOffset
+	}
+	return (toTs-offset)/secs > (fromTs-offset)/secs
+}
+
+// isEvaluated returns whether the ticks at timestamp are evaluated against the Condition according to its
+// EvaluationMode, i.e. every tick for WICK, but only candle closes otherwise.
+func (c Condition) isEvaluated(timestamp int) bool {
+	return c.EvaluationMode == WICK || c.closesCandle(timestamp)
+}
+
+// isSatisfied returns whether the Condition becomes true with the ticks at timestamp, according to its EvaluationMode,
+// given whether they satisfy its boolean condition. It keeps track of the Streak of satisfying candle closes.
+func (c *Condition) isSatisfied(holds bool, timestamp int) bool {
+	if c.EvaluationMode == WICK {
+		return holds
+	}
+	if !c.isEvaluated(timestamp) {
+		return false
+	}
+	if !holds {
+		c.State.Streak = 0
+		return false
+	}
+	c.State.Streak++
+	return c.EvaluationMode == CLOSE || c.State.Streak >= c.SustainedCandles
+}
+
+// Skip records that the market data between fromTs & toTs was skipped without running the Condition, because it
+// cannot decide it (see Condition.CanBeDecidedWithin). Thus, any candle that closed within it breaks the Streak.
+func (c *Condition) Skip(fromTs, toTs int) {
+	if c.EvaluationMode == SUSTAINED && c.closesCandleWithin(fromTs, toTs) {
+		c.State.Streak = 0
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Expr represents an arithmetic expression on Operands, e.g. "(COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT) / 2".
+// It's the AST of an EXPR Operand.
+type Expr struct {
+	Operator string   // one of "+", "-", "*", "/", or empty if the Expr is just its Literal
+	Operands []*Expr  // two, or only one if the Operator is "-" and it's a negation
+	Literal  *Operand // a COIN, MARKETCAP, INDICATOR or NUMBER Operand, only if the Operator is empty
+}
+
+var errUnknownExprOperator = errors.New("internal error: unknown arithmetic operator")
+
+// exprOperatorPrecedences is used to only add the necessary parentheses when formatting an Expr.
+var exprOperatorPrecedences = map[string]int{"+": 1, "-": 1, "*": 2, "/": 2}
+
+// Value resolves the Expr to a number, given the ticks of its non-literal operands.
+func (e *Expr) Value(ticks map[string]Tick) (float64, error) {
+	if e.Operator == "" {
+		return e.Literal.Value(ticks, 0)
+	}
+	values := []float64{}
+	for _, operand := range e.Operands {
+		value, err := operand.Value(ticks)
+		if err != nil {
+			return 0, err
+		}
+		values = append(values, value)
+	}
+	switch {
+	case e.Operator == "-" && len(values) == 1:
+		return -values[0], nil
+	case e.Operator == "+":
+		return values[0] + values[1], nil
+	case e.Operator == "-":
+		return values[0] - values[1], nil
+	case e.Operator == "*":
+		return values[0] * values[1], nil
+	case e.Operator == "/":
+		return values[0] / values[1], nil
+	}
+	return 0, fmt.Errorf("%w: %v", errUnknownExprOperator, e.Operator)
+}
+
+// NonNumberOperands returns the Literals of the Expr that are either COINs, MARKETCAPs or INDICATORs, but not NUMBERs.
+func (e *Expr) NonNumberOperands() []Operand {
+	if e.Operator == "" {
+		if e.Literal.Type == NUMBER {
+			return []Operand{}
+		}
+		return []Operand{*e.Literal}
+	}
+	ops := []Operand{}
+	for _, operand := range e.Operands {
+		ops = append(ops, operand.NonNumberOperands()...)
+	}
+	return ops
+}
+
+// String returns the Expr as it's written in a condition, e.g. "(COIN:BINANCE:BTC-USDT + 1000) / 2".
+func (e *Expr) String() string {
+	return e.Format(func(o Operand) string { return o.Str })
+}
+
+// Format returns the Expr as it's written in a condition, but with each Literal formatted by formatLiteral.
+func (e *Expr) Format(formatLiteral func(Operand) string) string {
+	if e.Operator == "" {
+		return formatLiteral(*e.Literal)
+	}
+	if len(e.Operands) == 1 {
+		operand := e.Operands[0].Format(formatLiteral)
+		if e.Operands[0].Operator != "" {
+			operand = fmt.Sprintf("(%v)", operand)
+		}
+		return fmt.Sprintf("%v%v", e.Operator, operand)
+	}
+
+	// Operators are left-associative, so the right operand needs parentheses even on equal precedence.
+	left, right := e.Operands[0].Format(formatLiteral), e.Operands[1].Format(formatLiteral)
+	if e.Operands[0].isBinary() && exprOperatorPrecedences[e.Operands[0].Operator] < exprOperatorPrecedences[e.Operator] {
+		left = fmt.Sprintf("(%v)", left)
+	}
+	if e.Operands[1].isBinary() && exprOperatorPrecedences[e.Operands[1].Operator] <= exprOperatorPrecedences[e.Operator] {
+		right = fmt.Sprintf("(%v)", right)
+	}
+	return fmt.Sprintf("%v %v %v", left, e.Operator, right)
+}
+
+func (e *Expr) isBinary() bool {
+	return len(e.Operands) == 2
+}
+
+// Clone returns a deep copy of Expr that does not share any memory with the original struct.
+func (e *Expr) Clone() *Expr {
+	if e == nil {
+		return nil
+	}
+	clone := &Expr{Operator: e.Operator}
+	if e.Literal != nil {
+		literal := *e.Literal
+		clone.Literal = &literal
+	}
+	for _, operand := range e.Operands {
+		clone.Operands = append(clone.Operands, operand.Clone())
+	}
+	return clone
+}
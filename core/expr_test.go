@@ -0,0 +1,110 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func literal(s string) *Expr {
+	op := operand(s)
+	return &Expr{Literal: &op}
+}
+
+func TestExprValue(t *testing.T) {
+	var (
+		anyError = errInvalidTickSupplied
+		ticks    = map[string]Tick{
+			"COIN:BINANCE:BTC-USDT": {Timestamp: tInt("2022-01-01 00:00:00"), Value: 30000},
+			"COIN:KUCOIN:BTC-USDT":  {Timestamp: tInt("2022-01-01 00:00:00"), Value: 30100},
+		}
+	)
+
+	tss := []struct {
+		name     string
+		expr     *Expr
+		err      error
+		expected float64
+	}{
+		{
+			name:     "number",
+			expr:     literal("1.5"),
+			expected: 1.5,
+		},
+		{
+			name:     "coin",
+			expr:     literal("COIN:BINANCE:BTC-USDT"),
+			expected: 30000,
+		},
+		{
+			name:     "spread",
+			expr:     &Expr{Operator: "-", Operands: []*Expr{literal("COIN:KUCOIN:BTC-USDT"), literal("COIN:BINANCE:BTC-USDT")}},
+			expected: 100,
+		},
+		{
+			name: "average",
+			expr: &Expr{Operator: "/", Operands: []*Expr{
+				{Operator: "+", Operands: []*Expr{literal("COIN:KUCOIN:BTC-USDT"), literal("COIN:BINANCE:BTC-USDT")}},
+				literal("2"),
+			}},
+			expected: 30050,
+		},
+		{
+			name:     "negation",
+			expr:     &Expr{Operator: "-", Operands: []*Expr{{Operator: "*", Operands: []*Expr{literal("2"), literal("3")}}}},
+			expected: -6,
+		},
+		{
+			name: "missing tick",
+			expr: &Expr{Operator: "-", Operands: []*Expr{literal("COIN:BINANCE:ETH-USDT"), literal("COIN:BINANCE:BTC-USDT")}},
+			err:  anyError,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			actual, err := ts.expr.Value(ticks)
+			if ts.err != nil {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, ts.expected, actual)
+		})
+	}
+}
+
+func TestExprString(t *testing.T) {
+	var (
+		a = literal("COIN:BINANCE:BTC-USDT")
+		b = literal("COIN:KUCOIN:BTC-USDT")
+		c = literal("2")
+	)
+
+	tss := []struct {
+		expr     *Expr
+		expected string
+	}{
+		{expr: a, expected: "COIN:BINANCE:BTC-USDT"},
+		{expr: &Expr{Operator: "-", Operands: []*Expr{a, b}}, expected: "COIN:BINANCE:BTC-USDT - COIN:KUCOIN:BTC-USDT"},
+		{expr: &Expr{Operator: "-", Operands: []*Expr{a}}, expected: "-COIN:BINANCE:BTC-USDT"},
+		{expr: &Expr{Operator: "-", Operands: []*Expr{{Operator: "+", Operands: []*Expr{a, b}}}}, expected: "-(COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT)"},
+		{expr: &Expr{Operator: "/", Operands: []*Expr{{Operator: "+", Operands: []*Expr{a, b}}, c}}, expected: "(COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT) / 2"},
+		{expr: &Expr{Operator: "+", Operands: []*Expr{a, {Operator: "/", Operands: []*Expr{b, c}}}}, expected: "COIN:BINANCE:BTC-USDT + COIN:KUCOIN:BTC-USDT / 2"},
+		{expr: &Expr{Operator: "-", Operands: []*Expr{{Operator: "-", Operands: []*Expr{a, b}}, c}}, expected: "COIN:BINANCE:BTC-USDT - COIN:KUCOIN:BTC-USDT - 2"},
+		{expr: &Expr{Operator: "-", Operands: []*Expr{a, {Operator: "-", Operands: []*Expr{b, c}}}}, expected: "COIN:BINANCE:BTC-USDT - (COIN:KUCOIN:BTC-USDT - 2)"},
+	}
+	for _, ts := range tss {
+		t.Run(ts.expected, func(t *testing.T) {
+			require.Equal(t, ts.expected, ts.expr.String())
+		})
+	}
+}
+
+func TestExprClone(t *testing.T) {
+	expr := &Expr{Operator: "-", Operands: []*Expr{literal("COIN:BINANCE:BTC-USDT"), literal("COIN:KUCOIN:BTC-USDT")}}
+	clone := expr.Clone()
+	require.Equal(t, expr, clone)
+
+	clone.Operands[0].Literal.Str = "COIN:BINANCE:ETH-USDT"
+	require.Equal(t, "COIN:BINANCE:BTC-USDT", expr.Operands[0].Literal.Str)
+}
@@ -0,0 +1,66 @@
+package core
+
+import "fmt"
+
+// ExchangeFailover is the switch of a Condition's operand to the same market on a fallback exchange, because its
+// exchange stopped providing market data, e.g. from COIN:BINANCE:BTC-USDT to COIN:KUCOIN:BTC-USDT.
+type ExchangeFailover struct {
+	PredictionUUID string
+	ConditionName  string
+	FromOperand    string
+	ToOperand      string
+	Reason         string
+	CreatedAt      ISO8601
+}
+
+// OnExchange returns the Operand on another exchange, e.g. COIN:KUCOIN:BTC-USDT for COIN:BINANCE:BTC-USDT. It's only
+// meant for COIN & INDICATOR Operands.
+func (o Operand) OnExchange(exchange string) Operand {
+	operand := o
+	operand.Provider = exchange
+	operand.Str = fmt.Sprintf("COIN:%v:%v-%v", exchange, o.BaseAsset, o.QuoteAsset)
+	if o.Type == INDICATOR {
+		operand.Str = fmt.Sprintf("%v(%v,%v)", o.Indicator, operand.Str, o.Window)
+	}
+	return operand
+}
+
+// FailOver switches the Condition's operand from to the same market on exchange, wherever it appears (including
+// within EXPRs), and records it in Failovers. The operand's last tick is forgotten, as it's from the other exchange.
+func (c *Condition) FailOver(predictionUUID string, from Operand, exchange, reason string, createdAt ISO8601) ExchangeFailover {
+	to := from.OnExchange(exchange)
+	for i := range c.Operands {
+		c.Operands[i] = c.Operands[i].replace(from, to)
+	}
+	delete(c.State.LastTicks, from.Str)
+	c.State.StalledRuns = 0
+
+	failover := ExchangeFailover{PredictionUUID: predictionUUID, ConditionName: c.Name, FromOperand: from.Str, ToOperand: to.Str, Reason: reason, CreatedAt: createdAt}
+	c.Failovers = append(c.Failovers, failover)
+	return failover
+}
+
+// replace returns the Operand with from replaced by to, keeping its Multiplier.
+func (o Operand) replace(from, to Operand) Operand {
+	switch {
+	case o.Type == EXPR:
+		o.Expr.replace(from, to)
+		o.Str = o.Expr.String()
+	case o.Type != NUMBER && o.Str == from.Str:
+		to.Multiplier = o.Multiplier
+		o = to
+	}
+	return o
+}
+
+// replace replaces the Literals of the Expr that are from by to.
+func (e *Expr) replace(from, to Operand) {
+	if e.Operator == "" {
+		literal := e.Literal.replace(from, to)
+		e.Literal = &literal
+		return
+	}
+	for _, operand := range e.Operands {
+		operand.replace(from, to)
+	}
+}
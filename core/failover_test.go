@@ -0,0 +1,49 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionFailOver(t *testing.T) {
+	var (
+		binance  = operand("COIN:BINANCE:BTC-USDT")
+		spreadEx = &Expr{Operator: "-", Operands: []*Expr{literal("COIN:KUCOIN:BTC-USDT"), literal("COIN:BINANCE:BTC-USDT")}}
+		sma      = Operand{Type: INDICATOR, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Indicator: "SMA", Window: "200d", Str: "SMA(COIN:BINANCE:BTC-USDT,200d)"}
+	)
+	binanceTimesTwo := binance
+	binanceTimesTwo.Multiplier = 2
+
+	cond := Condition{
+		Name:     "main",
+		Operator: "BETWEEN",
+		Operands: []Operand{binanceTimesTwo, {Type: EXPR, Expr: spreadEx, Str: spreadEx.String()}, sma},
+		State: ConditionState{
+			LastTicks: map[string]Tick{
+				"COIN:BINANCE:BTC-USDT": {Timestamp: tInt("2022-01-01 00:00:00"), Value: 30000},
+				"COIN:KUCOIN:BTC-USDT":  {Timestamp: tInt("2022-01-01 00:00:00"), Value: 30100},
+			},
+			StalledRuns: 3,
+		},
+	}
+
+	failover := cond.FailOver("", binance, "COINBASE", "no new ticks", "2022-01-02T00:00:00Z")
+	require.Equal(t, ExchangeFailover{ConditionName: "main", FromOperand: "COIN:BINANCE:BTC-USDT", ToOperand: "COIN:COINBASE:BTC-USDT", Reason: "no new ticks", CreatedAt: "2022-01-02T00:00:00Z"}, failover)
+	require.Equal(t, []ExchangeFailover{failover}, cond.Failovers)
+
+	require.Equal(t, "COIN:COINBASE:BTC-USDT * 2", cond.Operands[0].ConditionStr())
+	require.Equal(t, "COINBASE", cond.Operands[0].Provider)
+	require.Equal(t, "COIN:KUCOIN:BTC-USDT - COIN:COINBASE:BTC-USDT", cond.Operands[1].Str)
+	require.Equal(t, []Operand{operand("COIN:KUCOIN:BTC-USDT"), operand("COIN:COINBASE:BTC-USDT")}, cond.Operands[1].Expr.NonNumberOperands())
+	// The INDICATOR is on another market source, so it's a different operand.
+	require.Equal(t, sma, cond.Operands[2])
+
+	require.Equal(t, map[string]Tick{"COIN:KUCOIN:BTC-USDT": {Timestamp: tInt("2022-01-01 00:00:00"), Value: 30100}}, cond.State.LastTicks)
+	require.Equal(t, 0, cond.State.StalledRuns)
+
+	cond.FailOver("", sma, "KUCOIN", "market pair not found", "2022-01-03T00:00:00Z")
+	require.Equal(t, "SMA(COIN:KUCOIN:BTC-USDT,200d)", cond.Operands[2].Str)
+	require.Equal(t, "KUCOIN", cond.Operands[2].Provider)
+	require.Len(t, cond.Failovers, 2)
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// maxIndicatorWindow is the maximum number of candlesticks of an indicator's window, so that computing an indicator
+// doesn't require walking through years of market data.
+const maxIndicatorWindow = 1000
+
+var (
+	indicators           = map[string]bool{"SMA": true, "EMA": true, "RSI": true, "VOLUME": true}
+	volumeProviders      = map[string]bool{"BINANCE": true}
+	rxIndicatorWindow    = regexp.MustCompile(`^([0-9]+)([mhd])$`)
+	indicatorWindowUnits = map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+)
+
+// IsIndicator returns whether the name is that of a supported indicator for INDICATOR operands, i.e. SMA, EMA, RSI or
+// VOLUME.
+func IsIndicator(name string) bool {
+	return indicators[name]
+}
+
+// HasVolume returns whether traded volumes of the provider's markets are available for VOLUME indicators. Exchanges
+// don't provide volumes as part of their candlesticks, so only those with a volume data source have them.
+func HasVolume(provider string) bool {
+	return volumeProviders[provider]
+}
+
+// ParseIndicatorWindow parses the window of an INDICATOR operand into a number of candlesticks and their interval,
+// e.g. "200d" is 200 candlesticks of 1 day, and "14h" is 14 candlesticks of 1 hour.
+func ParseIndicatorWindow(window string) (int, time.Duration, error) {
+	matches := rxIndicatorWindow.FindStringSubmatch(window)
+	if len(matches) == 0 {
+		return 0, 0, fmt.Errorf("%w: %v", ErrInvalidIndicatorWindow, window)
+	}
+	count, _ := strconv.Atoi(matches[1])
+	if count < 1 || count > maxIndicatorWindow {
+		return 0, 0, fmt.Errorf("%w: %v", ErrInvalidIndicatorWindow, window)
+	}
+	return count, indicatorWindowUnits[matches[2]], nil
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+)
+
+// IMarket is the interface to the market data of non-NUMBER Operands, i.e. of COINs, MARKETCAPs and INDICATORs. Market
+// is the main implementation; it's an interface to be able to mock it for tests.
+type IMarket interface {
+	Iterator(operand Operand, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error)
+}
+
+// ICoinMarket is an interface to candles.Market, which provides the market data of COIN market sources, just to be able
+// to mock it for tests.
+type ICoinMarket interface {
+	Iterator(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error)
+}
+
+// Market is the IMarket that routes every Operand to the market of its type: COINs to the coin market (e.g. the
+// exchanges), and the other types to the markets plugged in with WithOperandMarket (e.g. market capitalizations are
+// provided by separate data sources, see the marketcap package).
+type Market struct {
+	coinMarket     ICoinMarket
+	operandMarkets map[OperandType]IMarket
+}
+
+// NewMarket constructs a Market for COIN operands on top of the supplied coin market, e.g. a candles.Market.
+func NewMarket(coinMarket ICoinMarket, options ...func(*Market)) Market {
+	m := Market{coinMarket: coinMarket, operandMarkets: map[OperandType]IMarket{}}
+
+	for _, option := range options {
+		option(&m)
+	}
+
+	return m
+}
+
+// WithOperandMarket plugs the market of an operand type into the Market, e.g. a marketcap.Market for MARKETCAPs.
+func WithOperandMarket(operandType OperandType, market IMarket) func(*Market) {
+	return func(m *Market) {
+		m.operandMarkets[operandType] = market
+	}
+}
+
+// Iterator returns a market iterator for a given operand at a given time and for a given candlestick interval. It
+// fails with ErrInvalidMarketType if there's no market for the operand's type.
+func (m Market) Iterator(operand Operand, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	if market, ok := m.operandMarkets[operand.Type]; ok {
+		return market.Iterator(operand, startTime, candlestickInterval)
+	}
+	if operand.Type != COIN {
+		return nil, fmt.Errorf("%w: there's no market for %v operands like %v", common.ErrInvalidMarketType, operand.Type, operand.Str)
+	}
+	return m.coinMarket.Iterator(operand.ToMarketSource(), startTime, candlestickInterval)
+}
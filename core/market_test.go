@@ -0,0 +1,55 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketRoutesOperandsByType(t *testing.T) {
+	var (
+		coinMarket      = &recordingCoinMarket{}
+		marketCapMarket = &recordingMarket{}
+		market          = NewMarket(coinMarket, WithOperandMarket(MARKETCAP, marketCapMarket))
+		startTime       = tp("2022-01-01 00:00:00")
+	)
+
+	_, err := market.Iterator(operand("COIN:BINANCE:BTC-USDT"), startTime, time.Minute)
+	require.ErrorIs(t, err, errRecorded)
+	require.Equal(t, []string{"COIN:BINANCE:BTC-USDT"}, coinMarket.calls)
+
+	_, err = market.Iterator(operand("MARKETCAP:MESSARI:BTC"), startTime, time.Minute)
+	require.ErrorIs(t, err, errRecorded)
+	require.Equal(t, []string{"MARKETCAP:MESSARI:BTC"}, marketCapMarket.calls)
+
+	// INDICATORs are computed from their coin's market data, but not by the coin market.
+	_, err = market.Iterator(Operand{Type: INDICATOR, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Indicator: "SMA", Window: "200d", Str: "SMA(COIN:BINANCE:BTC-USDT,200d)"}, startTime, time.Minute)
+	require.ErrorIs(t, err, common.ErrInvalidMarketType)
+	require.Len(t, coinMarket.calls, 1)
+}
+
+var errRecorded = errors.New("recorded")
+
+// recordingMarket records the operands it's asked for.
+type recordingMarket struct {
+	calls []string
+}
+
+func (m *recordingMarket) Iterator(operand Operand, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	m.calls = append(m.calls, operand.Str)
+	return nil, errRecorded
+}
+
+// recordingCoinMarket records the market sources it's asked for.
+type recordingCoinMarket struct {
+	calls []string
+}
+
+func (m *recordingCoinMarket) Iterator(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	m.calls = append(m.calls, marketSource.String())
+	return nil, errRecorded
+}
@@ -81,6 +81,24 @@ func (p *Prediction) ActionableUndecidedConditions() []*Condition {
 	return []*Condition{}
 }
 
+// CalculateNextRunTs returns the timestamp from which it's worth evolving this Prediction again, i.e. when the next
+// candlestick that any of its actionable conditions is waiting for closes. Evolving it earlier finds no new market
+// data. It returns 0 if the Prediction hasn't started yet, meaning that it should be evolved right away.
+//
+// It doesn't change the Prediction's state.
+func (p Prediction) CalculateNextRunTs() int {
+	if p.State.Status == UNSTARTED {
+		return 0
+	}
+	nextRunTs := 0
+	for _, cond := range p.ActionableUndecidedConditions() {
+		if ts := cond.nextTickTs() + tickIntervalSecs; nextRunTs == 0 || ts < nextRunTs {
+			nextRunTs = ts
+		}
+	}
+	return nextRunTs
+}
+
 // CalculateTags (for now) lists the set of all Operands in this Prediction.
 func (p *Prediction) CalculateTags() []string {
 	tags := map[string]struct{}{}
@@ -102,7 +120,8 @@ func (p *Prediction) CalculateTags() []string {
 // CalculateMainCoin returns the main Operand of this Prediction.
 func (p *Prediction) CalculateMainCoin() Operand {
 	switch p.Type {
-	case PredictionTypeCoinOperatorFloatDeadline, PredictionTypeCoinWillReachBeforeItReaches, PredictionTypeCoinWillRange:
+	case PredictionTypeCoinOperatorFloatDeadline, PredictionTypeCoinWillReachBeforeItReaches, PredictionTypeCoinWillRange, PredictionTypeTheFlippening,
+		PredictionTypeCoinOperatorCoinDeadline:
 		return p.Predict.Predict.Literal.Operands[0]
 	default:
 		// In unsupported cases, return the first available operand (Note: non-deterministic due to map).
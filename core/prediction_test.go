@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredictionCalculateNextRunTs(t *testing.T) {
+	newCondition := func(fromTs, lastTs int) *Condition {
+		status := UNSTARTED
+		if lastTs > 0 {
+			status = STARTED
+		}
+		return &Condition{
+			Operator: ">=",
+			Operands: []Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")},
+			FromTs:   fromTs,
+			ToTs:     tInt("2022-02-01 00:00:00"),
+			State:    ConditionState{Status: status, LastTs: lastTs, Value: UNDECIDED},
+		}
+	}
+	tss := []struct {
+		name       string
+		prediction Prediction
+		expected   int
+	}{
+		{
+			name: "unstarted predictions run right away",
+			prediction: Prediction{
+				Predict: Predict{Predict: BoolExpr{Operator: LITERAL, Literal: newCondition(tInt("2022-01-10 00:00:00"), 0)}},
+			},
+			expected: 0,
+		},
+		{
+			name: "started predictions run when the candlestick after the last one closes",
+			prediction: Prediction{
+				State:   PredictionState{Status: STARTED},
+				Predict: Predict{Predict: BoolExpr{Operator: LITERAL, Literal: newCondition(tInt("2022-01-01 00:00:00"), tInt("2022-01-05 10:00:00"))}},
+			},
+			expected: tInt("2022-01-05 10:02:00"),
+		},
+		{
+			name: "conditions that haven't started wait for the first candlestick from their start to close",
+			prediction: Prediction{
+				State:   PredictionState{Status: STARTED},
+				Predict: Predict{Predict: BoolExpr{Operator: LITERAL, Literal: newCondition(tInt("2022-01-10 00:00:30"), 0)}},
+			},
+			expected: tInt("2022-01-10 00:02:00"),
+		},
+		{
+			name: "the earliest actionable condition is the one that counts",
+			prediction: Prediction{
+				State: PredictionState{Status: STARTED},
+				Predict: Predict{
+					Predict: BoolExpr{Operator: LITERAL, Literal: newCondition(tInt("2022-01-01 00:00:00"), tInt("2022-01-05 10:00:00"))},
+					WrongIf: &BoolExpr{Operator: LITERAL, Literal: newCondition(tInt("2022-01-01 00:00:00"), tInt("2022-01-04 10:00:00"))},
+				},
+			},
+			expected: tInt("2022-01-04 10:02:00"),
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			state := ts.prediction.State
+			require.Equal(t, ts.expected, ts.prediction.CalculateNextRunTs())
+			require.Equal(t, state, ts.prediction.State)
+		})
+	}
+}
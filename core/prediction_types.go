@@ -14,7 +14,7 @@ type PredictionTypeCoinWillReachBeforeItReachesWrapper struct {
 
 // Coin is the only relevant operand.
 func (p PredictionTypeCoinWillReachBeforeItReachesWrapper) Coin() Operand {
-	return p.P.Predict.Predict.Literal.Operands[0]
+	return p.P.Predict.Predict.Operands[0].Literal.Operands[0]
 }
 
 // WillReach is the price the coin must reach (without error).
@@ -81,10 +81,22 @@ func (p PredictionTypeCoinOperatorFloatDeadlineWrapper) Coin() Operand {
 	return p.P.Predict.Predict.Literal.Operands[0]
 }
 
-// Goal is the price goal for the coin (without error).
-// Goal is the price goal for the coin (without error).
+// Goal is the price goal for the coin (without error). If the goal is a percentage (e.g. "+30%"), it's relative to
+// the price at post time, so it's zero until that price is resolved.
 func (p PredictionTypeCoinOperatorFloatDeadlineWrapper) Goal() JSONFloat64 {
-	return p.P.Predict.Predict.Literal.Operands[1].Number
+	cond := p.P.Predict.Predict.Literal
+	goal, _ := cond.Operands[1].Value(nil, cond.Baseline)
+	return JSONFloat64(goal)
+}
+
+// GoalPercent is the price goal for the coin relative to the price at post time (e.g. 30 for "+30%), only if the goal
+// is a percentage.
+func (p PredictionTypeCoinOperatorFloatDeadlineWrapper) GoalPercent() JSONFloat64 {
+	goal := p.P.Predict.Predict.Literal.Operands[1]
+	if goal.Type != PERCENT {
+		return 0
+	}
+	return goal.Number
 }
 
 // Operator is the operator for the prediction's condition: ">=" or "<=".
@@ -238,6 +250,9 @@ func endTimeTruncatedDueToResultInvalidation(p Prediction, condition Condition,
 }
 
 // PredictionTypeCoinWillRangeWrapper is a prediction type. This type decorator provides value facades.
+//
+// The coin must stay between RangeLow and RangeHigh (with error) for its whole duration, i.e. the prediction is
+// incorrect on the first tick outside of the range, and correct if it ranged until its deadline.
 type PredictionTypeCoinWillRangeWrapper struct {
 	P Prediction
 }
@@ -257,20 +272,22 @@ func (p PredictionTypeCoinWillRangeWrapper) RangeHigh() JSONFloat64 {
 	return p.P.Predict.Predict.Literal.Operands[2].Number
 }
 
-// RangeLowWithError that the coin will range between (with error)
+// RangeLowWithError that the coin will range between (with error). Half of the error margin is allowed on each end
+// of the range, as when evaluating BETWEEN conditions.
 func (p PredictionTypeCoinWillRangeWrapper) RangeLowWithError() JSONFloat64 {
 	rangeLow := p.RangeLow()
 	errorMarginRatio := p.ErrorMarginRatio()
 	errorDirection := -1.0
-	return rangeLow * JSONFloat64(1.0+errorDirection*errorMarginRatio)
+	return rangeLow * JSONFloat64(1.0+errorDirection*errorMarginRatio/2)
 }
 
-// RangeHighWithError that the coin will range between (with error)
+// RangeHighWithError that the coin will range between (with error). Half of the error margin is allowed on each end
+// of the range, as when evaluating BETWEEN conditions.
 func (p PredictionTypeCoinWillRangeWrapper) RangeHighWithError() JSONFloat64 {
-	rangeLow := p.RangeLow()
+	rangeHigh := p.RangeHigh()
 	errorMarginRatio := p.ErrorMarginRatio()
 	errorDirection := 1.0
-	return rangeLow * JSONFloat64(1.0+errorDirection*errorMarginRatio)
+	return rangeHigh * JSONFloat64(1.0+errorDirection*errorMarginRatio/2)
 }
 
 // ErrorMarginRatio is the error allowed to the price matching.
@@ -283,6 +300,11 @@ func (p PredictionTypeCoinWillRangeWrapper) Deadline() time.Time {
 	return time.Unix(int64(p.P.Predict.Predict.Literal.ToTs), 0)
 }
 
+// SustainedFor is how long the coin must range for, e.g. "2w".
+func (p PredictionTypeCoinWillRangeWrapper) SustainedFor() string {
+	return p.P.Predict.Predict.Literal.SustainedFor
+}
+
 // EndTime is the time the prediction finished or will finish. If available, use EndTimeTruncatedDueToResultInvalidation
 // instead!
 func (p PredictionTypeCoinWillRangeWrapper) EndTime() time.Time {
@@ -292,3 +314,81 @@ func (p PredictionTypeCoinWillRangeWrapper) EndTime() time.Time {
 	}
 	return time.Unix(int64(p.P.State.LastTs), 0)
 }
+
+// PredictionTypeTheFlippeningWrapper is a prediction type. This type decorator provides value facades.
+type PredictionTypeTheFlippeningWrapper struct {
+	P Prediction
+}
+
+// Coin is the marketcap that will flip the other one.
+func (p PredictionTypeTheFlippeningWrapper) Coin() Operand {
+	return p.P.Predict.Predict.Literal.Operands[0]
+}
+
+// OtherCoin is the marketcap that will be flipped.
+func (p PredictionTypeTheFlippeningWrapper) OtherCoin() Operand {
+	return p.P.Predict.Predict.Literal.Operands[1]
+}
+
+// Operator is the operator for the prediction's condition: ">=" or ">".
+func (p PredictionTypeTheFlippeningWrapper) Operator() string {
+	return p.P.Predict.Predict.Literal.Operator
+}
+
+// ErrorMarginRatio is the error allowed to the marketcap matching.
+func (p PredictionTypeTheFlippeningWrapper) ErrorMarginRatio() float64 {
+	return p.P.Predict.Predict.Literal.ErrorMarginRatio
+}
+
+// Deadline for the prediction (don't use for UI! Deprecated!)
+func (p PredictionTypeTheFlippeningWrapper) Deadline() time.Time {
+	return time.Unix(int64(p.P.Predict.Predict.Literal.ToTs), 0)
+}
+
+// EndTime is the time the prediction finished or will finish.
+func (p PredictionTypeTheFlippeningWrapper) EndTime() time.Time {
+	deadline := p.Deadline()
+	if p.P.State.Status != FINISHED {
+		return deadline
+	}
+	return time.Unix(int64(p.P.State.LastTs), 0)
+}
+
+// PredictionTypeCoinOperatorCoinDeadlineWrapper is a prediction type. This type decorator provides value facades.
+type PredictionTypeCoinOperatorCoinDeadlineWrapper struct {
+	P Prediction
+}
+
+// Coin is the coin being compared.
+func (p PredictionTypeCoinOperatorCoinDeadlineWrapper) Coin() Operand {
+	return p.P.Predict.Predict.Literal.Operands[0]
+}
+
+// OtherCoin is the coin compared against, possibly with a Multiplier, e.g. a tenth of Bitcoin's price.
+func (p PredictionTypeCoinOperatorCoinDeadlineWrapper) OtherCoin() Operand {
+	return p.P.Predict.Predict.Literal.Operands[1]
+}
+
+// Operator is the operator for the prediction's condition: ">=" or "<=".
+func (p PredictionTypeCoinOperatorCoinDeadlineWrapper) Operator() string {
+	return p.P.Predict.Predict.Literal.Operator
+}
+
+// ErrorMarginRatio is the error allowed to the price matching.
+func (p PredictionTypeCoinOperatorCoinDeadlineWrapper) ErrorMarginRatio() float64 {
+	return p.P.Predict.Predict.Literal.ErrorMarginRatio
+}
+
+// Deadline for the prediction (don't use for UI! Deprecated!)
+func (p PredictionTypeCoinOperatorCoinDeadlineWrapper) Deadline() time.Time {
+	return time.Unix(int64(p.P.Predict.Predict.Literal.ToTs), 0)
+}
+
+// EndTime is the time the prediction finished or will finish.
+func (p PredictionTypeCoinOperatorCoinDeadlineWrapper) EndTime() time.Time {
+	deadline := p.Deadline()
+	if p.P.State.Status != FINISHED {
+		return deadline
+	}
+	return time.Unix(int64(p.P.State.LastTs), 0)
+}
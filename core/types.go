@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/marianogappa/crypto-candles/candles/common"
-	"github.com/marianogappa/crypto-candles/candles/iterator"
 )
 
 var (
@@ -16,7 +15,6 @@ var (
 	// If a PredictionType is on this list, it's probably because the overlays or pretty printing are unsupported.
 	UIUnsupportedPredictionTypes = map[PredictionType]bool{
 		PredictionTypeUnsupported:                         true,
-		PredictionTypeCoinWillRange:                       true,
 		PredictionTypeCoinWillReachBeforeItReaches:        true,
 		PredictionTypeCoinWillReachInvalidatedIfItReaches: true,
 	}
@@ -45,6 +43,27 @@ var (
 	// ErrEqualBaseQuoteAssets means: base asset cannot be equal to quote asset
 	ErrEqualBaseQuoteAssets = errors.New("base asset cannot be equal to quote asset")
 
+	// ErrUnknownIndicator means: the only supported indicators are SMA, EMA, RSI and VOLUME
+	ErrUnknownIndicator = errors.New("the only supported indicators are SMA, EMA, RSI and VOLUME")
+
+	// ErrVolumeIndicatorUnavailable means: VOLUME indicators are only available on BINANCE
+	ErrVolumeIndicatorUnavailable = errors.New("VOLUME indicators are only available on BINANCE")
+
+	// ErrInvalidIndicatorWindow means: indicator windows must be up to 1000 candlesticks of a minute, an hour or a day (e.g. 200d)
+	ErrInvalidIndicatorWindow = errors.New("indicator windows must be up to 1000 candlesticks of a minute, an hour or a day (e.g. 200d)")
+
+	// ErrUnknownEvaluationMode means: the only supported evaluation modes are WICK, CLOSE and SUSTAINED
+	ErrUnknownEvaluationMode = errors.New("the only supported evaluation modes are WICK, CLOSE and SUSTAINED")
+
+	// ErrInvalidCandleInterval means: candle intervals must be one of 1m, 5m, 15m, 30m, 1h, 2h, 4h, 6h, 12h, 1d or 1w, and only for CLOSE or SUSTAINED evaluation modes
+	ErrInvalidCandleInterval = errors.New("candle intervals must be one of 1m, 5m, 15m, 30m, 1h, 2h, 4h, 6h, 12h, 1d or 1w, and only for CLOSE or SUSTAINED evaluation modes")
+
+	// ErrInvalidSustainedCandles means: sustained candles must be at least 1, and only for the SUSTAINED evaluation mode
+	ErrInvalidSustainedCandles = errors.New("sustained candles must be at least 1, and only for the SUSTAINED evaluation mode")
+
+	// ErrInvalidSustainedFor means: sustainedFor must be a valid duration that matches the condition's deadline, and cannot be combined with the SUSTAINED evaluation mode
+	ErrInvalidSustainedFor = errors.New("sustainedFor must be a valid duration that matches the condition's deadline, and cannot be combined with the SUSTAINED evaluation mode")
+
 	// ErrInvalidDuration means: invalid duration
 	ErrInvalidDuration = errors.New("invalid duration")
 
@@ -99,8 +118,11 @@ var (
 	// ErrUnknownAPIOrderBy means: unknown API order by
 	ErrUnknownAPIOrderBy = errors.New("unknown API order by")
 
-	// ErrInvalidExchange means: the only valid exchanges are 'binance', 'ftx', 'coinbase', 'huobi', 'kraken', 'kucoin' and 'binanceusdmfutures'
-	ErrInvalidExchange = errors.New("the only valid exchanges are 'binance', 'ftx', 'coinbase', 'huobi', 'kraken', 'kucoin' and 'binanceusdmfutures'")
+	// ErrInvalidExchange means: the only valid exchanges are 'binance', 'coinbase', 'kucoin', 'bitstamp', 'bitfinex' and 'binanceusdmfutures'
+	ErrInvalidExchange = errors.New("the only valid exchanges are 'binance', 'coinbase', 'kucoin', 'bitstamp', 'bitfinex' and 'binanceusdmfutures'")
+
+	// ErrInvalidAggregateProvider means: aggregate providers are ANY or MEDIAN, optionally of two or more different exchanges (e.g. MEDIAN(BINANCE,COINBASE,KUCOIN))
+	ErrInvalidAggregateProvider = errors.New("aggregate providers are ANY or MEDIAN, optionally of two or more different exchanges (e.g. MEDIAN(BINANCE,COINBASE,KUCOIN))")
 
 	// ErrBaseAssetRequired means: base asset is required (e.g. BTC)
 	ErrBaseAssetRequired = errors.New("base asset is required (e.g. BTC)")
@@ -114,28 +136,84 @@ var (
 	ErrStorageErrorRetrievingAccounts = errors.New("storage had error retrieving accounts")
 )
 
-// Operand represents an operand in a Condition. It can be one of three things:
+// Operand represents an operand in a Condition. It can be one of six things:
 //
 // 1) a COIN, which represents a market e.g. BTC/USDT
 // 2) a MARKETCAP, which represents the market capitalization of a crypto asset e.g. the marketcap of BTC
 // 3) a NUMBER, which represents a literal number e.g. 1.234
+// 4) a PERCENT, which represents a move relative to the Condition's Baseline e.g. -15%
+// 5) an EXPR, which represents an arithmetic expression on COINs, MARKETCAPs & NUMBERs e.g. BTC/USDT - BTC/USD
+// 6) an INDICATOR, which represents an indicator of a market over a window e.g. the 200 day SMA of BTC/USDT
 //
 // Operands are used in expressions together with an Operator to declare a Condition, e.g. BTC/USDT >= 45000.
 type Operand struct {
 	Type       OperandType
-	Provider   string      // e.g. "BINANCE", "KUCOIN", must be empty if Type == NUMBER
-	BaseAsset  string      // e.g. "BTC" in BTC/USDT, must be empty if Type == NUMBER
-	QuoteAsset string      // e.g. "USDT" in BTC/USDT, must be empty if Type in {MARKETCAP, NUMBER}
-	Number     JSONFloat64 // e.g. "1.234", must be empty if Type != NUMBER
-	Str        string      // e.g. "COIN:BINANCE:BTC-USDT", "MARKETCAP:MESSARI:BTC", "1.234"
+	Provider   string      // e.g. "BINANCE", "KUCOIN", must be empty if Type in {NUMBER, PERCENT, EXPR}
+	BaseAsset  string      // e.g. "BTC" in BTC/USDT, must be empty if Type in {NUMBER, PERCENT, EXPR}
+	QuoteAsset string      // e.g. "USDT" in BTC/USDT, must be empty if Type in {MARKETCAP, NUMBER, PERCENT, EXPR}
+	Number     JSONFloat64 // e.g. "1.234", or "-15" in "-15%", must be empty if Type not in {NUMBER, PERCENT}
+	Str        string      // e.g. "COIN:BINANCE:BTC-USDT", "MARKETCAP:MESSARI:BTC", "1.234", "-15%", "1.2 * 30000"
+	Multiplier JSONFloat64 // e.g. "0.1" in "COIN:BINANCE:BTC-USDT * 0.1", empty if the operand is not multiplied
+	Expr       *Expr       // e.g. the AST of "COIN:BINANCE:BTC-USDT - COIN:KUCOIN:BTC-USDT", must be nil if Type != EXPR
+	Indicator  string      // e.g. "SMA" in "SMA(COIN:BINANCE:BTC-USDT,200d)", must be empty if Type != INDICATOR
+	Window     string      // e.g. "200d" in "SMA(COIN:BINANCE:BTC-USDT,200d)", must be empty if Type != INDICATOR
+}
+
+// ConditionStr returns the Operand as it's written in a condition, e.g. "COIN:BINANCE:BTC-USDT * 0.1".
+func (o Operand) ConditionStr() string {
+	if o.Multiplier == 0 {
+		return o.Str
+	}
+	return fmt.Sprintf("%v * %v", o.Str, o.Multiplier)
+}
+
+// valueOf returns the Operand's value when its market is at value, i.e. applying its Multiplier.
+func (o Operand) valueOf(value float64) float64 {
+	if o.Multiplier == 0 {
+		return value
+	}
+	return value * float64(o.Multiplier)
+}
+
+// Value resolves the Operand to a number, given the ticks of its markets and the baseline of its percentages.
+func (o Operand) Value(ticks map[string]Tick, baseline float64) (float64, error) {
+	switch o.Type {
+	case NUMBER:
+		return float64(o.Number), nil
+	case PERCENT:
+		return baseline * (1 + float64(o.Number)/100), nil
+	case EXPR:
+		return o.Expr.Value(ticks)
+	default:
+		tick, ok := ticks[o.Str]
+		if !ok {
+			return 0, fmt.Errorf("internal error: ticker for operand %v was not supplied", o.Str)
+		}
+		return o.valueOf(float64(tick.Value)), nil
+	}
+}
+
+// NonNumberOperands returns the Operand if it's a COIN, a MARKETCAP or an INDICATOR, or those within it if it's an
+// EXPR.
+func (o Operand) NonNumberOperands() []Operand {
+	switch o.Type {
+	case NUMBER, PERCENT:
+		return []Operand{}
+	case EXPR:
+		return o.Expr.NonNumberOperands()
+	default:
+		return []Operand{o}
+	}
 }
 
-// ToMarketSource translates an Operand to a struct that the market package can work with.
+// ToMarketSource translates an Operand to a struct that the market package can work with. The market package only
+// supports COIN markets, so an INDICATOR translates to the market of the coin it's computed from, and a MARKETCAP to
+// an UNSUPPORTED one that only its data source understands (see Market).
 func (o Operand) ToMarketSource() common.MarketSource {
 	return common.MarketSource{Type: o.Type.toMarketType(), Provider: o.Provider, BaseAsset: o.BaseAsset, QuoteAsset: o.QuoteAsset}
 }
 
-// OperandType is the type of Operand in a condition. Can be NUMBER|COIN|MARKETCAP
+// OperandType is the type of Operand in a condition. Can be NUMBER|COIN|MARKETCAP|PERCENT|EXPR|INDICATOR
 type OperandType int
 
 const (
@@ -145,6 +223,12 @@ const (
 	COIN
 	// MARKETCAP is e.g. MARKETCAP:MESSARI:BTC
 	MARKETCAP
+	// PERCENT is e.g. -15%
+	PERCENT
+	// EXPR is e.g. COIN:BINANCE:BTC-USDT - COIN:KUCOIN:BTC-USDT
+	EXPR
+	// INDICATOR is e.g. SMA(COIN:BINANCE:BTC-USDT,200d)
+	INDICATOR
 )
 
 // OperandTypeFromString constructs an OperandType from a string
@@ -156,6 +240,12 @@ func OperandTypeFromString(s string) (OperandType, error) {
 		return COIN, nil
 	case "MARKETCAP":
 		return MARKETCAP, nil
+	case "PERCENT":
+		return PERCENT, nil
+	case "EXPR":
+		return EXPR, nil
+	case "INDICATOR":
+		return INDICATOR, nil
 	default:
 		return 0, fmt.Errorf("%w: %v", ErrUnknownOperandType, s)
 	}
@@ -168,6 +258,12 @@ func (v OperandType) String() string {
 		return "COIN"
 	case MARKETCAP:
 		return "MARKETCAP"
+	case PERCENT:
+		return "PERCENT"
+	case EXPR:
+		return "EXPR"
+	case INDICATOR:
+		return "INDICATOR"
 	default:
 		return ""
 	}
@@ -176,6 +272,8 @@ func (v OperandType) toMarketType() common.MarketType {
 	switch v {
 	case COIN:
 		return common.COIN
+	case INDICATOR:
+		return common.COIN
 	default:
 		return common.UNSUPPORTED
 	}
@@ -189,11 +287,23 @@ func (v OperandType) toMarketType() common.MarketType {
 //
 // - Status determines if the Condition has finished evolving or not, and Value determines its result. When Status
 //   is not FINISHED, Value must be UNDECIDED.
+//
+// - Streak is how many consecutive candle closes satisfied the boolean condition so far, only for Conditions with the
+//   SUSTAINED EvaluationMode.
+//
+// - StalledRuns is how many consecutive Daemon runs couldn't evolve the Condition because its market data stopped,
+//   e.g. its exchange delisted the market pair (see Condition.FailOver).
+//
+// - DecidingTicks are the Ticks that moved the Condition to TRUE or FALSE, one for each non-literal operand, as
+//   evidence of why it finished with its Value. They're empty until it's FINISHED.
 type ConditionState struct {
-	Status    ConditionStatus
-	LastTs    int
-	LastTicks map[string]Tick
-	Value     ConditionStateValue
+	Status        ConditionStatus
+	LastTs        int
+	LastTicks     map[string]Tick
+	Value         ConditionStateValue
+	Streak        int
+	StalledRuns   int
+	DecidingTicks []DecidingTick
 }
 
 // Clone returns a deep copy of ConditionState that does not share any memory with the original struct.
@@ -204,10 +314,13 @@ func (s ConditionState) Clone() ConditionState {
 	}
 
 	return ConditionState{
-		Status:    s.Status,
-		LastTs:    s.LastTs,
-		LastTicks: clonedLastTicks,
-		Value:     s.Value,
+		Status:        s.Status,
+		LastTs:        s.LastTs,
+		LastTicks:     clonedLastTicks,
+		Value:         s.Value,
+		Streak:        s.Streak,
+		StalledRuns:   s.StalledRuns,
+		DecidingTicks: append([]DecidingTick(nil), s.DecidingTicks...),
 	}
 }
 
@@ -354,6 +467,8 @@ type PredictionState struct {
 	Status ConditionStatus
 	LastTs int
 	Value  PredictionStateValue
+	// Difficulty is the prediction's Difficulty, calculated once when it becomes CORRECT (see AccountStats).
+	Difficulty float64
 }
 
 // APIFilters is the set of filters for requesting Predictions at API-level and storage-level.
@@ -370,6 +485,9 @@ type APIFilters struct {
 	Paused                *bool    `json:"paused"`
 	Hidden                *bool    `json:"hidden"`
 	IncludeUIUnsupported  bool     `json:"showUIUnsupported"`
+
+	// DueAt only matches predictions that should be evolved at this time. It's only used by the Daemon.
+	DueAt ISO8601 `json:"-"`
 }
 
 // ToQueryStringWithOrderBy converts the struct to a QueryString, including the orderBy parameter
@@ -552,13 +670,26 @@ func (t ISO8601) Millis() (int, error) {
 	return tm * 100, nil
 }
 
-// PredictionStateValueChange represents a database-row for the event of a prediction changing value.
+// PredictionStateValueChange represents a database-row for the event of a prediction changing value. A prediction may
+// change to the same value many times, e.g. when its state is cleared and it's evolved again.
+//
+// ConditionName & ConditionValue are the condition whose change of value triggered it and the value it changed to, and
+// Ticks are the ticks it changed on. They're empty for the prediction's initial value.
 type PredictionStateValueChange struct {
 	PredictionUUID string
 	StateValue     string
+	ConditionName  string
+	ConditionValue string
+	Ticks          map[string]Tick
 	CreatedAt      ISO8601
 }
 
+// PredictionStats are aggregate counts over a set of predictions.
+type PredictionStats struct {
+	Total        int            `json:"total"`
+	ByStateValue map[string]int `json:"byStateValue"`
+}
+
 // Account represents a post author's social media account, both at the API & database-level.
 type Account struct {
 	URL           *url.URL   `json:"url"`
@@ -595,6 +726,14 @@ const (
 	// PredictionTypeCoinWillReachInvalidatedIfItReaches is a type of prediction that looks like this:
 	// "If Bitcoin doesn't fall below $10k, it will reach $60k within 3 months"
 	PredictionTypeCoinWillReachInvalidatedIfItReaches
+
+	// PredictionTypeTheFlippening is a type of prediction that looks like this:
+	// "Ethereum's MarketCap will flip Bitcoin's MarketCap by end of year"
+	PredictionTypeTheFlippening
+
+	// PredictionTypeCoinOperatorCoinDeadline is a type of prediction that looks like this:
+	// "Ethereum will exceed a tenth of Bitcoin's price by end of year"
+	PredictionTypeCoinOperatorCoinDeadline
 )
 
 // PredictionTypeFromString constructs a PredictionType from a string.
@@ -608,6 +747,10 @@ func PredictionTypeFromString(s string) PredictionType {
 		return PredictionTypeCoinWillReachBeforeItReaches
 	case "PREDICTION_TYPE_COIN_WILL_REACH_INVALIDATED_IF_IT_REACHES":
 		return PredictionTypeCoinWillReachInvalidatedIfItReaches
+	case "PREDICTION_TYPE_THE_FLIPPENING":
+		return PredictionTypeTheFlippening
+	case "PREDICTION_TYPE_COIN_OPERATOR_COIN_DEADLINE":
+		return PredictionTypeCoinOperatorCoinDeadline
 	default:
 		return PredictionTypeUnsupported
 	}
@@ -622,6 +765,10 @@ func (v PredictionType) String() string {
 		return "PREDICTION_TYPE_COIN_WILL_REACH_BEFORE_IT_REACHES"
 	case PredictionTypeCoinWillReachInvalidatedIfItReaches:
 		return "PREDICTION_TYPE_COIN_WILL_REACH_INVALIDATED_IF_IT_REACHES"
+	case PredictionTypeTheFlippening:
+		return "PREDICTION_TYPE_THE_FLIPPENING"
+	case PredictionTypeCoinOperatorCoinDeadline:
+		return "PREDICTION_TYPE_COIN_OPERATOR_COIN_DEADLINE"
 	default:
 		return "PREDICTION_TYPE_UNSUPPORTED"
 	}
@@ -637,13 +784,16 @@ type PredictionInteraction struct {
 	Error              string
 }
 
-// IMarket is an interface to candles.Market just to be able to mock it for tests
-type IMarket interface {
-	Iterator(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error)
+// SourcedIterator is implemented by market iterators that resolve candlesticks from one of many exchanges, i.e. those of
+// operands with an aggregate provider (e.g. COIN:ANY:BTC-USDT). Source returns where the last candlestick came from.
+type SourcedIterator interface {
+	Source() string
 }
 
-// Tick is a subset of a candlestick for one of the 4 available prices
+// Tick is a subset of a candlestick for one of the 4 available prices. Source is the exchange the price was resolved
+// from, only for operands with an aggregate provider (e.g. COIN:ANY:BTC-USDT), as a comma-separated list for MEDIAN.
 type Tick struct {
 	Timestamp int                `json:"t"`
 	Value     common.JSONFloat64 `json:"v"`
+	Source    string             `json:"s,omitempty"`
 }
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -24,6 +25,8 @@ const (
 	actionTypePredictionCreated
 )
 
+const interactionsLeaseName = "prediction_interactions"
+
 var (
 	// ErrTweetingDisabled is returned when Daemon.ActionPrediction is called but tweeting is disabled
 	ErrTweetingDisabled = errors.New("tweeting is not enabled for the daemon; to enable set the PREDICTIONS_DAEMON_ENABLE_TWEETING env to any value")
@@ -52,8 +55,25 @@ func (a actionType) String() string {
 }
 
 // ActionPendingInteractions actions all pending social media interactions for all predictions that change status.
-func (r *Daemon) ActionPendingInteractions(timeNowFunc func() time.Time) error {
-	pendingInteractions, err := r.store.GetPendingPredictionInteractions()
+// Cancelling ctx stops it between interactions, but an interaction that was already posted always gets its status
+// updated, so that it isn't posted twice.
+func (r *Daemon) ActionPendingInteractions(ctx context.Context, timeNowFunc func() time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Only one Daemon at a time actions pending interactions, so that nothing is tweeted twice.
+	lease, acquired, err := r.acquireLease(ctx, interactionsLeaseName)
+	if err != nil || !acquired {
+		return err
+	}
+	defer func() {
+		if err := lease.release(uncancellableContext{ctx}); err != nil {
+			log.Error().Err(err).Msg("Daemon.ActionPendingInteractions: error releasing lease...ignoring.")
+		}
+	}()
+
+	pendingInteractions, err := r.store.GetPendingPredictionInteractions(ctx)
 	if err != nil {
 		log.Error().Err(err).Msgf("Daemon.ActionPendingInteractions: error actioning pending interactions.")
 		return err
@@ -63,7 +83,11 @@ func (r *Daemon) ActionPendingInteractions(timeNowFunc func() time.Time) error {
 		pendingInteractions = pendingInteractions[:3]
 	}
 	for _, interaction := range pendingInteractions {
-		tweetURL, err := r.ActionPendingInteraction(interaction, timeNowFunc)
+		if lease.ctx.Err() != nil {
+			break
+		}
+		interactionCtx := uncancellableContext{ctx}
+		tweetURL, err := r.ActionPendingInteraction(interactionCtx, interaction, timeNowFunc)
 
 		interaction.Status = "POSTED"
 		interaction.Error = ""
@@ -73,7 +97,7 @@ func (r *Daemon) ActionPendingInteractions(timeNowFunc func() time.Time) error {
 			interaction.Error = err.Error()
 		}
 
-		if err := r.store.UpdatePredictionInteractionStatus(interaction); err != nil {
+		if err := r.store.UpdatePredictionInteractionStatus(interactionCtx, interaction); err != nil {
 			log.Error().Err(err).Msg("Daemon.ActionPendingInteractions: error actioning pending interaction...ignoring.")
 		}
 	}
@@ -81,8 +105,8 @@ func (r *Daemon) ActionPendingInteractions(timeNowFunc func() time.Time) error {
 }
 
 // ActionPendingInteraction actions a pending social media interaction.
-func (r *Daemon) ActionPendingInteraction(interaction core.PredictionInteraction, timeNowFunc func() time.Time) (string, error) {
-	preds, err := r.store.GetPredictions(core.APIFilters{UUIDs: []string{interaction.PredictionUUID}}, []string{}, 1, 0)
+func (r *Daemon) ActionPendingInteraction(ctx context.Context, interaction core.PredictionInteraction, timeNowFunc func() time.Time) (string, error) {
+	preds, err := r.store.GetPredictions(ctx, core.APIFilters{UUIDs: []string{interaction.PredictionUUID}}, []string{}, 1, 0)
 	if err != nil {
 		log.Error().Err(err).Msgf("Daemon.ActionPendingInteractions: error actioning pending interactions.")
 		return "", err
@@ -97,14 +121,14 @@ func (r *Daemon) ActionPendingInteraction(interaction core.PredictionInteraction
 		return "", fmt.Errorf("unknown action type: [%v]", interaction.ActionType)
 	}
 
-	return r.ActionPrediction(preds[0], actType, int(timeNowFunc().Unix()))
+	return r.ActionPrediction(ctx, preds[0], actType, int(timeNowFunc().Unix()))
 }
 
 // ActionPrediction currently tweets when a prediction is created and when it becomes correct or incorrect.
 //
 // TODO: this should be extracted into a separate PredictionPublisher component that takes Twitter, Store & Market,
 // because BackOffice will probably end up using it, which means API needs to run it.
-func (r *Daemon) ActionPrediction(prediction core.Prediction, actType actionType, nowTs int) (string, error) {
+func (r *Daemon) ActionPrediction(ctx context.Context, prediction core.Prediction, actType actionType, nowTs int) (string, error) {
 	if !r.enableTweeting {
 		return "", ErrTweetingDisabled
 	}
@@ -129,7 +153,7 @@ func (r *Daemon) ActionPrediction(prediction core.Prediction, actType actionType
 		return "", fmt.Errorf("daemon.ActionPrediction: prediction's lastTs is older than 24hs, so I won't action it anymore")
 	}
 	interaction := core.PredictionInteraction{PredictionUUID: prediction.UUID, PostURL: prediction.PostURL, ActionType: actType.String()}
-	exists, err := r.store.NonPendingPredictionInteractionExists(interaction)
+	exists, err := r.store.NonPendingPredictionInteractionExists(ctx, interaction)
 	if err != nil {
 		return "", err
 	}
@@ -139,7 +163,7 @@ func (r *Daemon) ActionPrediction(prediction core.Prediction, actType actionType
 	if prediction.PostAuthorURL == "" {
 		return "", errors.New("daemon.ActionPrediction: prediction has no PostAuthorURL, so I cannot make an image")
 	}
-	accounts, err := r.store.GetAccounts(core.APIAccountFilters{URLs: []string{prediction.PostAuthorURL}}, nil, 1, 0)
+	accounts, err := r.store.GetAccounts(ctx, core.APIAccountFilters{URLs: []string{prediction.PostAuthorURL}}, nil, 1, 0)
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", core.ErrStorageErrorRetrievingAccounts, err)
 	}
@@ -0,0 +1,215 @@
+package daemon
+
+import (
+	"errors"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+)
+
+// coarseCandlestickIntervals are the candlestick intervals, from coarsest to finest, that a condEvolver uses to skip
+// over market data that cannot make its condition true, before falling back to 1 minute candlesticks.
+var coarseCandlestickIntervals = []time.Duration{24 * time.Hour, time.Hour}
+
+var errOutOfSyncCandlestick = errors.New("candlestick doesn't start at the expected time")
+
+// condEvolver evolves a condition with market data, from where it was left.
+//
+// Evaluating a condition minute by minute is slow when catching up on months of market data (e.g. a prediction posted
+// long ago, or one whose state was cleared), so conditions with a single non-literal operand are first evolved with
+// coarse candlesticks (daily, then hourly). If the lowest and highest prices of a coarse candlestick cannot decide the
+// condition (i.e. make it true, or violate it if it must be sustained), the whole candlestick is skipped; otherwise,
+// it's zoomed into with finer candlesticks, down to 1 minute ones, which are the only ones that actually evaluate the
+// condition. Thus, conditions are decided at exactly the same minute as if they were evolved minute by minute. The only
+// difference is that, if the prediction becomes final while a condition is skipping market data, that undecided
+// condition's state stays where it was last evolved.
+type condEvolver struct {
+	cond     *core.Condition
+	market   core.IMarket
+	operands []core.Operand
+
+	// nextTs is the timestamp of the next 1 minute candlestick to evolve the condition with.
+	nextTs int
+	// lastTickTs is the timestamp of the last tick the condition was evolved with.
+	lastTickTs int
+	// tickers are the iterators currently in use for each candlestick interval.
+	tickers map[time.Duration]*intervalTickers
+	// zoomedUntil is, for each coarse interval, until when the condition must be evolved with finer candlesticks.
+	zoomedUntil map[time.Duration]int
+	// unavailable are the coarse intervals that failed to provide candlesticks, so they're not tried again.
+	unavailable map[time.Duration]bool
+	// skippedTick is the last tick of the market data skipped since the condition was last evolved, if any.
+	skippedTick *core.Tick
+}
+
+// intervalTickers are the iterators of all non-literal operands of a condition for a candlestick interval, and the
+// timestamp of the candlestick they will return next.
+type intervalTickers struct {
+	tickers map[string]iterator.Iterator
+	nextTs  int
+}
+
+func newCondEvolver(cond *core.Condition, m core.IMarket, startTime time.Time, startFromNext bool) (*condEvolver, error) {
+	e := &condEvolver{
+		cond:        cond,
+		market:      m,
+		operands:    cond.NonNumberOperands(),
+		tickers:     map[time.Duration]*intervalTickers{},
+		zoomedUntil: map[time.Duration]int{},
+		unavailable: map[time.Duration]bool{},
+	}
+
+	tickers := map[string]iterator.Iterator{}
+	for _, operand := range e.operands {
+		ticker, err := m.Iterator(operand, startTime, time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		ticker.SetStartFromNext(startFromNext)
+
+		tickers[operand.Str] = ticker
+		e.nextTs = common.NormalizeTimestamp(startTime, time.Minute, operand.Provider, startFromNext)
+	}
+	e.tickers[time.Minute] = &intervalTickers{tickers: tickers, nextTs: e.nextTs}
+
+	return e, nil
+}
+
+// step evolves the condition with the next 1 minute candlestick, or skips as many minutes as a coarse candlestick
+// spans if it cannot make the condition true.
+func (e *condEvolver) step() error {
+	for _, interval := range coarseCandlestickIntervals {
+		if !e.canSkipWith(interval) {
+			continue
+		}
+		candlesticks, sources, err := e.nextCandlesticks(interval)
+		if err != nil {
+			// e.g. the exchange doesn't support this interval, or the candlestick hasn't closed yet: use finer ones.
+			e.unavailable[interval] = true
+			delete(e.tickers, interval)
+			continue
+		}
+		lowestTicks, highestTicks := candlesticksToTicks(candlesticks, sources)
+		if e.cond.CanBeDecidedWithin(lowestTicks, highestTicks) {
+			e.zoomedUntil[interval] = e.nextTs + int(interval/time.Second)
+			continue
+		}
+
+		e.cond.Skip(e.nextTs, e.nextTs+int(interval/time.Second))
+		e.nextTs += int(interval / time.Second)
+		key := e.operands[0].Str
+		e.skippedTick = &core.Tick{Timestamp: e.nextTs - 60, Value: candlesticks[key].ClosePrice, Source: sources[key]}
+		return nil
+	}
+
+	candlesticks, sources, err := e.nextCandlesticks(time.Minute)
+	if err != nil {
+		return err
+	}
+	if err := e.flushSkippedTick(); err != nil {
+		return err
+	}
+	if err := e.run(candlesticks, sources); err != nil {
+		return err
+	}
+	e.nextTs = e.tickers[time.Minute].nextTs
+	return nil
+}
+
+// run evolves the condition with the next 1 minute candlesticks. Conditions evaluated on candle closes only need the
+// close prices, but otherwise both the lowest & highest prices are evaluated, i.e. the wicks.
+func (e *condEvolver) run(candlesticks map[string]common.Candlestick, sources map[string]string) error {
+	for _, candlestick := range candlesticks {
+		if candlestick.Timestamp > e.lastTickTs {
+			e.lastTickTs = candlestick.Timestamp
+		}
+	}
+	if e.cond.EvaluationMode != core.WICK {
+		closeTicks := map[string]core.Tick{}
+		for key, candlestick := range candlesticks {
+			closeTicks[key] = core.Tick{Timestamp: candlestick.Timestamp, Value: candlestick.ClosePrice, Source: sources[key]}
+		}
+		return e.cond.Run(closeTicks)
+	}
+	lowestTicks, highestTicks := candlesticksToTicks(candlesticks, sources)
+	if err := e.cond.Run(lowestTicks); err != nil {
+		return err
+	}
+	return e.cond.Run(highestTicks)
+}
+
+// flushSkippedTick updates the condition's state as if it had been evolved up to the end of the skipped market data.
+// The skipped data cannot decide the condition, and it ends before the condition's deadline, so this doesn't
+// decide the condition.
+func (e *condEvolver) flushSkippedTick() error {
+	if e.skippedTick == nil {
+		return nil
+	}
+	tick := *e.skippedTick
+	e.skippedTick = nil
+	e.lastTickTs = tick.Timestamp
+	return e.cond.Run(map[string]core.Tick{e.operands[0].Str: tick})
+}
+
+// canSkipWith returns whether the next candlestick of the interval could be skipped, i.e. if it fully spans the
+// minutes left to evolve in the condition, and it's not being zoomed into already.
+func (e *condEvolver) canSkipWith(interval time.Duration) bool {
+	secs := int(interval / time.Second)
+	return len(e.operands) == 1 &&
+		!e.unavailable[interval] &&
+		e.nextTs%secs == 0 &&
+		e.nextTs >= e.cond.FromTs &&
+		e.nextTs+secs <= e.cond.ToTs &&
+		e.nextTs >= e.zoomedUntil[interval]
+}
+
+// nextCandlesticks returns the next candlestick of the interval for every non-literal operand, starting at nextTs,
+// and where each one came from if their iterator tells (i.e. for aggregate providers). Iterators are reused while
+// they are in sync with nextTs, and recreated otherwise (e.g. after skipping a day, the hourly iterators are a day
+// behind).
+func (e *condEvolver) nextCandlesticks(interval time.Duration) (map[string]common.Candlestick, map[string]string, error) {
+	tickers, ok := e.tickers[interval]
+	if !ok || tickers.nextTs != e.nextTs {
+		tickers = &intervalTickers{tickers: map[string]iterator.Iterator{}, nextTs: e.nextTs}
+		for _, operand := range e.operands {
+			ticker, err := e.market.Iterator(operand, time.Unix(int64(e.nextTs), 0).UTC(), interval)
+			if err != nil {
+				return nil, nil, err
+			}
+			tickers.tickers[operand.Str] = ticker
+		}
+		e.tickers[interval] = tickers
+	}
+
+	var (
+		candlesticks = map[string]common.Candlestick{}
+		sources      = map[string]string{}
+	)
+	for key, ticker := range tickers.tickers {
+		candlestick, err := ticker.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if interval != time.Minute && candlestick.Timestamp != e.nextTs {
+			return nil, nil, errOutOfSyncCandlestick
+		}
+		candlesticks[key] = candlestick
+		sources[key] = iteratorSource(ticker)
+		tickers.nextTs = candlestick.Timestamp + int(interval/time.Second)
+	}
+	return candlesticks, sources, nil
+}
+
+func candlesticksToTicks(candlesticks map[string]common.Candlestick, sources map[string]string) (map[string]core.Tick, map[string]core.Tick) {
+	var (
+		lowestTicks  = map[string]core.Tick{}
+		highestTicks = map[string]core.Tick{}
+	)
+	for key, candlestick := range candlesticks {
+		lowestTicks[key] = core.Tick{Timestamp: candlestick.Timestamp, Value: candlestick.LowestPrice, Source: sources[key]}
+		highestTicks[key] = core.Tick{Timestamp: candlestick.Timestamp, Value: candlestick.HighestPrice, Source: sources[key]}
+	}
+	return lowestTicks, highestTicks
+}
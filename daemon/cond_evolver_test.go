@@ -0,0 +1,207 @@
+package daemon
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredEvolverWithCoarseCandlesticksMatchesMinuteByMinute(t *testing.T) {
+	var (
+		fromTs = tInt("2022-01-01 15:20:00")
+		toTs   = tInt("2022-03-01 00:00:00")
+		btc    = operand("COIN:BINANCE:BTC-USDT")
+		cond   = func(name, operator string, operands ...string) *core.Condition {
+			c := &core.Condition{Name: name, Operator: operator, FromTs: fromTs, ToTs: toTs, State: core.ConditionState{LastTicks: map[string]core.Tick{}}}
+			for _, op := range operands {
+				c.Operands = append(c.Operands, operand(op))
+			}
+			return c
+		}
+	)
+
+	tss := []struct {
+		name       string
+		prediction func() core.Prediction
+		nowTs      int
+	}{
+		{
+			name: "becomes true at a peak",
+			prediction: func() core.Prediction {
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(cond("main", ">=", btc.Str, "54990"))})
+			},
+			nowTs: toTs + 86400,
+		},
+		{
+			name: "becomes true at a valley",
+			prediction: func() core.Prediction {
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(cond("main", "<", btc.Str, "45010"))})
+			},
+			nowTs: toTs + 86400,
+		},
+		{
+			name: "never becomes true",
+			prediction: func() core.Prediction {
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(cond("main", ">=", btc.Str, "56000"))})
+			},
+			nowTs: toTs + 86400,
+		},
+		{
+			name: "between with error margin",
+			prediction: func() core.Prediction {
+				c := cond("main", "BETWEEN", btc.Str, "55100", "56000")
+				c.ErrorMarginRatio = 0.01
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+			},
+			nowTs: toTs + 86400,
+		},
+		{
+			name: "becomes true at an hourly close",
+			prediction: func() core.Prediction {
+				c := cond("main", ">=", btc.Str, "54990")
+				c.EvaluationMode, c.CandleInterval = core.CLOSE, "1h"
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+			},
+			nowTs: toTs + 86400,
+		},
+		{
+			name: "becomes true after consecutive hourly closes",
+			prediction: func() core.Prediction {
+				c := cond("main", ">=", btc.Str, "54950")
+				c.EvaluationMode, c.CandleInterval, c.SustainedCandles = core.SUSTAINED, "1h", 2
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+			},
+			nowTs: toTs + 86400,
+		},
+		{
+			name: "sustained range is violated",
+			prediction: func() core.Prediction {
+				c := cond("main", "BETWEEN", btc.Str, "45010", "54990")
+				c.SustainedFor = "8w"
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+			},
+			nowTs: toTs + 86400,
+		},
+		{
+			name: "sustained range holds",
+			prediction: func() core.Prediction {
+				c := cond("main", "BETWEEN", btc.Str, "44000", "56000")
+				c.SustainedFor = "8w"
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+			},
+			nowTs: toTs + 86400,
+		},
+		{
+			name: "is still ongoing when market data runs out",
+			prediction: func() core.Prediction {
+				return newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(cond("main", ">=", btc.Str, "56000"))})
+			},
+			nowTs: tInt("2022-02-10 10:35:00"),
+		},
+		{
+			name: "reaches one value before the other",
+			prediction: func() core.Prediction {
+				wrongIf := literal(cond("b", "<=", btc.Str, "45010"))
+				return newPredictionWith(core.PrePredict{}, core.Predict{
+					Predict:                           literal(cond("a", ">=", btc.Str, "54990")),
+					WrongIf:                           &wrongIf,
+					IgnoreUndecidedIfPredictIsDefined: true,
+				})
+			},
+			nowTs: toTs + 86400,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			var (
+				adaptive         = ts.prediction()
+				minuteByMinute   = ts.prediction()
+				adaptiveMarket   = newSyntheticMarket(ts.nowTs, false)
+				minuteOnlyMarket = newSyntheticMarket(ts.nowTs, true)
+			)
+			evolve(t, &adaptive, core.NewMarket(adaptiveMarket), ts.nowTs)
+			evolve(t, &minuteByMinute, core.NewMarket(minuteOnlyMarket), ts.nowTs)
+
+			require.Equal(t, minuteByMinute.Evaluate(), adaptive.Evaluate())
+			require.Equal(t, minuteByMinute.State.LastTs, adaptive.State.LastTs)
+			for name, cond := range allConditions(minuteByMinute) {
+				require.Equal(t, cond.State.Value, allConditions(adaptive)[name].State.Value)
+				// Undecided conditions of a final prediction stop wherever they were when it became final.
+				if cond.State.Value != core.UNDECIDED || !minuteByMinute.Evaluate().IsFinal() {
+					require.Equal(t, cond.State.Status, allConditions(adaptive)[name].State.Status)
+					require.Equal(t, cond.State.LastTs, allConditions(adaptive)[name].State.LastTs)
+				}
+			}
+			require.Less(t, adaptiveMarket.candlesticks[60]*10, minuteOnlyMarket.candlesticks[60])
+		})
+	}
+}
+
+func TestPredEvolverContinuesFromSkippedMarketData(t *testing.T) {
+	var (
+		c = &core.Condition{
+			Name:     "main",
+			Operator: ">=",
+			FromTs:   tInt("2022-01-01 00:00:00"),
+			ToTs:     tInt("2022-03-01 00:00:00"),
+			Operands: []core.Operand{operand("COIN:BINANCE:BTC-USDT"), operand("54990")},
+			State:    core.ConditionState{LastTicks: map[string]core.Tick{}},
+		}
+		prediction = newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+		nowTs      = tInt("2022-01-02 12:00:00")
+	)
+	evolve(t, &prediction, core.NewMarket(newSyntheticMarket(nowTs, false)), nowTs)
+	require.Equal(t, core.STARTED, c.State.Status)
+	require.Equal(t, nowTs-60, c.State.LastTs)
+
+	nowTs = tInt("2022-03-02 00:00:00")
+	evolve(t, &prediction, core.NewMarket(newSyntheticMarket(nowTs, false)), nowTs)
+	require.Equal(t, core.TRUE, c.State.Value)
+}
+
+func evolve(t *testing.T, prediction *core.Prediction, market core.IMarket, nowTs int) {
+	predEvolver, errs := NewPredEvolver(prediction, market, nowTs)
+	require.Len(t, errs, 0)
+	require.Len(t, predEvolver.Run(context.Background(), false), 0)
+}
+
+func literal(c *core.Condition) core.BoolExpr {
+	return core.BoolExpr{Operator: core.LITERAL, Literal: c}
+}
+
+func allConditions(prediction core.Prediction) map[string]*core.Condition {
+	conds := map[string]*core.Condition{}
+	for _, c := range []*core.BoolExpr{&prediction.Predict.Predict, prediction.Predict.WrongIf} {
+		if c != nil {
+			conds[c.Literal.Name] = c.Literal
+		}
+	}
+	return conds
+}
+
+// newSyntheticMarket makes a market whose price oscillates between 45000 and 55000 every week, and that provides
+// candlesticks of any interval that are consistent with the minute ones. It can be limited to provide only 1 minute
+// candlesticks.
+func newSyntheticMarket(nowTs int, minuteOnly bool) *fakeMarket {
+	price := func(ts int) float64 { return 50000 + 5000*math.Sin(2*math.Pi*float64(ts)/float64(7*24*60*60)) }
+	return &fakeMarket{candlestick: func(_ common.MarketSource, _, ts, interval int) (common.Candlestick, error) {
+		if minuteOnly && interval != 60 {
+			return common.Candlestick{}, common.ErrUnsupportedCandlestickInterval
+		}
+		// Like exchanges, only provide candlesticks that have already closed.
+		if ts+interval > nowTs {
+			return common.Candlestick{}, common.ErrNoNewTicksYet
+		}
+		candlestick := common.Candlestick{Timestamp: ts, LowestPrice: math.MaxFloat64}
+		for minute := ts; minute < ts+interval; minute += 60 {
+			candlestick.LowestPrice = common.JSONFloat64(math.Min(float64(candlestick.LowestPrice), price(minute)-5))
+			candlestick.HighestPrice = common.JSONFloat64(math.Max(float64(candlestick.HighestPrice), price(minute)+5))
+			candlestick.ClosePrice = common.JSONFloat64(price(minute))
+		}
+		return candlestick, nil
+	}}
+}
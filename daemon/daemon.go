@@ -1,11 +1,14 @@
 package daemon
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"sync"
 	"time"
 
-	"github.com/marianogappa/crypto-candles/candles"
+	"github.com/google/uuid"
 	"github.com/marianogappa/predictions/core"
 	"github.com/marianogappa/predictions/imagebuilder"
 	"github.com/marianogappa/predictions/printer"
@@ -35,43 +38,143 @@ type Daemon struct {
 	enableTweeting   bool
 	enableReplying   bool
 	websiteURL       string
+	concurrency      int
+	exchangeLimiter  *exchangeLimiter
+	leaseOwner       string
+	leaseTTL         time.Duration
 
-	errs []error
+	failoverExchanges []string
+	failoverAfterRuns int
+
+	errsMu sync.Mutex
+	errs   []error
 }
 
-// NewDaemon is the constructor for the Daemon component.
+// NewDaemon is the constructor for the Daemon component. By default, it evolves one prediction at a time; use
+// SetConcurrency to evolve them in parallel.
+//
+// Many Daemons can run against the same storage: every prediction (and the pending interactions as a whole) is leased
+// to one Daemon at a time, so that no prediction is evolved, stored or tweeted twice.
 func NewDaemon(market core.IMarket, store statestorage.StateStorage, imgBuilder imagebuilder.PredictionImageBuilder, enableTweeting, enableReplying bool, websiteURL string) *Daemon {
-	return &Daemon{store: store, market: market, predImageBuilder: imgBuilder, enableTweeting: enableTweeting, enableReplying: enableReplying, websiteURL: websiteURL}
+	return &Daemon{
+		store:            store,
+		market:           market,
+		predImageBuilder: imgBuilder,
+		enableTweeting:   enableTweeting,
+		enableReplying:   enableReplying,
+		websiteURL:       websiteURL,
+		concurrency:      1,
+		exchangeLimiter:  newExchangeLimiter(0),
+		leaseOwner:       newLeaseOwner(),
+		leaseTTL:         5 * time.Minute,
+	}
+}
+
+// SetLeaseTTL sets for how long a Daemon holds the lease of a prediction; non-positive values are ignored. The lease
+// is renewed every third of this time while the prediction is being evolved, so it only needs to be long enough to
+// survive a slow storage round trip; but if a Daemon crashes, other Daemons will only be able to evolve its leased
+// predictions after this time.
+func (r *Daemon) SetLeaseTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	r.leaseTTL = ttl
+}
+
+// SetConcurrency sets how many predictions are evolved in parallel on each run, and how many of them can be reading
+// market data from the same exchange at the same time (0 means no per-exchange limit).
+func (r *Daemon) SetConcurrency(workers, perExchange int) {
+	if workers < 1 {
+		workers = 1
+	}
+	r.concurrency = workers
+	r.exchangeLimiter = newExchangeLimiter(perExchange)
 }
 
-// BlockinglyRunEvery does infinite Daemon runs, separated by a specified time.Sleep.
-func (r *Daemon) BlockinglyRunEvery(dur time.Duration) {
-	log.Info().Msgf("Daemon scheduler started and will run again every: %v", dur)
+// BlockinglyRunEvery does Daemon runs until ctx is cancelled. After each run, it waits until the next prediction is
+// due, but no longer than the specified duration, so that new predictions are picked up.
+func (r *Daemon) BlockinglyRunEvery(ctx context.Context, dur time.Duration) {
+	log.Info().Msgf("Daemon scheduler started and will run again at least every: %v", dur)
 	for {
-		r.Run(int(time.Now().Unix()))
-		time.Sleep(dur)
+		r.Run(ctx, int(time.Now().Unix()))
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Daemon scheduler stopped.")
+			return
+		case <-time.After(r.untilNextRun(ctx, time.Now(), dur)):
+		}
 	}
 }
 
-// Run sequentially evolves all evolvable predictions.
-func (r *Daemon) Run(nowTs int) []error {
+// minRunInterval is the minimum time between Daemon runs, so that predictions that are due but have no new market data
+// yet (e.g. the exchange is late) don't make the Daemon run back to back.
+const minRunInterval = 10 * time.Second
+
+// untilNextRun returns how long to wait until the next evolvable prediction is due, between minRunInterval and max.
+func (r *Daemon) untilNextRun(ctx context.Context, now time.Time, max time.Duration) time.Duration {
+	nextRunAt, ok, err := statestorage.GetNextEvolvablePredictionRunAt(ctx, r.store)
+	if err != nil {
+		log.Info().Err(err).Msg("Daemon.untilNextRun: couldn't find out when the next prediction is due")
+		return max
+	}
+	if !ok {
+		return max
+	}
+	wait := nextRunAt.Sub(now)
+	if wait < minRunInterval {
+		wait = minRunInterval
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// Run evolves all evolvable predictions that are due at nowTs, using as many workers as configured with SetConcurrency.
+// Each prediction is evolved, actioned and stored by exactly one worker. Conditions on the same operand and start time
+// share a single read of that market stream for the whole run. Cancelling ctx stops the run between predictions: the
+// predictions being evolved at that moment are still actioned and stored, so that a shutdown doesn't leave them
+// half-way.
+func (r *Daemon) Run(ctx context.Context, nowTs int) []error {
 	r.errs = []error{}
 	var (
-		predictionsScanner = statestorage.NewEvolvablePredictionsScanner(r.store)
+		predictionsScanner = statestorage.NewDuePredictionsScanner(ctx, r.store, time.Unix(int64(nowTs), 0))
 		prediction         core.Prediction
+		predictions        = make(chan core.Prediction)
+		wg                 sync.WaitGroup
+		market             = newMarketStreams(r.market)
 	)
 
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for prediction := range predictions {
+				r.runPrediction(uncancellableContext{ctx}, prediction, market, nowTs)
+			}
+		}()
+	}
+
 	for predictionsScanner.Scan(&prediction) {
-		r.maybeActionPredictionCreated(prediction, nowTs)
-		r.evolvePrediction(&prediction, r.market, nowTs)
-		r.maybeActionPredictionFinal(prediction, nowTs)
-		r.storeEvolvedPrediction(prediction)
+		select {
+		case predictions <- prediction:
+		case <-ctx.Done():
+		}
 	}
-	r.addErrs(nil, predictionsScanner.Error)
+	close(predictions)
+	wg.Wait()
 
-	r.ActionPendingInteractions(time.Now)
+	if ctx.Err() != nil {
+		log.Info().Msg("Daemon.Run: stopped early; the remaining predictions will be evolved on the next run.")
+	} else {
+		r.addErrs(nil, predictionsScanner.Error)
+	}
+
+	r.ActionPendingInteractions(ctx, time.Now)
 
-	log.Info().Msgf("Daemon.Run: finished with cache hit ratio of %.2f\n", r.market.(candles.Market).CalculateCacheHitRatio())
+	if market, ok := r.market.(interface{ CalculateCacheHitRatio() float64 }); ok {
+		log.Info().Msgf("Daemon.Run: finished with cache hit ratio of %.2f\n", market.CalculateCacheHitRatio())
+	}
 	if len(r.errs) > 0 {
 		log.Info().Errs("errs", r.errs).Msg("Daemon.Run: finished with errors")
 	}
@@ -79,54 +182,97 @@ func (r *Daemon) Run(nowTs int) []error {
 	return r.errs
 }
 
-func (r *Daemon) maybeActionPredictionCreated(prediction core.Prediction, nowTs int) {
-	if prediction.State.Status != core.UNSTARTED {
+func (r *Daemon) runPrediction(ctx context.Context, prediction core.Prediction, market core.IMarket, nowTs int) {
+	lease, acquired, err := r.acquireLease(ctx, fmt.Sprintf("prediction:%v", prediction.UUID))
+	if err != nil || !acquired {
+		// If not acquired, another Daemon is evolving this prediction right now.
+		r.addErrs(&prediction, err)
 		return
 	}
-	err := r.store.LogPredictionStateValueChange(core.PredictionStateValueChange{
-		PredictionUUID: prediction.UUID,
-		StateValue:     prediction.State.Value.String(),
-		CreatedAt:      core.ISO8601(time.Unix(int64(nowTs), 0).Format(time.RFC3339)),
-	})
-	r.addErrs(&prediction, err)
+	defer func() { r.addErrs(&prediction, lease.release(ctx)) }()
 
-	err = r.store.InsertPredictionInteraction(core.PredictionInteraction{
+	// Another Daemon may have evolved the prediction between it being scanned and the lease being acquired, so the
+	// scanned version might be stale.
+	latest, ok, err := statestorage.GetEvolvablePrediction(ctx, r.store, prediction.UUID)
+	if err != nil || !ok {
+		r.addErrs(&prediction, err)
+		return
+	}
+	prediction = latest
+
+	changes := r.maybeActionPredictionCreated(ctx, prediction)
+
+	release := r.exchangeLimiter.acquire(&prediction)
+	changes = append(changes, r.evolvePrediction(lease.ctx, &prediction, market, nowTs)...)
+	r.failOverStalledConditions(lease.ctx, &prediction, nowTs)
+	r.maybeCalculateDifficulty(&prediction, market)
+	release()
+
+	// If the lease expired while evolving, another Daemon may be evolving the prediction now, so it's not stored.
+	if err := lease.err(); err != nil {
+		r.addErrs(&prediction, err)
+		return
+	}
+
+	r.maybeActionPredictionFinal(ctx, prediction, nowTs)
+	r.storeEvolvedPrediction(ctx, prediction, changes)
+}
+
+// maybeCalculateDifficulty stores the prediction's Difficulty once it becomes CORRECT, so that account stats don't
+// need market data to weigh it. The price at post time is usually the first candlestick its conditions evolved with,
+// so it's read from the same market streams.
+func (r *Daemon) maybeCalculateDifficulty(prediction *core.Prediction, market core.IMarket) {
+	if prediction.Evaluate() != core.CORRECT {
+		return
+	}
+	prediction.State.Difficulty = prediction.Difficulty(core.NewMarketPriceFunc(market))
+}
+
+// maybeActionPredictionCreated returns the prediction's initial state value change, to be logged along with it, if it
+// hasn't started evolving yet. Its value starts at the time it was posted.
+func (r *Daemon) maybeActionPredictionCreated(ctx context.Context, prediction core.Prediction) []core.PredictionStateValueChange {
+	if prediction.State.Status != core.UNSTARTED {
+		return nil
+	}
+	err := r.store.InsertPredictionInteraction(ctx, core.PredictionInteraction{
 		PostURL:        prediction.PostURL,
 		PredictionUUID: prediction.UUID,
 		ActionType:     actionTypePredictionCreated.String(),
 		Status:         "PENDING",
 	})
 	r.addErrs(&prediction, err)
+
+	return []core.PredictionStateValueChange{{
+		PredictionUUID: prediction.UUID,
+		StateValue:     prediction.Evaluate().String(),
+		CreatedAt:      prediction.PostedAt,
+	}}
 }
 
-func (r *Daemon) evolvePrediction(prediction *core.Prediction, m core.IMarket, nowTs int) {
-	predRunner, errs := NewPredEvolver(prediction, r.market, nowTs)
+// evolvePrediction evolves the prediction, and returns the changes of its state value while evolving it.
+func (r *Daemon) evolvePrediction(ctx context.Context, prediction *core.Prediction, m core.IMarket, nowTs int) []core.PredictionStateValueChange {
+	predRunner, errs := NewPredEvolver(prediction, m, nowTs)
 	r.addErrs(prediction, errs...)
 	if len(errs) > 0 {
-		return
+		return nil
 	}
-	errs = predRunner.Run(false)
+	errs = predRunner.Run(ctx, false)
 	r.addErrs(predRunner.prediction, errs...)
+
+	return predRunner.stateValueChanges
 }
 
-func (r *Daemon) maybeActionPredictionFinal(prediction core.Prediction, nowTs int) {
+func (r *Daemon) maybeActionPredictionFinal(ctx context.Context, prediction core.Prediction, nowTs int) {
 	if !prediction.Evaluate().IsFinal() {
 		return
 	}
 
-	err := r.store.LogPredictionStateValueChange(core.PredictionStateValueChange{
-		PredictionUUID: prediction.UUID,
-		StateValue:     prediction.State.Value.String(),
-		CreatedAt:      core.ISO8601(time.Unix(int64(nowTs), 0).Format(time.RFC3339)),
-	})
-	r.addErrs(&prediction, err)
-
 	description := printer.NewPredictionPrettyPrinter(prediction).String()
 	log.Info().Msgf("Prediction just finished: [%v] with value [%v]!\n", description, prediction.State.Value)
 
 	// TODO this will have to change for prediction types where ANNULLED is a possible final state
 	if prediction.State.Value == core.CORRECT || prediction.State.Value == core.INCORRECT {
-		err := r.store.InsertPredictionInteraction(core.PredictionInteraction{
+		err := r.store.InsertPredictionInteraction(ctx, core.PredictionInteraction{
 			PostURL:        prediction.PostURL,
 			PredictionUUID: prediction.UUID,
 			ActionType:     actionTypeBecameFinal.String(),
@@ -136,12 +282,16 @@ func (r *Daemon) maybeActionPredictionFinal(prediction core.Prediction, nowTs in
 	}
 }
 
-func (r *Daemon) storeEvolvedPrediction(prediction core.Prediction) {
-	_, err := r.store.UpsertPredictions([]*core.Prediction{&prediction})
-	r.addErrs(&prediction, err)
+// storeEvolvedPrediction stores the prediction along with the changes of its state value, so that they are only
+// logged if it's stored.
+func (r *Daemon) storeEvolvedPrediction(ctx context.Context, prediction core.Prediction, changes []core.PredictionStateValueChange) {
+	r.addErrs(&prediction, r.store.StoreEvolvedPrediction(ctx, &prediction, changes))
 }
 
 func (r *Daemon) addErrs(prediction *core.Prediction, errs ...error) {
+	r.errsMu.Lock()
+	defer r.errsMu.Unlock()
+
 	for _, err := range errs {
 		if err == nil {
 			continue
@@ -156,3 +306,21 @@ func (r *Daemon) addErrs(prediction *core.Prediction, errs ...error) {
 		}
 	}
 }
+
+// uncancellableContext keeps the values of its parent context, but it's never cancelled. The Daemon uses it for work
+// that must not be left half-way (e.g. evolving a prediction and storing it) once it has started.
+type uncancellableContext struct {
+	parent context.Context
+}
+
+func (c uncancellableContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c uncancellableContext) Done() <-chan struct{}             { return nil }
+func (c uncancellableContext) Err() error                        { return nil }
+func (c uncancellableContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// newLeaseOwner returns an identifier for this Daemon that is unique across instances, and recognisable in the leases
+// storage.
+func newLeaseOwner() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%v:%v:%v", hostname, os.Getpid(), uuid.NewString())
+}
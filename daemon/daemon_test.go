@@ -0,0 +1,287 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/imagebuilder"
+	"github.com/marianogappa/predictions/markettest"
+	"github.com/marianogappa/predictions/statestorage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemonRunDoesNotStartPredictionsWhenContextIsCancelled(t *testing.T) {
+	store := statestorage.NewMemoryStateStorage()
+	prediction := newPredictionWith(
+		core.PrePredict{},
+		core.Predict{
+			Predict: core.BoolExpr{Operator: core.LITERAL, Operands: nil, Literal: &core.Condition{
+				Name:     "main",
+				FromTs:   tInt("2022-02-27 15:20:00"),
+				ToTs:     tInt("2022-03-27 15:20:00"),
+				Operands: []core.Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")},
+				State:    core.ConditionState{Value: core.UNDECIDED, LastTs: 0, LastTicks: map[string]core.Tick{}},
+			}},
+		})
+	_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tm := &fakeMarket{}
+	errs := NewDaemon(core.NewMarket(tm), store, imagebuilder.PredictionImageBuilder{}, false, false, "").Run(ctx, tInt("2022-02-28 15:20:00"))
+	require.Len(t, errs, 0)
+	require.Len(t, tm.calls, 0)
+
+	changes, err := store.GetPredictionStateValueChanges(context.Background(), prediction.UUID)
+	require.Nil(t, err)
+	require.Len(t, changes, 0)
+}
+
+func TestDaemonRunEvolvesEachPredictionOnceWithinConcurrencyLimits(t *testing.T) {
+	var (
+		store       = statestorage.NewMemoryStateStorage()
+		predictions = []*core.Prediction{}
+	)
+	for i := 0; i < 20; i++ {
+		coin := "COIN:BINANCE:BTC-USDT"
+		if i%2 == 1 {
+			coin = "COIN:KUCOIN:BTC-USDT"
+		}
+		prediction := newEvolvablePrediction(i, coin, tInt("2022-02-27 15:20:00")+i*60)
+		predictions = append(predictions, &prediction)
+	}
+	_, err := store.UpsertPredictions(context.Background(), predictions)
+	require.Nil(t, err)
+
+	var (
+		market = newCountingMarket(61000)
+		daemon = NewDaemon(core.NewMarket(market), store, imagebuilder.PredictionImageBuilder{}, false, false, "")
+	)
+	daemon.SetConcurrency(8, 2)
+	errs := daemon.Run(context.Background(), tInt("2022-02-28 15:20:00"))
+	require.Len(t, errs, 0)
+
+	for _, prediction := range predictions {
+		changes, err := store.GetPredictionStateValueChanges(context.Background(), prediction.UUID)
+		require.Nil(t, err)
+		require.Len(t, changes, 2)
+		require.Equal(t, core.CORRECT.String(), changes[1].StateValue)
+	}
+	stored, err := store.GetPredictions(context.Background(), core.APIFilters{PredictionStateValues: []string{core.CORRECT.String()}}, nil, 0, 0)
+	require.Nil(t, err)
+	require.Len(t, stored, len(predictions))
+
+	require.LessOrEqual(t, market.maxInFlight["BINANCE"], 2)
+	require.LessOrEqual(t, market.maxInFlight["KUCOIN"], 2)
+	require.Greater(t, market.maxInFlight["BINANCE"]+market.maxInFlight["KUCOIN"], 2)
+}
+
+func TestDaemonsRunningAgainstTheSameStoreEvolveEachPredictionOnce(t *testing.T) {
+	var (
+		store       = statestorage.NewMemoryStateStorage()
+		predictions = []*core.Prediction{}
+	)
+	for i := 0; i < 20; i++ {
+		prediction := newEvolvablePrediction(i, "COIN:BINANCE:BTC-USDT", tInt("2022-02-27 15:20:00")+i*60)
+		predictions = append(predictions, &prediction)
+	}
+	_, err := store.UpsertPredictions(context.Background(), predictions)
+	require.Nil(t, err)
+
+	var (
+		market = newCountingMarket(61000)
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < 3; i++ {
+		daemon := NewDaemon(core.NewMarket(market), store, imagebuilder.PredictionImageBuilder{}, false, false, "")
+		daemon.SetConcurrency(4, 0)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Len(t, daemon.Run(context.Background(), tInt("2022-02-28 15:20:00")), 0)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, len(predictions), len(market.calls))
+	for _, prediction := range predictions {
+		changes, err := store.GetPredictionStateValueChanges(context.Background(), prediction.UUID)
+		require.Nil(t, err)
+		require.Len(t, changes, 2)
+	}
+}
+
+func TestDaemonRunReadsEachMarketStreamOnce(t *testing.T) {
+	var (
+		store       = statestorage.NewMemoryStateStorage()
+		predictions = []*core.Prediction{}
+	)
+	for i := 0; i < 20; i++ {
+		coin := "COIN:BINANCE:BTC-USDT"
+		if i%2 == 1 {
+			coin = "COIN:KUCOIN:BTC-USDT"
+		}
+		prediction := newEvolvablePrediction(i, coin, tInt("2022-02-27 15:20:00"))
+		predictions = append(predictions, &prediction)
+	}
+	_, err := store.UpsertPredictions(context.Background(), predictions)
+	require.Nil(t, err)
+
+	var (
+		market = newCountingMarket(61000)
+		daemon = NewDaemon(core.NewMarket(market), store, imagebuilder.PredictionImageBuilder{}, false, false, "")
+	)
+	daemon.SetConcurrency(8, 0)
+	require.Len(t, daemon.Run(context.Background(), tInt("2022-02-28 15:20:00")), 0)
+
+	require.Equal(t, 2, len(market.calls))
+	stored, err := store.GetPredictions(context.Background(), core.APIFilters{PredictionStateValues: []string{core.CORRECT.String()}}, nil, 0, 0)
+	require.Nil(t, err)
+	require.Len(t, stored, len(predictions))
+}
+
+func TestDaemonRunFailsOverStalledConditions(t *testing.T) {
+	var (
+		store      = statestorage.NewMemoryStateStorage()
+		prediction = newEvolvablePrediction(0, "COIN:BINANCE:BTC-USDT", tInt("2022-01-01 00:00:00"))
+	)
+	_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+	require.Nil(t, err)
+
+	var (
+		market = markettest.NewCoinMarket(map[string][]core.Tick{
+			// BINANCE stops publishing BTC-USDT candlesticks on 2022-01-05, before the condition becomes true.
+			"COIN:BINANCE:BTC-USDT": {{Timestamp: tInt("2022-01-01 00:00:00"), Value: 40000}, {Timestamp: tInt("2022-01-05 00:00:00"), Value: 41000}},
+			"COIN:KUCOIN:ETH-USDT":  {{Timestamp: tInt("2022-01-01 00:00:00"), Value: 3000}, {Timestamp: tInt("2022-02-01 00:00:00"), Value: 3000}},
+			"COIN:COINBASE:BTC-USDT": {
+				{Timestamp: tInt("2022-01-01 00:00:00"), Value: 40100},
+				{Timestamp: tInt("2022-01-10 13:37:00"), Value: 61000},
+				{Timestamp: tInt("2022-01-10 13:38:00"), Value: 45000},
+				{Timestamp: tInt("2022-02-01 00:00:00"), Value: 45000},
+			},
+		})
+		daemon = NewDaemon(core.NewMarket(market), store, imagebuilder.PredictionImageBuilder{}, false, false, "")
+		nowTs  = tInt("2022-02-01 00:00:00")
+		stored = func() core.Condition {
+			preds, err := store.GetPredictions(context.Background(), core.APIFilters{UUIDs: []string{prediction.UUID}}, nil, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, preds, 1)
+			return *preds[0].Given["a"]
+		}
+	)
+	// KUCOIN doesn't have BTC-USDT, so it's skipped.
+	daemon.SetFailover([]string{"KUCOIN", "COINBASE"}, 2)
+
+	// The first run evolves the condition until BINANCE's last candlestick, and the next ones find no new ticks.
+	daemon.Run(context.Background(), nowTs)
+	require.Equal(t, 0, stored().State.StalledRuns)
+	daemon.Run(context.Background(), nowTs)
+	require.Equal(t, 1, stored().State.StalledRuns)
+	require.Equal(t, "COIN:BINANCE:BTC-USDT", stored().Operands[0].Str)
+
+	// Once it's stalled for two runs, it fails over to COINBASE, and continues from where BINANCE stopped.
+	daemon.Run(context.Background(), nowTs)
+	cond := stored()
+	require.Equal(t, "COIN:COINBASE:BTC-USDT", cond.Operands[0].Str)
+	require.Equal(t, 0, cond.State.StalledRuns)
+	require.Len(t, cond.Failovers, 1)
+	require.Equal(t, core.ExchangeFailover{
+		PredictionUUID: prediction.UUID,
+		ConditionName:  "a",
+		FromOperand:    "COIN:BINANCE:BTC-USDT",
+		ToOperand:      "COIN:COINBASE:BTC-USDT",
+		Reason:         "no new ticks since 2022-01-05T23:59:00Z",
+		CreatedAt:      core.ISO8601("2022-02-01T00:00:00Z"),
+	}, cond.Failovers[0])
+
+	failovers, err := store.GetExchangeFailovers(context.Background(), prediction.UUID)
+	require.Nil(t, err)
+	require.Equal(t, cond.Failovers, failovers)
+
+	daemon.Run(context.Background(), nowTs)
+	cond = stored()
+	require.Equal(t, core.TRUE, cond.State.Value)
+	require.Equal(t, tInt("2022-01-10 13:37:00"), cond.State.LastTs)
+
+	// The history shows which condition made the prediction CORRECT, on which tick, and when.
+	changes, err := store.GetPredictionStateValueChanges(context.Background(), prediction.UUID)
+	require.Nil(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, prediction.PostedAt, changes[0].CreatedAt)
+	require.Equal(t, core.PredictionStateValueChange{
+		PredictionUUID: prediction.UUID,
+		StateValue:     core.CORRECT.String(),
+		ConditionName:  "a",
+		ConditionValue: core.TRUE.String(),
+		Ticks:          map[string]core.Tick{"COIN:COINBASE:BTC-USDT": {Timestamp: tInt("2022-01-10 13:37:00"), Value: 61000}},
+		CreatedAt:      core.ISO8601("2022-01-10T13:37:00Z"),
+	}, changes[1])
+}
+
+func TestDaemonRunStoresTheDifficultyOfCorrectPredictions(t *testing.T) {
+	store := statestorage.NewMemoryStateStorage()
+	prediction := newEvolvablePrediction(0, "COIN:BINANCE:BTC-USDT", tInt("2022-02-27 15:20:00"))
+	prediction.Type = core.PredictionTypeCoinOperatorFloatDeadline
+	_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+	require.Nil(t, err)
+
+	errs := NewDaemon(core.NewMarket(newCountingMarket(61000)), store, imagebuilder.PredictionImageBuilder{}, false, false, "").Run(context.Background(), tInt("2022-02-28 15:20:00"))
+	require.Len(t, errs, 0)
+
+	// The goal is 60000 and BTC was at 61000 at post time.
+	stored, err := store.GetPredictions(context.Background(), core.APIFilters{UUIDs: []string{prediction.UUID}}, nil, 0, 0)
+	require.Nil(t, err)
+	require.Len(t, stored, 1)
+	require.Equal(t, core.CORRECT, stored[0].State.Value)
+	require.InDelta(t, 1000.0/61000, stored[0].State.Difficulty, 0.0001)
+}
+
+func TestUncancellableContextKeepsValuesButIsNeverCancelled(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	cancel()
+
+	ctx := uncancellableContext{parent}
+	require.Nil(t, ctx.Err())
+	require.Nil(t, ctx.Done())
+	require.Equal(t, "value", ctx.Value(key{}))
+}
+
+func newEvolvablePrediction(i int, coin string, fromTs int) core.Prediction {
+	cond := &core.Condition{
+		Name:     "a",
+		Operator: ">=",
+		FromTs:   fromTs,
+		ToTs:     tInt("2022-03-27 15:20:00"),
+		Operands: []core.Operand{operand(coin), operand("60000")},
+		State:    core.ConditionState{Value: core.UNDECIDED, LastTs: 0, LastTicks: map[string]core.Tick{}},
+	}
+	prediction := newPredictionWith(core.PrePredict{}, core.Predict{Predict: core.BoolExpr{Operator: core.LITERAL, Literal: cond}})
+	prediction.UUID = fmt.Sprintf("ed47db4d-cc0b-4c3c-af18-e6fcbff823%02d", i)
+	prediction.PostURL = fmt.Sprintf("https://twitter.com/trader1sz/status/%v", i)
+	prediction.CreatedAt = core.ISO8601("2022-02-27T15:14:00Z")
+	prediction.PostedAt = core.ISO8601(time.Unix(int64(fromTs), 0).UTC().Format(time.RFC3339))
+	prediction.Reporter = "admin"
+	prediction.PostAuthorURL = "https://twitter.com/trader1sz"
+	prediction.Given = map[string]*core.Condition{"a": cond}
+	return prediction
+}
+
+// newCountingMarket makes a market that returns a single candlestick at the start time for every market source, and
+// that takes a while to read it, so that it can tell how many iterators were reading from each exchange at once.
+func newCountingMarket(value float64) *fakeMarket {
+	v := common.JSONFloat64(value)
+	return &fakeMarket{latency: 5 * time.Millisecond, candlestick: func(_ common.MarketSource, i, ts, _ int) (common.Candlestick, error) {
+		if i > 0 {
+			return common.Candlestick{}, common.ErrOutOfTicks
+		}
+		return common.Candlestick{Timestamp: ts, OpenPrice: v, HighestPrice: v, LowestPrice: v, ClosePrice: v}, nil
+	}}
+}
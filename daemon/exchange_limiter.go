@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/marianogappa/predictions/core"
+)
+
+// exchangeLimiter caps how many predictions can be evolved at the same time against each exchange, so that running
+// many Daemon workers doesn't get the engine rate-limited by exchanges.
+type exchangeLimiter struct {
+	mu         sync.Mutex
+	limit      int
+	semaphores map[string]chan struct{}
+}
+
+// newExchangeLimiter constructs an exchangeLimiter. A limit of 0 or less means no limit.
+func newExchangeLimiter(limit int) *exchangeLimiter {
+	return &exchangeLimiter{limit: limit, semaphores: map[string]chan struct{}{}}
+}
+
+// acquire blocks until the prediction can be evolved against all the exchanges it needs, and returns the function
+// that releases them.
+func (l *exchangeLimiter) acquire(prediction *core.Prediction) func() {
+	if l.limit <= 0 {
+		return func() {}
+	}
+
+	// Exchanges are always acquired in the same order, so that two predictions that need the same exchanges can't
+	// deadlock each other.
+	exchanges := predictionExchanges(prediction)
+	semaphores := make([]chan struct{}, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		semaphore := l.semaphore(exchange)
+		semaphore <- struct{}{}
+		semaphores = append(semaphores, semaphore)
+	}
+
+	return func() {
+		for _, semaphore := range semaphores {
+			<-semaphore
+		}
+	}
+}
+
+func (l *exchangeLimiter) semaphore(exchange string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.semaphores[exchange]; !ok {
+		l.semaphores[exchange] = make(chan struct{}, l.limit)
+	}
+	return l.semaphores[exchange]
+}
+
+// predictionExchanges returns the sorted exchanges that the undecided conditions of a prediction read market data from.
+func predictionExchanges(prediction *core.Prediction) []string {
+	exchangeSet := map[string]struct{}{}
+	for _, condition := range prediction.UndecidedConditions() {
+		for _, operand := range condition.NonNumberOperands() {
+			for _, exchange := range operand.Exchanges() {
+				exchangeSet[strings.ToUpper(exchange)] = struct{}{}
+			}
+		}
+	}
+
+	exchanges := make([]string, 0, len(exchangeSet))
+	for exchange := range exchangeSet {
+		exchanges = append(exchanges, exchange)
+	}
+	sort.Strings(exchanges)
+	return exchanges
+}
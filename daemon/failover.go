@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+	"github.com/rs/zerolog/log"
+)
+
+// SetFailover sets the fallback exchanges, in order of preference, for the conditions that have stalled for afterRuns
+// consecutive runs, e.g. because their exchange delisted the market pair or stopped publishing candlesticks. Each
+// operand whose exchange doesn't provide market data anymore is switched to the same market on the first fallback
+// exchange that does. Failover is disabled if there are no fallback exchanges, which is the default.
+func (r *Daemon) SetFailover(fallbackExchanges []string, afterRuns int) {
+	if afterRuns < 1 {
+		afterRuns = 1
+	}
+	r.failoverExchanges = fallbackExchanges
+	r.failoverAfterRuns = afterRuns
+}
+
+// failOverStalledConditions switches the operands of the prediction's stalled conditions whose exchange doesn't provide
+// market data anymore to a fallback exchange, and logs each switch. Operands with an aggregate provider already fall
+// back to their other exchanges, and MARKETCAP ones aren't on exchanges, so they're left as they are.
+func (r *Daemon) failOverStalledConditions(ctx context.Context, prediction *core.Prediction, nowTs int) {
+	if len(r.failoverExchanges) == 0 {
+		return
+	}
+	for _, cond := range prediction.UndecidedConditions() {
+		if cond.State.StalledRuns < r.failoverAfterRuns {
+			continue
+		}
+		startTime, startFromNext := calculateStartTs(cond)
+		for _, operand := range cond.NonNumberOperands() {
+			if (operand.Type != core.COIN && operand.Type != core.INDICATOR) || core.IsAggregateProvider(operand.Provider) {
+				continue
+			}
+			reason := r.probeMarketData(operand, startTime, startFromNext, nowTs)
+			if reason == "" {
+				continue
+			}
+			exchange, ok := r.findFallbackExchange(operand, startTime, startFromNext, nowTs)
+			if !ok {
+				log.Info().Msgf("Daemon.failOverStalledConditions: no fallback exchange for %v on prediction %v: %v", operand.Str, prediction.UUID, reason)
+				continue
+			}
+			failover := cond.FailOver(prediction.UUID, operand, exchange, reason, core.ISO8601(time.Unix(int64(nowTs), 0).Format(time.RFC3339)))
+			r.addErrs(prediction, r.store.LogExchangeFailover(ctx, failover))
+			log.Info().Msgf("Daemon.failOverStalledConditions: switched %v to %v on prediction %v: %v", failover.FromOperand, failover.ToOperand, prediction.UUID, reason)
+		}
+	}
+}
+
+// findFallbackExchange returns the first fallback exchange that provides market data for the operand's market.
+func (r *Daemon) findFallbackExchange(operand core.Operand, startTime time.Time, startFromNext bool, nowTs int) (string, bool) {
+	for _, exchange := range r.failoverExchanges {
+		if exchange == operand.Provider {
+			continue
+		}
+		if r.probeMarketData(operand.OnExchange(exchange), startTime, startFromNext, nowTs) == "" {
+			return exchange, true
+		}
+	}
+	return "", false
+}
+
+// probeMarketData returns why the operand's market doesn't provide market data from startTime, or an empty string if
+// it does, i.e. if it has the next candlestick, or that candlestick isn't long overdue yet.
+//
+// It reads from the Daemon's market rather than from the run's market streams, because streams keep failing for the
+// whole run once they fail.
+func (r *Daemon) probeMarketData(operand core.Operand, startTime time.Time, startFromNext bool, nowTs int) string {
+	it, err := r.market.Iterator(operand, startTime, time.Minute)
+	if err != nil {
+		return err.Error()
+	}
+	it.SetStartFromNext(startFromNext)
+	it.SetTimeNowFunc(func() time.Time { return time.Unix(int64(nowTs), 0) })
+
+	_, err = it.Next()
+	switch {
+	case err == nil:
+		return ""
+	case err == common.ErrOutOfTicks || err == common.ErrNoNewTicksYet:
+		nextTs := common.NormalizeTimestamp(startTime, time.Minute, operand.Provider, startFromNext)
+		if nowTs-nextTs < stalledAfterSecs {
+			return ""
+		}
+		return fmt.Sprintf("no new ticks since %v", startTime.UTC().Format(time.RFC3339))
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,113 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+)
+
+// fakeMarket is the market of the Daemon's tests. Its iterators return the candlesticks that candlestick makes, until
+// it fails. It records which iterators were requested and what was read from them.
+type fakeMarket struct {
+	// candlestick makes the i-th candlestick of an iterator over marketSource, whose next candlestick starts at ts and
+	// lasts interval seconds. If nil, iterators return empty candlesticks forever.
+	candlestick func(marketSource common.MarketSource, i, ts, interval int) (common.Candlestick, error)
+	// err, if set, fails every Iterator call.
+	err error
+	// latency, if set, is how long reading a candlestick takes, like an exchange request does.
+	latency time.Duration
+
+	mu        sync.Mutex
+	calls     []marketCall
+	nextCalls int
+	// candlesticks is how many candlesticks were read per interval in seconds.
+	candlesticks map[int]int
+	// inFlight and maxInFlight are how many candlesticks are being read from each provider, and at most at once.
+	inFlight    map[string]int
+	maxInFlight map[string]int
+}
+
+type marketCall struct {
+	marketSource  common.MarketSource
+	tm            time.Time
+	startFromNext bool
+}
+
+func (m *fakeMarket) Iterator(marketSource common.MarketSource, tm time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, marketCall{marketSource, tm, false})
+	return &fakeIterator{
+		mkt:          m,
+		call:         len(m.calls) - 1,
+		marketSource: marketSource,
+		ts:           common.NormalizeTimestamp(tm, candlestickInterval, marketSource.Provider, false),
+		interval:     int(candlestickInterval / time.Second),
+	}, nil
+}
+
+// read records that a candlestick is being read from the provider, and returns when it's been read.
+func (m *fakeMarket) read(provider string, interval int) {
+	m.mu.Lock()
+	if m.candlesticks == nil {
+		m.candlesticks, m.inFlight, m.maxInFlight = map[int]int{}, map[string]int{}, map[string]int{}
+	}
+	m.nextCalls++
+	m.candlesticks[interval]++
+	m.inFlight[provider]++
+	if m.inFlight[provider] > m.maxInFlight[provider] {
+		m.maxInFlight[provider] = m.inFlight[provider]
+	}
+	m.mu.Unlock()
+
+	time.Sleep(m.latency)
+
+	m.mu.Lock()
+	m.inFlight[provider]--
+	m.mu.Unlock()
+}
+
+type fakeIterator struct {
+	mkt           *fakeMarket
+	call          int
+	marketSource  common.MarketSource
+	ts            int
+	interval      int
+	read          int
+	startFromNext bool
+}
+
+func (i *fakeIterator) Next() (common.Candlestick, error) {
+	i.mkt.read(i.marketSource.Provider, i.interval)
+	if i.mkt.candlestick == nil {
+		return common.Candlestick{}, nil
+	}
+	if i.startFromNext {
+		i.ts += i.interval
+		i.startFromNext = false
+	}
+	candlestick, err := i.mkt.candlestick(i.marketSource, i.read, i.ts, i.interval)
+	if err != nil {
+		return common.Candlestick{}, err
+	}
+	i.read++
+	i.ts += i.interval
+	return candlestick, nil
+}
+
+func (i *fakeIterator) SetStartFromNext(b bool) {
+	i.startFromNext = b
+	i.mkt.mu.Lock()
+	defer i.mkt.mu.Unlock()
+	i.mkt.calls[i.call].startFromNext = b
+}
+
+// Not using the Scanner interface
+func (i *fakeIterator) Scan(*common.Candlestick) bool   { return false }
+func (i *fakeIterator) Error() error                    { return nil }
+func (i *fakeIterator) SetTimeNowFunc(func() time.Time) {}
@@ -0,0 +1,92 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// errLeaseLost means that the lease couldn't be renewed, so another Daemon may have taken it over.
+var errLeaseLost = errors.New("lost the lease before finishing")
+
+// lease is a lease held by the Daemon, which is renewed in the background every third of the lease TTL until it's
+// released, so that it doesn't expire while the work it guards takes longer than expected (e.g. evolving a
+// prediction through months of market data). If it cannot be renewed, ctx is cancelled, so that the work stops and
+// isn't stored over what the new owner might be doing.
+type lease struct {
+	ctx context.Context
+
+	store  leaseStore
+	name   string
+	owner  string
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	lost bool
+}
+
+// leaseStore is the part of the StateStorage that handles leases.
+type leaseStore interface {
+	AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, owner string) error
+}
+
+// acquireLease takes the named lease for the Daemon, and keeps it until it's released. It returns false if another
+// Daemon holds it.
+func (r *Daemon) acquireLease(ctx context.Context, name string) (*lease, bool, error) {
+	acquired, err := r.store.AcquireLease(ctx, name, r.leaseOwner, r.leaseTTL)
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+	leaseCtx, cancel := context.WithCancel(ctx)
+	l := &lease{ctx: leaseCtx, store: r.store, name: name, owner: r.leaseOwner, cancel: cancel, stop: make(chan struct{})}
+	l.wg.Add(1)
+	go l.keepRenewing(ctx, r.leaseTTL)
+	return l, true, nil
+}
+
+func (l *lease) keepRenewing(ctx context.Context, ttl time.Duration) {
+	defer l.wg.Done()
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			renewed, err := l.store.AcquireLease(ctx, l.name, l.owner, ttl)
+			if err == nil && renewed {
+				continue
+			}
+			log.Error().Err(err).Msgf("Daemon: couldn't renew lease %v, so stopping what it guards.", l.name)
+			l.mu.Lock()
+			l.lost = true
+			l.mu.Unlock()
+			l.cancel()
+			return
+		}
+	}
+}
+
+// err returns errLeaseLost if the lease couldn't be renewed.
+func (l *lease) err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost {
+		return errLeaseLost
+	}
+	return nil
+}
+
+// release stops renewing the lease, and gives it up. It must be called once the guarded work is done.
+func (l *lease) release(ctx context.Context) error {
+	close(l.stop)
+	l.wg.Wait()
+	l.cancel()
+	return l.store.ReleaseLease(ctx, l.name, l.owner)
+}
@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/imagebuilder"
+	"github.com/marianogappa/predictions/statestorage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaseIsRenewedUntilReleased(t *testing.T) {
+	store := statestorage.NewMemoryStateStorage()
+	daemon := NewDaemon(core.NewMarket(&fakeMarket{}), store, imagebuilder.PredictionImageBuilder{}, false, false, "")
+	daemon.SetLeaseTTL(30 * time.Millisecond)
+
+	lease, acquired, err := daemon.acquireLease(context.Background(), "lease")
+	require.Nil(t, err)
+	require.True(t, acquired)
+
+	time.Sleep(100 * time.Millisecond)
+	acquired, err = store.AcquireLease(context.Background(), "lease", "another daemon", time.Minute)
+	require.Nil(t, err)
+	require.False(t, acquired, "the lease should have been renewed past its TTL")
+	require.Nil(t, lease.err())
+
+	require.Nil(t, lease.release(context.Background()))
+	acquired, err = store.AcquireLease(context.Background(), "lease", "another daemon", time.Minute)
+	require.Nil(t, err)
+	require.True(t, acquired)
+}
+
+func TestLeaseCancelsItsContextWhenLost(t *testing.T) {
+	store := statestorage.NewMemoryStateStorage()
+	daemon := NewDaemon(core.NewMarket(&fakeMarket{}), store, imagebuilder.PredictionImageBuilder{}, false, false, "")
+	daemon.SetLeaseTTL(30 * time.Millisecond)
+
+	lease, acquired, err := daemon.acquireLease(context.Background(), "lease")
+	require.Nil(t, err)
+	require.True(t, acquired)
+
+	// e.g. the Daemon stalled for longer than the TTL, and another one took over.
+	require.Nil(t, store.ReleaseLease(context.Background(), "lease", daemon.leaseOwner))
+	acquired, err = store.AcquireLease(context.Background(), "lease", "another daemon", time.Minute)
+	require.Nil(t, err)
+	require.True(t, acquired)
+
+	select {
+	case <-lease.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the lease's context should have been cancelled")
+	}
+	require.ErrorIs(t, lease.err(), errLeaseLost)
+	require.Nil(t, lease.release(context.Background()))
+}
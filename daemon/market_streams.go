@@ -0,0 +1,233 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+)
+
+// marketStreams is a core.IMarket that reads every market stream (i.e. an operand from a start time) only once,
+// no matter how many conditions subscribe to it. Conditions on the same operand that start at the same time (e.g. all
+// the BTC-USDT predictions that were evolved up to the previous Daemon run) share the stream's candlesticks, instead
+// of each walking them again through its own iterator.
+//
+// Streams drop the candlesticks that every subscriber has read once there are many of them (see
+// maxStreamReadCandlesticks), and a marketStreams should only live for one Daemon run.
+type marketStreams struct {
+	market core.IMarket
+
+	mu      sync.Mutex
+	streams map[marketStreamKey]*marketStream
+}
+
+type marketStreamKey struct {
+	operand             string
+	startTs             int
+	candlestickInterval time.Duration
+}
+
+func newMarketStreams(market core.IMarket) *marketStreams {
+	return &marketStreams{market: market, streams: map[marketStreamKey]*marketStream{}}
+}
+
+// Iterator subscribes to the stream of the operand from startTime, opening the stream if nobody had subscribed to it
+// yet, or if its first candlesticks were already dropped.
+func (m *marketStreams) Iterator(operand core.Operand, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	key := marketStreamKey{
+		operand:             operand.Str,
+		startTs:             common.NormalizeTimestamp(startTime, candlestickInterval, operand.Provider, false),
+		candlestickInterval: candlestickInterval,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stream, ok := m.streams[key]; ok {
+		if subscription, ok := stream.subscribe(); ok {
+			return subscription, nil
+		}
+	}
+	it, err := m.market.Iterator(operand, startTime, candlestickInterval)
+	if err != nil {
+		return nil, err
+	}
+	stream := newMarketStream(it)
+	m.streams[key] = stream
+	subscription, _ := stream.subscribe()
+	return subscription, nil
+}
+
+// maxStreamReadCandlesticks is how many candlesticks that every subscriber has read a stream keeps, e.g. a day of 1
+// minute candlesticks.
+const maxStreamReadCandlesticks = 24 * 60
+
+// marketStream reads candlesticks from its iterator as subscribers need them, and keeps them for the rest of the
+// subscribers. Once the iterator fails, the stream ends there with that error for every subscriber, so a failing
+// exchange isn't requested once per subscriber.
+//
+// Only one subscriber reads from the iterator at a time, without holding the lock, so that the other subscribers can
+// keep reading the candlesticks that were already read while it waits for the exchange.
+//
+// If the iterator is a core.SourcedIterator, the stream also keeps where each candlestick came from.
+type marketStream struct {
+	mu   sync.Mutex
+	read *sync.Cond // signalled when reading from the iterator finishes.
+
+	it      iterator.Iterator
+	reading bool
+	err     error
+
+	// candlesticks and sources start at the offset-th candlestick of the stream, i.e. the earliest that some
+	// subscriber hasn't read yet.
+	offset       int
+	candlesticks []common.Candlestick
+	sources      []string
+	subscribers  []*marketStreamSubscription
+}
+
+func newMarketStream(it iterator.Iterator) *marketStream {
+	s := &marketStream{it: it}
+	s.read = sync.NewCond(&s.mu)
+	return s
+}
+
+// subscribe returns a new subscription from the start of the stream, or false if its first candlesticks were already
+// dropped.
+func (s *marketStream) subscribe() (*marketStreamSubscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offset > 0 {
+		return nil, false
+	}
+	subscription := &marketStreamSubscription{stream: s}
+	s.subscribers = append(s.subscribers, subscription)
+	return subscription, true
+}
+
+// next returns the subscriber's next candlestick, reading it from the iterator if nobody has yet.
+func (s *marketStream) next(subscription *marketStreamSubscription) (common.Candlestick, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if i := subscription.next - s.offset; i < len(s.candlesticks) {
+			candlestick, source := s.candlesticks[i], s.sources[i]
+			subscription.next++
+			s.trim()
+			return candlestick, source, nil
+		}
+		if s.err != nil {
+			return common.Candlestick{}, "", s.err
+		}
+		if s.reading {
+			s.read.Wait()
+			continue
+		}
+
+		s.reading = true
+		s.mu.Unlock()
+		candlestick, err := s.it.Next()
+		source := iteratorSource(s.it)
+		s.mu.Lock()
+		s.reading = false
+		s.read.Broadcast()
+
+		if err != nil {
+			s.err = err
+			continue
+		}
+		s.candlesticks = append(s.candlesticks, candlestick)
+		s.sources = append(s.sources, source)
+	}
+}
+
+// trim drops the candlesticks that every subscriber has read, once there are at least maxStreamReadCandlesticks of
+// them. Streams are only trimmed when catching up on long periods, so that the usual short streams can still be shared
+// with conditions that subscribe later in the run.
+func (s *marketStream) trim() {
+	read := s.subscribers[0].next
+	for _, subscription := range s.subscribers[1:] {
+		if subscription.next < read {
+			read = subscription.next
+		}
+	}
+	drop := read - s.offset
+	if drop < maxStreamReadCandlesticks {
+		return
+	}
+	s.candlesticks = append([]common.Candlestick{}, s.candlesticks[drop:]...)
+	s.sources = append([]string{}, s.sources[drop:]...)
+	s.offset = read
+}
+
+// marketStreamSubscription is the iterator.Iterator of a subscriber to a marketStream. Its next is guarded by the
+// stream's lock.
+type marketStreamSubscription struct {
+	stream     *marketStream
+	next       int
+	lastErr    error
+	lastSource string
+}
+
+// Next returns the subscriber's next candlestick from the stream.
+func (it *marketStreamSubscription) Next() (common.Candlestick, error) {
+	candlestick, source, err := it.stream.next(it)
+	if err != nil {
+		return common.Candlestick{}, err
+	}
+	it.lastSource = source
+	return candlestick, nil
+}
+
+// Source returns where the subscriber's last candlestick came from, if the stream's iterator tells (see
+// core.SourcedIterator).
+func (it *marketStreamSubscription) Source() string {
+	return it.lastSource
+}
+
+// Scan is the Scanner interface implementation.
+func (it *marketStreamSubscription) Scan(candlestick *common.Candlestick) bool {
+	cs, err := it.Next()
+	it.lastErr = err
+	*candlestick = cs
+	return err == nil
+}
+
+// Error returns the error of the last Scan operation, or nil if it was successful.
+func (it *marketStreamSubscription) Error() error {
+	return it.lastErr
+}
+
+// SetStartFromNext skips the stream's first candlestick, which is the one at the start time. Like on the market's
+// iterators, it must be called before Next.
+func (it *marketStreamSubscription) SetStartFromNext(b bool) {
+	it.stream.mu.Lock()
+	defer it.stream.mu.Unlock()
+	if b {
+		it.next = 1
+	} else {
+		it.next = 0
+	}
+}
+
+// SetTimeNowFunc overrides time.Now() on the stream's iterator, so it affects every subscriber of the stream.
+func (it *marketStreamSubscription) SetTimeNowFunc(f func() time.Time) {
+	it.stream.mu.Lock()
+	defer it.stream.mu.Unlock()
+	for it.stream.reading {
+		it.stream.read.Wait()
+	}
+	it.stream.it.SetTimeNowFunc(f)
+}
+
+// iteratorSource returns where the last candlestick of the iterator came from, or empty if it doesn't tell, i.e. if
+// it's not a core.SourcedIterator.
+func iteratorSource(it iterator.Iterator) string {
+	if sourced, ok := it.(core.SourcedIterator); ok {
+		return sourced.Source()
+	}
+	return ""
+}
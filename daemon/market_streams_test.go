@@ -0,0 +1,143 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketStreamsReadEachStreamOnce(t *testing.T) {
+	var (
+		market    = newSequenceMarket(3)
+		streams   = newMarketStreams(core.NewMarket(market))
+		btc       = operand("COIN:BINANCE:BTC-USDT")
+		startTime = time.Unix(int64(tInt("2022-02-27 15:20:00")), 0)
+	)
+
+	first, err := streams.Iterator(btc, startTime, time.Minute)
+	require.Nil(t, err)
+	second, err := streams.Iterator(btc, startTime.Add(30*time.Second).Add(-time.Minute), time.Minute)
+	require.Nil(t, err)
+	second.SetStartFromNext(true)
+
+	require.Equal(t, []int{0, 60, 120}, readTimestamps(t, first, startTime))
+	require.Equal(t, []int{60, 120}, readTimestamps(t, second, startTime))
+	require.Equal(t, 1, len(market.calls))
+	require.Equal(t, 4, market.nextCalls)
+
+	_, err = first.Next()
+	require.ErrorIs(t, err, common.ErrOutOfTicks)
+	require.Equal(t, 4, market.nextCalls)
+
+	other, err := streams.Iterator(btc, startTime.Add(time.Minute), time.Minute)
+	require.Nil(t, err)
+	require.Equal(t, []int{0, 60, 120}, readTimestamps(t, other, startTime.Add(time.Minute)))
+	require.Equal(t, 2, len(market.calls))
+}
+
+func TestMarketStreamsDropWhatEverySubscriberHasRead(t *testing.T) {
+	var (
+		length    = 3 * maxStreamReadCandlesticks
+		market    = newSequenceMarket(length)
+		streams   = newMarketStreams(core.NewMarket(market))
+		btc       = operand("COIN:BINANCE:BTC-USDT")
+		startTime = time.Unix(int64(tInt("2022-02-27 15:20:00")), 0)
+	)
+	first, err := streams.Iterator(btc, startTime, time.Minute)
+	require.Nil(t, err)
+	second, err := streams.Iterator(btc, startTime, time.Minute)
+	require.Nil(t, err)
+
+	require.Len(t, readTimestamps(t, first, startTime), length)
+	stream := first.(*marketStreamSubscription).stream
+	require.Equal(t, 0, stream.offset)
+	require.Len(t, stream.candlesticks, length)
+
+	require.Len(t, readTimestamps(t, second, startTime), length)
+	require.Equal(t, length, stream.offset+len(stream.candlesticks))
+	require.Less(t, len(stream.candlesticks), maxStreamReadCandlesticks)
+
+	// A late subscriber can't read the dropped candlesticks from the stream, so it gets a new one.
+	late, err := streams.Iterator(btc, startTime, time.Minute)
+	require.Nil(t, err)
+	require.Len(t, readTimestamps(t, late, startTime), length)
+	require.Equal(t, 2, len(market.calls))
+}
+
+func TestMarketStreamsDontWaitForTheExchangeToReadWhatWasRead(t *testing.T) {
+	var (
+		requested = make(chan struct{})
+		exchange  = make(chan struct{})
+		market    = &fakeMarket{candlestick: func(_ common.MarketSource, i, ts, _ int) (common.Candlestick, error) {
+			if i == 1 {
+				close(requested)
+				<-exchange
+			}
+			return common.Candlestick{Timestamp: ts}, nil
+		}}
+		streams   = newMarketStreams(core.NewMarket(market))
+		btc       = operand("COIN:BINANCE:BTC-USDT")
+		startTime = time.Unix(int64(tInt("2022-02-27 15:20:00")), 0)
+	)
+	first, err := streams.Iterator(btc, startTime, time.Minute)
+	require.Nil(t, err)
+	second, err := streams.Iterator(btc, startTime, time.Minute)
+	require.Nil(t, err)
+	_, err = first.Next()
+	require.Nil(t, err)
+
+	// The first subscriber waits for the exchange to return the second candlestick...
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		_, err := first.Next()
+		require.Nil(t, err)
+	}()
+	<-requested
+
+	// ...while the second one reads the first candlestick, and then waits for the same request.
+	candlestick, err := second.Next()
+	require.Nil(t, err)
+	require.Equal(t, int(startTime.Unix()), candlestick.Timestamp)
+
+	close(exchange)
+	<-firstDone
+	candlestick, err = second.Next()
+	require.Nil(t, err)
+	require.Equal(t, int(startTime.Unix())+60, candlestick.Timestamp)
+	require.Equal(t, 1, len(market.calls))
+}
+
+func TestMarketStreamsReturnIteratorErrors(t *testing.T) {
+	var (
+		errUnsupported = errors.New("unsupported")
+		streams        = newMarketStreams(core.NewMarket(&fakeMarket{err: errUnsupported}))
+	)
+	_, err := streams.Iterator(operand("COIN:NOPE:BTC-USDT"), time.Now(), time.Minute)
+	require.ErrorIs(t, err, errUnsupported)
+}
+
+func readTimestamps(t *testing.T, it iterator.Iterator, startTime time.Time) []int {
+	timestamps := []int{}
+	var candlestick common.Candlestick
+	for it.Scan(&candlestick) {
+		timestamps = append(timestamps, candlestick.Timestamp-int(startTime.Unix()))
+	}
+	require.ErrorIs(t, it.Error(), common.ErrOutOfTicks)
+	return timestamps
+}
+
+// newSequenceMarket makes a market that returns `length` consecutive candlesticks from the start time.
+func newSequenceMarket(length int) *fakeMarket {
+	return &fakeMarket{candlestick: func(_ common.MarketSource, i, ts, _ int) (common.Candlestick, error) {
+		if i == length {
+			return common.Candlestick{}, common.ErrOutOfTicks
+		}
+		return common.Candlestick{Timestamp: ts}, nil
+	}}
+}
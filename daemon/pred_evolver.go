@@ -1,22 +1,35 @@
 package daemon
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/marianogappa/crypto-candles/candles/common"
-	"github.com/marianogappa/crypto-candles/candles/iterator"
 	"github.com/marianogappa/predictions/core"
 )
 
 // PredEvolver is the struct that evolves a prediction's state upon reading market data.
 type PredEvolver struct {
 	prediction *core.Prediction
-	tickers    map[string]map[string]iterator.Iterator
+	conditions map[string]*condEvolver
+	nowTs      int
+
+	// stateValue is the prediction's value as of the last recorded change, and stateValueChanges are the changes of
+	// value while evolving it, oldest first.
+	stateValue        core.PredictionStateValue
+	stateValueChanges []core.PredictionStateValueChange
+
+	// onStep, if set, is called every time a condition is evolved, e.g. to trace a replay (see Replay).
+	onStep func(cond *core.Condition)
 }
 
+// stalledAfterSecs is how late the next candlestick of a condition must be for its market data to be considered
+// stopped, rather than just not closed yet or slow to be published by the exchange.
+const stalledAfterSecs = 10 * 60
+
 var (
 	errPredictionAtFinalStateAtCreation = errors.New("prediction is in final state at creation time")
 )
@@ -24,28 +37,24 @@ var (
 // NewPredEvolver is the constructor for PredEvolver.
 func NewPredEvolver(prediction *core.Prediction, m core.IMarket, nowTs int) (*PredEvolver, []error) {
 	errs := []error{}
-	result := PredEvolver{prediction: prediction, tickers: make(map[string]map[string]iterator.Iterator)}
+	result := PredEvolver{prediction: prediction, conditions: map[string]*condEvolver{}, nowTs: nowTs}
 
 	predStateValue := prediction.Evaluate()
 	if predStateValue != core.ONGOINGPREPREDICTION && predStateValue != core.ONGOINGPREDICTION {
 		errs = append(errs, errPredictionAtFinalStateAtCreation)
 		return nil, errs
 	}
+	result.stateValue = predStateValue
 
 	for _, condition := range prediction.UndecidedConditions() {
 		startTime, startFromNext := calculateStartTs(condition)
 
-		result.tickers[condition.Name] = map[string]iterator.Iterator{}
-		for _, operand := range condition.NonNumberOperands() {
-			ticker, err := m.Iterator(operand.ToMarketSource(), startTime, 1*time.Minute)
-			if err != nil {
-				errs = append(errs, err)
-				return &result, errs
-			}
-			ticker.SetStartFromNext(startFromNext)
-
-			result.tickers[condition.Name][operand.Str] = ticker
+		condEvolver, err := newCondEvolver(condition, m, startTime, startFromNext)
+		if err != nil {
+			errs = append(errs, err)
+			return &result, errs
 		}
+		result.conditions[condition.Name] = condEvolver
 	}
 
 	if len(errs) > 0 {
@@ -56,20 +65,47 @@ func NewPredEvolver(prediction *core.Prediction, m core.IMarket, nowTs int) (*Pr
 }
 
 // Run evolves the prediction until it hits an error, or there's no more recent market data, or the prediction finishes.
-func (r *PredEvolver) Run(once bool) []error {
+// If ctx is cancelled, it stops between candlesticks and returns ctx's error; the prediction keeps the state it had
+// evolved to by then.
+//
+// Conditions are evolved in chronological order: on each step, only the conditions that are furthest behind are
+// evolved. Since conditions may skip over long periods of market data (see condEvolver), this ensures that the
+// prediction's conditions are decided in the same order as if they were evolved minute by minute. If once is true,
+// every condition is evolved by one step instead.
+//
+// Conditions that get stuck without having evolved at all, because their market data stopped, count one more run in
+// their StalledRuns (see Daemon.SetFailover).
+func (r *PredEvolver) Run(ctx context.Context, once bool) (errs []error) {
 	var (
-		errs            = []error{}
 		stuckConditions = map[string]struct{}{}
+		stuckErrs       = map[string]error{}
+		evolved         = map[string]bool{}
 		conds           = r.actionableNonStuckUndecidedConditions(stuckConditions)
 	)
+	errs = []error{}
+	defer func() {
+		r.updateStalledRuns(evolved, stuckErrs)
+		errs = append(errs, r.flushSkippedTicks()...)
+	}()
+
 	for len(conds) > 0 {
+		if !once {
+			conds = r.earliestConditions(conds)
+		}
 		for _, cond := range conds {
-			if err := r.runCondition(cond); err != nil {
+			if err := ctx.Err(); err != nil {
+				return append(errs, err)
+			}
+			if err := r.conditions[cond.Name].step(); err != nil {
 				stuckConditions[cond.Name] = struct{}{}
+				stuckErrs[cond.Name] = err
 				if err != common.ErrOutOfTicks && err != common.ErrNoNewTicksYet {
 					errs = append(errs, err)
 				}
+				continue
 			}
+			evolved[cond.Name] = true
+			r.stepped(cond)
 		}
 		if once {
 			break
@@ -80,25 +116,95 @@ func (r *PredEvolver) Run(once bool) []error {
 	return errs
 }
 
-func (r *PredEvolver) runCondition(cond *core.Condition) error {
-	var (
-		lowestTicks  = map[string]core.Tick{}
-		highestTicks = map[string]core.Tick{}
-	)
-	for key, ticker := range r.tickers[cond.Name] {
-		candlestick, err := ticker.Next()
-		if err != nil {
-			return err
+// updateStalledRuns counts one more stalled run for the conditions that got stuck without evolving at all, if their
+// exchange failed or their next candlestick is long overdue, and resets it for the rest of the conditions that ran.
+func (r *PredEvolver) updateStalledRuns(evolved map[string]bool, stuckErrs map[string]error) {
+	for name, condEvolver := range r.conditions {
+		err, isStuck := stuckErrs[name]
+		if !isStuck && !evolved[name] {
+			continue
 		}
-		lowestTicks[key] = core.Tick{Timestamp: candlestick.Timestamp, Value: candlestick.LowestPrice}
-		highestTicks[key] = core.Tick{Timestamp: candlestick.Timestamp, Value: candlestick.HighestPrice}
+		isWaiting := err == common.ErrOutOfTicks || err == common.ErrNoNewTicksYet
+		if evolved[name] || (isWaiting && r.nowTs-condEvolver.nextTs < stalledAfterSecs) {
+			condEvolver.cond.State.StalledRuns = 0
+			continue
+		}
+		condEvolver.cond.State.StalledRuns++
 	}
+}
 
-	err := cond.Run(lowestTicks)
-	if err != nil {
-		return err
+// flushSkippedTicks updates the state of the conditions that skipped market data since they were last evolved, unless
+// the prediction is already final, in which case its state should stay as it was when it became final.
+func (r *PredEvolver) flushSkippedTicks() []error {
+	errs := []error{}
+	if r.prediction.Evaluate().IsFinal() {
+		return errs
+	}
+	for _, condEvolver := range r.conditions {
+		if err := condEvolver.flushSkippedTick(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.stepped(condEvolver.cond)
+	}
+	r.prediction.Evaluate()
+	return errs
+}
+
+// stepped is called every time cond is evolved.
+func (r *PredEvolver) stepped(cond *core.Condition) {
+	r.recordStateValueChange(cond)
+	if r.onStep != nil {
+		r.onStep(cond)
+	}
+}
+
+// recordStateValueChange records a change of the prediction's value, if evolving cond changed it.
+func (r *PredEvolver) recordStateValueChange(cond *core.Condition) {
+	value := r.prediction.Evaluate()
+	if value == r.stateValue {
+		return
+	}
+	r.stateValue = value
+
+	ticks := make(map[string]core.Tick, len(cond.State.LastTicks))
+	for k, v := range cond.State.LastTicks {
+		ticks[k] = v
+	}
+	r.stateValueChanges = append(r.stateValueChanges, core.PredictionStateValueChange{
+		PredictionUUID: r.prediction.UUID,
+		StateValue:     value.String(),
+		ConditionName:  cond.Name,
+		ConditionValue: cond.State.Value.String(),
+		Ticks:          ticks,
+		CreatedAt:      core.ISO8601(time.Unix(int64(r.changedAt(cond)), 0).UTC().Format(time.RFC3339)),
+	})
+}
+
+// changedAt returns when evolving cond changed the prediction's value, i.e. the timestamp of the tick it was last
+// evolved with, or its deadline if that tick was past it.
+func (r *PredEvolver) changedAt(cond *core.Condition) int {
+	ts := r.conditions[cond.Name].lastTickTs
+	if ts > cond.ToTs {
+		return cond.ToTs
+	}
+	return ts
+}
+
+func (r *PredEvolver) earliestConditions(conds []*core.Condition) []*core.Condition {
+	earliestTs := 0
+	for _, cond := range conds {
+		if ts := r.conditions[cond.Name].nextTs; earliestTs == 0 || ts < earliestTs {
+			earliestTs = ts
+		}
+	}
+	earliest := []*core.Condition{}
+	for _, cond := range conds {
+		if r.conditions[cond.Name].nextTs == earliestTs {
+			earliest = append(earliest, cond)
+		}
 	}
-	return cond.Run(highestTicks)
+	return earliest
 }
 
 func (r *PredEvolver) actionableNonStuckUndecidedConditions(stuckConditions map[string]struct{}) []*core.Condition {
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -9,8 +10,10 @@ import (
 	"time"
 
 	"github.com/marianogappa/crypto-candles/candles/common"
-	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/aggregate"
 	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/marketcap"
+	"github.com/marianogappa/predictions/markettest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -205,8 +208,8 @@ func TestNewPredRunner(t *testing.T) {
 	}
 	for _, ts := range tss {
 		t.Run(ts.name, func(t *testing.T) {
-			tm := &testMarket{}
-			_, errs := NewPredEvolver(&ts.prediction, tm, ts.nowTs)
+			tm := &fakeMarket{}
+			_, errs := NewPredEvolver(&ts.prediction, core.NewMarket(tm), ts.nowTs)
 			if len(errs) > 0 && !ts.isError {
 				t.Logf("should not have errored but these errors happened: %v", errs)
 				t.FailNow()
@@ -222,6 +225,29 @@ func TestNewPredRunner(t *testing.T) {
 	}
 }
 
+func TestPredEvolverRunStopsWhenContextIsCancelled(t *testing.T) {
+	prediction := newPredictionWith(
+		core.PrePredict{},
+		core.Predict{
+			Predict: core.BoolExpr{Operator: core.LITERAL, Operands: nil, Literal: &core.Condition{
+				FromTs:   tInt("2022-02-27 15:20:00"),
+				ToTs:     tInt("2022-03-27 15:20:00"),
+				Operands: []core.Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")},
+				State:    core.ConditionState{Value: core.UNDECIDED, LastTs: 0, LastTicks: map[string]core.Tick{}},
+			}},
+		})
+	predRunner, errs := NewPredEvolver(&prediction, core.NewMarket(&fakeMarket{}), tInt("2022-02-28 15:20:00"))
+	require.Len(t, errs, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errs = predRunner.Run(ctx, false)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], context.Canceled)
+	require.Equal(t, 0, prediction.Predict.Predict.Literal.State.LastTs)
+}
+
 func mapOperand(v string) (core.Operand, error) {
 	v = strings.ToUpper(v)
 	f, err := strconv.ParseFloat(v, 64)
@@ -277,34 +303,111 @@ func tInt(s string) int {
 	return int(tp(s).Unix())
 }
 
-type marketCall struct {
-	marketSource  common.MarketSource
-	tm            time.Time
-	startFromNext bool
-}
-
-type testMarket struct {
-	calls []marketCall
-}
+func TestPredEvolverEvolvesMarketCapOperands(t *testing.T) {
+	var (
+		fixture = markettest.NewTickDataSource("MESSARI", map[string][]core.Tick{
+			"BTC": {
+				{Timestamp: tInt("2022-01-01 00:00:00"), Value: 900000000000},
+				{Timestamp: tInt("2022-01-10 13:37:00"), Value: 1010000000000},
+				{Timestamp: tInt("2022-01-10 13:38:00"), Value: 950000000000},
+				{Timestamp: tInt("2022-02-01 00:00:00"), Value: 950000000000},
+			},
+		})
+		market = core.NewMarket(&fakeMarket{}, core.WithOperandMarket(core.MARKETCAP, marketcap.NewMarket(marketcap.WithDataSource(fixture))))
+		c      = &core.Condition{
+			Name:     "main",
+			Operator: ">=",
+			FromTs:   tInt("2022-01-01 00:00:00"),
+			ToTs:     tInt("2022-01-31 00:00:00"),
+			Operands: []core.Operand{operand("MARKETCAP:MESSARI:BTC"), operand("1000000000000")},
+			State:    core.ConditionState{LastTicks: map[string]core.Tick{}},
+		}
+		prediction = newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+	)
+	evolve(t, &prediction, market, tInt("2022-02-01 00:00:00"))
 
-func (m *testMarket) Iterator(marketSource common.MarketSource, tm time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
-	m.calls = append(m.calls, marketCall{marketSource, tm, false})
-	return testIterator{m}, nil
+	require.Equal(t, core.TRUE, c.State.Value)
+	require.Equal(t, tInt("2022-01-10 13:37:00"), c.State.LastTs)
+	require.Equal(t, core.CORRECT, prediction.Evaluate())
 }
 
-type testIterator struct{ mkt *testMarket }
-
-func (i testIterator) NextTick() (core.Tick, error) {
-	return core.Tick{}, nil
-}
+func TestPredEvolverEvolvesAggregateOperands(t *testing.T) {
+	var (
+		market = aggregate.NewMarket(markettest.NewCoinMarket(map[string][]core.Tick{
+			// BINANCE delists BTC-USDT before the condition becomes true, so it's resolved on COINBASE.
+			"COIN:BINANCE:BTC-USDT": {{Timestamp: tInt("2022-01-01 00:00:00"), Value: 40000}, {Timestamp: tInt("2022-01-05 00:00:00"), Value: 41000}},
+			"COIN:COINBASE:BTC-USDT": {
+				{Timestamp: tInt("2022-01-01 00:00:00"), Value: 40100},
+				{Timestamp: tInt("2022-01-10 13:37:00"), Value: 50100},
+				{Timestamp: tInt("2022-01-10 13:38:00"), Value: 45000},
+				{Timestamp: tInt("2022-02-01 00:00:00"), Value: 45000},
+			},
+		}))
+		btc = core.Operand{Type: core.COIN, Provider: "ANY(BINANCE,COINBASE)", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:ANY(BINANCE,COINBASE):BTC-USDT"}
+		c   = &core.Condition{
+			Name:     "main",
+			Operator: ">=",
+			FromTs:   tInt("2022-01-01 00:00:00"),
+			ToTs:     tInt("2022-01-31 00:00:00"),
+			Operands: []core.Operand{btc, operand("50000")},
+			State:    core.ConditionState{LastTicks: map[string]core.Tick{}},
+		}
+		prediction = newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+	)
+	evolve(t, &prediction, newMarketStreams(core.NewMarket(market)), tInt("2022-02-01 00:00:00"))
 
-func (i testIterator) Next() (common.Candlestick, error) {
-	return common.Candlestick{}, nil
+	require.Equal(t, core.TRUE, c.State.Value)
+	require.Equal(t, tInt("2022-01-10 13:37:00"), c.State.LastTs)
+	require.Equal(t, "COINBASE", c.State.LastTicks[btc.Str].Source)
 }
 
-// Not using the Scanner interface
-func (i testIterator) Scan(*common.Candlestick) bool { return false }
-func (i testIterator) Error() error                  { return nil }
+func TestPredEvolverRecordsStateValueChangesAtTheTimeTheyHappen(t *testing.T) {
+	tss := []struct {
+		name     string
+		peak     common.JSONFloat64
+		expected core.PredictionStateValueChange
+	}{
+		{
+			name:     "at the tick that decided the condition",
+			peak:     61000,
+			expected: core.PredictionStateValueChange{StateValue: core.CORRECT.String(), ConditionValue: core.TRUE.String(), CreatedAt: core.ISO8601("2022-01-10T13:37:00Z")},
+		},
+		{
+			name:     "at the deadline of the condition if it expired",
+			peak:     59000,
+			expected: core.PredictionStateValueChange{StateValue: core.INCORRECT.String(), ConditionValue: core.FALSE.String(), CreatedAt: core.ISO8601("2022-01-31T00:00:00Z")},
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			var (
+				market = markettest.NewCoinMarket(map[string][]core.Tick{
+					"COIN:BINANCE:BTC-USDT": {
+						{Timestamp: tInt("2022-01-01 00:00:00"), Value: 40000},
+						{Timestamp: tInt("2022-01-10 13:37:00"), Value: ts.peak},
+						{Timestamp: tInt("2022-01-10 13:38:00"), Value: 45000},
+						{Timestamp: tInt("2022-02-01 00:00:00"), Value: 45000},
+					},
+				})
+				c = &core.Condition{
+					Name:     "main",
+					Operator: ">=",
+					FromTs:   tInt("2022-01-01 00:00:00"),
+					ToTs:     tInt("2022-01-31 00:00:00"),
+					Operands: []core.Operand{operand("COIN:BINANCE:BTC-USDT"), operand("60000")},
+					State:    core.ConditionState{LastTicks: map[string]core.Tick{}},
+				}
+				prediction = newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+			)
+			predEvolver, errs := NewPredEvolver(&prediction, core.NewMarket(market), tInt("2022-02-01 00:00:00"))
+			require.Len(t, errs, 0)
+			require.Len(t, predEvolver.Run(context.Background(), false), 0)
 
-func (i testIterator) SetStartFromNext(b bool)         { i.mkt.calls[len(i.mkt.calls)-1].startFromNext = b }
-func (i testIterator) SetTimeNowFunc(func() time.Time) {}
+			require.Len(t, predEvolver.stateValueChanges, 1)
+			change := predEvolver.stateValueChanges[0]
+			require.Equal(t, ts.expected.StateValue, change.StateValue)
+			require.Equal(t, ts.expected.ConditionValue, change.ConditionValue)
+			require.Equal(t, ts.expected.CreatedAt, change.CreatedAt)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+)
+
+// recordedMaxCandlesticks is how many candlesticks a recordedDataSource returns per request, like an exchange's page.
+const recordedMaxCandlesticks = 1000
+
+// RecordedMarket is a core.ICoinMarket that provides recorded market data of COIN markets, rather than the exchanges'
+// (e.g. for replaying predictions without depending on exchanges, see Replay). Between two recorded ticks, the price
+// is that of the earlier one, so candlesticks of any interval can be provided.
+type RecordedMarket struct {
+	markets map[string]*recordedDataSource
+}
+
+// NewRecordedMarketFromJSON constructs a RecordedMarket from a JSON object with the recorded ticks of each market, like:
+//
+// {"COIN:BINANCE:BTC-USDT": [{"t": 1640995200, "v": 46216.93}, ...], ...}
+func NewRecordedMarketFromJSON(r io.Reader) (RecordedMarket, error) {
+	raw := map[string][]core.Tick{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return RecordedMarket{}, err
+	}
+	m := RecordedMarket{markets: map[string]*recordedDataSource{}}
+	for key, ticks := range raw {
+		parts := strings.Split(strings.ToUpper(key), ":")
+		if len(parts) != 3 || parts[0] != "COIN" || !strings.Contains(parts[2], "-") {
+			return RecordedMarket{}, fmt.Errorf("invalid recorded market %v: expected e.g. COIN:BINANCE:BTC-USDT", key)
+		}
+		m.markets[strings.ToUpper(key)] = newRecordedDataSource(parts[1], ticks)
+	}
+	return m, nil
+}
+
+// Iterator returns a market iterator over the recorded market data of the market source. It fails with
+// ErrInvalidMarketPair if the market source wasn't recorded.
+func (m RecordedMarket) Iterator(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	if marketSource.Type != common.COIN {
+		return nil, common.ErrInvalidMarketPair
+	}
+	market, ok := m.markets[strings.ToUpper(marketSource.String())]
+	if !ok {
+		return nil, common.ErrInvalidMarketPair
+	}
+	return iterator.NewIterator(marketSource, startTime, candlestickInterval, nil, market)
+}
+
+// recordedDataSource is the candlestick provider of one recorded market, named as the exchange it was recorded from.
+type recordedDataSource struct {
+	name  string
+	ticks []core.Tick
+}
+
+func newRecordedDataSource(name string, ticks []core.Tick) *recordedDataSource {
+	sorted := make([]core.Tick, len(ticks))
+	copy(sorted, ticks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+	return &recordedDataSource{name: name, ticks: sorted}
+}
+
+// RequestCandlesticks returns the recorded candlesticks starting from startTime rounded to the next
+// candlestickInterval. Candlesticks before the first recorded tick are left out, and it fails with ErrOutOfTicks if
+// startTime is after the last one.
+func (ds *recordedDataSource) RequestCandlesticks(_ common.MarketSource, startTime time.Time, candlestickInterval time.Duration) ([]common.Candlestick, error) {
+	if len(ds.ticks) == 0 {
+		return nil, common.ErrInvalidMarketPair
+	}
+
+	var (
+		intervalSecs = int(candlestickInterval / time.Second)
+		startTs      = common.NormalizeTimestamp(startTime, candlestickInterval, ds.name, false)
+		lastTs       = ds.ticks[len(ds.ticks)-1].Timestamp
+	)
+	if startTs > lastTs {
+		return nil, common.ErrOutOfTicks
+	}
+	if startTs < ds.ticks[0].Timestamp {
+		startTs = common.NormalizeTimestamp(time.Unix(int64(ds.ticks[0].Timestamp), 0), candlestickInterval, ds.name, false)
+	}
+
+	candlesticks := []common.Candlestick{}
+	for ts := startTs; ts <= lastTs && len(candlesticks) < recordedMaxCandlesticks; ts += intervalSecs {
+		candlesticks = append(candlesticks, ds.candlestickAt(ts, intervalSecs))
+	}
+	return candlesticks, nil
+}
+
+// candlestickAt builds the candlestick of the interval starting at ts, which must not be before the first tick.
+func (ds *recordedDataSource) candlestickAt(ts, intervalSecs int) common.Candlestick {
+	// Index of the last tick at or before ts, i.e. the price when the candlestick opens.
+	i := sort.Search(len(ds.ticks), func(i int) bool { return ds.ticks[i].Timestamp > ts }) - 1
+
+	open := ds.ticks[i].Value
+	candlestick := common.Candlestick{Timestamp: ts, OpenPrice: open, ClosePrice: open, LowestPrice: open, HighestPrice: open}
+	for _, tick := range ds.ticks[i+1:] {
+		if tick.Timestamp >= ts+intervalSecs {
+			break
+		}
+		candlestick.ClosePrice = tick.Value
+		candlestick.LowestPrice = common.JSONFloat64(math.Min(float64(candlestick.LowestPrice), float64(tick.Value)))
+		candlestick.HighestPrice = common.JSONFloat64(math.Max(float64(candlestick.HighestPrice), float64(tick.Value)))
+	}
+	return candlestick
+}
+
+// Patience is zero, because recorded market data is known beforehand.
+func (ds *recordedDataSource) Patience() time.Duration { return 0 }
+
+// Name is the uppercase name of the exchange the market was recorded from, e.g. BINANCE.
+func (ds *recordedDataSource) Name() string { return ds.name }
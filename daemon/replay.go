@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/printer"
+)
+
+// Replay evolves the prediction from scratch (i.e. from its conditions' FromTs) against the market until nowTs, and
+// writes a trace of every change of state of its conditions & itself to w, with the ticks that caused it. Nothing is
+// stored, so it's safe to replay any prediction, e.g. to debug its PrePredict & Predict logic on real cases.
+//
+// The market may be the exchanges' or one with recorded market data (see RecordedMarket).
+func Replay(ctx context.Context, prediction *core.Prediction, m core.IMarket, nowTs int, w io.Writer) []error {
+	prediction.ClearState()
+	fmt.Fprintf(w, "Replaying %v: %v\n", prediction.UUID, printer.NewPredictionPrettyPrinter(*prediction).String())
+
+	tracer := newReplayTracer(prediction, w)
+	predRunner, errs := NewPredEvolver(prediction, m, nowTs)
+	if len(errs) > 0 {
+		if errors.Is(errs[0], errPredictionAtFinalStateAtCreation) {
+			fmt.Fprintf(w, "Prediction is %v before evolving any condition.\n", prediction.State.Value)
+			return nil
+		}
+		return errs
+	}
+	predRunner.onStep = tracer.trace
+
+	errs = predRunner.Run(ctx, false)
+	prediction.Evaluate()
+
+	fmt.Fprintf(w, "Replay finished with prediction %v (%v).\n", prediction.State.Value, prediction.State.Status)
+	for _, name := range tracer.names {
+		cond := prediction.Given[name]
+		fmt.Fprintf(w, "  %v: %v (%v), last evolved at %v\n", name, cond.State.Value, cond.State.Status, formatReplayTs(cond.State.LastTs))
+	}
+	return errs
+}
+
+// replayTracer writes the changes of state of a prediction's conditions & itself as they're evolved.
+type replayTracer struct {
+	prediction *core.Prediction
+	w          io.Writer
+	names      []string
+	conds      map[string]replayCondState
+	value      core.PredictionStateValue
+}
+
+// replayCondState is the part of a condition's state that is traced.
+type replayCondState struct {
+	Status core.ConditionStatus
+	Value  core.ConditionStateValue
+	Streak int
+}
+
+func newReplayTracer(prediction *core.Prediction, w io.Writer) *replayTracer {
+	t := &replayTracer{prediction: prediction, w: w, conds: map[string]replayCondState{}, value: prediction.Evaluate()}
+	for name, cond := range prediction.Given {
+		t.names = append(t.names, name)
+		t.conds[name] = replayCondState{cond.State.Status, cond.State.Value, cond.State.Streak}
+	}
+	sort.Strings(t.names)
+	return t
+}
+
+func (t *replayTracer) trace(cond *core.Condition) {
+	var (
+		prev    = t.conds[cond.Name]
+		curr    = replayCondState{cond.State.Status, cond.State.Value, cond.State.Streak}
+		value   = t.prediction.Evaluate()
+		changes = []string{}
+	)
+	if curr.Status != prev.Status {
+		changes = append(changes, fmt.Sprintf("%v %v -> %v", cond.Name, prev.Status, curr.Status))
+	}
+	if curr.Value != prev.Value {
+		changes = append(changes, fmt.Sprintf("%v %v -> %v", cond.Name, prev.Value, curr.Value))
+	}
+	if curr.Streak != prev.Streak {
+		changes = append(changes, fmt.Sprintf("%v streak %v -> %v", cond.Name, prev.Streak, curr.Streak))
+	}
+	if value != t.value {
+		changes = append(changes, fmt.Sprintf("prediction %v -> %v", t.value, value))
+	}
+	t.conds[cond.Name], t.value = curr, value
+	if len(changes) == 0 {
+		return
+	}
+
+	// Conditions may finish on ticks they aren't evolved with (e.g. after their deadline), which are their evidence.
+	ts, ticks := cond.State.LastTs, cond.State.LastTicks
+	if curr.Status == core.FINISHED && prev.Status != core.FINISHED && len(cond.State.DecidingTicks) > 0 {
+		ts, ticks = cond.State.DecidingTicks[0].Timestamp, map[string]core.Tick{}
+		for _, tick := range cond.State.DecidingTicks {
+			ticks[tick.Operand] = core.Tick{Timestamp: tick.Timestamp, Value: tick.Value, Source: replayTickSource(cond, tick)}
+		}
+	}
+	fmt.Fprintf(t.w, "%v %v [%v]\n", formatReplayTs(ts), strings.Join(changes, ", "), formatReplayTicks(ticks))
+}
+
+// replayTickSource is the exchange of a deciding tick, but only for operands with aggregate providers (e.g.
+// COIN:ANY:BTC-USDT), like the ticks' sources in the conditions' states.
+func replayTickSource(cond *core.Condition, tick core.DecidingTick) string {
+	for _, operand := range cond.Operands {
+		if operand.Str == tick.Operand && operand.Provider == tick.Exchange {
+			return ""
+		}
+	}
+	return tick.Exchange
+}
+
+func formatReplayTs(ts int) string {
+	if ts == 0 {
+		return "never"
+	}
+	return time.Unix(int64(ts), 0).UTC().Format(time.RFC3339)
+}
+
+func formatReplayTicks(ticks map[string]core.Tick) string {
+	keys := []string{}
+	for key := range ticks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	strs := []string{}
+	for _, key := range keys {
+		str := fmt.Sprintf("%v=%v", key, float64(ticks[key].Value))
+		if ticks[key].Source != "" {
+			str += fmt.Sprintf(" on %v", ticks[key].Source)
+		}
+		strs = append(strs, str)
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	market, err := NewRecordedMarketFromJSON(strings.NewReader(`{"COIN:BINANCE:BTC-USDT": [
+		{"t": 1640995200, "v": 40000},
+		{"t": 1641822960, "v": 50100},
+		{"t": 1641823020, "v": 45000},
+		{"t": 1643673600, "v": 45000}
+	]}`))
+	require.Nil(t, err)
+
+	var (
+		c = &core.Condition{
+			Name:     "main",
+			Operator: ">=",
+			FromTs:   tInt("2022-01-01 00:00:00"),
+			ToTs:     tInt("2022-01-31 00:00:00"),
+			Operands: []core.Operand{operand("COIN:BINANCE:BTC-USDT"), operand("50000")},
+			// Replays start from scratch, regardless of the prediction's state.
+			State: core.ConditionState{Status: core.FINISHED, Value: core.FALSE, LastTs: tInt("2022-01-31 00:00:00"), LastTicks: map[string]core.Tick{}},
+		}
+		prediction = newPredictionWith(core.PrePredict{}, core.Predict{Predict: literal(c)})
+		out        = &bytes.Buffer{}
+	)
+	prediction.Given["main"] = c
+
+	errs := Replay(context.Background(), &prediction, core.NewMarket(market), tInt("2022-02-01 00:00:00"), out)
+	require.Len(t, errs, 0)
+
+	require.Equal(t, core.CORRECT, prediction.Evaluate())
+	require.Equal(t, tInt("2022-01-10 13:56:00"), c.State.LastTs)
+	require.Equal(t, strings.Join([]string{
+		"Replaying ed47db4d-cc0b-4c3c-af18-e6fcbff82338: JohnDoe predicts that Bitcoin >= 50k by 2022-01-31T00:00:00Z ",
+		"2022-01-10T13:00:00Z main UNSTARTED -> STARTED [COIN:BINANCE:BTC-USDT=40000]",
+		"2022-01-10T13:56:00Z main STARTED -> FINISHED, main UNDECIDED -> TRUE, prediction ONGOING_PREDICTION -> CORRECT [COIN:BINANCE:BTC-USDT=50100]",
+		"Replay finished with prediction CORRECT (FINISHED).",
+		"  main: TRUE (FINISHED), last evolved at 2022-01-10T13:56:00Z",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestRecordedMarketFailsOnUnrecordedMarkets(t *testing.T) {
+	market, err := NewRecordedMarketFromJSON(strings.NewReader(`{"COIN:BINANCE:BTC-USDT": [{"t": 1640995200, "v": 40000}]}`))
+	require.Nil(t, err)
+
+	_, err = market.Iterator(common.MarketSource{Type: common.COIN, Provider: "KUCOIN", BaseAsset: "BTC", QuoteAsset: "USDT"}, tp("2022-01-01 00:00:00"), time.Minute)
+	require.ErrorIs(t, err, common.ErrInvalidMarketPair)
+
+	_, err = NewRecordedMarketFromJSON(strings.NewReader(`{"BINANCE:BTC-USDT": []}`))
+	require.NotNil(t, err)
+}
@@ -0,0 +1,190 @@
+package indicator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+)
+
+// BinanceVolume is a VolumeDataSource of Binance's markets. Binance's klines have the volume traded during each
+// candlestick, in the market's base asset, so VOLUME(COIN:BINANCE:BTC-USDT,1d) is the amount of BTC traded in a day.
+type BinanceVolume struct {
+	apiURL    string
+	debug     bool
+	lock      sync.Mutex
+	requester common.RequesterWithRetry
+}
+
+// NewBinanceVolume is the constructor for BinanceVolume.
+func NewBinanceVolume() *BinanceVolume {
+	return newBinanceVolume("https://api.binance.com/api/v3/")
+}
+
+func newBinanceVolume(apiURL string) *BinanceVolume {
+	v := &BinanceVolume{apiURL: apiURL}
+	v.requester = common.NewRequesterWithRetry(
+		v.requestVolumes,
+		common.RetryStrategy{Attempts: 3, FirstSleepTime: 1 * time.Second, SleepTimeMultiplier: 2.0},
+		&v.debug,
+	)
+	return v
+}
+
+// RequestCandlesticks requests candlesticks with the traded volumes of the given market source as their prices, of a
+// given candlestick interval, starting at a given time.Time. Nothing was traded during gaps, so they're patched with
+// candlesticks of zero volume, rather than cloned like an exchange's candlesticks are.
+func (v *BinanceVolume) RequestCandlesticks(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) ([]common.Candlestick, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	candlesticks, err := v.requester.Request(marketSource.BaseAsset, marketSource.QuoteAsset, startTime, candlestickInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	return patchVolumeHoles(candlesticks, common.NormalizeTimestamp(startTime, candlestickInterval, common.BINANCE, false), int(candlestickInterval/time.Second)), nil
+}
+
+// patchVolumeHoles drops the candlesticks before startTs, and patches any holes after it with zero volume candlesticks.
+func patchVolumeHoles(candlesticks []common.Candlestick, startTs, intervalSecs int) []common.Candlestick {
+	patched := []common.Candlestick{}
+	nextTs := startTs
+	for _, candlestick := range candlesticks {
+		if candlestick.Timestamp < nextTs {
+			continue
+		}
+		for ; nextTs < candlestick.Timestamp; nextTs += intervalSecs {
+			patched = append(patched, common.Candlestick{Timestamp: nextTs})
+		}
+		patched = append(patched, candlestick)
+		nextTs += intervalSecs
+	}
+	return patched
+}
+
+// Patience returns the delay that Binance usually takes in order for it to return candlesticks.
+func (v *BinanceVolume) Patience() time.Duration { return 0 * time.Minute }
+
+// Name is the name of the exchange whose volumes this data source provides.
+func (v *BinanceVolume) Name() string { return common.BINANCE }
+
+// SetDebug sets debug logging of the retried requests.
+func (v *BinanceVolume) SetDebug(debug bool) {
+	v.debug = debug
+}
+
+const binanceErrInvalidSymbol = -1121
+
+var binanceIntervals = map[time.Duration]string{
+	1 * time.Minute:    "1m",
+	3 * time.Minute:    "3m",
+	5 * time.Minute:    "5m",
+	15 * time.Minute:   "15m",
+	30 * time.Minute:   "30m",
+	1 * time.Hour:      "1h",
+	2 * time.Hour:      "2h",
+	4 * time.Hour:      "4h",
+	6 * time.Hour:      "6h",
+	8 * time.Hour:      "8h",
+	12 * time.Hour:     "12h",
+	24 * time.Hour:     "1d",
+	3 * 24 * time.Hour: "3d",
+	7 * 24 * time.Hour: "1w",
+}
+
+type binanceErrorResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// requestVolumes requests Binance's klines, e.g.
+// https://api.binance.com/api/v3/klines?symbol=BTCUSDT&interval=1m&limit=1000&startTime=1642329924000
+//
+// Each kline is [open time in millis, open, high, low, close, volume, close time, quote asset volume, ...].
+func (v *BinanceVolume) requestVolumes(baseAsset string, quoteAsset string, startTime time.Time, candlestickInterval time.Duration) ([]common.Candlestick, error) {
+	interval, ok := binanceIntervals[candlestickInterval]
+	if !ok {
+		return nil, common.CandleReqError{IsNotRetryable: true, Err: common.ErrUnsupportedCandlestickInterval}
+	}
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%vklines", v.apiURL), nil)
+	q := req.URL.Query()
+	q.Add("symbol", fmt.Sprintf("%v%v", strings.ToUpper(baseAsset), strings.ToUpper(quoteAsset)))
+	q.Add("interval", interval)
+	q.Add("limit", "1000")
+	q.Add("startTime", fmt.Sprintf("%v", startTime.Unix()*1000))
+	req.URL.RawQuery = q.Encode()
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, common.CandleReqError{IsNotRetryable: true, Err: fmt.Errorf("%w: %v", common.ErrExecutingRequest, err)}
+	}
+	defer resp.Body.Close()
+
+	byts, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, common.CandleReqError{IsNotRetryable: false, Err: common.ErrBrokenBodyResponse}
+	}
+
+	maybeErrorResponse := binanceErrorResponse{}
+	if err := json.Unmarshal(byts, &maybeErrorResponse); err == nil && maybeErrorResponse.Code != 0 {
+		if resp.StatusCode == http.StatusTooManyRequests && len(resp.Header["Retry-After"]) == 1 {
+			seconds, _ := strconv.Atoi(resp.Header["Retry-After"][0])
+			return nil, common.CandleReqError{Code: maybeErrorResponse.Code, Err: common.ErrRateLimit, RetryAfter: time.Duration(seconds) * time.Second}
+		}
+		if maybeErrorResponse.Code == binanceErrInvalidSymbol {
+			return nil, common.CandleReqError{IsNotRetryable: true, Code: maybeErrorResponse.Code, Err: common.ErrInvalidMarketPair}
+		}
+		return nil, common.CandleReqError{Code: maybeErrorResponse.Code, Err: errors.New(maybeErrorResponse.Msg)}
+	}
+
+	klines := [][]interface{}{}
+	if err := json.Unmarshal(byts, &klines); err != nil {
+		return nil, common.CandleReqError{Err: common.ErrInvalidJSONResponse}
+	}
+	candlesticks := make([]common.Candlestick, len(klines))
+	for i, kline := range klines {
+		candlestick, err := klineToVolumeCandlestick(kline)
+		if err != nil {
+			return nil, common.CandleReqError{Err: fmt.Errorf("kline %v: %w", i, err)}
+		}
+		candlesticks[i] = candlestick
+	}
+	if len(candlesticks) == 0 {
+		return nil, common.CandleReqError{Err: common.ErrOutOfCandlesticks}
+	}
+	return candlesticks, nil
+}
+
+func klineToVolumeCandlestick(kline []interface{}) (common.Candlestick, error) {
+	if len(kline) < 6 {
+		return common.Candlestick{}, fmt.Errorf("%w: has len %v < 6", common.ErrInvalidJSONResponse, len(kline))
+	}
+	openTime, ok := kline[0].(float64)
+	if !ok {
+		return common.Candlestick{}, fmt.Errorf("%w: non-numeric open time %v", common.ErrInvalidJSONResponse, kline[0])
+	}
+	rawVolume, ok := kline[5].(string)
+	if !ok {
+		return common.Candlestick{}, fmt.Errorf("%w: non-string volume %v", common.ErrInvalidJSONResponse, kline[5])
+	}
+	volume, err := strconv.ParseFloat(rawVolume, 64)
+	if err != nil {
+		return common.Candlestick{}, fmt.Errorf("%w: invalid volume %v", common.ErrInvalidJSONResponse, rawVolume)
+	}
+	return common.Candlestick{
+		Timestamp:    int(openTime / 1000),
+		OpenPrice:    common.JSONFloat64(volume),
+		ClosePrice:   common.JSONFloat64(volume),
+		LowestPrice:  common.JSONFloat64(volume),
+		HighestPrice: common.JSONFloat64(volume),
+	}, nil
+}
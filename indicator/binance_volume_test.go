@@ -0,0 +1,69 @@
+package indicator
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinanceVolume(t *testing.T) {
+	tss := []struct {
+		name     string
+		status   int
+		body     string
+		interval time.Duration
+		err      error
+		expected []common.Candlestick
+	}{
+		{
+			name:     "Volumes are the candlesticks' prices, and nothing was traded during holes",
+			status:   http.StatusOK,
+			body:     `[[1642329960000,"43086.22","43086.22","43069.48","43070.00","8.65209000",1642330019999,"372709.68",384,"2.52","108606.91","0"],[1642330080000,"43072.59","43080.00","43072.59","43079.00","1.50000000",1642330139999,"64616.00",100,"1.00","43079.00","0"]]`,
+			interval: time.Minute,
+			expected: []common.Candlestick{
+				{Timestamp: 1642329960, OpenPrice: 8.65209, ClosePrice: 8.65209, LowestPrice: 8.65209, HighestPrice: 8.65209},
+				{Timestamp: 1642330020},
+				{Timestamp: 1642330080, OpenPrice: 1.5, ClosePrice: 1.5, LowestPrice: 1.5, HighestPrice: 1.5},
+			},
+		},
+		{
+			name:     "Invalid symbol",
+			status:   http.StatusBadRequest,
+			body:     `{"code":-1121,"msg":"Invalid symbol."}`,
+			interval: time.Minute,
+			err:      common.ErrInvalidMarketPair,
+		},
+		{
+			name:     "Unsupported interval",
+			interval: 2 * time.Minute,
+			err:      common.ErrUnsupportedCandlestickInterval,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/klines", r.URL.Path)
+				require.Equal(t, "BTCUSDT", r.URL.Query().Get("symbol"))
+				require.Equal(t, fmt.Sprintf("%v", 1642329960000), r.URL.Query().Get("startTime"))
+				w.WriteHeader(ts.status)
+				fmt.Fprint(w, ts.body)
+			}))
+			defer server.Close()
+
+			volume := newBinanceVolume(server.URL + "/")
+			actual, err := volume.RequestCandlesticks(common.MarketSource{Type: common.COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT"}, time.Unix(1642329960, 0), ts.interval)
+			if ts.err != nil {
+				// Like exchanges' errors, they're wrapped in a common.CandleReqError for the iterator.
+				require.IsType(t, common.CandleReqError{}, err)
+				err = err.(common.CandleReqError).Err
+			}
+			require.ErrorIs(t, err, ts.err)
+			require.Equal(t, ts.expected, actual)
+		})
+	}
+}
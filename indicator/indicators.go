@@ -0,0 +1,82 @@
+package indicator
+
+import "github.com/marianogappa/crypto-candles/candles/common"
+
+// warmUpFactor is how many windows of candlesticks indicators with infinite memory (i.e. EMA & RSI) are computed over.
+// Candlesticks older than that weigh less than 2% on the value, so they're ignored.
+const warmUpFactor = 3
+
+// indicator computes an indicator from the last candlesticks of its window. Since it only depends on those
+// candlesticks, an indicator has the same value at a given time no matter when its iterator started.
+type indicator struct {
+	// candlesticks is how many candlesticks are needed for a window of n candlesticks, including the warm-up.
+	candlesticks func(n int) int
+	// value computes the indicator from exactly as many candlesticks as needed.
+	value func(candlesticks []common.Candlestick, n int) float64
+}
+
+var indicators = map[string]indicator{
+	"SMA":    {candlesticks: func(n int) int { return n }, value: sma},
+	"EMA":    {candlesticks: func(n int) int { return warmUpFactor * n }, value: ema},
+	"RSI":    {candlesticks: func(n int) int { return warmUpFactor*n + 1 }, value: rsi},
+	"VOLUME": {candlesticks: func(n int) int { return n }, value: volume},
+}
+
+// sma is the simple moving average of the close prices of n candlesticks.
+func sma(candlesticks []common.Candlestick, n int) float64 {
+	sum := 0.0
+	for _, candlestick := range candlesticks {
+		sum += float64(candlestick.ClosePrice)
+	}
+	return sum / float64(n)
+}
+
+// ema is the exponential moving average of the close prices of a window of n candlesticks. It starts as the simple
+// moving average of the first n candlesticks, and then smooths in the rest.
+func ema(candlesticks []common.Candlestick, n int) float64 {
+	var (
+		value = sma(candlesticks[:n], n)
+		alpha = 2 / float64(n+1)
+	)
+	for _, candlestick := range candlesticks[n:] {
+		value = alpha*float64(candlestick.ClosePrice) + (1-alpha)*value
+	}
+	return value
+}
+
+// rsi is Wilder's relative strength index of the close prices of a window of n candlesticks. The average gains &
+// losses start as the simple average of the first n price changes, and then smooth in the rest.
+func rsi(candlesticks []common.Candlestick, n int) float64 {
+	var avgGain, avgLoss float64
+	for i := 1; i < len(candlesticks); i++ {
+		gain, loss := 0.0, 0.0
+		if change := float64(candlesticks[i].ClosePrice - candlesticks[i-1].ClosePrice); change > 0 {
+			gain = change
+		} else {
+			loss = -change
+		}
+		if i <= n {
+			avgGain += gain / float64(n)
+			avgLoss += loss / float64(n)
+			continue
+		}
+		avgGain = (avgGain*float64(n-1) + gain) / float64(n)
+		avgLoss = (avgLoss*float64(n-1) + loss) / float64(n)
+	}
+	switch {
+	case avgGain == 0 && avgLoss == 0:
+		return 50
+	case avgLoss == 0:
+		return 100
+	}
+	return 100 - 100/(1+avgGain/avgLoss)
+}
+
+// volume is the traded volume over n candlesticks. Volume candlesticks have the traded volume as their prices.
+func volume(candlesticks []common.Candlestick, n int) float64 {
+	sum := 0.0
+	for _, candlestick := range candlesticks {
+		sum += float64(candlestick.ClosePrice)
+	}
+	return sum
+}
@@ -0,0 +1,90 @@
+package indicator
+
+import (
+	"testing"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/stretchr/testify/require"
+)
+
+func closes(prices ...float64) []common.Candlestick {
+	candlesticks := []common.Candlestick{}
+	for _, price := range prices {
+		candlesticks = append(candlesticks, common.Candlestick{ClosePrice: common.JSONFloat64(price)})
+	}
+	return candlesticks
+}
+
+func TestIndicators(t *testing.T) {
+	tss := []struct {
+		name         string
+		indicator    string
+		n            int
+		candlesticks []common.Candlestick
+		expected     float64
+	}{
+		{
+			name:         "SMA is the average close price",
+			indicator:    "SMA",
+			n:            3,
+			candlesticks: closes(1, 2, 3),
+			expected:     2,
+		},
+		{
+			name:         "EMA starts as the SMA of the first window and smooths in the rest",
+			indicator:    "EMA",
+			n:            3,
+			candlesticks: closes(1, 2, 3, 4, 5, 6, 7, 8, 9),
+			expected:     8,
+		},
+		{
+			name:         "EMA of a flat market is the price",
+			indicator:    "EMA",
+			n:            2,
+			candlesticks: closes(5, 5, 5, 5, 5, 5),
+			expected:     5,
+		},
+		{
+			name:         "RSI uses Wilder's smoothing",
+			indicator:    "RSI",
+			n:            2,
+			candlesticks: closes(10, 12, 11, 13, 13, 12, 14),
+			expected:     100 - 100/(1+1.1875/0.28125),
+		},
+		{
+			name:         "RSI is 100 if there are only gains",
+			indicator:    "RSI",
+			n:            2,
+			candlesticks: closes(1, 2, 3, 4, 5, 6, 7),
+			expected:     100,
+		},
+		{
+			name:         "RSI is 0 if there are only losses",
+			indicator:    "RSI",
+			n:            2,
+			candlesticks: closes(7, 6, 5, 4, 3, 2, 1),
+			expected:     0,
+		},
+		{
+			name:         "RSI of a flat market is 50",
+			indicator:    "RSI",
+			n:            2,
+			candlesticks: closes(5, 5, 5, 5, 5, 5, 5),
+			expected:     50,
+		},
+		{
+			name:         "VOLUME is the volume traded over the window",
+			indicator:    "VOLUME",
+			n:            3,
+			candlesticks: closes(100, 200, 300),
+			expected:     600,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			ind := indicators[ts.indicator]
+			require.Len(t, ts.candlesticks, ind.candlesticks(ts.n))
+			require.InDelta(t, ts.expected, ind.value(ts.candlesticks, ts.n), 1e-9)
+		})
+	}
+}
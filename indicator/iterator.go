@@ -0,0 +1,175 @@
+package indicator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+)
+
+// indicatorIterator is an iterator over the candlesticks of an indicator, computed from the candlesticks of its
+// source iterator (e.g. the daily candlesticks of BTC/USDT for SMA(COIN:BINANCE:BTC-USDT,200d)).
+//
+// The indicator only changes when a source candlestick closes, so a candlestick of the indicator opens at the value
+// from the source candlesticks that had closed by its start, and closes at the value from those that had closed by
+// its end.
+type indicatorIterator struct {
+	operand             core.Operand
+	source              iterator.Iterator
+	sourceInterval      int
+	candlesticks        int
+	value               func([]common.Candlestick) float64
+	candlestickInterval int
+	timeNowFunc         func() time.Time
+	startFromNext       bool
+	hasStarted          bool
+	lastErr             error
+
+	// buffer has the source candlesticks that were read and are still needed, and nextSourceTs is the earliest time
+	// the next source candlestick can start at.
+	buffer       []common.Candlestick
+	nextSourceTs int
+
+	// ts is the start of the next candlestick of the indicator.
+	ts int
+}
+
+type indicatorIteratorParams struct {
+	operand             core.Operand
+	source              iterator.Iterator
+	sourceStartTs       int
+	sourceInterval      time.Duration
+	candlesticks        int
+	value               func([]common.Candlestick) float64
+	startTime           time.Time
+	candlestickInterval time.Duration
+}
+
+func newIndicatorIterator(params indicatorIteratorParams) *indicatorIterator {
+	return &indicatorIterator{
+		operand:             params.operand,
+		source:              params.source,
+		sourceInterval:      int(params.sourceInterval / time.Second),
+		candlesticks:        params.candlesticks,
+		value:               params.value,
+		candlestickInterval: int(params.candlestickInterval / time.Second),
+		timeNowFunc:         time.Now,
+		nextSourceTs:        params.sourceStartTs,
+		ts:                  common.NormalizeTimestamp(params.startTime, params.candlestickInterval, params.operand.Provider, false),
+	}
+}
+
+// SetTimeNowFunc overrides time.Now() for testing purposes, both for the indicator and its source.
+func (it *indicatorIterator) SetTimeNowFunc(f func() time.Time) {
+	it.timeNowFunc = f
+	it.source.SetTimeNowFunc(f)
+}
+
+// SetStartFromNext moves the startTime to one candlestickInterval in the future. It must be called before Next().
+func (it *indicatorIterator) SetStartFromNext(b bool) {
+	if it.hasStarted {
+		panic("SetStartFromNext() cannot be called after Next() is called")
+	}
+	it.startFromNext = b
+}
+
+// Next is the "Next" iterator function, providing the next available Candlestick of the indicator.
+//
+// Besides the source's errors, it fails with ErrNoNewTicksYet if the candlestick hasn't closed yet, and with
+// ErrOutOfTicks if the source doesn't have enough candlesticks before it to compute the indicator.
+func (it *indicatorIterator) Next() (common.Candlestick, error) {
+	if !it.hasStarted && it.startFromNext {
+		it.ts += it.candlestickInterval
+	}
+	it.hasStarted = true
+
+	endTs := it.ts + it.candlestickInterval
+	if endTs > int(it.timeNowFunc().Unix()) {
+		return common.Candlestick{}, common.ErrNoNewTicksYet
+	}
+	if err := it.readSourceUntil(endTs); err != nil {
+		return common.Candlestick{}, err
+	}
+
+	open, err := it.valueAt(it.ts)
+	if err != nil {
+		return common.Candlestick{}, err
+	}
+	candlestick := common.Candlestick{Timestamp: it.ts, OpenPrice: open, ClosePrice: open, LowestPrice: open, HighestPrice: open}
+	for _, sourceCandlestick := range it.buffer {
+		closeTs := sourceCandlestick.Timestamp + it.sourceInterval
+		if closeTs <= it.ts || closeTs > endTs {
+			continue
+		}
+		value, err := it.valueAt(closeTs)
+		if err != nil {
+			return common.Candlestick{}, err
+		}
+		candlestick.ClosePrice = value
+		if value < candlestick.LowestPrice {
+			candlestick.LowestPrice = value
+		}
+		if value > candlestick.HighestPrice {
+			candlestick.HighestPrice = value
+		}
+	}
+
+	it.ts = endTs
+	it.pruneBuffer()
+	return candlestick, nil
+}
+
+// Scan is the Scanner interface implementation. Returns true if the scanning happened without errors. If it returns
+// false, the error is available on iter.Error().
+func (it *indicatorIterator) Scan(candlestick *common.Candlestick) bool {
+	cs, err := it.Next()
+	it.lastErr = err
+	*candlestick = cs
+	return err == nil
+}
+
+// Error returns the error of the last Scan operation, or nil if it was successful.
+func (it *indicatorIterator) Error() error {
+	return it.lastErr
+}
+
+// readSourceUntil reads source candlesticks until the buffer has every one that closes by ts.
+func (it *indicatorIterator) readSourceUntil(ts int) error {
+	for it.nextSourceTs+it.sourceInterval <= ts {
+		candlestick, err := it.source.Next()
+		if err != nil {
+			return err
+		}
+		it.buffer = append(it.buffer, candlestick)
+		it.nextSourceTs = candlestick.Timestamp + it.sourceInterval
+	}
+	return nil
+}
+
+// closedBy returns how many candlesticks in the buffer had closed by ts.
+func (it *indicatorIterator) closedBy(ts int) int {
+	closed := 0
+	for closed < len(it.buffer) && it.buffer[closed].Timestamp+it.sourceInterval <= ts {
+		closed++
+	}
+	return closed
+}
+
+// valueAt computes the indicator from the source candlesticks that had closed by ts.
+func (it *indicatorIterator) valueAt(ts int) (common.JSONFloat64, error) {
+	closed := it.closedBy(ts)
+	if closed < it.candlesticks {
+		at := time.Unix(int64(ts), 0).UTC().Format(time.RFC3339)
+		return 0, fmt.Errorf("%w: %v needs %v candlesticks before %v but there are only %v", common.ErrOutOfTicks, it.operand.Str, it.candlesticks, at, closed)
+	}
+	return common.JSONFloat64(it.value(it.buffer[closed-it.candlesticks : closed])), nil
+}
+
+// pruneBuffer drops the source candlesticks that are no longer needed for computing the next candlesticks.
+func (it *indicatorIterator) pruneBuffer() {
+	if closed := it.closedBy(it.ts); closed > it.candlesticks {
+		it.buffer = it.buffer[closed-it.candlesticks:]
+	}
+}
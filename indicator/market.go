@@ -0,0 +1,98 @@
+// Package indicator provides technical indicators of markets, e.g. moving averages, for evolving predictions with
+// INDICATOR operands, e.g. SMA(COIN:BINANCE:BTC-USDT,200d).
+package indicator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+)
+
+// VolumeDataSource is the interface for a provider of traded volumes of an exchange's markets. It's the same interface
+// as an exchange's, except that candlesticks have the volume traded during the candlestick as their prices. Exchanges
+// don't provide volumes as part of their candlesticks, so VOLUME indicators need one of these.
+type VolumeDataSource interface {
+	common.CandlestickProvider
+}
+
+// Market is a core.IMarket that provides iterators over indicators for INDICATOR operands, computed from the market
+// data of their coins.
+//
+// The indicator at a given time is computed from the last candlesticks of its window that had closed by then, e.g.
+// SMA(COIN:BINANCE:BTC-USDT,200d) is the average close price of the last 200 daily candlesticks. Iterators start
+// reading candlesticks early enough for the indicator to be warmed up at their start time.
+type Market struct {
+	coinMarket        core.ICoinMarket
+	volumeDataSources map[string]VolumeDataSource
+}
+
+// NewMarket constructs a Market on top of the supplied coin market, e.g. an aggregate.Market.
+func NewMarket(coinMarket core.ICoinMarket, options ...func(*Market)) Market {
+	m := Market{coinMarket: coinMarket, volumeDataSources: map[string]VolumeDataSource{}}
+
+	for _, option := range options {
+		option(&m)
+	}
+
+	return m
+}
+
+// WithVolumeDataSource plugs a traded volume data source into the Market, for VOLUME indicators on the exchange that
+// matches its Name().
+func WithVolumeDataSource(dataSource VolumeDataSource) func(*Market) {
+	return func(m *Market) {
+		m.volumeDataSources[strings.ToUpper(dataSource.Name())] = dataSource
+	}
+}
+
+// Iterator returns a market iterator for a given INDICATOR operand at a given time and for a given candlestick
+// interval.
+func (m Market) Iterator(operand core.Operand, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	if operand.Type != core.INDICATOR {
+		return nil, fmt.Errorf("%w: %v is not an INDICATOR", common.ErrInvalidMarketType, operand.Str)
+	}
+	name, window, coin := operand.Indicator, operand.Window, operand.ToMarketSource()
+	ind, ok := indicators[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", core.ErrUnknownIndicator, name)
+	}
+	n, interval, err := core.ParseIndicatorWindow(window)
+	if err != nil {
+		return nil, err
+	}
+
+	candlesticks := ind.candlesticks(n)
+	// The oldest candlestick needed at startTime is the first of the last ones that had closed by then.
+	warmUpStartTime := startTime.Truncate(interval).Add(-time.Duration(candlesticks) * interval)
+	source, err := m.sourceIterator(name, coin, warmUpStartTime, interval)
+	if err != nil {
+		return nil, err
+	}
+	return newIndicatorIterator(indicatorIteratorParams{
+		operand:             operand,
+		source:              source,
+		sourceStartTs:       common.NormalizeTimestamp(warmUpStartTime, interval, coin.Provider, false),
+		sourceInterval:      interval,
+		candlesticks:        candlesticks,
+		value:               func(cs []common.Candlestick) float64 { return ind.value(cs, n) },
+		startTime:           startTime,
+		candlestickInterval: candlestickInterval,
+	}), nil
+}
+
+// sourceIterator returns an iterator over the candlesticks an indicator is computed from, i.e. the traded volumes for
+// VOLUME, and the coin's candlesticks otherwise.
+func (m Market) sourceIterator(name string, coin common.MarketSource, startTime time.Time, interval time.Duration) (iterator.Iterator, error) {
+	if name != "VOLUME" {
+		return m.coinMarket.Iterator(coin, startTime, interval)
+	}
+	dataSource := m.volumeDataSources[strings.ToUpper(coin.Provider)]
+	if dataSource == nil {
+		return nil, fmt.Errorf("%w: there's no volume data source for '%v'", common.ErrUnsuportedCandlestickProvider, coin.Provider)
+	}
+	return iterator.NewIterator(coin, startTime, interval, nil, dataSource)
+}
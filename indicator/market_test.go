@@ -0,0 +1,163 @@
+package indicator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/markettest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketIterator(t *testing.T) {
+	var (
+		// Daily closes of BTC-USDT are 10, 20, 30, ... from 2022-01-01.
+		btc    = dailyTicks("2022-01-01 00:00:00", 10, 20, 30, 40, 50, 60, 70, 80, 90, 100)
+		volume = dailyTicks("2022-01-01 00:00:00", 1000, 2000, 3000, 4000, 5000, 6000, 7000, 8000, 9000, 10000)
+		market = NewMarket(
+			markettest.NewCoinMarket(map[string][]core.Tick{"COIN:BINANCE:BTC-USDT": btc}),
+			WithVolumeDataSource(markettest.NewTickDataSource("BINANCE", map[string][]core.Tick{"BTC": volume})),
+		)
+		sma = indicatorOperand("SMA", "3d")
+	)
+
+	tss := []struct {
+		name                string
+		operand             core.Operand
+		startTime           time.Time
+		candlestickInterval time.Duration
+		timeNow             time.Time
+		err                 error
+		expected            []common.Candlestick
+	}{
+		{
+			name:                "Minute candlesticks are computed from the daily candlesticks closed by then",
+			operand:             sma,
+			startTime:           tp("2022-01-05 00:00:00"),
+			candlestickInterval: time.Minute,
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-05 00:00:00"), OpenPrice: 30, ClosePrice: 30, LowestPrice: 30, HighestPrice: 30},
+				{Timestamp: tInt("2022-01-05 00:01:00"), OpenPrice: 30, ClosePrice: 30, LowestPrice: 30, HighestPrice: 30},
+			},
+		},
+		{
+			name:                "A candlestick changes when a daily candlestick closes",
+			operand:             sma,
+			startTime:           tp("2022-01-05 22:00:00"),
+			candlestickInterval: time.Hour,
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-05 22:00:00"), OpenPrice: 30, ClosePrice: 30, LowestPrice: 30, HighestPrice: 30},
+				{Timestamp: tInt("2022-01-05 23:00:00"), OpenPrice: 30, ClosePrice: 40, LowestPrice: 30, HighestPrice: 40},
+				{Timestamp: tInt("2022-01-06 00:00:00"), OpenPrice: 40, ClosePrice: 40, LowestPrice: 40, HighestPrice: 40},
+			},
+		},
+		{
+			name:                "Candlesticks coarser than the indicator's span every change",
+			operand:             indicatorOperand("SMA", "24h"),
+			startTime:           tp("2022-01-05 00:00:00"),
+			candlestickInterval: 24 * time.Hour,
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-05 00:00:00"), OpenPrice: 40, ClosePrice: 50, LowestPrice: 40, HighestPrice: 50},
+			},
+		},
+		{
+			name:                "Volume comes from the volume data source",
+			operand:             indicatorOperand("VOLUME", "2d"),
+			startTime:           tp("2022-01-05 00:00:00"),
+			candlestickInterval: 24 * time.Hour,
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-05 00:00:00"), OpenPrice: 7000, ClosePrice: 9000, LowestPrice: 7000, HighestPrice: 9000},
+			},
+		},
+		{
+			name:                "Fails if the market doesn't have enough history to compute the indicator",
+			operand:             sma,
+			startTime:           tp("2022-01-02 00:00:00"),
+			candlestickInterval: time.Minute,
+			err:                 common.ErrExchangeReturnedOutOfSyncTick,
+		},
+		{
+			name:                "Fails if the candlestick hasn't closed yet",
+			operand:             sma,
+			startTime:           tp("2022-01-05 00:00:00"),
+			candlestickInterval: time.Minute,
+			timeNow:             tp("2022-01-05 00:00:30"),
+			err:                 common.ErrNoNewTicksYet,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			it, err := market.Iterator(ts.operand, ts.startTime, ts.candlestickInterval)
+			require.Nil(t, err)
+			if !ts.timeNow.IsZero() {
+				it.SetTimeNowFunc(func() time.Time { return ts.timeNow })
+			}
+
+			if ts.err != nil {
+				_, err := it.Next()
+				require.ErrorIs(t, err, ts.err)
+				return
+			}
+			actual := []common.Candlestick{}
+			for range ts.expected {
+				candlestick, err := it.Next()
+				require.Nil(t, err)
+				actual = append(actual, candlestick)
+			}
+			require.Equal(t, ts.expected, actual)
+		})
+	}
+}
+
+func TestMarketIteratorStartFromNext(t *testing.T) {
+	market := NewMarket(markettest.NewCoinMarket(map[string][]core.Tick{"COIN:BINANCE:BTC-USDT": dailyTicks("2022-01-01 00:00:00", 10, 20, 30, 40, 50)}))
+
+	it, err := market.Iterator(indicatorOperand("SMA", "3d"), tp("2022-01-04 00:00:00"), 24*time.Hour)
+	require.Nil(t, err)
+	it.SetStartFromNext(true)
+
+	candlestick, err := it.Next()
+	require.Nil(t, err)
+	require.Equal(t, tInt("2022-01-05 00:00:00"), candlestick.Timestamp)
+	require.Equal(t, common.JSONFloat64(30), candlestick.OpenPrice)
+	require.Equal(t, common.JSONFloat64(40), candlestick.ClosePrice)
+}
+
+func TestMarketIteratorErrors(t *testing.T) {
+	market := NewMarket(markettest.NewCoinMarket(map[string][]core.Tick{"COIN:BINANCE:BTC-USDT": dailyTicks("2022-01-01 00:00:00", 10)}))
+
+	_, err := market.Iterator(indicatorOperand("MACD", "3d"), tp("2022-01-05 00:00:00"), time.Minute)
+	require.ErrorIs(t, err, core.ErrUnknownIndicator)
+
+	_, err = market.Iterator(indicatorOperand("SMA", "3y"), tp("2022-01-05 00:00:00"), time.Minute)
+	require.ErrorIs(t, err, core.ErrInvalidIndicatorWindow)
+
+	_, err = market.Iterator(indicatorOperand("VOLUME", "3d"), tp("2022-01-05 00:00:00"), time.Minute)
+	require.ErrorIs(t, err, common.ErrUnsuportedCandlestickProvider)
+
+	_, err = market.Iterator(core.Operand{Type: core.COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:BINANCE:BTC-USDT"}, tp("2022-01-01 00:00:00"), time.Minute)
+	require.ErrorIs(t, err, common.ErrInvalidMarketType)
+}
+
+func indicatorOperand(indicator, window string) core.Operand {
+	return core.Operand{Type: core.INDICATOR, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Indicator: indicator, Window: window, Str: fmt.Sprintf("%v(COIN:BINANCE:BTC-USDT,%v)", indicator, window)}
+}
+
+func dailyTicks(from string, values ...float64) []core.Tick {
+	ticks := []core.Tick{}
+	for i, value := range values {
+		ticks = append(ticks, core.Tick{Timestamp: tInt(from) + i*24*60*60, Value: common.JSONFloat64(value)})
+	}
+	return ticks
+}
+
+func tp(s string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04:05", s)
+	return t
+}
+
+func tInt(s string) int {
+	return int(tp(s).Unix())
+}
@@ -1,23 +1,32 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"os/user"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/marianogappa/crypto-candles/candles"
+	"github.com/marianogappa/predictions/aggregate"
 	"github.com/marianogappa/predictions/api"
 	"github.com/marianogappa/predictions/backoffice"
+	"github.com/marianogappa/predictions/core"
 	"github.com/marianogappa/predictions/daemon"
 	"github.com/marianogappa/predictions/imagebuilder"
+	"github.com/marianogappa/predictions/indicator"
+	"github.com/marianogappa/predictions/marketcap"
 	"github.com/marianogappa/predictions/metadatafetcher"
 	"github.com/marianogappa/predictions/statestorage"
 )
@@ -33,12 +42,28 @@ var (
 	flagBackOffice = flag.Bool("backoffice", false, "only run Back Office")
 	flagDaemon     = flag.Bool("daemon", false, "only run Daemon")
 	flagDaemonOnce = flag.Bool("daemononce", false, "only run Daemon once")
+
+	// The in-memory storage lets the whole engine run without a database, e.g. for trying it out locally. Nothing is
+	// persisted, so all predictions are lost when the binary stops. Overrides the PREDICTIONS_STORAGE_DRIVER env.
+	flagStorage = flag.String("storage", "", "state storage to use: postgres, sqlite or memory (default postgres)")
+
+	// Replaying a prediction evolves it from scratch & prints a trace of its evolution, without storing anything, e.g.
+	// to debug a prediction's logic on real cases. No components run.
+	flagReplay        = flag.String("replay", "", "only replay the prediction with this UUID, or this prediction JSON (or path to it)")
+	flagReplayCandles = flag.String("replaycandles", "", "path to recorded candles to replay against, rather than exchanges'")
 )
 
 func main() {
 	flag.Parse()
 	loadEnvsFromConfigJSON()
 
+	if *flagReplay != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		runReplay(ctx, *flagReplay, *flagReplayCandles)
+		return
+	}
+
 	// Parse flags and figure out what components need to run.
 	var (
 		runAll        = (!*flagAPI && !*flagBackOffice && !*flagDaemon && !*flagDaemonOnce) || (*flagAPI && *flagBackOffice && *flagDaemon)
@@ -65,21 +90,10 @@ func main() {
 			"PREDICTIONS_API_PORT. Otherwise I don't know how to reach the API.")
 	}
 
-	osCurUser, err := user.Current()
-	if err != nil {
-		log.Error().Err(err).Msgf("Failed to get current user (ignoring).")
-	}
-	postgresConf := statestorage.PostgresConf{User: osCurUser.Username, Pass: "", Port: "5432", Database: osCurUser.Username, Host: "localhost"}
-	postgresConf.User = envOrStr("PREDICTIONS_POSTGRES_USER", postgresConf.User)
-	postgresConf.Pass = envOrStr("PREDICTIONS_POSTGRES_PASS", postgresConf.Pass)
-	postgresConf.Port = envOrStr("PREDICTIONS_POSTGRES_PORT", postgresConf.Port)
-	postgresConf.Database = envOrStr("PREDICTIONS_POSTGRES_DATABASE", postgresConf.Database)
-	postgresConf.Host = envOrStr("PREDICTIONS_POSTGRES_HOST", postgresConf.Host)
-
 	// Resolve & instantiate all components.
 	var (
 		// The state storage component is responsible for durably storing predictions.
-		postgresDBStorage = statestorage.MustNewPostgresDBStateStorage(postgresConf)
+		store = mustResolveStateStorage(resolveStorageDriver(), false)
 
 		marketCacheSizes = map[time.Duration]int{
 			time.Minute:    envOrInt("PREDICTIONS_MARKET_CACHE_SIZE_1_MINUTE", 10000),
@@ -87,8 +101,10 @@ func main() {
 			24 * time.Hour: envOrInt("PREDICTIONS_MARKET_CACHE_SIZE_1_DAY", 1000),
 		}
 
-		// The market component queries all exchange APIs for market data.
-		market = candles.NewMarket(candles.WithCacheSizes(marketCacheSizes))
+		// The market component queries all exchange APIs for market data, and market cap data sources for the market
+		// capitalizations of MARKETCAP operands (see newMarket).
+		coinMarket = candles.NewMarket(candles.WithCacheSizes(marketCacheSizes))
+		market     = newMarket(coinMarket)
 
 		// The metadataFetcher component queries the Twitter/Youtube APIs for social post metadata, e.g. timestamps.
 		metadataFetcher = metadatafetcher.NewMetadataFetcher()
@@ -102,13 +118,27 @@ func main() {
 		basicAuthPass = envOrStr("PREDICTIONS_BASIC_AUTH_PASS", "admin")
 
 		// The API component is responsible for CRUDing predictions and related entities.
-		api = api.NewAPI(market, postgresDBStorage, *metadataFetcher, predictionImageBuilder, basicAuthUser, basicAuthPass)
+		api = api.NewAPI(market, store, *metadataFetcher, predictionImageBuilder, basicAuthUser, basicAuthPass)
 
 		// The Daemon component is responsible for continuously running prediction state machines against market data.
 		enableTweeting = envOrStr("PREDICTIONS_DAEMON_ENABLE_TWEETING", "") != ""
 		enableReplying = envOrStr("PREDICTIONS_DAEMON_ENABLE_REPLYING", "") != ""
 		websiteURL     = envOrStr("PREDICTIONS_WEBSITE_URL", "")
-		daemon         = daemon.NewDaemon(market, postgresDBStorage, predictionImageBuilder, enableTweeting, enableReplying, websiteURL)
+		daemon         = daemon.NewDaemon(market, store, predictionImageBuilder, enableTweeting, enableReplying, websiteURL)
+
+		// Predictions are evolved in parallel by a pool of Daemon workers, but only a few of them may read from the
+		// same exchange at the same time, to keep clear of exchange rate limits.
+		daemonConcurrency         = envOrInt("PREDICTIONS_DAEMON_CONCURRENCY", 4)
+		daemonExchangeConcurrency = envOrInt("PREDICTIONS_DAEMON_EXCHANGE_CONCURRENCY", 2)
+
+		// Many Daemons may run against the same storage, each leasing the predictions it evolves. If a Daemon crashes,
+		// others take over its predictions after its leases expire.
+		daemonLeaseTTL = envOrDur("PREDICTIONS_DAEMON_LEASE_TTL", 5*time.Minute)
+
+		// Conditions whose exchange stops providing market data (e.g. it delists the market pair) are switched to the
+		// first fallback exchange that provides it, after being stalled for a few runs. Disabled if there are none.
+		daemonFailoverExchanges = envOrList("PREDICTIONS_DAEMON_FAILOVER_EXCHANGES", nil)
+		daemonFailoverAfterRuns = envOrInt("PREDICTIONS_DAEMON_FAILOVER_AFTER_RUNS", 5)
 
 		// The BackOffice component is a UI for admins to maintain the predictions system.
 		backOffice = backoffice.NewBackOfficeUI(files, basicAuthUser, basicAuthPass)
@@ -118,35 +148,131 @@ func main() {
 		apiURL         = envOrStr("PREDICTIONS_API_URL", fmt.Sprintf("http://0.0.0.0:%v", apiPort))
 		backOfficePort = envOrInt("PREDICTIONS_BACKOFFICE_PORT", 1234)
 		daemonDuration = envOrDur("PREDICTIONS_DAEMON_DURATION", 60*time.Second)
+
+		// On SIGINT/SIGTERM, components get this long to drain requests & store in-flight work before the binary exits.
+		shutdownTimeout = envOrDur("PREDICTIONS_SHUTDOWN_TIMEOUT", 30*time.Second)
 	)
 
+	daemon.SetConcurrency(daemonConcurrency, daemonExchangeConcurrency)
+	daemon.SetLeaseTTL(daemonLeaseTTL)
+	daemon.SetFailover(daemonFailoverExchanges, daemonFailoverAfterRuns)
+
 	if os.Getenv("PREDICTIONS_DEBUG") != "" {
-		postgresDBStorage.SetDebug(true)
+		store.SetDebug(true)
 		backOffice.SetDebug(true)
 		api.SetDebug(true)
-		market.SetDebug(true)
+		coinMarket.SetDebug(true)
+	}
+
+	// Run all components until a SIGINT/SIGTERM is received.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if runDaemonOnce {
+		daemon.Run(ctx, int(time.Now().Unix()))
+		return
 	}
 
-	// Run all components.
+	var wg sync.WaitGroup
 	if runAPI {
-		go api.MustBlockinglyListenAndServe(apiURL)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			api.MustBlockinglyListenAndServe(ctx, apiURL, shutdownTimeout)
+		}()
 	}
 
 	if runBackOffice {
-		go backOffice.MustBlockinglyServe(backOfficePort, apiURL)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			backOffice.MustBlockinglyServe(ctx, backOfficePort, apiURL, shutdownTimeout)
+		}()
 	}
 
-	if !runDaemonOnce && runDaemon {
-		go daemon.BlockinglyRunEvery(daemonDuration)
+	if runDaemon {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			daemon.BlockinglyRunEvery(ctx, daemonDuration)
+		}()
 	}
 
-	if runDaemonOnce {
-		daemon.Run(int(time.Now().Unix()))
-	} else {
-		select {}
+	<-ctx.Done()
+	stop()
+	log.Info().Msgf("Shutting down; waiting up to %v for components to finish (signal again to exit immediately)...", shutdownTimeout)
+
+	allDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+	select {
+	case <-allDone:
+		log.Info().Msg("Shut down cleanly.")
+	case <-time.After(shutdownTimeout):
+		log.Fatal().Msgf("Components didn't finish within PREDICTIONS_SHUTDOWN_TIMEOUT (%v); exiting anyway.", shutdownTimeout)
 	}
 }
 
+type debuggableStateStorage interface {
+	statestorage.StateStorage
+	SetDebug(debug bool)
+}
+
+func resolveStorageDriver() string {
+	if *flagStorage != "" {
+		return *flagStorage
+	}
+	return envOrStr("PREDICTIONS_STORAGE_DRIVER", "postgres")
+}
+
+// mustResolveStateStorage resolves the state storage. If readOnly, it leaves the storage as it is, e.g. it doesn't run
+// migrations.
+func mustResolveStateStorage(storage string, readOnly bool) debuggableStateStorage {
+	switch storage {
+	case "sqlite":
+		options := []func(*statestorage.SQLiteDBStateStorage){}
+		if readOnly {
+			options = append(options, statestorage.WithReadOnlySQLite())
+		}
+		return statestorage.MustNewSQLiteDBStateStorage(envOrStr("PREDICTIONS_SQLITE_PATH", "predictions.db"), options...)
+	case "memory":
+		log.Info().Msg("Using in-memory state storage. Nothing will be persisted!")
+		return statestorage.NewMemoryStateStorage()
+	case "postgres":
+		osCurUser, err := user.Current()
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to get current user (ignoring).")
+		}
+		postgresConf := statestorage.PostgresConf{User: osCurUser.Username, Pass: "", Port: "5432", Database: osCurUser.Username, Host: "localhost"}
+		postgresConf.User = envOrStr("PREDICTIONS_POSTGRES_USER", postgresConf.User)
+		postgresConf.Pass = envOrStr("PREDICTIONS_POSTGRES_PASS", postgresConf.Pass)
+		postgresConf.Port = envOrStr("PREDICTIONS_POSTGRES_PORT", postgresConf.Port)
+		postgresConf.Database = envOrStr("PREDICTIONS_POSTGRES_DATABASE", postgresConf.Database)
+		postgresConf.Host = envOrStr("PREDICTIONS_POSTGRES_HOST", postgresConf.Host)
+		options := []func(*statestorage.PostgresDBStateStorage){}
+		if readOnly {
+			options = append(options, statestorage.WithReadOnlyPostgres())
+		}
+		return statestorage.MustNewPostgresDBStateStorage(postgresConf, options...)
+	default:
+		log.Fatal().Msgf("Unknown -storage %v. Supported storages are: postgres, sqlite, memory.", storage)
+		return nil
+	}
+}
+
+// newMarket constructs the market on top of the exchanges' coin market. Operands with aggregate providers (e.g.
+// COIN:ANY:BTC-USDT) combine the market data of several exchanges, INDICATOR operands are computed from the exchanges'
+// market data (or Binance's traded volumes, for VOLUME), and MARKETCAP operands come from the market cap data sources.
+func newMarket(coinMarket core.ICoinMarket) core.Market {
+	aggregateMarket := aggregate.NewMarket(coinMarket)
+	return core.NewMarket(aggregateMarket,
+		core.WithOperandMarket(core.MARKETCAP, marketcap.NewMarket(marketcap.WithDataSource(marketcap.NewMessari("")))),
+		core.WithOperandMarket(core.INDICATOR, indicator.NewMarket(aggregateMarket, indicator.WithVolumeDataSource(indicator.NewBinanceVolume()))),
+	)
+}
+
 func envOrStr(env, or string) string {
 	s := os.Getenv(env)
 	if s == "" {
@@ -170,6 +296,20 @@ func envOrDur(s string, or time.Duration) time.Duration {
 	return d
 }
 
+// envOrList parses a comma-separated list, e.g. "KUCOIN,COINBASE", upper-casing its items.
+func envOrList(s string, or []string) []string {
+	list := []string{}
+	for _, item := range strings.Split(os.Getenv(s), ",") {
+		if item = strings.ToUpper(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return or
+	}
+	return list
+}
+
 func loadEnvsFromConfigJSON() {
 	file, err := ioutil.ReadFile("config.json")
 	if err != nil {
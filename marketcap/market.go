@@ -0,0 +1,73 @@
+// Package marketcap provides the market capitalizations of crypto assets, for evolving predictions with MARKETCAP
+// operands, e.g. MARKETCAP:MESSARI:BTC.
+//
+// Exchanges don't provide market capitalizations, so they come from separate data sources. A Market puts them behind
+// the same core.IMarket interface as the exchanges (see core.WithOperandMarket), so the rest of the engine doesn't
+// need to tell them apart.
+package marketcap
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/cache"
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+)
+
+// DataSource is the interface for a provider of market capitalizations, e.g. Messari. It's the same interface as an
+// exchange's, except that candlesticks are of the market capitalization of the marketSource's BaseAsset (its
+// QuoteAsset is always empty, and its Type is UNSUPPORTED, see core.Operand.ToMarketSource).
+type DataSource interface {
+	common.CandlestickProvider
+}
+
+// Market is a core.IMarket that provides iterators over market capitalizations for MARKETCAP operands.
+type Market struct {
+	dataSources map[string]DataSource
+	cache       *cache.MemoryCache
+}
+
+// NewMarket constructs a Market with the supplied options, e.g. WithDataSource.
+func NewMarket(options ...func(*Market)) Market {
+	m := Market{dataSources: map[string]DataSource{}}
+
+	for _, option := range options {
+		option(&m)
+	}
+	if m.cache == nil {
+		m.cache = cache.NewMemoryCache(map[time.Duration]int{time.Minute: 10000, time.Hour: 1000, 24 * time.Hour: 1000})
+	}
+
+	return m
+}
+
+// WithDataSource plugs a market capitalization data source into the Market, for MARKETCAP operands whose provider
+// matches its Name().
+func WithDataSource(dataSource DataSource) func(*Market) {
+	return func(m *Market) {
+		m.dataSources[strings.ToUpper(dataSource.Name())] = dataSource
+	}
+}
+
+// WithCacheSizes configures the cache sizes of market capitalization candlesticks at construction time.
+func WithCacheSizes(cacheSizes map[time.Duration]int) func(*Market) {
+	return func(m *Market) {
+		m.cache = cache.NewMemoryCache(cacheSizes)
+	}
+}
+
+// Iterator returns a market iterator for a given MARKETCAP operand at a given time and for a given candlestick
+// interval.
+func (m Market) Iterator(operand core.Operand, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	if operand.Type != core.MARKETCAP {
+		return nil, fmt.Errorf("%w: %v is not a MARKETCAP", common.ErrInvalidMarketType, operand.Str)
+	}
+	dataSource := m.dataSources[strings.ToUpper(operand.Provider)]
+	if dataSource == nil {
+		return nil, fmt.Errorf("%w: the '%v' market cap provider is not supported", common.ErrUnsuportedCandlestickProvider, operand.Provider)
+	}
+	return iterator.NewIterator(operand.ToMarketSource(), startTime, candlestickInterval, m.cache, dataSource)
+}
@@ -0,0 +1,49 @@
+package marketcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/markettest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketIterator(t *testing.T) {
+	var (
+		fixture = markettest.NewTickDataSource("MESSARI", map[string][]core.Tick{
+			"BTC": {{Timestamp: tInt("2022-01-01 00:00:00"), Value: 1000}, {Timestamp: tInt("2022-01-01 00:02:30"), Value: 2000}},
+		})
+		market    = NewMarket(WithDataSource(fixture))
+		startTime = tp("2022-01-01 00:00:00")
+		btcCap    = core.Operand{Type: core.MARKETCAP, Provider: "MESSARI", BaseAsset: "BTC", Str: "MARKETCAP:MESSARI:BTC"}
+	)
+
+	it, err := market.Iterator(btcCap, startTime, time.Minute)
+	require.Nil(t, err)
+	var (
+		candlestick common.Candlestick
+		closes      = []common.JSONFloat64{}
+	)
+	for it.Scan(&candlestick) {
+		closes = append(closes, candlestick.ClosePrice)
+	}
+	require.ErrorIs(t, it.Error(), common.ErrOutOfTicks)
+	require.Equal(t, []common.JSONFloat64{1000, 1000, 2000}, closes)
+
+	_, err = market.Iterator(core.Operand{Type: core.MARKETCAP, Provider: "COINGECKO", BaseAsset: "BTC", Str: "MARKETCAP:COINGECKO:BTC"}, startTime, time.Minute)
+	require.ErrorIs(t, err, common.ErrUnsuportedCandlestickProvider)
+
+	_, err = market.Iterator(core.Operand{Type: core.COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT", Str: "COIN:BINANCE:BTC-USDT"}, startTime, time.Minute)
+	require.ErrorIs(t, err, common.ErrInvalidMarketType)
+}
+
+func tp(s string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04:05", s)
+	return t
+}
+
+func tInt(s string) int {
+	return int(tp(s).Unix())
+}
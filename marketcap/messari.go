@@ -0,0 +1,182 @@
+package marketcap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/request"
+)
+
+// Messari is a DataSource of the market capitalizations on https://messari.io, for MARKETCAP:MESSARI operands. The
+// market capitalization is the outstanding one, in USD.
+//
+// Messari has no market capitalizations of a minute, so finer candlesticks than its 5 minute ones carry its value
+// forward, e.g. the 1 minute candlesticks of 10:00 to 10:04 all have the market capitalization of 10:00.
+type Messari struct {
+	apiKey    string
+	apiURL    string
+	debug     bool
+	lock      sync.Mutex
+	requester common.RequesterWithRetry
+}
+
+// NewMessari constructs a Messari. Requests are authenticated with the PREDICTIONS_MESSARI_API_KEY environment
+// variable if set, which is optional but raises Messari's rate limits.
+func NewMessari(apiURL string) *Messari {
+	if apiURL == "" {
+		apiURL = "https://data.messari.io/api/v1"
+	}
+	m := &Messari{apiKey: os.Getenv("PREDICTIONS_MESSARI_API_KEY"), apiURL: apiURL}
+	m.requester = common.NewRequesterWithRetry(
+		m.requestMarketCaps,
+		common.RetryStrategy{Attempts: 3, FirstSleepTime: 1 * time.Second, SleepTimeMultiplier: 2.0},
+		&m.debug,
+	)
+	return m
+}
+
+// RequestCandlesticks requests the candlesticks of the market capitalization of the marketSource's BaseAsset, of a
+// given candlestick interval, starting at a given time.Time.
+func (m *Messari) RequestCandlesticks(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) ([]common.Candlestick, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	candlesticks, err := m.requester.Request(marketSource.BaseAsset, "", startTime, candlestickInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	return common.PatchCandlestickHoles(candlesticks, int(startTime.Unix()), int(candlestickInterval/time.Second)), nil
+}
+
+// Patience is zero, because Messari returns market capitalizations as soon as their candlestick closes.
+func (m *Messari) Patience() time.Duration { return 0 }
+
+// Name is the name of the MARKETCAP provider, i.e. MESSARI.
+func (m *Messari) Name() string { return "MESSARI" }
+
+// SetDebug sets debug logging of Messari's requests.
+func (m *Messari) SetDebug(debug bool) {
+	m.debug = debug
+}
+
+// messariIntervals are the intervals of Messari's time series.
+var messariIntervals = map[time.Duration]string{
+	5 * time.Minute:  "5m",
+	15 * time.Minute: "15m",
+	30 * time.Minute: "30m",
+	1 * time.Hour:    "1h",
+	24 * time.Hour:   "1d",
+}
+
+// messariMaxValues is how many values are requested per request, below Messari's limit of 2016.
+const messariMaxValues = 1000
+
+// messariInterval returns the interval of Messari's time series that candlesticks of the supplied interval are made
+// of, i.e. the same one, or 5 minutes for finer ones that divide it.
+func messariInterval(candlestickInterval time.Duration) (time.Duration, bool) {
+	if _, ok := messariIntervals[candlestickInterval]; ok {
+		return candlestickInterval, true
+	}
+	if candlestickInterval > 0 && candlestickInterval < 5*time.Minute && (5*time.Minute)%candlestickInterval == 0 {
+		return 5 * time.Minute, true
+	}
+	return 0, false
+}
+
+type messariResponse struct {
+	Status struct {
+		ErrorCode    int    `json:"error_code"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"status"`
+	Data struct {
+		Values [][]float64 `json:"values"`
+	} `json:"data"`
+}
+
+// messariResult is the result of a request to Messari, with its error as a common.CandleReqError, like an exchange's.
+type messariResult struct {
+	candlesticks []common.Candlestick
+	err          error
+}
+
+func parseMessariError(err error) messariResult {
+	return messariResult{err: common.CandleReqError{Err: fmt.Errorf("%w: %v", common.ErrExecutingRequest, err)}}
+}
+
+// requestMarketCaps requests a time series of Messari's market capitalizations, e.g.
+// https://data.messari.io/api/v1/assets/btc/metrics/mcap.out/time-series?start=2022-01-01T00:00:00Z&interval=1d
+//
+// Each value is [timestamp in millis, market capitalization].
+func (m *Messari) requestMarketCaps(asset string, _ string, startTime time.Time, candlestickInterval time.Duration) ([]common.Candlestick, error) {
+	sourceInterval, ok := messariInterval(candlestickInterval)
+	if !ok {
+		return nil, common.CandleReqError{IsNotRetryable: true, Err: common.ErrUnsupportedCandlestickInterval}
+	}
+	sourceStartTime := startTime.UTC().Truncate(sourceInterval)
+
+	headers := map[string]string{"Accept": "application/json"}
+	if m.apiKey != "" {
+		headers["x-messari-api-key"] = m.apiKey
+	}
+	req := request.Request[messariResponse, messariResult]{
+		BaseURL: m.apiURL,
+		Path:    fmt.Sprintf("assets/%v/metrics/mcap.out/time-series", strings.ToLower(asset)),
+		QueryString: map[string][]string{
+			"start":    {sourceStartTime.Format(time.RFC3339)},
+			"end":      {sourceStartTime.Add(messariMaxValues * sourceInterval).Format(time.RFC3339)},
+			"interval": {messariIntervals[sourceInterval]},
+		},
+		Headers: headers,
+		ParseResponse: func(resp messariResponse) (messariResult, error) {
+			return messariResponseToResult(resp, sourceInterval, candlestickInterval), nil
+		},
+		ParseError: parseMessariError,
+	}
+
+	result := request.MakeRequest(req, m.debug)
+	if result.err != nil {
+		return nil, result.err
+	}
+	return result.candlesticks, nil
+}
+
+// messariResponseToResult makes the candlesticks of a time series of Messari's market capitalizations, splitting each
+// value into as many candlesticks as fit in its interval.
+func messariResponseToResult(resp messariResponse, sourceInterval, candlestickInterval time.Duration) messariResult {
+	switch {
+	case resp.Status.ErrorCode == http.StatusNotFound:
+		return messariResult{err: common.CandleReqError{IsNotRetryable: true, Code: resp.Status.ErrorCode, Err: common.ErrInvalidMarketPair}}
+	case resp.Status.ErrorCode == http.StatusTooManyRequests:
+		return messariResult{err: common.CandleReqError{Code: resp.Status.ErrorCode, Err: common.ErrRateLimit, RetryAfter: time.Minute}}
+	case resp.Status.ErrorCode != 0:
+		return messariResult{err: common.CandleReqError{Code: resp.Status.ErrorCode, Err: errors.New(resp.Status.ErrorMessage)}}
+	case len(resp.Data.Values) == 0:
+		// There are no market caps yet for the interval that's ongoing, so there's no point in retrying right away.
+		return messariResult{err: common.CandleReqError{IsNotRetryable: true, Err: common.ErrOutOfCandlesticks}}
+	}
+
+	candlesticks := []common.Candlestick{}
+	for i, value := range resp.Data.Values {
+		if len(value) < 2 {
+			return messariResult{err: common.CandleReqError{Err: fmt.Errorf("%w: value %v has len %v < 2", common.ErrInvalidJSONResponse, i, len(value))}}
+		}
+		ts := int(value[0] / 1000)
+		for split := 0; split < int(sourceInterval/candlestickInterval); split++ {
+			candlesticks = append(candlesticks, common.Candlestick{
+				Timestamp:    ts + split*int(candlestickInterval/time.Second),
+				OpenPrice:    common.JSONFloat64(value[1]),
+				ClosePrice:   common.JSONFloat64(value[1]),
+				LowestPrice:  common.JSONFloat64(value[1]),
+				HighestPrice: common.JSONFloat64(value[1]),
+			})
+		}
+	}
+	return messariResult{candlesticks: candlesticks}
+}
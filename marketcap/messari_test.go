@@ -0,0 +1,102 @@
+package marketcap
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessariRequestCandlesticks(t *testing.T) {
+	tss := []struct {
+		name             string
+		body             string
+		startTime        time.Time
+		interval         time.Duration
+		expectedInterval string
+		expectedStart    string
+		err              error
+		expected         []common.Candlestick
+	}{
+		{
+			name:             "Daily candlesticks",
+			body:             `{"status": {"elapsed": 10}, "data": {"values": [[1640995200000, 883000000000], [1641081600000, 900000000000]]}}`,
+			startTime:        tp("2022-01-01 00:00:00"),
+			interval:         24 * time.Hour,
+			expectedInterval: "1d",
+			expectedStart:    "2022-01-01T00:00:00Z",
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-01 00:00:00"), OpenPrice: 883000000000, ClosePrice: 883000000000, LowestPrice: 883000000000, HighestPrice: 883000000000},
+				{Timestamp: tInt("2022-01-02 00:00:00"), OpenPrice: 900000000000, ClosePrice: 900000000000, LowestPrice: 900000000000, HighestPrice: 900000000000},
+			},
+		},
+		{
+			name:             "Minute candlesticks carry the 5 minute market cap forward",
+			body:             `{"status": {"elapsed": 10}, "data": {"values": [[1640995200000, 1000], [1640995500000, 2000]]}}`,
+			startTime:        tp("2022-01-01 00:03:00"),
+			interval:         time.Minute,
+			expectedInterval: "5m",
+			expectedStart:    "2022-01-01T00:00:00Z",
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-01 00:03:00"), OpenPrice: 1000, ClosePrice: 1000, LowestPrice: 1000, HighestPrice: 1000},
+				{Timestamp: tInt("2022-01-01 00:04:00"), OpenPrice: 1000, ClosePrice: 1000, LowestPrice: 1000, HighestPrice: 1000},
+				{Timestamp: tInt("2022-01-01 00:05:00"), OpenPrice: 2000, ClosePrice: 2000, LowestPrice: 2000, HighestPrice: 2000},
+				{Timestamp: tInt("2022-01-01 00:06:00"), OpenPrice: 2000, ClosePrice: 2000, LowestPrice: 2000, HighestPrice: 2000},
+				{Timestamp: tInt("2022-01-01 00:07:00"), OpenPrice: 2000, ClosePrice: 2000, LowestPrice: 2000, HighestPrice: 2000},
+				{Timestamp: tInt("2022-01-01 00:08:00"), OpenPrice: 2000, ClosePrice: 2000, LowestPrice: 2000, HighestPrice: 2000},
+				{Timestamp: tInt("2022-01-01 00:09:00"), OpenPrice: 2000, ClosePrice: 2000, LowestPrice: 2000, HighestPrice: 2000},
+			},
+		},
+		{
+			name:             "Unknown asset",
+			body:             `{"status": {"elapsed": 10, "error_code": 404, "error_message": "Asset with key = 'btc' not found."}}`,
+			startTime:        tp("2022-01-01 00:00:00"),
+			interval:         24 * time.Hour,
+			expectedInterval: "1d",
+			expectedStart:    "2022-01-01T00:00:00Z",
+			err:              common.ErrInvalidMarketPair,
+		},
+		{
+			name:             "No market caps yet",
+			body:             `{"status": {"elapsed": 10}, "data": {"values": null}}`,
+			startTime:        tp("2022-01-01 00:00:00"),
+			interval:         24 * time.Hour,
+			expectedInterval: "1d",
+			expectedStart:    "2022-01-01T00:00:00Z",
+			err:              common.ErrOutOfCandlesticks,
+		},
+		{
+			name:      "Unsupported interval",
+			startTime: tp("2022-01-01 00:00:00"),
+			interval:  7 * time.Minute,
+			err:       common.ErrUnsupportedCandlestickInterval,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			t.Setenv("PREDICTIONS_MESSARI_API_KEY", "key")
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/assets/btc/metrics/mcap.out/time-series", r.URL.Path)
+				require.Equal(t, ts.expectedInterval, r.URL.Query().Get("interval"))
+				require.Equal(t, ts.expectedStart, r.URL.Query().Get("start"))
+				require.Equal(t, "key", r.Header.Get("x-messari-api-key"))
+				fmt.Fprint(w, ts.body)
+			}))
+			defer server.Close()
+
+			messari := NewMessari(server.URL)
+			actual, err := messari.RequestCandlesticks(common.MarketSource{Type: common.UNSUPPORTED, Provider: "MESSARI", BaseAsset: "BTC"}, ts.startTime, ts.interval)
+			if ts.err != nil {
+				// Like exchanges' errors, they're wrapped in a common.CandleReqError for the iterator.
+				require.IsType(t, common.CandleReqError{}, err)
+				err = err.(common.CandleReqError).Err
+			}
+			require.ErrorIs(t, err, ts.err)
+			require.Equal(t, ts.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package markettest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/crypto-candles/candles/iterator"
+	"github.com/marianogappa/predictions/core"
+)
+
+// CoinMarket is a core.ICoinMarket with known prices of COIN markets, like an exchanges' market would have.
+type CoinMarket struct {
+	exchanges map[string]map[string]*TickDataSource
+}
+
+// NewCoinMarket constructs a CoinMarket with the known prices of each market, e.g.
+//
+// NewCoinMarket(map[string][]core.Tick{"COIN:BINANCE:BTC-USDT": {{Timestamp: 1640995200, Value: 46216.93}}})
+func NewCoinMarket(prices map[string][]core.Tick) CoinMarket {
+	m := CoinMarket{exchanges: map[string]map[string]*TickDataSource{}}
+	for key, ticks := range prices {
+		parts := strings.Split(strings.ToUpper(key), ":")
+		if len(parts) != 3 || parts[0] != "COIN" || !strings.Contains(parts[2], "-") {
+			panic(fmt.Sprintf("invalid market %v: expected e.g. COIN:BINANCE:BTC-USDT", key))
+		}
+		exchange, pair := parts[1], parts[2]
+		if m.exchanges[exchange] == nil {
+			m.exchanges[exchange] = map[string]*TickDataSource{}
+		}
+		m.exchanges[exchange][pair] = NewTickDataSource(exchange, map[string][]core.Tick{strings.Split(pair, "-")[0]: ticks})
+	}
+	return m
+}
+
+// Iterator returns a market iterator over the known prices of the market source.
+//
+// * Fails with ErrUnsuportedCandlestickProvider if there are no known prices on the market source's exchange.
+// * Fails with ErrInvalidMarketPair if there are no known prices of the market source's pair on its exchange.
+func (m CoinMarket) Iterator(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) (iterator.Iterator, error) {
+	if marketSource.Type != common.COIN {
+		return nil, common.ErrInvalidMarketType
+	}
+	pairs, ok := m.exchanges[strings.ToUpper(marketSource.Provider)]
+	if !ok {
+		return nil, common.ErrUnsuportedCandlestickProvider
+	}
+	dataSource, ok := pairs[strings.ToUpper(fmt.Sprintf("%v-%v", marketSource.BaseAsset, marketSource.QuoteAsset))]
+	if !ok {
+		return nil, common.ErrInvalidMarketPair
+	}
+	return iterator.NewIterator(marketSource, startTime, candlestickInterval, nil, dataSource)
+}
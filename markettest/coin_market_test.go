@@ -0,0 +1,39 @@
+package markettest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoinMarketIterator(t *testing.T) {
+	var (
+		market = NewCoinMarket(map[string][]core.Tick{
+			"COIN:BINANCE:BTC-USDT": {{Timestamp: tInt("2022-01-01 00:00:00"), Value: 40000}, {Timestamp: tInt("2022-01-01 00:01:00"), Value: 41000}},
+			"COIN:BINANCE:BTC-USDC": {{Timestamp: tInt("2022-01-01 00:00:00"), Value: 39000}},
+		})
+		btcUSDT   = common.MarketSource{Type: common.COIN, Provider: "BINANCE", BaseAsset: "BTC", QuoteAsset: "USDT"}
+		startTime = tp("2022-01-01 00:00:00")
+	)
+
+	it, err := market.Iterator(btcUSDT, startTime, time.Minute)
+	require.Nil(t, err)
+	var (
+		candlestick common.Candlestick
+		closes      = []common.JSONFloat64{}
+	)
+	for it.Scan(&candlestick) {
+		closes = append(closes, candlestick.ClosePrice)
+	}
+	require.ErrorIs(t, it.Error(), common.ErrOutOfTicks)
+	require.Equal(t, []common.JSONFloat64{40000, 41000}, closes)
+
+	_, err = market.Iterator(common.MarketSource{Type: common.COIN, Provider: "BINANCE", BaseAsset: "ETH", QuoteAsset: "USDT"}, startTime, time.Minute)
+	require.ErrorIs(t, err, common.ErrInvalidMarketPair)
+
+	_, err = market.Iterator(common.MarketSource{Type: common.COIN, Provider: "KUCOIN", BaseAsset: "BTC", QuoteAsset: "USDT"}, startTime, time.Minute)
+	require.ErrorIs(t, err, common.ErrUnsuportedCandlestickProvider)
+}
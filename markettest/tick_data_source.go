@@ -0,0 +1,100 @@
+// Package markettest provides market data for tests, so that predictions can be evolved against known prices and
+// market capitalizations rather than against exchanges' and market cap data sources' APIs.
+package markettest
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+)
+
+// tickMaxCandlesticks is how many candlesticks a TickDataSource returns per request, like an exchange's page.
+const tickMaxCandlesticks = 1000
+
+// TickDataSource is a common.CandlestickProvider backed by a fixed set of known values per asset, rather than by an
+// API, e.g. a marketcap.DataSource with known market capitalizations.
+//
+// Between two known values, the value is that of the earlier one, so candlesticks of any interval can be provided.
+type TickDataSource struct {
+	name   string
+	values map[string][]core.Tick
+}
+
+// NewTickDataSource constructs a TickDataSource named as the provider it stands for (e.g. "MESSARI"), with the known
+// values of each asset (e.g. "BTC").
+func NewTickDataSource(name string, values map[string][]core.Tick) *TickDataSource {
+	ds := &TickDataSource{name: strings.ToUpper(name), values: map[string][]core.Tick{}}
+	for asset, ticks := range values {
+		sorted := make([]core.Tick, len(ticks))
+		copy(sorted, ticks)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+		ds.values[strings.ToUpper(asset)] = sorted
+	}
+	return ds
+}
+
+// RequestCandlesticks returns the candlesticks of the values of the marketSource's BaseAsset, starting from startTime
+// rounded to the next candlestickInterval.
+//
+// * Fails with ErrInvalidMarketPair if there are no known values for the asset.
+// * Fails with ErrOutOfTicks if startTime is after the last known value.
+func (ds *TickDataSource) RequestCandlesticks(marketSource common.MarketSource, startTime time.Time, candlestickInterval time.Duration) ([]common.Candlestick, error) {
+	ticks := ds.values[strings.ToUpper(marketSource.BaseAsset)]
+	if len(ticks) == 0 {
+		return nil, common.ErrInvalidMarketPair
+	}
+
+	var (
+		intervalSecs = int(candlestickInterval / time.Second)
+		startTs      = common.NormalizeTimestamp(startTime, candlestickInterval, ds.name, false)
+		lastTs       = ticks[len(ticks)-1].Timestamp
+	)
+	if startTs > lastTs {
+		return nil, common.ErrOutOfTicks
+	}
+
+	// Values before the first known one are unknown, so candlesticks start after it.
+	if startTs < ticks[0].Timestamp {
+		startTs = common.NormalizeTimestamp(time.Unix(int64(ticks[0].Timestamp), 0), candlestickInterval, ds.name, false)
+	}
+
+	candlesticks := []common.Candlestick{}
+	for ts := startTs; ts <= lastTs && len(candlesticks) < tickMaxCandlesticks; ts += intervalSecs {
+		candlesticks = append(candlesticks, candlestickAt(ticks, ts, intervalSecs))
+	}
+	return candlesticks, nil
+}
+
+// candlestickAt builds the candlestick of the interval starting at ts, which must not be before the first tick.
+func candlestickAt(ticks []core.Tick, ts, intervalSecs int) common.Candlestick {
+	// Index of the last tick at or before ts, i.e. the value when the candlestick opens.
+	i := sort.Search(len(ticks), func(i int) bool { return ticks[i].Timestamp > ts }) - 1
+
+	open := float64(ticks[i].Value)
+	candlestick := common.Candlestick{
+		Timestamp:    ts,
+		OpenPrice:    common.JSONFloat64(open),
+		ClosePrice:   common.JSONFloat64(open),
+		LowestPrice:  common.JSONFloat64(open),
+		HighestPrice: common.JSONFloat64(open),
+	}
+	for _, tick := range ticks[i+1:] {
+		if tick.Timestamp >= ts+intervalSecs {
+			break
+		}
+		candlestick.ClosePrice = tick.Value
+		candlestick.LowestPrice = common.JSONFloat64(math.Min(float64(candlestick.LowestPrice), float64(tick.Value)))
+		candlestick.HighestPrice = common.JSONFloat64(math.Max(float64(candlestick.HighestPrice), float64(tick.Value)))
+	}
+	return candlestick
+}
+
+// Patience is zero, because values are known beforehand.
+func (ds *TickDataSource) Patience() time.Duration { return 0 }
+
+// Name is the uppercase name of the provider this TickDataSource stands for, e.g. MESSARI.
+func (ds *TickDataSource) Name() string { return ds.name }
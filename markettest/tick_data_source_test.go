@@ -0,0 +1,96 @@
+package markettest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marianogappa/crypto-candles/candles/common"
+	"github.com/marianogappa/predictions/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTickDataSourceRequestCandlesticks(t *testing.T) {
+	fixture := NewTickDataSource("messari", map[string][]core.Tick{
+		"BTC": {
+			{Timestamp: tInt("2022-01-02 00:00:00"), Value: 1500},
+			{Timestamp: tInt("2022-01-01 00:00:00"), Value: 1000},
+			{Timestamp: tInt("2022-01-01 12:00:00"), Value: 500},
+			{Timestamp: tInt("2022-01-01 18:00:00"), Value: 800},
+		},
+	})
+	btcCap := core.Operand{Type: core.MARKETCAP, Provider: "MESSARI", BaseAsset: "BTC"}.ToMarketSource()
+
+	tss := []struct {
+		name      string
+		asset     string
+		startTime time.Time
+		interval  time.Duration
+		expected  []common.Candlestick
+		err       error
+	}{
+		{
+			name:      "daily candlesticks",
+			startTime: tp("2022-01-01 00:00:00"),
+			interval:  24 * time.Hour,
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-01 00:00:00"), OpenPrice: 1000, ClosePrice: 800, LowestPrice: 500, HighestPrice: 1000},
+				{Timestamp: tInt("2022-01-02 00:00:00"), OpenPrice: 1500, ClosePrice: 1500, LowestPrice: 1500, HighestPrice: 1500},
+			},
+		},
+		{
+			name:      "hourly candlesticks keep the last known market cap",
+			startTime: tp("2022-01-01 20:30:00"),
+			interval:  time.Hour,
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-01 21:00:00"), OpenPrice: 800, ClosePrice: 800, LowestPrice: 800, HighestPrice: 800},
+				{Timestamp: tInt("2022-01-01 22:00:00"), OpenPrice: 800, ClosePrice: 800, LowestPrice: 800, HighestPrice: 800},
+				{Timestamp: tInt("2022-01-01 23:00:00"), OpenPrice: 800, ClosePrice: 800, LowestPrice: 800, HighestPrice: 800},
+				{Timestamp: tInt("2022-01-02 00:00:00"), OpenPrice: 1500, ClosePrice: 1500, LowestPrice: 1500, HighestPrice: 1500},
+			},
+		},
+		{
+			name:      "starts at the first known market cap",
+			startTime: tp("2021-12-31 22:00:00"),
+			interval:  24 * time.Hour,
+			expected: []common.Candlestick{
+				{Timestamp: tInt("2022-01-01 00:00:00"), OpenPrice: 1000, ClosePrice: 800, LowestPrice: 500, HighestPrice: 1000},
+				{Timestamp: tInt("2022-01-02 00:00:00"), OpenPrice: 1500, ClosePrice: 1500, LowestPrice: 1500, HighestPrice: 1500},
+			},
+		},
+		{
+			name:      "after the last known market cap",
+			startTime: tp("2022-01-02 00:01:00"),
+			interval:  time.Minute,
+			err:       common.ErrOutOfTicks,
+		},
+		{
+			name:      "unknown asset",
+			asset:     "ETH",
+			startTime: tp("2022-01-01 00:00:00"),
+			interval:  time.Minute,
+			err:       common.ErrInvalidMarketPair,
+		},
+	}
+	for _, ts := range tss {
+		t.Run(ts.name, func(t *testing.T) {
+			marketSource := btcCap
+			if ts.asset != "" {
+				marketSource.BaseAsset = ts.asset
+			}
+			candlesticks, err := fixture.RequestCandlesticks(marketSource, ts.startTime, ts.interval)
+			require.ErrorIs(t, err, ts.err)
+			if ts.err == nil {
+				require.Equal(t, ts.expected, candlesticks)
+			}
+		})
+	}
+}
+
+func tp(s string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04:05", s)
+	return t
+}
+
+func tInt(s string) int {
+	return int(tp(s).Unix())
+}
@@ -2,6 +2,7 @@ package printer
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -21,15 +22,56 @@ func printCondition(c core.Condition, ignoreFromTs bool) string {
 			temporalPart = parseDuration(c.ToDuration, time.Unix(int64(c.FromTs), 0))
 		}
 	}
+	if c.SustainedFor != "" {
+		temporalPart = fmt.Sprintf("%v ", parseSustainedFor(c.SustainedFor, time.Unix(int64(c.FromTs), 0)))
+	}
+
+	if evaluationPart := printEvaluationMode(c); evaluationPart != "" {
+		temporalPart = fmt.Sprintf("%v %v", evaluationPart, temporalPart)
+	}
 
 	suffix := ""
 	if len(c.Assumed) > 0 {
 		suffix = fmt.Sprintf("(%v assumed from prediction text)", strings.Join(c.Assumed, ", "))
 	}
 	if c.Operator == "BETWEEN" {
-		return fmt.Sprintf("%v BETWEEN %v AND %v %v%v", legacyParseOperand(c.Operands[0]), legacyParseOperand(c.Operands[1]), legacyParseOperand(c.Operands[2]), temporalPart, suffix)
+		return fmt.Sprintf("%v BETWEEN %v AND %v %v%v", legacyParseOperand(c.Operands[0]), legacyParseConditionOperand(c, c.Operands[1]), legacyParseConditionOperand(c, c.Operands[2]), temporalPart, suffix)
+	}
+	return fmt.Sprintf("%v %v %v %v%v", legacyParseOperand(c.Operands[0]), c.Operator, legacyParseConditionOperand(c, c.Operands[1]), temporalPart, suffix)
+}
+
+// parseConditionOperand is like parseOperand, but percentages also show the price they resolve to given the
+// Condition's Baseline, e.g. "$39k (+30%)", once the Baseline is resolved.
+func parseConditionOperand(c core.Condition, op core.Operand, useDollarSign bool) (string, bool) {
+	if op.Type != core.PERCENT || c.Baseline == 0 {
+		return parseOperand(op, useDollarSign)
 	}
-	return fmt.Sprintf("%v %v %v %v%v", legacyParseOperand(c.Operands[0]), c.Operator, legacyParseOperand(c.Operands[1]), temporalPart, suffix)
+	value, _ := op.Value(nil, c.Baseline)
+	price := parseNumber(core.JSONFloat64(math.Round(value*100)/100), useDollarSign)
+	return fmt.Sprintf("%v (%v)", price, op.Str), useDollarSign
+}
+
+func legacyParseConditionOperand(c core.Condition, op core.Operand) string {
+	s, _ := parseConditionOperand(c, op, false)
+	return s
+}
+
+// printEvaluationMode describes which prices are evaluated against the Condition, e.g. "on a weekly close" or "for 3
+// consecutive daily closes", or returns an empty string for WICK Conditions, where any price is.
+func printEvaluationMode(c core.Condition) string {
+	candleInterval := c.CandleInterval
+	if name, ok := candleIntervalNames[c.CandleInterval]; ok {
+		candleInterval = name
+	}
+	switch {
+	case c.EvaluationMode == core.CLOSE && c.SustainedFor != "":
+		return fmt.Sprintf("on every %v close", candleInterval)
+	case c.EvaluationMode == core.CLOSE, c.EvaluationMode == core.SUSTAINED && c.SustainedCandles == 1:
+		return fmt.Sprintf("on a %v close", candleInterval)
+	case c.EvaluationMode == core.SUSTAINED:
+		return fmt.Sprintf("for %v consecutive %v closes", c.SustainedCandles, candleInterval)
+	}
+	return ""
 }
 
 func formatTs(ts int) string {
@@ -42,6 +84,20 @@ var (
 	rxDurationMonths = regexp.MustCompile(`([0-9]+)m`)
 	rxDurationHours  = regexp.MustCompile(`([0-9]+)h`)
 
+	candleIntervalNames = map[string]string{
+		"1m":  "1-minute",
+		"5m":  "5-minute",
+		"15m": "15-minute",
+		"30m": "30-minute",
+		"1h":  "hourly",
+		"2h":  "2-hour",
+		"4h":  "4-hour",
+		"6h":  "6-hour",
+		"12h": "12-hour",
+		"1d":  "daily",
+		"1w":  "weekly",
+	}
+
 	knownCoinNames = map[string]string{
 		"BTC":   "Bitcoin",
 		"XBT":   "Bitcoin",
@@ -163,12 +219,37 @@ func parseOperand(op core.Operand, useDollarSign bool) (string, bool) {
 	if op.Type == core.NUMBER {
 		return parseNumber(op.Number, useDollarSign), useDollarSign
 	}
+	if op.Type == core.PERCENT {
+		return op.Str, false
+	}
+	if op.Type == core.EXPR {
+		return op.Expr.Format(legacyParseOperand), false
+	}
+	if op.Multiplier != 0 {
+		multiplier := op.Multiplier
+		op.Multiplier = 0
+		parsed, isStableCoin := parseOperand(op, useDollarSign)
+		return fmt.Sprintf("%v × %v", multiplier, parsed), isStableCoin
+	}
+	if op.Type == core.INDICATOR {
+		coin := op
+		coin.Type = core.COIN
+		parsed, isStableCoin := parseOperand(coin, useDollarSign)
+		indicator := op.Indicator
+		if indicator == "VOLUME" {
+			indicator = "volume"
+		}
+		return fmt.Sprintf("%v %v of %v", op.Window, indicator, parsed), isStableCoin && (op.Indicator == "SMA" || op.Indicator == "EMA")
+	}
 	if op.Type == core.MARKETCAP {
+		if name := knownCoinNames[op.BaseAsset]; name != "" {
+			return fmt.Sprintf("%v's MarketCap", name), false
+		}
 		return fmt.Sprintf("%v's MarketCap", op.BaseAsset), false
 	}
 	suffix := ""
 	if op.Provider != "BINANCE" {
-		suffix = fmt.Sprintf(" (on %v)", op.Provider)
+		suffix = fmt.Sprintf(" (%v)", parseProvider(op.Provider))
 	}
 
 	baseAssetIsKnownCoin := knownCoinNames[op.BaseAsset] != ""
@@ -182,6 +263,29 @@ func parseOperand(op core.Operand, useDollarSign bool) (string, bool) {
 	return parsedMarket, quoteAssetIsStableCoin
 }
 
+// parseProvider returns where a market's price is from, e.g. "on KUCOIN", or "median of BINANCE, COINBASE & KUCOIN"
+// for aggregate providers. Those with the default exchanges don't list them.
+func parseProvider(provider string) string {
+	if !core.IsAggregateProvider(provider) {
+		return fmt.Sprintf("on %v", provider)
+	}
+	switch provider {
+	case core.AggregateAny:
+		return "on any exchange"
+	case core.AggregateMedian:
+		return "median across exchanges"
+	}
+	aggregation, exchanges, err := core.ParseAggregateProvider(provider)
+	if err != nil {
+		return fmt.Sprintf("on %v", provider)
+	}
+	listed := strings.Join(exchanges[:len(exchanges)-1], ", ") + " & " + exchanges[len(exchanges)-1]
+	if aggregation == core.AggregateAny {
+		return fmt.Sprintf("on any of %v", listed)
+	}
+	return fmt.Sprintf("median of %v", listed)
+}
+
 func legacyParseOperand(op core.Operand) string {
 	s, _ := parseOperand(op, false)
 	return s
@@ -202,6 +306,18 @@ func parseNumber(num core.JSONFloat64, useDollarSign bool) string {
 	return fmt.Sprintf("%v%v", dollarSign, num)
 }
 
+// parseSustainedFor describes how long a sustained Condition must hold, e.g. "for 2 weeks" or "until end of year".
+func parseSustainedFor(dur string, fromTime time.Time) string {
+	description := parseDuration(dur, fromTime)
+	switch {
+	case strings.HasPrefix(description, "within "):
+		return fmt.Sprintf("for %v", strings.TrimPrefix(description, "within "))
+	case strings.HasPrefix(description, "by "):
+		return fmt.Sprintf("until %v", strings.TrimPrefix(description, "by "))
+	}
+	return description
+}
+
 func parseDuration(dur string, fromTime time.Time) string {
 	dur = strings.ToLower(dur)
 	if dur == "eoy" {
@@ -27,10 +27,14 @@ func (p PredictionPrettyPrinter) String() string {
 		if p.prediction.Predict.Predict.Literal != nil && len(p.prediction.Predict.Predict.Literal.Operands) == 2 {
 			return p.predictionTypeCoinOperatorFloatDeadline()
 		}
-	// case core.PREDICTION_TYPE_COIN_WILL_RANGE:
-	// 	p.predictionTypeCoinWillRange()
+	case core.PredictionTypeCoinWillRange:
+		return p.predictionTypeCoinWillRange()
 	case core.PredictionTypeCoinWillReachBeforeItReaches:
 		p.predictionTypeCoinWillReachBeforeItReaches()
+	case core.PredictionTypeTheFlippening:
+		return p.predictionTypeTheFlippening()
+	case core.PredictionTypeCoinOperatorCoinDeadline:
+		return p.predictionTypeCoinOperatorCoinDeadline()
 	}
 
 	if p.prediction.PrePredict.Predict != nil {
@@ -42,13 +46,16 @@ func (p PredictionPrettyPrinter) String() string {
 func (p PredictionPrettyPrinter) predictionTypeCoinOperatorFloatDeadline() string {
 	cond := p.prediction.Predict.Predict.Literal
 	coin, useDollarSign := parseOperand(cond.Operands[0], false)
-	number, _ := parseOperand(cond.Operands[1], useDollarSign)
+	number, _ := parseConditionOperand(*cond, cond.Operands[1], useDollarSign)
 
 	humanToTs := time.Unix(int64(cond.ToTs), 0).Format("Jan 2, 2006")
 	temporalPart := fmt.Sprintf("by %v", humanToTs)
 	if cond.ToDuration != "" {
 		temporalPart = parseDuration(cond.ToDuration, time.Unix(int64(cond.FromTs), 0))
 	}
+	if evaluationPart := printEvaluationMode(*cond); evaluationPart != "" {
+		temporalPart = fmt.Sprintf("%v %v", evaluationPart, temporalPart)
+	}
 
 	operator := ""
 	switch cond.Operator {
@@ -61,10 +68,20 @@ func (p PredictionPrettyPrinter) predictionTypeCoinOperatorFloatDeadline() strin
 	return fmt.Sprintf("%v %v %v %v", coin, operator, number, temporalPart)
 }
 
-// func (p PredictionPrettyPrinter) predictionTypeCoinWillRange() string {
-// 	// TODO
-// 	return ""
-// }
+func (p PredictionPrettyPrinter) predictionTypeCoinWillRange() string {
+	typedPred := core.PredictionTypeCoinWillRangeWrapper{P: p.prediction}
+	cond := p.prediction.Predict.Predict.Literal
+	coin, useDollarSign := parseOperand(typedPred.Coin(), false)
+	rangeLow := parseNumber(typedPred.RangeLow(), useDollarSign)
+	rangeHigh := parseNumber(typedPred.RangeHigh(), useDollarSign)
+
+	temporalPart := parseSustainedFor(typedPred.SustainedFor(), time.Unix(int64(cond.FromTs), 0))
+	if evaluationPart := printEvaluationMode(*cond); evaluationPart != "" {
+		temporalPart = fmt.Sprintf("%v %v", evaluationPart, temporalPart)
+	}
+
+	return fmt.Sprintf("%v will range between %v and %v %v", coin, rangeLow, rangeHigh, temporalPart)
+}
 
 func (p PredictionPrettyPrinter) predictionTypeCoinWillReachBeforeItReaches() string {
 	coin := legacyParseOperand(p.prediction.Predict.Predict.Operands[0].Literal.Operands[0])
@@ -72,3 +89,41 @@ func (p PredictionPrettyPrinter) predictionTypeCoinWillReachBeforeItReaches() st
 	beforeIfReaches := parseNumber(p.prediction.Predict.Predict.Operands[1].Operands[0].Literal.Operands[1].Number, false)
 	return fmt.Sprintf("%v will reach %v before it reaches %v", coin, willReach, beforeIfReaches)
 }
+
+func (p PredictionPrettyPrinter) predictionTypeTheFlippening() string {
+	typedPred := core.PredictionTypeTheFlippeningWrapper{P: p.prediction}
+	cond := p.prediction.Predict.Predict.Literal
+	coin, _ := parseOperand(typedPred.Coin(), false)
+	otherCoin, _ := parseOperand(typedPred.OtherCoin(), false)
+
+	humanToTs := time.Unix(int64(cond.ToTs), 0).Format("Jan 2, 2006")
+	temporalPart := fmt.Sprintf("by %v", humanToTs)
+	if cond.ToDuration != "" {
+		temporalPart = parseDuration(cond.ToDuration, time.Unix(int64(cond.FromTs), 0))
+	}
+
+	return fmt.Sprintf("%v will flip %v %v", coin, otherCoin, temporalPart)
+}
+
+func (p PredictionPrettyPrinter) predictionTypeCoinOperatorCoinDeadline() string {
+	typedPred := core.PredictionTypeCoinOperatorCoinDeadlineWrapper{P: p.prediction}
+	cond := p.prediction.Predict.Predict.Literal
+	coin, _ := parseOperand(typedPred.Coin(), false)
+	otherCoin, _ := parseOperand(typedPred.OtherCoin(), false)
+
+	humanToTs := time.Unix(int64(cond.ToTs), 0).Format("Jan 2, 2006")
+	temporalPart := fmt.Sprintf("by %v", humanToTs)
+	if cond.ToDuration != "" {
+		temporalPart = parseDuration(cond.ToDuration, time.Unix(int64(cond.FromTs), 0))
+	}
+
+	operator := ""
+	switch typedPred.Operator() {
+	case ">", ">=":
+		operator = "will exceed"
+	case "<", "<=":
+		operator = "will be below"
+	}
+
+	return fmt.Sprintf("%v %v %v %v", coin, operator, otherCoin, temporalPart)
+}
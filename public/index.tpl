@@ -190,6 +190,19 @@
         function compileDataset() {
             const rawDataset = prediction.summary.candlestickMap[prediction.summary.coin]
             const dataset = []
+            if (prediction.summary.otherCoin) {
+                // Chart the ratio between both coins (e.g. the flippening happens when it goes over 1).
+                const otherDataset = prediction.summary.candlestickMap[prediction.summary.otherCoin]
+                const multiplier = prediction.summary.otherCoinMultiplier || 1
+                rawDataset.forEach((c, i) => {
+                    const o = otherDataset[i]
+                    const [open, close] = [c.o / (o.o * multiplier), c.c / (o.c * multiplier)]
+                    const low = Math.min(c.l / (o.h * multiplier), open, close)
+                    const high = Math.max(c.h / (o.l * multiplier), open, close)
+                    dataset.push([new Date(c.t * 1000), low, open, close, high])
+                })
+                return dataset
+            }
             rawDataset.forEach(c => dataset.push([new Date(c.t * 1000), c.l, c.o, c.c, c.h]))
             return dataset
         }
@@ -232,6 +245,16 @@
                 }
             };
 
+            // goalCaption also shows the percentage and the price it resolved to, if the goal is relative to the price
+            // at post time (e.g. "+30%").
+            function goalCaption(summary) {
+                if (!summary.goalPercent) {
+                    return 'Goal'
+                }
+                const sign = summary.goalPercent > 0 ? '+' : ''
+                return `Goal ${Math.round(summary.goal * 100) / 100} (${sign}${summary.goalPercent}%)`
+            }
+
             function calculateOverlaysBasedOnPredictionData(prediction, options) {
                 let upperGreenLineAt = null
                 let lowerGreenLineAt = null
@@ -254,7 +277,17 @@
                     options.vAxis.minValue = prediction.summary.rangeLow * 0.99
                     options.vAxis.maxValue = prediction.summary.rangeHigh * 1.01
                     lowerRedLineAt = [prediction.summary.rangeLow, prediction.summary.rangeLowWithError]
-                    upperRedLineAt = [prediction.summary.rangeLow, prediction.summary.rangeHighWithError]
+                    upperRedLineAt = [prediction.summary.rangeHigh, prediction.summary.rangeHighWithError]
+                }
+
+                if (prediction.summary.otherCoin && prediction.summary.operator.startsWith('>')) {
+                    options.vAxis.maxValue = 1.01
+                    upperGreenLineAt = [1, 1 - prediction.summary.errorMarginRatio]
+                }
+
+                if (prediction.summary.otherCoin && prediction.summary.operator.startsWith('<')) {
+                    options.vAxis.minValue = 0.99
+                    lowerGreenLineAt = [1, 1 + prediction.summary.errorMarginRatio]
                 }
 
                 if (prediction.summary.willReach && prediction.summary.beforeItReaches) {
@@ -319,7 +352,7 @@
                         softGoalBoxClass: 'yellowBox',
                         hardGoalBoxClass: 'greenBox',
                         softGoalCaption: `${prediction.summary.errorMarginRatio * 100}% error margin`,
-                        hardGoalCaption: 'Goal',
+                        hardGoalCaption: goalCaption(prediction.summary),
                         xMin,
                         xMax,
                     }, cli)
@@ -335,7 +368,7 @@
                         softGoalBoxClass: 'yellowBox',
                         hardGoalBoxClass: 'greenBox',
                         softGoalCaption: `${prediction.summary.errorMarginRatio * 100}% error margin`,
-                        hardGoalCaption: 'Goal',
+                        hardGoalCaption: goalCaption(prediction.summary),
                         xMin,
                         xMax,
                     }, cli)
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/marianogappa/crypto-candles/candles"
+	"github.com/marianogappa/predictions/compiler"
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/daemon"
+	"github.com/marianogappa/predictions/metadatafetcher"
+)
+
+// runReplay replays a prediction against market data and prints a trace of its evolution (see daemon.Replay). The
+// prediction is either a stored prediction's UUID, or a prediction's JSON (or a path to a file with it). Nothing is
+// stored, not even for stored predictions.
+func runReplay(ctx context.Context, predictionArg, candlesPath string) {
+	market := mustResolveReplayMarket(candlesPath)
+	prediction, err := resolveReplayPrediction(ctx, predictionArg, market)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to resolve -replay prediction.")
+	}
+
+	errs := daemon.Replay(ctx, &prediction, market, int(time.Now().Unix()), os.Stdout)
+	for _, err := range errs {
+		log.Error().Err(err).Msg("Error while replaying prediction.")
+	}
+}
+
+// resolveReplayPrediction loads the prediction from storage if predictionArg is a UUID, or compiles it otherwise. The
+// market resolves the baselines of conditions with percentages, e.g. "COIN:BINANCE:BTC-USDT >= +30%".
+func resolveReplayPrediction(ctx context.Context, predictionArg string, market core.IMarket) (core.Prediction, error) {
+	if _, err := uuid.Parse(predictionArg); err == nil {
+		// The storage is read-only, so replaying against a production database can't e.g. migrate it.
+		store := mustResolveStateStorage(resolveStorageDriver(), true)
+		// Deleted, hidden & paused predictions may be replayed too.
+		preds, err := store.GetPredictions(ctx, core.APIFilters{UUIDs: []string{predictionArg}, IncludeUIUnsupported: true}, nil, 1, 0)
+		if err != nil {
+			return core.Prediction{}, err
+		}
+		if len(preds) == 0 {
+			return core.Prediction{}, fmt.Errorf("prediction %v not found", predictionArg)
+		}
+		return preds[0], nil
+	}
+
+	predictionBs := []byte(predictionArg)
+	if bs, err := os.ReadFile(predictionArg); err == nil {
+		predictionBs = bs
+	}
+	prediction, _, err := compiler.NewPredictionCompiler(metadatafetcher.NewMetadataFetcher(), time.Now, compiler.WithMarket(market)).Compile(predictionBs)
+	return prediction, err
+}
+
+// mustResolveReplayMarket resolves the market to replay predictions against: the exchanges' one, unless a recorded
+// candles file is supplied (see daemon.NewRecordedMarketFromJSON).
+func mustResolveReplayMarket(candlesPath string) core.IMarket {
+	if candlesPath == "" {
+		return newMarket(candles.NewMarket())
+	}
+	file, err := os.Open(candlesPath)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to open -replaycandles %v.", candlesPath)
+	}
+	defer file.Close()
+	recordedMarket, err := daemon.NewRecordedMarketFromJSON(file)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to parse -replaycandles %v.", candlesPath)
+	}
+	return newMarket(recordedMarket)
+}
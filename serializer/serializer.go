@@ -147,9 +147,9 @@ func (s PredictionSerializer) SerializeForAPI(p *core.Prediction, includeSummary
 
 func marshalInnerCondition(c *core.Condition) string {
 	if c.Operator == "BETWEEN" {
-		return fmt.Sprintf(`%v BETWEEN %v AND %v`, c.Operands[0].Str, c.Operands[1].Str, c.Operands[2].Str)
+		return fmt.Sprintf(`%v BETWEEN %v AND %v`, c.Operands[0].ConditionStr(), c.Operands[1].ConditionStr(), c.Operands[2].ConditionStr())
 	}
-	return fmt.Sprintf(`%v %v %v`, c.Operands[0].Str, c.Operator, c.Operands[1].Str)
+	return fmt.Sprintf(`%v %v %v`, c.Operands[0].ConditionStr(), c.Operator, c.Operands[1].ConditionStr())
 }
 
 func marshalGiven(given map[string]*core.Condition) map[string]compiler.Condition {
@@ -162,18 +162,63 @@ func marshalGiven(given map[string]*core.Condition) map[string]compiler.Conditio
 			ToDuration:       cond.ToDuration,
 			Assumed:          cond.Assumed,
 			ErrorMarginRatio: cond.ErrorMarginRatio,
+			Baseline:         cond.Baseline,
+			CandleInterval:   cond.CandleInterval,
+			SustainedCandles: cond.SustainedCandles,
+			SustainedFor:     cond.SustainedFor,
+			Failovers:        marshalFailovers(cond.Failovers),
 			State: compiler.ConditionState{
-				Status:    cond.State.Status.String(),
-				LastTs:    cond.State.LastTs,
-				LastTicks: cond.State.LastTicks,
-				Value:     cond.State.Value.String(),
+				Status:        cond.State.Status.String(),
+				LastTs:        cond.State.LastTs,
+				LastTicks:     cond.State.LastTicks,
+				Value:         cond.State.Value.String(),
+				Streak:        cond.State.Streak,
+				StalledRuns:   cond.State.StalledRuns,
+				DecidingTicks: marshalDecidingTicks(cond.State.DecidingTicks),
 			},
 		}
+		// WICK is the default, so it's omitted to keep the blobs of most predictions unchanged.
+		if cond.EvaluationMode != core.WICK {
+			c.EvaluationMode = cond.EvaluationMode.String()
+		}
 		result[key] = c
 	}
 	return result
 }
 
+func marshalDecidingTicks(ticks []core.DecidingTick) []compiler.DecidingTick {
+	if len(ticks) == 0 {
+		return nil
+	}
+	result := []compiler.DecidingTick{}
+	for _, tick := range ticks {
+		result = append(result, compiler.DecidingTick{
+			Operand:        tick.Operand,
+			Exchange:       tick.Exchange,
+			CandleInterval: tick.CandleInterval,
+			Timestamp:      tick.Timestamp,
+			Value:          tick.Value,
+		})
+	}
+	return result
+}
+
+func marshalFailovers(failovers []core.ExchangeFailover) []compiler.ExchangeFailover {
+	if len(failovers) == 0 {
+		return nil
+	}
+	result := []compiler.ExchangeFailover{}
+	for _, failover := range failovers {
+		result = append(result, compiler.ExchangeFailover{
+			From:      failover.FromOperand,
+			To:        failover.ToOperand,
+			Reason:    failover.Reason,
+			CreatedAt: failover.CreatedAt,
+		})
+	}
+	return result
+}
+
 func marshalBoolExpr(b *core.BoolExpr, nestLevel int) (*string, error) {
 	if b == nil {
 		return nil, nil
@@ -262,9 +307,10 @@ func marshalPredict(p core.Predict) (compiler.Predict, error) {
 
 func marshalPredictionState(ps core.PredictionState) compiler.PredictionState {
 	return compiler.PredictionState{
-		Status: ps.Status.String(),
-		LastTs: ps.LastTs,
-		Value:  ps.Value.String(),
+		Status:     ps.Status.String(),
+		LastTs:     ps.LastTs,
+		Value:      ps.Value.String(),
+		Difficulty: ps.Difficulty,
 	}
 }
 
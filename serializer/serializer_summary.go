@@ -20,6 +20,10 @@ func (s PredictionSerializer) BuildPredictionMarketSummary(p core.Prediction) (c
 		return s.predictionTypeCoinWillRange(p)
 	case core.PredictionTypeCoinWillReachBeforeItReaches:
 		return s.predictionTypeCoinWillReachBeforeItReaches(p)
+	case core.PredictionTypeTheFlippening:
+		return s.predictionTypeTheFlippening(p)
+	case core.PredictionTypeCoinOperatorCoinDeadline:
+		return s.predictionTypeCoinOperatorCoinDeadline(p)
 	}
 	return compiler.PredictionSummary{}, nil
 }
@@ -34,7 +38,7 @@ func (s PredictionSerializer) predictionTypeCoinOperatorFloatDeadline(p core.Pre
 
 	candlesticks := map[string][]common.Candlestick{}
 	opStr := typedPred.Coin().Str
-	it, err := (*s.mkt).Iterator(typedPred.Coin().ToMarketSource(), chartParams.startTime, chartParams.candlestickInterval)
+	it, err := (*s.mkt).Iterator(typedPred.Coin(), chartParams.startTime, chartParams.candlestickInterval)
 	if err != nil {
 		return compiler.PredictionSummary{}, err
 	}
@@ -52,6 +56,7 @@ func (s PredictionSerializer) predictionTypeCoinOperatorFloatDeadline(p core.Pre
 		Operator:                                typedPred.Operator(),
 		Goal:                                    typedPred.Goal(),
 		GoalWithError:                           typedPred.GoalWithError(),
+		GoalPercent:                             typedPred.GoalPercent(),
 		ErrorMarginRatio:                        core.JSONFloat64(typedPred.ErrorMarginRatio()),
 		Deadline:                                core.ISO8601(typedPred.Deadline().Format(time.RFC3339)),
 		EndedAt:                                 core.ISO8601(typedPred.EndTime().Format(time.RFC3339)),
@@ -70,7 +75,7 @@ func (s PredictionSerializer) predictionTypeCoinWillReachInvalidatedIfItReaches(
 
 	candlesticks := map[string][]common.Candlestick{}
 	opStr := typedPred.Coin().Str
-	it, err := (*s.mkt).Iterator(typedPred.Coin().ToMarketSource(), chartParams.startTime, chartParams.candlestickInterval)
+	it, err := (*s.mkt).Iterator(typedPred.Coin(), chartParams.startTime, chartParams.candlestickInterval)
 	if err != nil {
 		return compiler.PredictionSummary{}, err
 	}
@@ -108,7 +113,7 @@ func (s PredictionSerializer) predictionTypeCoinWillRange(p core.Prediction) (co
 
 	candlesticks := map[string][]common.Candlestick{}
 	opStr := coin.Str
-	it, err := (*s.mkt).Iterator(typedPred.Coin().ToMarketSource(), chartParams.startTime, chartParams.candlestickInterval)
+	it, err := (*s.mkt).Iterator(typedPred.Coin(), chartParams.startTime, chartParams.candlestickInterval)
 	if err != nil {
 		return compiler.PredictionSummary{}, err
 	}
@@ -145,7 +150,7 @@ func (s PredictionSerializer) predictionTypeCoinWillReachBeforeItReaches(p core.
 
 	candlesticks := map[string][]common.Candlestick{}
 	opStr := coin.Str
-	it, err := (*s.mkt).Iterator(typedPred.Coin().ToMarketSource(), chartParams.startTime, chartParams.candlestickInterval)
+	it, err := (*s.mkt).Iterator(typedPred.Coin(), chartParams.startTime, chartParams.candlestickInterval)
 	if err != nil {
 		return compiler.PredictionSummary{}, err
 	}
@@ -171,6 +176,77 @@ func (s PredictionSerializer) predictionTypeCoinWillReachBeforeItReaches(p core.
 	}, nil
 }
 
+func (s PredictionSerializer) predictionTypeTheFlippening(p core.Prediction) (compiler.PredictionSummary, error) {
+	typedPred := core.PredictionTypeTheFlippeningWrapper{P: p}
+
+	chartParams, err := getCandlestickChartParams(p)
+	if err != nil {
+		return compiler.PredictionSummary{}, err
+	}
+
+	candlesticks, err := s.candlesticksOfBoth(typedPred.Coin(), typedPred.OtherCoin(), chartParams)
+	if err != nil {
+		return compiler.PredictionSummary{}, err
+	}
+
+	return compiler.PredictionSummary{
+		PredictionType:      p.Type.String(),
+		CandlestickMap:      candlesticks,
+		Operator:            typedPred.Operator(),
+		ErrorMarginRatio:    core.JSONFloat64(typedPred.ErrorMarginRatio()),
+		Deadline:            core.ISO8601(typedPred.Deadline().Format(time.RFC3339)),
+		EndedAt:             core.ISO8601(typedPred.EndTime().Format(time.RFC3339)),
+		Coin:                typedPred.Coin().Str,
+		OtherCoin:           typedPred.OtherCoin().Str,
+		OtherCoinMultiplier: typedPred.OtherCoin().Multiplier,
+	}, nil
+}
+
+func (s PredictionSerializer) predictionTypeCoinOperatorCoinDeadline(p core.Prediction) (compiler.PredictionSummary, error) {
+	typedPred := core.PredictionTypeCoinOperatorCoinDeadlineWrapper{P: p}
+
+	chartParams, err := getCandlestickChartParams(p)
+	if err != nil {
+		return compiler.PredictionSummary{}, err
+	}
+
+	candlesticks, err := s.candlesticksOfBoth(typedPred.Coin(), typedPred.OtherCoin(), chartParams)
+	if err != nil {
+		return compiler.PredictionSummary{}, err
+	}
+
+	return compiler.PredictionSummary{
+		PredictionType:      p.Type.String(),
+		CandlestickMap:      candlesticks,
+		Operator:            typedPred.Operator(),
+		ErrorMarginRatio:    core.JSONFloat64(typedPred.ErrorMarginRatio()),
+		Deadline:            core.ISO8601(typedPred.Deadline().Format(time.RFC3339)),
+		EndedAt:             core.ISO8601(typedPred.EndTime().Format(time.RFC3339)),
+		Coin:                typedPred.Coin().Str,
+		OtherCoin:           typedPred.OtherCoin().Str,
+		OtherCoinMultiplier: typedPred.OtherCoin().Multiplier,
+	}, nil
+}
+
+// candlesticksOfBoth gets the candlesticks of both operands over the same period, so that they can be compared.
+func (s PredictionSerializer) candlesticksOfBoth(coin, otherCoin core.Operand, chartParams candlestickChartParams) (map[string][]common.Candlestick, error) {
+	candlesticks := map[string][]common.Candlestick{}
+	for _, operand := range []core.Operand{coin, otherCoin} {
+		it, err := (*s.mkt).Iterator(operand, chartParams.startTime, chartParams.candlestickInterval)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < chartParams.candlestickCount; i++ {
+			candlestick, err := it.Next()
+			if err != nil {
+				return nil, err
+			}
+			candlesticks[operand.Str] = append(candlesticks[operand.Str], candlestick)
+		}
+	}
+	return candlesticks, nil
+}
+
 type candlestickChartParams struct {
 	startTime           time.Time
 	candlestickCount    int
@@ -0,0 +1,49 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/marketcap"
+	"github.com/marianogappa/predictions/markettest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildPredictionMarketSummaryTheFlippening(t *testing.T) {
+	var (
+		eth = core.Operand{Type: core.MARKETCAP, Provider: "MESSARI", BaseAsset: "ETH", Str: "MARKETCAP:MESSARI:ETH"}
+		btc = core.Operand{Type: core.MARKETCAP, Provider: "MESSARI", BaseAsset: "BTC", Str: "MARKETCAP:MESSARI:BTC"}
+		c   = &core.Condition{
+			Name:     "main",
+			Operator: ">",
+			Operands: []core.Operand{eth, btc},
+			FromTs:   int(tp("2022-01-01 00:00:00").Unix()),
+			ToTs:     int(tp("2022-01-03 00:00:00").Unix()),
+		}
+		p = core.Prediction{
+			PostedAt: tpToISO("2022-01-01 00:00:00"),
+			Given:    map[string]*core.Condition{"main": c},
+			Predict:  core.Predict{Predict: core.BoolExpr{Operator: core.LITERAL, Literal: c}},
+			State:    core.PredictionState{Status: core.FINISHED, Value: core.INCORRECT, LastTs: c.ToTs},
+			Type:     core.PredictionTypeTheFlippening,
+		}
+		fixture = markettest.NewTickDataSource("MESSARI", map[string][]core.Tick{
+			"ETH": {{Timestamp: int(tp("2021-12-31 00:00:00").Unix()), Value: 500}, {Timestamp: c.ToTs, Value: 500}},
+			"BTC": {{Timestamp: int(tp("2021-12-31 00:00:00").Unix()), Value: 900}, {Timestamp: c.ToTs, Value: 900}},
+		})
+		market = core.IMarket(marketcap.NewMarket(marketcap.WithDataSource(fixture)))
+	)
+
+	summary, err := NewPredictionSerializer(&market).BuildPredictionMarketSummary(p)
+	require.Nil(t, err)
+	require.Equal(t, "PREDICTION_TYPE_THE_FLIPPENING", summary.PredictionType)
+	require.Equal(t, eth.Str, summary.Coin)
+	require.Equal(t, btc.Str, summary.OtherCoin)
+	require.Equal(t, ">", summary.Operator)
+	require.Len(t, summary.CandlestickMap, 2)
+	require.Len(t, summary.CandlestickMap[eth.Str], 30)
+	require.Len(t, summary.CandlestickMap[btc.Str], 30)
+	for i := range summary.CandlestickMap[eth.Str] {
+		require.Equal(t, summary.CandlestickMap[btc.Str][i].Timestamp, summary.CandlestickMap[eth.Str][i].Timestamp)
+	}
+}
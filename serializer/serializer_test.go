@@ -3,9 +3,11 @@ package serializer
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/marianogappa/predictions/compiler"
 	"github.com/marianogappa/predictions/core"
 	"github.com/stretchr/testify/require"
 )
@@ -171,3 +173,134 @@ func tp(s string) time.Time {
 	t, _ := time.Parse("2006-01-02 15:04:05", s)
 	return t
 }
+
+func TestSerializeRoundTripsConditions(t *testing.T) {
+	tss := []string{
+		"COIN:BINANCE:ETH-USDT > COIN:BINANCE:BTC-USDT * 0.1",
+		"COIN:BINANCE:BTC-USDT >= 1.2 * 30000",
+		"COIN:BINANCE:BTC-USDT <= -15%",
+		"COIN:KUCOIN:BTC-USDT - COIN:BINANCE:BTC-USDT > 100",
+		"COIN:BINANCE:BTC-USDT BETWEEN (COIN:KUCOIN:BTC-USDT + COIN:COINBASE:BTC-USD) / 2 AND +5%",
+		"COIN:BINANCE:BTC-USDT > SMA(COIN:BINANCE:BTC-USDT,200d)",
+		"RSI(COIN:BINANCE:ETH-USDT,14h) - 30 < EMA(COIN:BINANCE:ETH-USDT,9d) / 100",
+		"COIN:ANY:BTC-USDT - SMA(COIN:MEDIAN(BINANCE,COINBASE,KUCOIN):BTC-USDT,200d) > 1000",
+	}
+	for _, condition := range tss {
+		t.Run(condition, func(t *testing.T) {
+			rawPrediction := fmt.Sprintf(`{
+				"reporter": "admin",
+				"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+				"postAuthor": "CryptoCapo_",
+				"postAuthorURL": "https://twitter.com/CryptoCapo_",
+				"postedAt": "2022-02-09T10:25:26.000Z",
+				"given": {
+					"main": {
+						"condition": "%v",
+						"toDuration": "eoy",
+						"baseline": 44000
+					}
+				},
+				"predict": {
+					"predict": "main"
+				}
+			}`, condition)
+
+			predictionCompiler := compiler.NewPredictionCompiler(nil, time.Now)
+			pred, _, err := predictionCompiler.Compile([]byte(rawPrediction))
+			require.Nil(t, err)
+
+			bs, err := NewPredictionSerializer(nil).Serialize(&pred)
+			require.Nil(t, err)
+
+			roundTripped, _, err := predictionCompiler.Compile(bs)
+			require.Nil(t, err)
+			require.Equal(t, condition, marshalInnerCondition(roundTripped.Given["main"]))
+			require.Equal(t, pred.Given["main"].Operands, roundTripped.Given["main"].Operands)
+			require.Equal(t, 44000.0, roundTripped.Given["main"].Baseline)
+		})
+	}
+}
+
+func TestSerializeRoundTripsExchangeFailovers(t *testing.T) {
+	rawPrediction := `{
+		"uuid": "3a7bc95e-480d-4232-8e8b-f848d5389806",
+		"reporter": "admin",
+		"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+		"postAuthor": "CryptoCapo_",
+		"postAuthorURL": "https://twitter.com/CryptoCapo_",
+		"postedAt": "2022-02-09T10:25:26.000Z",
+		"given": {
+			"main": {
+				"condition": "COIN:BINANCE:BTC-USDT > 60000",
+				"toDuration": "eoy"
+			}
+		},
+		"predict": {
+			"predict": "main"
+		}
+	}`
+
+	predictionCompiler := compiler.NewPredictionCompiler(nil, time.Now)
+	pred, _, err := predictionCompiler.Compile([]byte(rawPrediction))
+	require.Nil(t, err)
+
+	pred.Given["main"].FailOver(pred.UUID, pred.Given["main"].Operands[0], "KUCOIN", "no new ticks", "2022-03-01T00:00:00Z")
+	pred.Given["main"].State.StalledRuns = 2
+
+	bs, err := NewPredictionSerializer(nil).Serialize(&pred)
+	require.Nil(t, err)
+
+	roundTripped, _, err := predictionCompiler.Compile(bs)
+	require.Nil(t, err)
+	require.Equal(t, "COIN:KUCOIN:BTC-USDT > 60000", marshalInnerCondition(roundTripped.Given["main"]))
+	require.Equal(t, 2, roundTripped.Given["main"].State.StalledRuns)
+	require.Equal(t, []core.ExchangeFailover{{
+		PredictionUUID: "3a7bc95e-480d-4232-8e8b-f848d5389806",
+		ConditionName:  "main",
+		FromOperand:    "COIN:BINANCE:BTC-USDT",
+		ToOperand:      "COIN:KUCOIN:BTC-USDT",
+		Reason:         "no new ticks",
+		CreatedAt:      "2022-03-01T00:00:00Z",
+	}}, roundTripped.Given["main"].Failovers)
+}
+
+func TestSerializeRoundTripsDecidingTicks(t *testing.T) {
+	rawPrediction := `{
+		"uuid": "3a7bc95e-480d-4232-8e8b-f848d5389806",
+		"reporter": "admin",
+		"postUrl": "https://twitter.com/CryptoCapo_/status/1491357566974054400",
+		"postAuthor": "CryptoCapo_",
+		"postAuthorURL": "https://twitter.com/CryptoCapo_",
+		"postedAt": "2022-02-09T10:25:26.000Z",
+		"given": {
+			"main": {
+				"condition": "COIN:BINANCE:BTC-USDT > 60000",
+				"toDuration": "eoy"
+			}
+		},
+		"predict": {
+			"predict": "main"
+		}
+	}`
+
+	predictionCompiler := compiler.NewPredictionCompiler(nil, time.Now)
+	pred, _, err := predictionCompiler.Compile([]byte(rawPrediction))
+	require.Nil(t, err)
+
+	tick := core.Tick{Timestamp: 1646092800, Value: 60000.5}
+	require.Nil(t, pred.Given["main"].Run(map[string]core.Tick{"COIN:BINANCE:BTC-USDT": tick}))
+	require.Equal(t, core.TRUE, pred.Given["main"].State.Value)
+
+	bs, err := NewPredictionSerializer(nil).Serialize(&pred)
+	require.Nil(t, err)
+
+	roundTripped, _, err := predictionCompiler.Compile(bs)
+	require.Nil(t, err)
+	require.Equal(t, []core.DecidingTick{{
+		Operand:        "COIN:BINANCE:BTC-USDT",
+		Exchange:       "BINANCE",
+		CandleInterval: "1m",
+		Timestamp:      1646092800,
+		Value:          60000.5,
+	}}, roundTripped.Given["main"].State.DecidingTicks)
+}
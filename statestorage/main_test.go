@@ -14,7 +14,7 @@ import (
 )
 
 var (
-	store         StateStorage
+	store         *PostgresDBStateStorage
 	mainTestStore = "test_predictions"
 	apiTestStore  = "test_predictions_statestorage"
 )
@@ -29,7 +29,7 @@ func setupTestDB(t *testing.T) StateStorage {
 	return store
 }
 
-func connectToTestStore(t *testing.T, databaseName string) StateStorage {
+func connectToTestStore(t *testing.T, databaseName string) *PostgresDBStateStorage {
 	if !strings.Contains(databaseName, "test_") {
 		log.Error().Msgf("I'm not gonna let you connect to a non-test database!")
 		return nil
@@ -57,11 +57,7 @@ func connectToTestStore(t *testing.T, databaseName string) StateStorage {
 			return nil
 		}
 	}
-	return castStore(&_store)
-}
-
-func castStore(s *PostgresDBStateStorage) StateStorage {
-	return s
+	return &_store
 }
 
 func mustTruncateTables(t *testing.T, db *sql.DB) {
@@ -70,6 +66,8 @@ func mustTruncateTables(t *testing.T, db *sql.DB) {
 		"prediction_state_value_change",
 		"accounts",
 		"prediction_interactions",
+		"exchange_failovers",
+		"account_aliases",
 	}
 	for _, table := range tables {
 		if _, err := db.Exec(fmt.Sprintf("TRUNCATE TABLE %v", table)); err != nil {
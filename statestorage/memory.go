@@ -0,0 +1,939 @@
+package statestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marianogappa/predictions/compiler"
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/serializer"
+	"github.com/rs/zerolog/log"
+)
+
+// MemoryStateStorage is the in-memory implementation of StateStorage. It has the same semantics as the Postgres
+// implementation, but nothing is persisted, so it's only meant for tests and for quick local runs. Operations don't
+// block on I/O, so contexts are only checked for cancellation before doing any work.
+type MemoryStateStorage struct {
+	mu sync.RWMutex
+
+	predictions            []*memPrediction
+	accounts               []*core.Account
+	accountAliases         map[string]string
+	predictionStateChanges []core.PredictionStateValueChange
+	exchangeFailovers      []core.ExchangeFailover
+	predictionInteractions []*memPredictionInteraction
+	leases                 map[string]memLease
+
+	debug bool
+}
+
+// memPrediction is the equivalent of a row in the predictions table. Like in Postgres, the prediction is stored as a
+// serialized blob, and it's compiled back on every read, so callers never share memory with the storage-layer.
+type memPrediction struct {
+	uuid      string
+	blob      []byte
+	createdAt core.ISO8601
+	postedAt  core.ISO8601
+	tags      []string
+	postURL   string
+	nextRunTs int
+	paused    bool
+	hidden    bool
+	deleted   bool
+
+	// These are the fields that are read from the blob by the Postgres filters.
+	postAuthor    string
+	postAuthorURL string
+	stateStatus   string
+	stateValue    string
+	predType      string
+}
+
+type memPredictionInteraction struct {
+	uuid      string
+	createdAt time.Time
+	core.PredictionInteraction
+}
+
+type memLease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+// NewMemoryStateStorage constructs a MemoryStateStorage.
+func NewMemoryStateStorage() *MemoryStateStorage {
+	return &MemoryStateStorage{leases: map[string]memLease{}, accountAliases: map[string]string{}}
+}
+
+// SetDebug sets the debug logging setting across the storage layer.
+func (s *MemoryStateStorage) SetDebug(debug bool) {
+	s.debug = debug
+}
+
+// filterPredictions is the in-memory equivalent of a WHERE clause built with pgPredictionsWhere.
+func (s *MemoryStateStorage) filterPredictions(filters core.APIFilters) []*memPrediction {
+	matchers := []func(*memPrediction) bool{
+		memPredictionsAuthorHandles(filters.AuthorHandles),
+		memPredictionsAuthorURLs(filters.AuthorURLs),
+		memPredictionsFlag(filters.Deleted, func(p *memPrediction) bool { return p.deleted }),
+		memPredictionsFlag(filters.Hidden, func(p *memPrediction) bool { return p.hidden }),
+		memPredictionsFlag(filters.Paused, func(p *memPrediction) bool { return p.paused }),
+		memPredictionsPredictionStateStatuses(filters.PredictionStateStatus),
+		memPredictionsPredictionStateValues(filters.PredictionStateValues),
+		memPredictionsUUIDs(filters.UUIDs),
+		memPredictionsURLs(filters.URLs),
+		memPredictionsTags(filters.Tags),
+		memGreaterThanUUID(filters.GreaterThanUUID),
+		memIncludeUIUnsupported(filters.IncludeUIUnsupported),
+		memPredictionsDueAt(filters.DueAt),
+	}
+
+	rows := []*memPrediction{}
+	for _, p := range s.predictions {
+		if matchesAll(p, matchers) {
+			rows = append(rows, p)
+		}
+	}
+	return rows
+}
+
+// GetPredictions returns predictions from memory, with the same filtering, ordering & paging as Postgres.
+func (s *MemoryStateStorage) GetPredictions(ctx context.Context, filters core.APIFilters, orderBys []string, limit, offset int) ([]core.Prediction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := s.filterPredictions(filters)
+	orderBy := predictionsBuildOrderBy(orderBys)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessByOrderBy(orderBy, func(column string) int {
+			switch column {
+			case "created_at":
+				return compareISO8601(rows[i].createdAt, rows[j].createdAt)
+			case "posted_at":
+				return compareISO8601(rows[i].postedAt, rows[j].postedAt)
+			case "uuid":
+				return strings.Compare(rows[i].uuid, rows[j].uuid)
+			}
+			return 0
+		})
+	})
+	rows = paginate(rows, limit, offset)
+
+	if s.debug {
+		log.Info().Msgf("MemoryStateStorage.GetPredictions: for filters %+v and orderBy %+v: %v results\n", filters, orderBys, len(rows))
+	}
+
+	result := []core.Prediction{}
+	for _, row := range rows {
+		pred, _, err := compiler.NewPredictionCompiler(nil, nil).Compile(row.blob)
+		if err != nil {
+			log.Info().Msgf("read corrupted prediction from memory, with error: %v\n", err)
+			continue
+		}
+		pred.UUID = row.uuid
+		pred.Paused = row.paused
+		pred.Hidden = row.hidden
+		pred.Deleted = row.deleted
+		result = append(result, pred)
+	}
+
+	return result, nil
+}
+
+// CountPredictions counts the predictions in memory that match the filters.
+func (s *MemoryStateStorage) CountPredictions(ctx context.Context, filters core.APIFilters) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.filterPredictions(filters)), nil
+}
+
+// GetNextPredictionRunAt returns the earliest time at which any of the predictions in memory that match the filters
+// should be evolved.
+func (s *MemoryStateStorage) GetNextPredictionRunAt(ctx context.Context, filters core.APIFilters) (time.Time, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return time.Time{}, false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := s.filterPredictions(filters)
+	if len(rows) == 0 {
+		return time.Time{}, false, nil
+	}
+	nextRunTs := rows[0].nextRunTs
+	for _, row := range rows {
+		if row.nextRunTs < nextRunTs {
+			nextRunTs = row.nextRunTs
+		}
+	}
+	if nextRunTs == 0 {
+		return time.Time{}, true, nil
+	}
+	return time.Unix(int64(nextRunTs), 0).UTC(), true, nil
+}
+
+// GetPredictionStats counts the predictions in memory that match the filters, by state value.
+func (s *MemoryStateStorage) GetPredictionStats(ctx context.Context, filters core.APIFilters) (core.PredictionStats, error) {
+	if err := ctx.Err(); err != nil {
+		return core.PredictionStats{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := core.PredictionStats{ByStateValue: map[string]int{}}
+	for _, p := range s.filterPredictions(filters) {
+		stats.ByStateValue[p.stateValue]++
+		stats.Total++
+	}
+	return stats, nil
+}
+
+// GetAccountStats calculates the AccountStats of the authors of the visible predictions in memory that match the
+// filters, or only of the top limit ones if limit > 0.
+func (s *MemoryStateStorage) GetAccountStats(ctx context.Context, filters core.AccountStatsFilters, limit int) ([]core.AccountStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f := false
+	preds := []core.Prediction{}
+	for _, row := range s.filterPredictions(core.APIFilters{AuthorHandles: filters.AuthorHandles, AuthorURLs: filters.AuthorURLs, PredictionStateValues: finishedPredictionStateValues, Deleted: &f, Hidden: &f, IncludeUIUnsupported: true}) {
+		pred, _, err := compiler.NewPredictionCompiler(nil, nil).Compile(row.blob)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+	return paginate(core.CalculateAccountStats(preds, filters), limit, 0), nil
+}
+
+// GetAccounts returns accounts from memory, with the same filtering, ordering & paging as Postgres.
+func (s *MemoryStateStorage) GetAccounts(ctx context.Context, filters core.APIAccountFilters, orderBys []string, limit, offset int) ([]core.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := []*core.Account{}
+	for _, a := range s.accounts {
+		if len(filters.Handles) > 0 && !containsStr(filters.Handles, a.Handle) {
+			continue
+		}
+		if len(filters.URLs) > 0 && !containsStr(filters.URLs, a.URL.String()) {
+			continue
+		}
+		rows = append(rows, a)
+	}
+
+	orderBy := accountsBuildOrderBy(orderBys)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessByOrderBy(orderBy, func(column string) int {
+			switch column {
+			case "created_at":
+				return compareNullableTime(rows[i].CreatedAt, rows[j].CreatedAt)
+			case "follower_count":
+				return rows[i].FollowerCount - rows[j].FollowerCount
+			}
+			return 0
+		})
+	})
+	rows = paginate(rows, limit, offset)
+
+	if s.debug {
+		log.Info().Msgf("MemoryStateStorage.GetAccounts: for filters %+v and orderBy %+v: %v results\n", filters, orderBys, len(rows))
+	}
+
+	result := []core.Account{}
+	for _, a := range rows {
+		result = append(result, copyAccount(*a))
+	}
+	return result, nil
+}
+
+// UpsertPredictions UPSERTs predictions in memory. Like in Postgres, either all predictions are upserted or none is.
+func (s *MemoryStateStorage) UpsertPredictions(ctx context.Context, ps []*core.Prediction) ([]*core.Prediction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ps) == 0 {
+		return ps, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return ps, s.upsertPredictions(ps)
+}
+
+// StoreEvolvedPrediction upserts the prediction and logs the changes of its PredictionStateValue in memory, all at
+// once or not at all.
+func (s *MemoryStateStorage) StoreEvolvedPrediction(ctx context.Context, p *core.Prediction, changes []core.PredictionStateValueChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.upsertPredictions([]*core.Prediction{p}); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		s.logPredictionStateValueChange(c)
+	}
+	return nil
+}
+
+// upsertPredictions upserts the predictions, or none of them if any fails. s.mu must be held.
+func (s *MemoryStateStorage) upsertPredictions(ps []*core.Prediction) error {
+	rows := []*memPrediction{}
+	seenUUIDs := map[string]bool{}
+	for i := range ps {
+		if ps[i].UUID == "" {
+			ps[i].UUID = uuid.NewString()
+		}
+		if seenUUIDs[ps[i].UUID] {
+			return fmt.Errorf("%w: %v", ErrDuplicateUpsert, ps[i].UUID)
+		}
+		seenUUIDs[ps[i].UUID] = true
+
+		blob, err := serializer.NewPredictionSerializer(nil).SerializeForDB(ps[i])
+		if err != nil {
+			log.Info().Msgf("Failed to marshal prediction, with error: %v\n", err)
+		}
+		rows = append(rows, &memPrediction{
+			uuid:          ps[i].UUID,
+			blob:          blob,
+			createdAt:     ps[i].CreatedAt,
+			postedAt:      ps[i].PostedAt,
+			tags:          ps[i].CalculateTags(),
+			postURL:       ps[i].PostURL,
+			nextRunTs:     ps[i].CalculateNextRunTs(),
+			postAuthor:    ps[i].PostAuthor,
+			postAuthorURL: ps[i].PostAuthorURL,
+			stateStatus:   ps[i].State.Status.String(),
+			stateValue:    ps[i].State.Value.String(),
+			predType:      ps[i].Type.String(),
+		})
+	}
+
+	for i, row := range rows {
+		for _, existing := range append(s.predictions[:len(s.predictions):len(s.predictions)], rows[:i]...) {
+			if existing.uuid != row.uuid && existing.postURL == row.postURL {
+				return fmt.Errorf("%w: post_url %v", ErrUniqueConstraintViolation, row.postURL)
+			}
+		}
+	}
+
+	for _, row := range rows {
+		existing := s.findPrediction(row.uuid)
+		if existing == nil {
+			s.predictions = append(s.predictions, row)
+			continue
+		}
+		row.paused, row.hidden, row.deleted = existing.paused, existing.hidden, existing.deleted
+		*existing = *row
+	}
+	return nil
+}
+
+// PausePrediction sets a prediction to paused in memory. Paused predictions are visible but don't evolve.
+func (s *MemoryStateStorage) PausePrediction(ctx context.Context, uuid string) error {
+	return s.setPredictionFlag(ctx, uuid, func(p *memPrediction) { p.paused = true })
+}
+
+// UnpausePrediction sets a prediction to unpaused in memory. Paused predictions are visible but don't evolve.
+func (s *MemoryStateStorage) UnpausePrediction(ctx context.Context, uuid string) error {
+	return s.setPredictionFlag(ctx, uuid, func(p *memPrediction) { p.paused = false })
+}
+
+// HidePrediction sets a prediction to hidden in memory. Hidden predictions are invisible but still evolve.
+func (s *MemoryStateStorage) HidePrediction(ctx context.Context, uuid string) error {
+	return s.setPredictionFlag(ctx, uuid, func(p *memPrediction) { p.hidden = true })
+}
+
+// UnhidePrediction sets a prediction to visible in memory. Hidden predictions are invisible but still evolve.
+func (s *MemoryStateStorage) UnhidePrediction(ctx context.Context, uuid string) error {
+	return s.setPredictionFlag(ctx, uuid, func(p *memPrediction) { p.hidden = false })
+}
+
+// DeletePrediction sets a prediction to deleted in memory. Deleted predictions are invisible and don't evolve.
+func (s *MemoryStateStorage) DeletePrediction(ctx context.Context, uuid string) error {
+	return s.setPredictionFlag(ctx, uuid, func(p *memPrediction) { p.deleted = true })
+}
+
+// UndeletePrediction restores a deleted prediction in memory. Deleted predictions are invisible and don't evolve.
+func (s *MemoryStateStorage) UndeletePrediction(ctx context.Context, uuid string) error {
+	return s.setPredictionFlag(ctx, uuid, func(p *memPrediction) { p.deleted = false })
+}
+
+func (s *MemoryStateStorage) setPredictionFlag(ctx context.Context, uuid string, set func(*memPrediction)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findPrediction(uuid)
+	if p == nil {
+		return fmt.Errorf("uuid not found: %v", uuid)
+	}
+	set(p)
+	return nil
+}
+
+func (s *MemoryStateStorage) findPrediction(uuid string) *memPrediction {
+	for _, p := range s.predictions {
+		if p.uuid == uuid {
+			return p
+		}
+	}
+	return nil
+}
+
+// UpsertAccounts UPSERTs accounts in memory, except for aliases of other accounts. Like in Postgres, either all
+// accounts are upserted or none is.
+func (s *MemoryStateStorage) UpsertAccounts(ctx context.Context, as []*core.Account) ([]*core.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(as) == 0 {
+		return as, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seenURLs := map[string]bool{}
+	for _, a := range as {
+		if seenURLs[a.URL.String()] {
+			return as, fmt.Errorf("%w: %v", ErrDuplicateUpsert, a.URL.String())
+		}
+		seenURLs[a.URL.String()] = true
+	}
+
+	for _, a := range as {
+		if _, ok := s.accountAliases[a.URL.String()]; ok {
+			continue
+		}
+		account := copyAccount(*a)
+		replaced := false
+		for i := range s.accounts {
+			if s.accounts[i].URL.String() == a.URL.String() {
+				s.accounts[i] = &account
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.accounts = append(s.accounts, &account)
+		}
+	}
+	return as, nil
+}
+
+// DeleteAccount deletes an account from memory.
+func (s *MemoryStateStorage) DeleteAccount(ctx context.Context, url string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.accounts {
+		if s.accounts[i].URL.String() == url {
+			s.accounts = append(s.accounts[:i], s.accounts[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("url not found: %v", url)
+}
+
+// MergeAccounts merges the account with fromURL into the account into in memory. Like in Postgres, either the whole
+// merge happens or nothing does.
+func (s *MemoryStateStorage) MergeAccounts(ctx context.Context, fromURL string, into core.Account) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accountIndex := -1
+	for i := range s.accounts {
+		if s.accounts[i].URL.String() == fromURL {
+			accountIndex = i
+		}
+	}
+	if accountIndex == -1 {
+		return 0, fmt.Errorf("url not found: %v", fromURL)
+	}
+
+	blobs := map[*memPrediction][]byte{}
+	for _, row := range s.predictions {
+		if row.postAuthorURL != fromURL {
+			continue
+		}
+		pred, _, err := compiler.NewPredictionCompiler(nil, nil).Compile(row.blob)
+		if err != nil {
+			return 0, err
+		}
+		pred.PostAuthor, pred.PostAuthorURL = into.Handle, into.URL.String()
+		blob, err := serializer.NewPredictionSerializer(nil).SerializeForDB(&pred)
+		if err != nil {
+			return 0, err
+		}
+		blobs[row] = blob
+	}
+
+	for row, blob := range blobs {
+		row.blob, row.postAuthor, row.postAuthorURL = blob, into.Handle, into.URL.String()
+	}
+	s.accounts = append(s.accounts[:accountIndex], s.accounts[accountIndex+1:]...)
+	// Aliases of the merged account become aliases of the account it's merged into.
+	for aliasURL, aliasOf := range s.accountAliases {
+		if aliasOf == fromURL {
+			s.accountAliases[aliasURL] = into.URL.String()
+		}
+	}
+	s.accountAliases[fromURL] = into.URL.String()
+	return len(blobs), nil
+}
+
+// GetAccountAliases returns the URL of the account that each of the urls is an alias of, from memory.
+func (s *MemoryStateStorage) GetAccountAliases(ctx context.Context, urls []string) (map[string]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	aliases := map[string]string{}
+	for _, u := range urls {
+		if aliasOf, ok := s.accountAliases[u]; ok {
+			aliases[u] = aliasOf
+		}
+	}
+	return aliases, nil
+}
+
+// LogPredictionStateValueChange logs the fact that a prediction changed PredictionStateValue in memory.
+func (s *MemoryStateStorage) LogPredictionStateValueChange(ctx context.Context, c core.PredictionStateValueChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logPredictionStateValueChange(c)
+	return nil
+}
+
+// logPredictionStateValueChange logs the change, with a copy of its ticks. s.mu must be held.
+func (s *MemoryStateStorage) logPredictionStateValueChange(c core.PredictionStateValueChange) {
+	if c.Ticks != nil {
+		ticks := make(map[string]core.Tick, len(c.Ticks))
+		for k, v := range c.Ticks {
+			ticks[k] = v
+		}
+		c.Ticks = ticks
+	}
+	s.predictionStateChanges = append(s.predictionStateChanges, c)
+}
+
+// GetPredictionStateValueChanges returns the state value changes of a prediction from memory, oldest first.
+func (s *MemoryStateStorage) GetPredictionStateValueChanges(ctx context.Context, predictionUUID string) ([]core.PredictionStateValueChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes := []core.PredictionStateValueChange{}
+	for _, c := range s.predictionStateChanges {
+		if c.PredictionUUID == predictionUUID {
+			changes = append(changes, c)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return compareISO8601(changes[i].CreatedAt, changes[j].CreatedAt) < 0 })
+	return changes, nil
+}
+
+// LogExchangeFailover logs the fact that a prediction's condition failed over to another exchange in memory.
+func (s *MemoryStateStorage) LogExchangeFailover(ctx context.Context, f core.ExchangeFailover) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.exchangeFailovers = append(s.exchangeFailovers, f)
+	return nil
+}
+
+// GetExchangeFailovers returns the exchange failovers of a prediction's conditions from memory, oldest first.
+func (s *MemoryStateStorage) GetExchangeFailovers(ctx context.Context, predictionUUID string) ([]core.ExchangeFailover, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	failovers := []core.ExchangeFailover{}
+	for _, f := range s.exchangeFailovers {
+		if f.PredictionUUID == predictionUUID {
+			failovers = append(failovers, f)
+		}
+	}
+	sort.SliceStable(failovers, func(i, j int) bool { return compareISO8601(failovers[i].CreatedAt, failovers[j].CreatedAt) < 0 })
+	return failovers, nil
+}
+
+// AcquireLease takes the named lease for owner in memory.
+func (s *MemoryStateStorage) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if lease, ok := s.leases[name]; ok && lease.owner != owner && lease.expiresAt.After(now) {
+		return false, nil
+	}
+	s.leases[name] = memLease{owner: owner, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// ReleaseLease gives up the named lease in memory, if owner holds it.
+func (s *MemoryStateStorage) ReleaseLease(ctx context.Context, name, owner string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lease, ok := s.leases[name]; ok && lease.owner == owner {
+		delete(s.leases, name)
+	}
+	return nil
+}
+
+// NonPendingPredictionInteractionExists checks in memory to see if a predictions creation or finalization Tweet post
+// happened.
+func (s *MemoryStateStorage) NonPendingPredictionInteractionExists(ctx context.Context, interaction core.PredictionInteraction) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, i := range s.predictionInteractions {
+		if i.sameKey(interaction) && i.Status != "PENDING" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// InsertPredictionInteraction logs the fact that a Tweet was sent when a prediction was created or finalized.
+func (s *MemoryStateStorage) InsertPredictionInteraction(ctx context.Context, i core.PredictionInteraction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.predictionInteractions {
+		if existing.sameKey(i) {
+			return fmt.Errorf("%w: prediction interaction for %v %v %v", ErrUniqueConstraintViolation, i.PostURL, i.ActionType, i.PredictionUUID)
+		}
+	}
+	s.predictionInteractions = append(s.predictionInteractions, &memPredictionInteraction{uuid: uuid.NewString(), createdAt: time.Now(), PredictionInteraction: i})
+	return nil
+}
+
+// UpdatePredictionInteractionStatus changes the status of a PredictionInteraction.
+func (s *MemoryStateStorage) UpdatePredictionInteractionStatus(ctx context.Context, i core.PredictionInteraction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rowsAffected := 0
+	for _, existing := range s.predictionInteractions {
+		if existing.sameKey(i) && existing.Status == "PENDING" {
+			existing.Status = i.Status
+			existing.Error = i.Error
+			rowsAffected++
+		}
+	}
+	if rowsAffected == 0 {
+		return errors.New("update of prediction interaction status didn't update any rows")
+	}
+	return nil
+}
+
+// GetPendingPredictionInteractions returns pending prediction interactions from memory, oldest first.
+func (s *MemoryStateStorage) GetPendingPredictionInteractions(ctx context.Context) ([]core.PredictionInteraction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	interactions := []core.PredictionInteraction{}
+	for _, i := range s.predictionInteractions {
+		if i.Status != "PENDING" {
+			continue
+		}
+		// Postgres doesn't SELECT the error column here either.
+		interaction := i.PredictionInteraction
+		interaction.Error = ""
+		interactions = append(interactions, interaction)
+	}
+	return interactions, nil
+}
+
+// GetPredictionInteractions returns prediction interactions in any status from memory, oldest first.
+func (s *MemoryStateStorage) GetPredictionInteractions(ctx context.Context, predictionUUID string) ([]core.PredictionInteraction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	interactions := []core.PredictionInteraction{}
+	for _, i := range s.predictionInteractions {
+		if predictionUUID == "" || i.PredictionUUID == predictionUUID {
+			interactions = append(interactions, i.PredictionInteraction)
+		}
+	}
+	return interactions, nil
+}
+
+func (i memPredictionInteraction) sameKey(o core.PredictionInteraction) bool {
+	return i.PredictionUUID == o.PredictionUUID && i.PostURL == o.PostURL && i.ActionType == o.ActionType
+}
+
+func matchesAll(p *memPrediction, matchers []func(*memPrediction) bool) bool {
+	for _, matches := range matchers {
+		if !matches(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func memPredictionsFlag(flag *bool, get func(*memPrediction) bool) func(*memPrediction) bool {
+	return func(p *memPrediction) bool { return flag == nil || *flag == get(p) }
+}
+
+func memPredictionsAuthorHandles(authorHandles []string) func(*memPrediction) bool {
+	return func(p *memPrediction) bool {
+		return len(authorHandles) == 0 || containsStr(authorHandles, p.postAuthor)
+	}
+}
+
+func memPredictionsAuthorURLs(authorURLs []string) func(*memPrediction) bool {
+	return func(p *memPrediction) bool { return len(authorURLs) == 0 || containsStr(authorURLs, p.postAuthorURL) }
+}
+
+func memPredictionsPredictionStateValues(predictionStateValues []string) func(*memPrediction) bool {
+	valid := []string{}
+	for _, rawPredictionStateValue := range predictionStateValues {
+		if _, err := core.PredictionStateValueFromString(rawPredictionStateValue); err != nil {
+			continue
+		}
+		valid = append(valid, rawPredictionStateValue)
+	}
+	return func(p *memPrediction) bool { return len(valid) == 0 || containsStr(valid, p.stateValue) }
+}
+
+func memPredictionsPredictionStateStatuses(predictionStateStatuses []string) func(*memPrediction) bool {
+	valid := []string{}
+	for _, rawPredictionStateStatus := range predictionStateStatuses {
+		if _, err := core.ConditionStatusFromString(rawPredictionStateStatus); err != nil {
+			continue
+		}
+		valid = append(valid, rawPredictionStateStatus)
+	}
+	return func(p *memPrediction) bool { return len(valid) == 0 || containsStr(valid, p.stateStatus) }
+}
+
+func memPredictionsUUIDs(uuids []string) func(*memPrediction) bool {
+	return func(p *memPrediction) bool { return len(uuids) == 0 || containsStr(uuids, p.uuid) }
+}
+
+func memPredictionsURLs(urls []string) func(*memPrediction) bool {
+	return func(p *memPrediction) bool { return len(urls) == 0 || containsStr(urls, p.postURL) }
+}
+
+func memPredictionsTags(tags []string) func(*memPrediction) bool {
+	return func(p *memPrediction) bool {
+		if len(tags) == 0 {
+			return true
+		}
+		for _, tag := range p.tags {
+			if containsStr(tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func memGreaterThanUUID(uuid string) func(*memPrediction) bool {
+	return func(p *memPrediction) bool { return uuid == "" || p.uuid > uuid }
+}
+
+func memPredictionsDueAt(dueAt core.ISO8601) func(*memPrediction) bool {
+	dueTm, err := dueAt.Time()
+	return func(p *memPrediction) bool {
+		return dueAt == "" || err != nil || p.nextRunTs == 0 || p.nextRunTs <= int(dueTm.Unix())
+	}
+}
+
+func memIncludeUIUnsupported(includeUIUnsupported bool) func(*memPrediction) bool {
+	return func(p *memPrediction) bool {
+		if includeUIUnsupported {
+			return true
+		}
+		for predictionType := range core.UIUnsupportedPredictionTypes {
+			if predictionType.String() == p.predType {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// lessByOrderBy interprets an ORDER BY clause as built by predictionsBuildOrderBy or accountsBuildOrderBy, so that
+// the in-memory ordering cannot drift from the Postgres one. compare must return <0, 0 or >0 for the given column.
+func lessByOrderBy(orderBy string, compare func(column string) int) bool {
+	for _, clause := range strings.Split(orderBy, ", ") {
+		parts := strings.Fields(clause)
+		if len(parts) != 2 {
+			continue
+		}
+		cmp := compare(parts[0])
+		if parts[1] == "DESC" {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return false
+}
+
+func compareISO8601(a, b core.ISO8601) int {
+	ta, _ := a.Time()
+	tb, _ := b.Time()
+	return compareTime(ta, tb)
+}
+
+// compareNullableTime sorts nil times as larger than any other time, which is how Postgres sorts NULLs by default.
+func compareNullableTime(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return compareTime(*a, *b)
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// paginate mirrors " LIMIT limit OFFSET offset", which is only applied if limit > 0.
+func paginate[T any](rows []T, limit, offset int) []T {
+	if limit <= 0 {
+		return rows
+	}
+	if offset >= len(rows) {
+		return []T{}
+	}
+	rows = rows[offset:]
+	if limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func copyAccount(a core.Account) core.Account {
+	if a.URL != nil {
+		u := *a.URL
+		a.URL = &u
+	}
+	var thumbnails []*url.URL
+	for _, thumbnail := range a.Thumbnails {
+		t := *thumbnail
+		thumbnails = append(thumbnails, &t)
+	}
+	a.Thumbnails = thumbnails
+	if a.CreatedAt != nil {
+		createdAt := *a.CreatedAt
+		a.CreatedAt = &createdAt
+	}
+	return a
+}
+
+func containsStr(ss []string, s string) bool {
+	for _, candidate := range ss {
+		if candidate == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+package statestorage
+
+import "testing"
+
+func TestMemory(t *testing.T) {
+	for _, ts := range storeContractTests {
+		t.Run(ts.name, func(t *testing.T) {
+			ts.test(t, NewMemoryStateStorage())
+		})
+	}
+}
@@ -0,0 +1,5 @@
+CREATE TABLE leases (
+    name text PRIMARY KEY,
+    owner text NOT NULL,
+    expires_at timestamp without time zone NOT NULL
+);
@@ -0,0 +1,3 @@
+DROP INDEX predictions_next_run_at_idx;
+
+ALTER TABLE predictions DROP COLUMN next_run_at;
@@ -0,0 +1,3 @@
+ALTER TABLE predictions ADD COLUMN next_run_at timestamp without time zone;
+
+CREATE INDEX predictions_next_run_at_idx ON predictions(next_run_at timestamp_ops);
@@ -0,0 +1,10 @@
+CREATE TABLE exchange_failovers (
+    prediction_uuid uuid NOT NULL,
+    condition_name text NOT NULL,
+    from_operand text NOT NULL,
+    to_operand text NOT NULL,
+    reason text NOT NULL,
+    created_at timestamp without time zone DEFAULT now()
+);
+
+CREATE INDEX exchange_failovers_prediction_uuid_idx ON exchange_failovers(prediction_uuid);
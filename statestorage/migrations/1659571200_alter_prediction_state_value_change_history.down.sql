@@ -0,0 +1,13 @@
+DROP INDEX prediction_state_value_change_prediction_uuid_idx;
+
+-- Only the latest change to each value fits in the old primary key.
+DELETE FROM prediction_state_value_change a USING prediction_state_value_change b
+  WHERE a.prediction_uuid = b.prediction_uuid AND a.state_value = b.state_value AND a.id < b.id;
+
+ALTER TABLE prediction_state_value_change
+  DROP COLUMN id,
+  DROP COLUMN condition_name,
+  DROP COLUMN condition_value,
+  DROP COLUMN ticks;
+
+ALTER TABLE prediction_state_value_change ADD PRIMARY KEY (prediction_uuid, state_value);
@@ -0,0 +1,9 @@
+ALTER TABLE prediction_state_value_change DROP CONSTRAINT prediction_state_value_change_pkey;
+
+ALTER TABLE prediction_state_value_change
+  ADD COLUMN id bigserial PRIMARY KEY,
+  ADD COLUMN condition_name text NOT NULL DEFAULT '',
+  ADD COLUMN condition_value text NOT NULL DEFAULT '',
+  ADD COLUMN ticks jsonb;
+
+CREATE INDEX prediction_state_value_change_prediction_uuid_idx ON prediction_state_value_change(prediction_uuid);
@@ -1,12 +1,14 @@
 package statestorage
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"errors"
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 
@@ -32,16 +34,17 @@ type PostgresConf struct {
 
 // PostgresDBStateStorage is the Postgres implementation of StateStorage.
 type PostgresDBStateStorage struct {
-	db    *sql.DB
-	debug bool
+	db       *sql.DB
+	debug    bool
+	readOnly bool
 }
 
 //go:embed migrations/*.sql
 var fs embed.FS
 
 // MustNewPostgresDBStateStorage constructs a PostgresDBStateStorage. May fatal.
-func MustNewPostgresDBStateStorage(c PostgresConf) *PostgresDBStateStorage {
-	p, err := NewPostgresDBStateStorage(c)
+func MustNewPostgresDBStateStorage(c PostgresConf, options ...func(*PostgresDBStateStorage)) *PostgresDBStateStorage {
+	p, err := NewPostgresDBStateStorage(c, options...)
 	if err != nil {
 		connStr := fmt.Sprintf("postgres://%v:%v@%v:%v/%v?sslmode=disable", c.User, c.Pass, c.Host, c.Port, c.Database)
 		log.Fatal().Err(err).Msgf("An addressable postgres database is required. Currently looking for it in: %v. Configure these parameters via the PREDICTIONS_POSTGRES_ env variables described in the README.", connStr)
@@ -49,29 +52,47 @@ func MustNewPostgresDBStateStorage(c PostgresConf) *PostgresDBStateStorage {
 	return &p
 }
 
-// NewPostgresDBStateStorage constructs a PostgresDBStateStorage.
-func NewPostgresDBStateStorage(c PostgresConf) (PostgresDBStateStorage, error) {
-	connStr := fmt.Sprintf("postgres://%v:%v@%v:%v/%v?sslmode=disable", c.User, c.Pass, c.Host, c.Port, c.Database)
-
-	d, err := iofs.New(fs, "migrations")
-	if err != nil {
-		return PostgresDBStateStorage{}, err
+// WithReadOnlyPostgres makes the PostgresDBStateStorage leave the database as it is, e.g. for tools that inspect a
+// production database: migrations aren't run, and every transaction is read-only, so writes fail.
+func WithReadOnlyPostgres() func(*PostgresDBStateStorage) {
+	return func(s *PostgresDBStateStorage) {
+		s.readOnly = true
 	}
-	m, err := migrate.NewWithSourceInstance("iofs", d, connStr)
-	if err != nil {
-		return PostgresDBStateStorage{}, err
+}
+
+// NewPostgresDBStateStorage constructs a PostgresDBStateStorage. Unless it's read-only, pending migrations are run.
+func NewPostgresDBStateStorage(c PostgresConf, options ...func(*PostgresDBStateStorage)) (PostgresDBStateStorage, error) {
+	s := PostgresDBStateStorage{}
+	for _, option := range options {
+		option(&s)
 	}
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return PostgresDBStateStorage{}, err
+
+	connStr := fmt.Sprintf("postgres://%v:%v@%v:%v/%v?sslmode=disable", c.User, c.Pass, c.Host, c.Port, c.Database)
+	if s.readOnly {
+		// Unknown connection parameters are sent to Postgres as run-time parameters.
+		connStr += "&default_transaction_read_only=on"
+	} else {
+		d, err := iofs.New(fs, "migrations")
+		if err != nil {
+			return PostgresDBStateStorage{}, err
+		}
+		m, err := migrate.NewWithSourceInstance("iofs", d, connStr)
+		if err != nil {
+			return PostgresDBStateStorage{}, err
+		}
+		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+			return PostgresDBStateStorage{}, err
+		}
 	}
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return PostgresDBStateStorage{}, err
 	}
+	s.db = db
 
-	log.Info().Str("url", connStr).Msgf("Connected to Postgres DB")
-	return PostgresDBStateStorage{db: db}, nil
+	log.Info().Str("url", connStr).Bool("readOnly", s.readOnly).Msgf("Connected to Postgres DB")
+	return s, nil
 }
 
 // SetDebug sets the debug logging setting across the storage layer.
@@ -79,7 +100,8 @@ func (s *PostgresDBStateStorage) SetDebug(debug bool) {
 	s.debug = debug
 }
 
-// DB returns the DB for raw queries
+// DB returns the DB for raw queries. It's not part of StateStorage on purpose: it's only meant for setting up test
+// databases.
 func (s *PostgresDBStateStorage) DB() *sql.DB {
 	return s.db
 }
@@ -106,9 +128,8 @@ func predictionsBuildOrderBy(orderBys []string) string {
 	return strings.Join(resultArr, ", ")
 }
 
-// GetPredictions SELECTs predictions from the database.
-func (s PostgresDBStateStorage) GetPredictions(filters core.APIFilters, orderBys []string, limit, offset int) ([]core.Prediction, error) {
-	where, args := (&pgWhereBuilder{}).addFilters([]filterable{
+func pgPredictionsWhere(filters core.APIFilters) (string, []interface{}) {
+	return (&pgWhereBuilder{}).addFilters([]filterable{
 		pgPredictionsAuthorHandles{filters.AuthorHandles},
 		pgPredictionsAuthorURLs{filters.AuthorURLs},
 		pgPredictionsDeleted{filters.Deleted},
@@ -121,8 +142,13 @@ func (s PostgresDBStateStorage) GetPredictions(filters core.APIFilters, orderBys
 		pgPredictionsTags{filters.Tags},
 		pgGreaterThanUUID{filters.GreaterThanUUID},
 		pgIncludeUIUnsupported{filters.IncludeUIUnsupported},
+		pgPredictionsDueAt{filters.DueAt},
 	}).build()
+}
 
+// GetPredictions SELECTs predictions from the database.
+func (s PostgresDBStateStorage) GetPredictions(ctx context.Context, filters core.APIFilters, orderBys []string, limit, offset int) ([]core.Prediction, error) {
+	where, args := pgPredictionsWhere(filters)
 	orderBy := predictionsBuildOrderBy(orderBys)
 	limitStr := ""
 	if limit > 0 {
@@ -135,7 +161,7 @@ func (s PostgresDBStateStorage) GetPredictions(filters core.APIFilters, orderBys
 		log.Info().Msgf("PostgresDBStateStorage.GetPredictions: for filters %+v and orderBy %+v: %v\n", filters, orderBys, sql)
 	}
 
-	rows, err := s.db.Query(sql, args...)
+	rows, err := s.db.QueryContext(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -166,6 +192,111 @@ func (s PostgresDBStateStorage) GetPredictions(filters core.APIFilters, orderBys
 	return result, nil
 }
 
+// CountPredictions counts the predictions in the database that match the filters.
+func (s PostgresDBStateStorage) CountPredictions(ctx context.Context, filters core.APIFilters) (int, error) {
+	where, args := pgPredictionsWhere(filters)
+	var count int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM predictions WHERE %v", where), args...).Scan(&count)
+	return count, err
+}
+
+// GetNextPredictionRunAt returns the earliest next_run_at of the predictions in the database that match the filters.
+func (s PostgresDBStateStorage) GetNextPredictionRunAt(ctx context.Context, filters core.APIFilters) (time.Time, bool, error) {
+	where, args := pgPredictionsWhere(filters)
+	var nextRunAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT next_run_at FROM predictions WHERE %v ORDER BY next_run_at ASC NULLS FIRST LIMIT 1", where), args...).Scan(&nextRunAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if !nextRunAt.Valid {
+		return time.Time{}, true, nil
+	}
+	return nextRunAt.Time.UTC(), true, nil
+}
+
+// GetPredictionStats counts the predictions in the database that match the filters, by state value.
+func (s PostgresDBStateStorage) GetPredictionStats(ctx context.Context, filters core.APIFilters) (core.PredictionStats, error) {
+	where, args := pgPredictionsWhere(filters)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT blob->'state'->>'value', COUNT(*) FROM predictions WHERE %v GROUP BY 1", where), args...)
+	if err != nil {
+		return core.PredictionStats{}, err
+	}
+	defer rows.Close()
+
+	stats := core.PredictionStats{ByStateValue: map[string]int{}}
+	for rows.Next() {
+		var (
+			stateValue sql.NullString
+			count      int
+		)
+		if err := rows.Scan(&stateValue, &count); err != nil {
+			return core.PredictionStats{}, err
+		}
+		stats.ByStateValue[stateValue.String] += count
+		stats.Total += count
+	}
+	return stats, rows.Err()
+}
+
+// GetAccountStats calculates the AccountStats of the authors of the visible predictions in the database that match
+// the filters, or only of the top limit ones if limit > 0.
+func (s PostgresDBStateStorage) GetAccountStats(ctx context.Context, filters core.AccountStatsFilters, limit int) ([]core.AccountStats, error) {
+	f := false
+	where, args := (&pgWhereBuilder{}).addFilters([]filterable{
+		pgPredictionsAuthorHandles{filters.AuthorHandles},
+		pgPredictionsAuthorURLs{filters.AuthorURLs},
+		pgPredictionsDeleted{&f},
+		pgPredictionsHidden{&f},
+		pgPredictionsPredictionStateValues{finishedPredictionStateValues},
+		pgPredictionsTypes{filters.PredictionTypes},
+		pgPredictionsPostedAt{filters.PostedFrom, filters.PostedTo},
+		pgPredictionsCoins{filters.Coins},
+	}).build()
+	limitStr := ""
+	if limit > 0 {
+		limitStr = fmt.Sprintf(" LIMIT %v", limit)
+	}
+	// Ordered like core.RankAccountStats, so that the limit keeps the top ones.
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT handle, correct, incorrect, annulled, score FROM (
+			SELECT
+				blob->>'postAuthor' AS handle,
+				SUM(CASE WHEN blob->'state'->>'value' = 'CORRECT' THEN 1 ELSE 0 END) AS correct,
+				SUM(CASE WHEN blob->'state'->>'value' = 'INCORRECT' THEN 1 ELSE 0 END) AS incorrect,
+				SUM(CASE WHEN blob->'state'->>'value' = 'ANNULLED' THEN 1 ELSE 0 END) AS annulled,
+				SUM(CASE blob->'state'->>'value'
+					WHEN 'CORRECT' THEN 1 + COALESCE((blob->'state'->>'difficulty')::float8, 0)
+					WHEN 'INCORRECT' THEN -1
+					ELSE 0
+				END) AS score
+			FROM predictions WHERE %v GROUP BY 1
+		) stats
+		ORDER BY
+			score DESC,
+			CASE WHEN correct + incorrect > 0 THEN correct::float8 / (correct + incorrect) ELSE 0 END DESC,
+			handle COLLATE "C"%v`, where, limitStr), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []core.AccountStats{}
+	for rows.Next() {
+		var accountStats core.AccountStats
+		if err := rows.Scan(&accountStats.Handle, &accountStats.Correct, &accountStats.Incorrect, &accountStats.Annulled, &accountStats.Score); err != nil {
+			return nil, err
+		}
+		stats = append(stats, accountStats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return core.RankAccountStats(stats), nil
+}
+
 func accountsBuildOrderBy(orderBys []string) string {
 	if len(orderBys) == 0 {
 		orderBys = []string{core.AccountFollowerCountDesc.String()}
@@ -185,7 +316,7 @@ func accountsBuildOrderBy(orderBys []string) string {
 }
 
 // GetAccounts SELECTs accounts from the database.
-func (s PostgresDBStateStorage) GetAccounts(filters core.APIAccountFilters, orderBys []string, limit, offset int) ([]core.Account, error) {
+func (s PostgresDBStateStorage) GetAccounts(ctx context.Context, filters core.APIAccountFilters, orderBys []string, limit, offset int) ([]core.Account, error) {
 	where, args := (&pgWhereBuilder{}).addFilters([]filterable{
 		pgAccountsHandles{filters.Handles},
 		pgAccountsURLs{filters.URLs},
@@ -203,7 +334,7 @@ func (s PostgresDBStateStorage) GetAccounts(filters core.APIAccountFilters, orde
 		log.Info().Msgf("PostgresDBStateStorage.GetAccounts: for filters %+v and orderBy %+v: %v\n", filters, orderBys, query)
 	}
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -251,12 +382,40 @@ func (s PostgresDBStateStorage) GetAccounts(filters core.APIAccountFilters, orde
 }
 
 // UpsertPredictions UPSERTs predictions to the database.
-func (s PostgresDBStateStorage) UpsertPredictions(ps []*core.Prediction) ([]*core.Prediction, error) {
+func (s PostgresDBStateStorage) UpsertPredictions(ctx context.Context, ps []*core.Prediction) ([]*core.Prediction, error) {
 	if len(ps) == 0 {
 		return ps, nil
 	}
 
-	builder := newPGUpsertManyBuilder([]string{"uuid", "blob", "created_at", "posted_at", "tags", "post_url"}, "predictions", "uuid")
+	return ps, pgUpsertPredictions(ctx, s.db, ps)
+}
+
+// StoreEvolvedPrediction upserts the prediction and logs the changes of its PredictionStateValue in one transaction.
+func (s PostgresDBStateStorage) StoreEvolvedPrediction(ctx context.Context, p *core.Prediction, changes []core.PredictionStateValueChange) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := pgUpsertPredictions(ctx, tx, []*core.Prediction{p}); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if err := pgLogPredictionStateValueChange(ctx, tx, c); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// sqlExecer is what *sql.DB and *sql.Tx have in common to run statements, so that they can run in a transaction or not.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func pgUpsertPredictions(ctx context.Context, db sqlExecer, ps []*core.Prediction) error {
+	builder := newPGUpsertManyBuilder([]string{"uuid", "blob", "created_at", "posted_at", "tags", "post_url", "next_run_at"}, "predictions", "uuid")
 	for i := range ps {
 		if ps[i].UUID == "" {
 			ps[i].UUID = uuid.NewString()
@@ -265,16 +424,16 @@ func (s PostgresDBStateStorage) UpsertPredictions(ps []*core.Prediction) ([]*cor
 		if err != nil {
 			log.Info().Msgf("Failed to marshal prediction, with error: %v\n", err)
 		}
-		builder.addRow(ps[i].UUID, blob, ps[i].CreatedAt, ps[i].PostedAt, pq.Array(ps[i].CalculateTags()), ps[i].PostURL)
+		builder.addRow(ps[i].UUID, blob, ps[i].CreatedAt, ps[i].PostedAt, pq.Array(ps[i].CalculateTags()), ps[i].PostURL, pgNextRunAt(*ps[i]))
 	}
 	sql, args := builder.build()
-	_, err := s.db.Exec(sql, args...)
-	return ps, err
+	_, err := db.ExecContext(ctx, sql, args...)
+	return err
 }
 
 // PausePrediction sets a prediction to paused on the database. Paused predictions are visible but don't evolve.
-func (s PostgresDBStateStorage) PausePrediction(uuid string) error {
-	res, err := s.db.Exec("UPDATE predictions SET paused = true WHERE uuid::text = $1", uuid)
+func (s PostgresDBStateStorage) PausePrediction(ctx context.Context, uuid string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE predictions SET paused = true WHERE uuid::text = $1", uuid)
 	if err != nil {
 		return err
 	}
@@ -289,8 +448,8 @@ func (s PostgresDBStateStorage) PausePrediction(uuid string) error {
 }
 
 // UnpausePrediction sets a prediction to unpaused on the database. Paused predictions are visible but don't evolve.
-func (s PostgresDBStateStorage) UnpausePrediction(uuid string) error {
-	res, err := s.db.Exec("UPDATE predictions SET paused = false WHERE uuid::text = $1", uuid)
+func (s PostgresDBStateStorage) UnpausePrediction(ctx context.Context, uuid string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE predictions SET paused = false WHERE uuid::text = $1", uuid)
 	if err != nil {
 		return err
 	}
@@ -305,8 +464,8 @@ func (s PostgresDBStateStorage) UnpausePrediction(uuid string) error {
 }
 
 // HidePrediction sets a prediction to hidden on the database. Hidden predictions are invisible but still evolve.
-func (s PostgresDBStateStorage) HidePrediction(uuid string) error {
-	res, err := s.db.Exec("UPDATE predictions SET hidden = true WHERE uuid::text = $1", uuid)
+func (s PostgresDBStateStorage) HidePrediction(ctx context.Context, uuid string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE predictions SET hidden = true WHERE uuid::text = $1", uuid)
 	if err != nil {
 		return err
 	}
@@ -321,8 +480,8 @@ func (s PostgresDBStateStorage) HidePrediction(uuid string) error {
 }
 
 // UnhidePrediction sets a prediction to visible on the database. Hidden predictions are invisible but still evolve.
-func (s PostgresDBStateStorage) UnhidePrediction(uuid string) error {
-	res, err := s.db.Exec("UPDATE predictions SET hidden = false WHERE uuid::text = $1", uuid)
+func (s PostgresDBStateStorage) UnhidePrediction(ctx context.Context, uuid string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE predictions SET hidden = false WHERE uuid::text = $1", uuid)
 	if err != nil {
 		return err
 	}
@@ -337,8 +496,8 @@ func (s PostgresDBStateStorage) UnhidePrediction(uuid string) error {
 }
 
 // DeletePrediction sets a prediction to deleted on the database. Deleted predictions are invisible and don't evolve.
-func (s *PostgresDBStateStorage) DeletePrediction(uuid string) error {
-	res, err := s.db.Exec("UPDATE predictions SET deleted = true WHERE uuid::text = $1", uuid)
+func (s *PostgresDBStateStorage) DeletePrediction(ctx context.Context, uuid string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE predictions SET deleted = true WHERE uuid::text = $1", uuid)
 	if err != nil {
 		return err
 	}
@@ -353,8 +512,8 @@ func (s *PostgresDBStateStorage) DeletePrediction(uuid string) error {
 }
 
 // UndeletePrediction restores a deleted prediction on the database. Deleted predictions are invisible and don't evolve.
-func (s *PostgresDBStateStorage) UndeletePrediction(uuid string) error {
-	res, err := s.db.Exec("UPDATE predictions SET deleted = false WHERE uuid::text = $1", uuid)
+func (s *PostgresDBStateStorage) UndeletePrediction(ctx context.Context, uuid string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE predictions SET deleted = false WHERE uuid::text = $1", uuid)
 	if err != nil {
 		return err
 	}
@@ -368,41 +527,223 @@ func (s *PostgresDBStateStorage) UndeletePrediction(uuid string) error {
 	return nil
 }
 
-// UpsertAccounts UPSERTs accounts to the database.
-func (s PostgresDBStateStorage) UpsertAccounts(as []*core.Account) ([]*core.Account, error) {
+// UpsertAccounts UPSERTs accounts to the database, except for aliases of other accounts.
+func (s PostgresDBStateStorage) UpsertAccounts(ctx context.Context, as []*core.Account) ([]*core.Account, error) {
 	if len(as) == 0 {
 		return as, nil
 	}
+	aliases, err := s.GetAccountAliases(ctx, accountURLs(as))
+	if err != nil {
+		return as, err
+	}
 
 	builder := newPGUpsertManyBuilder([]string{"url", "account_type", "handle", "follower_count", "thumbnails", "name", "description", "created_at", "is_verified"}, "accounts", "url")
 	for _, a := range as {
+		if _, ok := aliases[a.URL.String()]; ok {
+			continue
+		}
 		thumbnails := []string{}
 		for _, thumb := range a.Thumbnails {
 			thumbnails = append(thumbnails, thumb.String())
 		}
 		builder.addRow(a.URL.String(), a.AccountType, a.Handle, a.FollowerCount, pq.Array(thumbnails), a.Name, a.Description, a.CreatedAt, a.IsVerified)
 	}
+	if builder.rowCount == 0 {
+		return as, nil
+	}
 	sql, args := builder.build()
-	_, err := s.db.Exec(sql, args...)
+	_, err = s.db.ExecContext(ctx, sql, args...)
 	return as, err
 }
 
+// DeleteAccount deletes an account from the database.
+func (s PostgresDBStateStorage) DeleteAccount(ctx context.Context, url string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM accounts WHERE url = $1", url)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return nil
+	}
+	if count == 0 {
+		return fmt.Errorf("url not found: %v", url)
+	}
+	return nil
+}
+
+// MergeAccounts merges the account with fromURL into the account into on the database, in a transaction.
+func (s PostgresDBStateStorage) MergeAccounts(ctx context.Context, fromURL string, into core.Account) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM accounts WHERE url = $1", fromURL)
+	if err != nil {
+		return 0, err
+	}
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return 0, fmt.Errorf("url not found: %v", fromURL)
+	}
+
+	res, err = tx.ExecContext(ctx, `UPDATE predictions
+		SET blob = jsonb_set(jsonb_set(blob, '{postAuthorURL}', to_jsonb($2::text)), '{postAuthor}', to_jsonb($3::text))
+		WHERE blob->>'postAuthorURL' = $1`, fromURL, into.URL.String(), into.Handle)
+	if err != nil {
+		return 0, err
+	}
+	repointed, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	// Aliases of the merged account become aliases of the account it's merged into.
+	if _, err := tx.ExecContext(ctx, "UPDATE account_aliases SET alias_of = $2 WHERE alias_of = $1", fromURL, into.URL.String()); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO account_aliases (url, alias_of) VALUES ($1, $2) ON CONFLICT (url) DO UPDATE SET alias_of = EXCLUDED.alias_of", fromURL, into.URL.String()); err != nil {
+		return 0, err
+	}
+
+	return int(repointed), tx.Commit()
+}
+
+// GetAccountAliases returns the URL of the account that each of the urls is an alias of, from the database.
+func (s PostgresDBStateStorage) GetAccountAliases(ctx context.Context, urls []string) (map[string]string, error) {
+	aliases := map[string]string{}
+	if len(urls) == 0 {
+		return aliases, nil
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT url, alias_of FROM account_aliases WHERE url = ANY($1)", pq.Array(urls))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var aliasURL, aliasOf string
+		if err := rows.Scan(&aliasURL, &aliasOf); err != nil {
+			return nil, err
+		}
+		aliases[aliasURL] = aliasOf
+	}
+	return aliases, rows.Err()
+}
+
 // LogPredictionStateValueChange logs the fact that a prediction changed PredictionStateValue to the database.
-func (s PostgresDBStateStorage) LogPredictionStateValueChange(c core.PredictionStateValueChange) error {
-	_, err := s.db.Exec(`
+func (s PostgresDBStateStorage) LogPredictionStateValueChange(ctx context.Context, c core.PredictionStateValueChange) error {
+	return pgLogPredictionStateValueChange(ctx, s.db, c)
+}
+
+func pgLogPredictionStateValueChange(ctx context.Context, db sqlExecer, c core.PredictionStateValueChange) error {
+	ticks, err := marshalTicks(c.Ticks)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
 		INSERT INTO prediction_state_value_change
-		(prediction_uuid, state_value, created_at)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (prediction_uuid, state_value) DO UPDATE SET created_at = EXCLUDED.created_at
-		`, c.PredictionUUID, c.StateValue, c.CreatedAt)
+		(prediction_uuid, state_value, condition_name, condition_value, ticks, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		`, c.PredictionUUID, c.StateValue, c.ConditionName, c.ConditionValue, ticks, c.CreatedAt)
+
+	return err
+}
+
+// GetPredictionStateValueChanges SELECTs the state value changes of a prediction from the database, oldest first.
+func (s PostgresDBStateStorage) GetPredictionStateValueChanges(ctx context.Context, predictionUUID string) ([]core.PredictionStateValueChange, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT prediction_uuid, state_value, condition_name, condition_value, ticks, created_at FROM prediction_state_value_change WHERE prediction_uuid::text = $1 ORDER BY created_at, id", predictionUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []core.PredictionStateValueChange{}
+	for rows.Next() {
+		var (
+			change    core.PredictionStateValueChange
+			ticks     sql.NullString
+			createdAt pq.NullTime
+		)
+		if err := rows.Scan(&change.PredictionUUID, &change.StateValue, &change.ConditionName, &change.ConditionValue, &ticks, &createdAt); err != nil {
+			return nil, err
+		}
+		if change.Ticks, err = unmarshalTicks(ticks); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			change.CreatedAt = core.ISO8601(createdAt.Time.Format(time.RFC3339))
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// LogExchangeFailover logs the fact that a prediction's condition failed over to another exchange to the database.
+func (s PostgresDBStateStorage) LogExchangeFailover(ctx context.Context, f core.ExchangeFailover) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO exchange_failovers
+		(prediction_uuid, condition_name, from_operand, to_operand, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		`, f.PredictionUUID, f.ConditionName, f.FromOperand, f.ToOperand, f.Reason, f.CreatedAt)
+
+	return err
+}
+
+// GetExchangeFailovers SELECTs the exchange failovers of a prediction's conditions from the database, oldest first.
+func (s PostgresDBStateStorage) GetExchangeFailovers(ctx context.Context, predictionUUID string) ([]core.ExchangeFailover, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT prediction_uuid, condition_name, from_operand, to_operand, reason, created_at FROM exchange_failovers WHERE prediction_uuid::text = $1 ORDER BY created_at", predictionUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failovers := []core.ExchangeFailover{}
+	for rows.Next() {
+		var (
+			failover  core.ExchangeFailover
+			createdAt pq.NullTime
+		)
+		if err := rows.Scan(&failover.PredictionUUID, &failover.ConditionName, &failover.FromOperand, &failover.ToOperand, &failover.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			failover.CreatedAt = core.ISO8601(createdAt.Time.Format(time.RFC3339))
+		}
+		failovers = append(failovers, failover)
+	}
+	return failovers, rows.Err()
+}
 
+// AcquireLease takes the named lease for owner on the database. Expiry is checked against the database's clock, so
+// that instances with skewed clocks agree on it.
+func (s PostgresDBStateStorage) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO leases (name, owner, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+		WHERE leases.owner = EXCLUDED.owner OR leases.expires_at <= NOW()
+		`, name, owner, ttl.Seconds())
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
+
+// ReleaseLease gives up the named lease on the database, if owner holds it.
+func (s PostgresDBStateStorage) ReleaseLease(ctx context.Context, name, owner string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM leases WHERE name = $1 AND owner = $2", name, owner)
 	return err
 }
 
 // NonPendingPredictionInteractionExists checks the database to see if a predictions creation or finalization Tweet post happened.
-func (s PostgresDBStateStorage) NonPendingPredictionInteractionExists(interaction core.PredictionInteraction) (bool, error) {
+func (s PostgresDBStateStorage) NonPendingPredictionInteractionExists(ctx context.Context, interaction core.PredictionInteraction) (bool, error) {
 	var exists bool
-	res, err := s.db.Query(`
+	res, err := s.db.QueryContext(ctx, `
 	SELECT EXISTS(SELECT * FROM prediction_interactions WHERE prediction_uuid = $1 AND post_url = $2 AND action_type = $3 AND status != 'PENDING');
 		`, interaction.PredictionUUID, interaction.PostURL, interaction.ActionType)
 	if err != nil {
@@ -416,8 +757,8 @@ func (s PostgresDBStateStorage) NonPendingPredictionInteractionExists(interactio
 }
 
 // InsertPredictionInteraction logs the fact that a Tweet was sent when a prediction was created or finalized.
-func (s PostgresDBStateStorage) InsertPredictionInteraction(i core.PredictionInteraction) error {
-	_, err := s.db.Query(`
+func (s PostgresDBStateStorage) InsertPredictionInteraction(ctx context.Context, i core.PredictionInteraction) error {
+	_, err := s.db.QueryContext(ctx, `
 	INSERT INTO prediction_interactions (uuid, prediction_uuid, post_url, action_type, interaction_post_url, status, error) VALUES ($1, $2, $3, $4, $5, $6, $7);
 		`, uuid.NewString(), i.PredictionUUID, i.PostURL, i.ActionType, i.InteractionPostURL, i.Status, i.Error)
 	if err != nil {
@@ -427,8 +768,8 @@ func (s PostgresDBStateStorage) InsertPredictionInteraction(i core.PredictionInt
 }
 
 // UpdatePredictionInteractionStatus changes the status of a PredictionInteraction.
-func (s PostgresDBStateStorage) UpdatePredictionInteractionStatus(i core.PredictionInteraction) error {
-	res, err := s.db.Exec(`
+func (s PostgresDBStateStorage) UpdatePredictionInteractionStatus(ctx context.Context, i core.PredictionInteraction) error {
+	res, err := s.db.ExecContext(ctx, `
 	UPDATE prediction_interactions SET status = $1, error = $2 WHERE post_url = $3 AND action_type = $4 AND prediction_uuid = $5 AND status = 'PENDING';
 		`, i.Status, i.Error, i.PostURL, i.ActionType, i.PredictionUUID)
 	if err != nil {
@@ -445,8 +786,8 @@ func (s PostgresDBStateStorage) UpdatePredictionInteractionStatus(i core.Predict
 }
 
 // GetPendingPredictionInteractions SELECTs pending prediction interactions from the database.
-func (s PostgresDBStateStorage) GetPendingPredictionInteractions() ([]core.PredictionInteraction, error) {
-	rows, err := s.db.Query("SELECT prediction_uuid, post_url, action_type, interaction_post_url, status FROM prediction_interactions WHERE status = 'PENDING' ORDER BY created_at")
+func (s PostgresDBStateStorage) GetPendingPredictionInteractions(ctx context.Context) ([]core.PredictionInteraction, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT prediction_uuid, post_url, action_type, interaction_post_url, status FROM prediction_interactions WHERE status = 'PENDING' ORDER BY created_at")
 	if err != nil {
 		return nil, err
 	}
@@ -466,6 +807,25 @@ func (s PostgresDBStateStorage) GetPendingPredictionInteractions() ([]core.Predi
 	return interactions, nil
 }
 
+// GetPredictionInteractions SELECTs prediction interactions in any status from the database, oldest first.
+func (s PostgresDBStateStorage) GetPredictionInteractions(ctx context.Context, predictionUUID string) ([]core.PredictionInteraction, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT prediction_uuid, post_url, action_type, interaction_post_url, status, COALESCE(error, '') FROM prediction_interactions WHERE $1 = '' OR prediction_uuid = $1 ORDER BY created_at", predictionUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	interactions := []core.PredictionInteraction{}
+	for rows.Next() {
+		var row core.PredictionInteraction
+		if err := rows.Scan(&row.PredictionUUID, &row.PostURL, &row.ActionType, &row.InteractionPostURL, &row.Status, &row.Error); err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, row)
+	}
+	return interactions, rows.Err()
+}
+
 type pgPredictionsDeleted struct{ deleted *bool }
 
 func (d pgPredictionsDeleted) filter() (string, []interface{}) {
@@ -586,6 +946,42 @@ func (f pgPredictionsTags) filter() (string, []interface{}) {
 	return "", nil
 }
 
+type pgPredictionsTypes struct{ predictionTypes []core.PredictionType }
+
+func (f pgPredictionsTypes) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, predictionType := range f.predictionTypes {
+		args = append(args, predictionType.String())
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("blob->>'type' IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type pgPredictionsPostedAt struct{ from, to time.Time }
+
+func (f pgPredictionsPostedAt) filter() (string, []interface{}) {
+	filters, args := []string{}, []interface{}{}
+	if !f.from.IsZero() {
+		filters, args = append(filters, "posted_at >= ∆"), append(args, f.from.UTC())
+	}
+	if !f.to.IsZero() {
+		filters, args = append(filters, "posted_at < ∆"), append(args, f.to.UTC())
+	}
+	return strings.Join(filters, " AND "), args
+}
+
+type pgPredictionsCoins struct{ coins []string }
+
+func (f pgPredictionsCoins) filter() (string, []interface{}) {
+	args := accountStatsCoinPatterns(f.coins)
+	if len(args) > 0 {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM unnest(tags) AS tag WHERE upper(tag) LIKE ANY (ARRAY[%v]))", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
 type pgPredictionsURLs struct{ urls []string }
 
 func (f pgPredictionsURLs) filter() (string, []interface{}) {
@@ -634,6 +1030,15 @@ func (f pgGreaterThanUUID) filter() (string, []interface{}) {
 	return "uuid > ∆", []interface{}{f.uuid}
 }
 
+type pgPredictionsDueAt struct{ dueAt core.ISO8601 }
+
+func (f pgPredictionsDueAt) filter() (string, []interface{}) {
+	if f.dueAt == "" {
+		return "", nil
+	}
+	return "(next_run_at IS NULL OR next_run_at <= ∆)", []interface{}{f.dueAt}
+}
+
 type pgIncludeUIUnsupported struct{ includeUIUnsupported bool }
 
 func (f pgIncludeUIUnsupported) filter() (string, []interface{}) {
@@ -649,3 +1054,12 @@ func (f pgIncludeUIUnsupported) filter() (string, []interface{}) {
 	}
 	return "", nil
 }
+
+// pgNextRunAt is the value of the next_run_at column of a prediction. It's NULL if it should be evolved right away.
+func pgNextRunAt(p core.Prediction) interface{} {
+	nextRunTs := p.CalculateNextRunTs()
+	if nextRunTs == 0 {
+		return nil
+	}
+	return time.Unix(int64(nextRunTs), 0).UTC().Format(time.RFC3339)
+}
@@ -1,6 +1,7 @@
 package statestorage
 
 import (
+	"context"
 	"net/url"
 	"testing"
 	"time"
@@ -19,10 +20,10 @@ func TestPostgres(t *testing.T) {
 			name: "prediction upsert: base case",
 			test: func(t *testing.T, store StateStorage) {
 				prediction, _ := compile(t, sampleRawPrediction)
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
 				require.Nil(t, err)
 
-				actualPreds, err := store.GetPredictions(core.APIFilters{UUIDs: []string{prediction.UUID}}, []string{}, 0, 0)
+				actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{UUIDs: []string{prediction.UUID}}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 1)
 				require.Equal(t, prediction.PostURL, actualPreds[0].PostURL)
@@ -35,10 +36,10 @@ func TestPostgres(t *testing.T) {
 				prediction2, _ := compile(t, sampleRawPrediction)
 				prediction2.PostURL = "http://different.url"
 
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction1, &prediction2})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction1, &prediction2})
 				require.Nil(t, err)
 
-				actualPreds, err := store.GetPredictions(core.APIFilters{}, []string{}, 0, 0)
+				actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 2)
 				require.Equal(t, prediction1.PostURL, actualPreds[0].PostURL)
@@ -50,7 +51,7 @@ func TestPostgres(t *testing.T) {
 			test: func(t *testing.T, store StateStorage) {
 				prediction, _ := compile(t, sampleRawPrediction)
 
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction, &prediction})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction, &prediction})
 				require.NotNil(t, err)
 				postgresErr := err.(*pq.Error)
 				require.Equal(t, "ON CONFLICT DO UPDATE command cannot affect row a second time", postgresErr.Message)
@@ -60,18 +61,18 @@ func TestPostgres(t *testing.T) {
 			name: "prediction hide",
 			test: func(t *testing.T, store StateStorage) {
 				prediction, _ := compile(t, sampleRawPrediction)
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
 				require.Nil(t, err)
 
-				err = store.HidePrediction(prediction.UUID)
+				err = store.HidePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				actualPreds, err := store.GetPredictions(core.APIFilters{Hidden: pBool(true)}, []string{}, 0, 0)
+				actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{Hidden: pBool(true)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 1)
 				require.Equal(t, prediction.PostURL, actualPreds[0].PostURL)
 
-				actualPreds, err = store.GetPredictions(core.APIFilters{Hidden: pBool(false)}, []string{}, 0, 0)
+				actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{Hidden: pBool(false)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 0)
 			},
@@ -80,21 +81,21 @@ func TestPostgres(t *testing.T) {
 			name: "prediction unhide",
 			test: func(t *testing.T, store StateStorage) {
 				prediction, _ := compile(t, sampleRawPrediction)
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
 				require.Nil(t, err)
 
-				err = store.HidePrediction(prediction.UUID)
+				err = store.HidePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				err = store.UnhidePrediction(prediction.UUID)
+				err = store.UnhidePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				actualPreds, err := store.GetPredictions(core.APIFilters{Hidden: pBool(false)}, []string{}, 0, 0)
+				actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{Hidden: pBool(false)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 1)
 				require.Equal(t, prediction.PostURL, actualPreds[0].PostURL)
 
-				actualPreds, err = store.GetPredictions(core.APIFilters{Hidden: pBool(true)}, []string{}, 0, 0)
+				actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{Hidden: pBool(true)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 0)
 			},
@@ -103,18 +104,18 @@ func TestPostgres(t *testing.T) {
 			name: "prediction delete",
 			test: func(t *testing.T, store StateStorage) {
 				prediction, _ := compile(t, sampleRawPrediction)
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
 				require.Nil(t, err)
 
-				err = store.DeletePrediction(prediction.UUID)
+				err = store.DeletePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				actualPreds, err := store.GetPredictions(core.APIFilters{Deleted: pBool(true)}, []string{}, 0, 0)
+				actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{Deleted: pBool(true)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 1)
 				require.Equal(t, prediction.PostURL, actualPreds[0].PostURL)
 
-				actualPreds, err = store.GetPredictions(core.APIFilters{Deleted: pBool(false)}, []string{}, 0, 0)
+				actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{Deleted: pBool(false)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 0)
 			},
@@ -123,21 +124,21 @@ func TestPostgres(t *testing.T) {
 			name: "prediction undelete",
 			test: func(t *testing.T, store StateStorage) {
 				prediction, _ := compile(t, sampleRawPrediction)
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
 				require.Nil(t, err)
 
-				err = store.DeletePrediction(prediction.UUID)
+				err = store.DeletePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				err = store.UndeletePrediction(prediction.UUID)
+				err = store.UndeletePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				actualPreds, err := store.GetPredictions(core.APIFilters{Deleted: pBool(false)}, []string{}, 0, 0)
+				actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{Deleted: pBool(false)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 1)
 				require.Equal(t, prediction.PostURL, actualPreds[0].PostURL)
 
-				actualPreds, err = store.GetPredictions(core.APIFilters{Deleted: pBool(true)}, []string{}, 0, 0)
+				actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{Deleted: pBool(true)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 0)
 			},
@@ -146,18 +147,18 @@ func TestPostgres(t *testing.T) {
 			name: "prediction pause",
 			test: func(t *testing.T, store StateStorage) {
 				prediction, _ := compile(t, sampleRawPrediction)
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
 				require.Nil(t, err)
 
-				err = store.PausePrediction(prediction.UUID)
+				err = store.PausePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				actualPreds, err := store.GetPredictions(core.APIFilters{Paused: pBool(true)}, []string{}, 0, 0)
+				actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{Paused: pBool(true)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 1)
 				require.Equal(t, prediction.PostURL, actualPreds[0].PostURL)
 
-				actualPreds, err = store.GetPredictions(core.APIFilters{Paused: pBool(false)}, []string{}, 0, 0)
+				actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{Paused: pBool(false)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 0)
 			},
@@ -166,21 +167,21 @@ func TestPostgres(t *testing.T) {
 			name: "prediction unpause",
 			test: func(t *testing.T, store StateStorage) {
 				prediction, _ := compile(t, sampleRawPrediction)
-				_, err := store.UpsertPredictions([]*core.Prediction{&prediction})
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
 				require.Nil(t, err)
 
-				err = store.PausePrediction(prediction.UUID)
+				err = store.PausePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				err = store.UnpausePrediction(prediction.UUID)
+				err = store.UnpausePrediction(context.Background(), prediction.UUID)
 				require.Nil(t, err)
 
-				actualPreds, err := store.GetPredictions(core.APIFilters{Paused: pBool(false)}, []string{}, 0, 0)
+				actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{Paused: pBool(false)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 1)
 				require.Equal(t, prediction.PostURL, actualPreds[0].PostURL)
 
-				actualPreds, err = store.GetPredictions(core.APIFilters{Paused: pBool(true)}, []string{}, 0, 0)
+				actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{Paused: pBool(true)}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualPreds, 0)
 			},
@@ -189,10 +190,10 @@ func TestPostgres(t *testing.T) {
 			name: "account upsert: base case",
 			test: func(t *testing.T, store StateStorage) {
 				_, account := compile(t, sampleRawPrediction)
-				_, err := store.UpsertAccounts([]*core.Account{account})
+				_, err := store.UpsertAccounts(context.Background(), []*core.Account{account})
 				require.Nil(t, err)
 
-				actualAccounts, err := store.GetAccounts(core.APIAccountFilters{URLs: []string{account.URL.String()}}, []string{}, 0, 0)
+				actualAccounts, err := store.GetAccounts(context.Background(), core.APIAccountFilters{URLs: []string{account.URL.String()}}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualAccounts, 1)
 				require.Equal(t, account.Handle, actualAccounts[0].Handle)
@@ -205,10 +206,10 @@ func TestPostgres(t *testing.T) {
 				_, account2 := compile(t, sampleRawPrediction)
 				account2.URL, _ = url.Parse("http://twitter.com/different")
 				account2.Handle = "different"
-				_, err := store.UpsertAccounts([]*core.Account{account1, account2})
+				_, err := store.UpsertAccounts(context.Background(), []*core.Account{account1, account2})
 				require.Nil(t, err)
 
-				actualAccounts, err := store.GetAccounts(core.APIAccountFilters{}, []string{}, 0, 0)
+				actualAccounts, err := store.GetAccounts(context.Background(), core.APIAccountFilters{}, []string{}, 0, 0)
 				require.Nil(t, err)
 				require.Len(t, actualAccounts, 2)
 				require.Equal(t, account1.Handle, actualAccounts[0].Handle)
@@ -220,7 +221,7 @@ func TestPostgres(t *testing.T) {
 			test: func(t *testing.T, store StateStorage) {
 				_, account1 := compile(t, sampleRawPrediction)
 				_, account2 := compile(t, sampleRawPrediction)
-				_, err := store.UpsertAccounts([]*core.Account{account1, account2})
+				_, err := store.UpsertAccounts(context.Background(), []*core.Account{account1, account2})
 				require.NotNil(t, err)
 				postgresErr := err.(*pq.Error)
 				require.Equal(t, "ON CONFLICT DO UPDATE command cannot affect row a second time", postgresErr.Message)
@@ -241,6 +242,11 @@ func tpToISO(s string) core.ISO8601 {
 	return core.ISO8601(t.Format(time.RFC3339))
 }
 
+func tp(s string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04:05", s)
+	return t
+}
+
 type storeTest struct {
 	name string
 	test func(t *testing.T, store StateStorage)
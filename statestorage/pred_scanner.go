@@ -1,11 +1,17 @@
 package statestorage
 
-import "github.com/marianogappa/predictions/core"
+import (
+	"context"
+	"time"
+
+	"github.com/marianogappa/predictions/core"
+)
 
 // PredictionScanner is a storage-layer Prediction iterator that follows the Scanner interface.
 type PredictionScanner struct {
 	Error error
 
+	ctx         context.Context
 	store       StateStorage
 	predictions []core.Prediction
 	lastUUID    string
@@ -14,15 +20,42 @@ type PredictionScanner struct {
 }
 
 // NewEvolvablePredictionsScanner constructs a PredictionScanner that only retrieves predictions that are not in a
-// final state, nor paused nor deleted.
-func NewEvolvablePredictionsScanner(store StateStorage) *PredictionScanner {
-	return newPredictionScanner(store, filterEvolvable, 100)
+// final state, nor paused nor deleted. Scanning stops with ctx's error if ctx is cancelled.
+func NewEvolvablePredictionsScanner(ctx context.Context, store StateStorage) *PredictionScanner {
+	return newPredictionScanner(ctx, store, filterEvolvable, 100)
+}
+
+// NewDuePredictionsScanner constructs a PredictionScanner that only retrieves evolvable predictions (see
+// NewEvolvablePredictionsScanner) that are due at the specified time, i.e. that there may be new market data to evolve
+// them with. Scanning stops with ctx's error if ctx is cancelled.
+func NewDuePredictionsScanner(ctx context.Context, store StateStorage, now time.Time) *PredictionScanner {
+	filters := filterEvolvable
+	filters.DueAt = core.ISO8601(now.UTC().Format(time.RFC3339))
+	return newPredictionScanner(ctx, store, filters, 100)
 }
 
 // NewAllPredictionsScanner constructs a PredictionScanner that retrieves all available predictions in the
-// storage-layer, even if they are final, paused or deleted.
-func NewAllPredictionsScanner(store StateStorage) *PredictionScanner {
-	return newPredictionScanner(store, filterAll, 100)
+// storage-layer, even if they are final, paused or deleted. Scanning stops with ctx's error if ctx is cancelled.
+func NewAllPredictionsScanner(ctx context.Context, store StateStorage) *PredictionScanner {
+	return newPredictionScanner(ctx, store, filterAll, 100)
+}
+
+// GetEvolvablePrediction retrieves the latest stored version of a prediction, and returns false if it's no longer
+// evolvable (i.e. it's in a final state, paused or deleted).
+func GetEvolvablePrediction(ctx context.Context, store StateStorage, uuid string) (core.Prediction, bool, error) {
+	filters := filterEvolvable
+	filters.UUIDs = []string{uuid}
+	preds, err := store.GetPredictions(ctx, filters, nil, 1, 0)
+	if err != nil || len(preds) == 0 {
+		return core.Prediction{}, false, err
+	}
+	return preds[0], true, nil
+}
+
+// GetNextEvolvablePredictionRunAt returns the earliest time at which an evolvable prediction should be evolved, or false
+// if there are no evolvable predictions. It's the zero time if a prediction should be evolved right away.
+func GetNextEvolvablePredictionRunAt(ctx context.Context, store StateStorage) (time.Time, bool, error) {
+	return store.GetNextPredictionRunAt(ctx, filterEvolvable)
 }
 
 var (
@@ -44,11 +77,11 @@ var (
 	}
 )
 
-func newPredictionScanner(store StateStorage, filters core.APIFilters, batchSize int) *PredictionScanner {
+func newPredictionScanner(ctx context.Context, store StateStorage, filters core.APIFilters, batchSize int) *PredictionScanner {
 	if batchSize == 0 {
 		batchSize = 100
 	}
-	return &PredictionScanner{store: store, filters: filters, limit: batchSize}
+	return &PredictionScanner{ctx: ctx, store: store, filters: filters, limit: batchSize}
 }
 
 func (it *PredictionScanner) query() ([]core.Prediction, error) {
@@ -60,6 +93,7 @@ func (it *PredictionScanner) query() ([]core.Prediction, error) {
 	}
 
 	preds, err := it.store.GetPredictions(
+		it.ctx,
 		filters,
 		[]string{core.PredictionsUUIDAsc.String()},
 		it.limit, 0,
@@ -78,6 +112,11 @@ func (it *PredictionScanner) query() ([]core.Prediction, error) {
 // there are no predictions left or there is an error. To differentiate these cases, inspect the Error property.
 func (it *PredictionScanner) Scan(prediction *core.Prediction) bool {
 	it.Error = nil
+	if err := it.ctx.Err(); err != nil {
+		it.Error = err
+		*prediction = core.Prediction{}
+		return false
+	}
 	if len(it.predictions) == 0 {
 		var err error
 		it.predictions, err = it.query()
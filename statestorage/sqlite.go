@@ -0,0 +1,961 @@
+package statestorage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+
+	// Storage engine
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/google/uuid"
+	"github.com/marianogappa/predictions/compiler"
+	"github.com/marianogappa/predictions/core"
+	"github.com/marianogappa/predictions/serializer"
+	"github.com/rs/zerolog/log"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteDBStateStorage is the SQLite implementation of StateStorage. The whole state lives in a single file, which
+// is convenient for small deployments.
+//
+// Note that the SQL in this file uses Postgres-style $N placeholders & EXCLUDED, which SQLite understands, so the
+// pgUpsertManyBuilder & pgWhereBuilder are reused. Only the filters that query the JSON blob or the tags differ.
+type SQLiteDBStateStorage struct {
+	db       *sql.DB
+	debug    bool
+	readOnly bool
+}
+
+//go:embed sqlite_migrations/*.sql
+var sqliteFS embed.FS
+
+// sqliteTimestampLayout is the format of all timestamps stored in SQLite. It's the format of CURRENT_TIMESTAMP.
+const sqliteTimestampLayout = "2006-01-02 15:04:05"
+
+// MustNewSQLiteDBStateStorage constructs a SQLiteDBStateStorage. May fatal.
+func MustNewSQLiteDBStateStorage(path string, options ...func(*SQLiteDBStateStorage)) *SQLiteDBStateStorage {
+	s, err := NewSQLiteDBStateStorage(path, options...)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to open SQLite database at %v. Configure its path via the PREDICTIONS_SQLITE_PATH env described in the README.", path)
+	}
+	return &s
+}
+
+// WithReadOnlySQLite makes the SQLiteDBStateStorage leave the database as it is, e.g. for tools that inspect a
+// production database: migrations aren't run, the file isn't created if it doesn't exist, and writes fail.
+func WithReadOnlySQLite() func(*SQLiteDBStateStorage) {
+	return func(s *SQLiteDBStateStorage) {
+		s.readOnly = true
+	}
+}
+
+// NewSQLiteDBStateStorage constructs a SQLiteDBStateStorage. Unless it's read-only, the database file is created if
+// it doesn't exist, and pending migrations are run.
+func NewSQLiteDBStateStorage(path string, options ...func(*SQLiteDBStateStorage)) (SQLiteDBStateStorage, error) {
+	s := SQLiteDBStateStorage{}
+	for _, option := range options {
+		option(&s)
+	}
+
+	dsn := path
+	if s.readOnly {
+		dsn = fmt.Sprintf("file:%v?mode=ro", path)
+	} else {
+		d, err := iofs.New(sqliteFS, "sqlite_migrations")
+		if err != nil {
+			return SQLiteDBStateStorage{}, err
+		}
+		m, err := migrate.NewWithSourceInstance("iofs", d, fmt.Sprintf("sqlite://%v", path))
+		if err != nil {
+			return SQLiteDBStateStorage{}, err
+		}
+		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+			return SQLiteDBStateStorage{}, err
+		}
+		if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+			return SQLiteDBStateStorage{}, fmt.Errorf("closing migrations: %v, %v", srcErr, dbErr)
+		}
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return SQLiteDBStateStorage{}, err
+	}
+	// sql.Open doesn't connect, so a missing file would otherwise only fail on the first query.
+	if err := db.Ping(); err != nil {
+		return SQLiteDBStateStorage{}, err
+	}
+	// SQLite only supports one writer at a time, and the API & Daemon write concurrently. Rather than handling
+	// SQLITE_BUSY errors everywhere, all queries go through a single connection.
+	db.SetMaxOpenConns(1)
+
+	s.db = db
+
+	log.Info().Str("path", path).Bool("readOnly", s.readOnly).Msgf("Connected to SQLite DB")
+	return s, nil
+}
+
+// SetDebug sets the debug logging setting across the storage layer.
+func (s *SQLiteDBStateStorage) SetDebug(debug bool) {
+	s.debug = debug
+}
+
+func sqlitePredictionsWhere(filters core.APIFilters) (string, []interface{}) {
+	return (&pgWhereBuilder{}).addFilters([]filterable{
+		sqlitePredictionsAuthorHandles{filters.AuthorHandles},
+		sqlitePredictionsAuthorURLs{filters.AuthorURLs},
+		pgPredictionsDeleted{filters.Deleted},
+		pgPredictionsHidden{filters.Hidden},
+		pgPredictionsPaused{filters.Paused},
+		sqlitePredictionsPredictionStateStatuses{filters.PredictionStateStatus},
+		sqlitePredictionsPredictionStateValues{filters.PredictionStateValues},
+		sqlitePredictionsUUIDs{filters.UUIDs},
+		sqlitePredictionsURLs{filters.URLs},
+		sqlitePredictionsTags{filters.Tags},
+		pgGreaterThanUUID{filters.GreaterThanUUID},
+		sqliteIncludeUIUnsupported{filters.IncludeUIUnsupported},
+		sqlitePredictionsDueAt{filters.DueAt},
+	}).build()
+}
+
+// GetPredictions SELECTs predictions from the database.
+func (s SQLiteDBStateStorage) GetPredictions(ctx context.Context, filters core.APIFilters, orderBys []string, limit, offset int) ([]core.Prediction, error) {
+	where, args := sqlitePredictionsWhere(filters)
+	orderBy := predictionsBuildOrderBy(orderBys)
+	limitStr := ""
+	if limit > 0 {
+		limitStr = fmt.Sprintf(" LIMIT %v OFFSET %v", limit, offset)
+	}
+
+	query := fmt.Sprintf("SELECT uuid, blob, COALESCE(paused, false), COALESCE(hidden, false), COALESCE(deleted, false) FROM predictions WHERE %v ORDER BY %v%v", where, orderBy, limitStr)
+
+	if s.debug {
+		log.Info().Msgf("SQLiteDBStateStorage.GetPredictions: for filters %+v and orderBy %+v: %v\n", filters, orderBys, query)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []core.Prediction{}
+	for rows.Next() {
+		var (
+			clUUID, clBlob                string
+			clPaused, clHidden, clDeleted bool
+		)
+		err := rows.Scan(&clUUID, &clBlob, &clPaused, &clHidden, &clDeleted)
+		if err != nil {
+			log.Info().Msgf("error reading predictions fields from db, with error: %v\n", err)
+		}
+		var pred core.Prediction
+		if pred, _, err = compiler.NewPredictionCompiler(nil, nil).Compile([]byte(clBlob)); err != nil {
+			log.Info().Msgf("read corrupted prediction from db, with error: %v\n", err)
+			continue
+		}
+		pred.UUID = clUUID
+		pred.Paused = clPaused
+		pred.Hidden = clHidden
+		pred.Deleted = clDeleted
+		result = append(result, pred)
+	}
+
+	return result, rows.Err()
+}
+
+// CountPredictions counts the predictions in the database that match the filters.
+func (s SQLiteDBStateStorage) CountPredictions(ctx context.Context, filters core.APIFilters) (int, error) {
+	where, args := sqlitePredictionsWhere(filters)
+	var count int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM predictions WHERE %v", where), args...).Scan(&count)
+	return count, err
+}
+
+// GetNextPredictionRunAt returns the earliest next_run_at of the predictions in the database that match the filters.
+func (s SQLiteDBStateStorage) GetNextPredictionRunAt(ctx context.Context, filters core.APIFilters) (time.Time, bool, error) {
+	where, args := sqlitePredictionsWhere(filters)
+	var nextRunAt sql.NullString
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT next_run_at FROM predictions WHERE %v ORDER BY next_run_at ASC NULLS FIRST LIMIT 1", where), args...).Scan(&nextRunAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if !nextRunAt.Valid {
+		return time.Time{}, true, nil
+	}
+	t, err := time.Parse(sqliteTimestampLayout, nextRunAt.String)
+	return t, err == nil, err
+}
+
+// GetPredictionStats counts the predictions in the database that match the filters, by state value.
+func (s SQLiteDBStateStorage) GetPredictionStats(ctx context.Context, filters core.APIFilters) (core.PredictionStats, error) {
+	where, args := sqlitePredictionsWhere(filters)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT json_extract(blob, '$.state.value'), COUNT(*) FROM predictions WHERE %v GROUP BY 1", where), args...)
+	if err != nil {
+		return core.PredictionStats{}, err
+	}
+	defer rows.Close()
+
+	stats := core.PredictionStats{ByStateValue: map[string]int{}}
+	for rows.Next() {
+		var (
+			stateValue sql.NullString
+			count      int
+		)
+		if err := rows.Scan(&stateValue, &count); err != nil {
+			return core.PredictionStats{}, err
+		}
+		stats.ByStateValue[stateValue.String] += count
+		stats.Total += count
+	}
+	return stats, rows.Err()
+}
+
+// GetAccountStats calculates the AccountStats of the authors of the visible predictions in the database that match
+// the filters, or only of the top limit ones if limit > 0.
+func (s SQLiteDBStateStorage) GetAccountStats(ctx context.Context, filters core.AccountStatsFilters, limit int) ([]core.AccountStats, error) {
+	f := false
+	where, args := (&pgWhereBuilder{}).addFilters([]filterable{
+		sqlitePredictionsAuthorHandles{filters.AuthorHandles},
+		sqlitePredictionsAuthorURLs{filters.AuthorURLs},
+		pgPredictionsDeleted{&f},
+		pgPredictionsHidden{&f},
+		sqlitePredictionsPredictionStateValues{finishedPredictionStateValues},
+		sqlitePredictionsTypes{filters.PredictionTypes},
+		sqlitePredictionsPostedAt{filters.PostedFrom, filters.PostedTo},
+		sqlitePredictionsCoins{filters.Coins},
+	}).build()
+	limitStr := ""
+	if limit > 0 {
+		limitStr = fmt.Sprintf(" LIMIT %v", limit)
+	}
+	// Ordered like core.RankAccountStats, so that the limit keeps the top ones.
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT handle, correct, incorrect, annulled, score FROM (
+			SELECT
+				json_extract(blob, '$.postAuthor') AS handle,
+				SUM(CASE WHEN json_extract(blob, '$.state.value') = 'CORRECT' THEN 1 ELSE 0 END) AS correct,
+				SUM(CASE WHEN json_extract(blob, '$.state.value') = 'INCORRECT' THEN 1 ELSE 0 END) AS incorrect,
+				SUM(CASE WHEN json_extract(blob, '$.state.value') = 'ANNULLED' THEN 1 ELSE 0 END) AS annulled,
+				SUM(CASE json_extract(blob, '$.state.value')
+					WHEN 'CORRECT' THEN 1 + COALESCE(json_extract(blob, '$.state.difficulty'), 0)
+					WHEN 'INCORRECT' THEN -1
+					ELSE 0
+				END) AS score
+			FROM predictions WHERE %v GROUP BY 1
+		) stats
+		ORDER BY
+			score DESC,
+			CASE WHEN correct + incorrect > 0 THEN CAST(correct AS REAL) / (correct + incorrect) ELSE 0 END DESC,
+			handle%v`, where, limitStr), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []core.AccountStats{}
+	for rows.Next() {
+		var accountStats core.AccountStats
+		if err := rows.Scan(&accountStats.Handle, &accountStats.Correct, &accountStats.Incorrect, &accountStats.Annulled, &accountStats.Score); err != nil {
+			return nil, err
+		}
+		stats = append(stats, accountStats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return core.RankAccountStats(stats), nil
+}
+
+// GetAccounts SELECTs accounts from the database.
+func (s SQLiteDBStateStorage) GetAccounts(ctx context.Context, filters core.APIAccountFilters, orderBys []string, limit, offset int) ([]core.Account, error) {
+	where, args := (&pgWhereBuilder{}).addFilters([]filterable{
+		pgAccountsHandles{filters.Handles},
+		pgAccountsURLs{filters.URLs},
+	}).build()
+
+	orderBy := accountsBuildOrderBy(orderBys)
+	limitStr := ""
+	if limit > 0 {
+		limitStr = fmt.Sprintf(" LIMIT %v OFFSET %v", limit, offset)
+	}
+
+	query := fmt.Sprintf("SELECT url, account_type, handle, follower_count, thumbnails, name, description, created_at, is_verified FROM accounts WHERE %v ORDER BY %v%v", where, orderBy, limitStr)
+
+	if s.debug {
+		log.Info().Msgf("SQLiteDBStateStorage.GetAccounts: for filters %+v and orderBy %+v: %v\n", filters, orderBys, query)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []core.Account{}
+	for rows.Next() {
+		var (
+			a                                     core.Account
+			handle, description, name, thumbnails sql.NullString
+			createdAt                             sql.NullString
+			dbURL                                 string
+		)
+
+		err := rows.Scan(&dbURL, &a.AccountType, &handle, &a.FollowerCount, &thumbnails, &name, &description, &createdAt, &a.IsVerified)
+		if err != nil {
+			log.Info().Msgf("error reading account from db, with error: %v\n", err)
+		}
+
+		u, err := url.Parse(dbURL)
+		if err != nil {
+			log.Info().Msgf("error reading url from account from db, with error: %v\n", err)
+		}
+		a.URL = u
+		a.Handle = handle.String
+		a.Name = name.String
+		a.Description = description.String
+
+		var thumbnailURLs []string
+		if thumbnails.Valid {
+			if err := json.Unmarshal([]byte(thumbnails.String), &thumbnailURLs); err != nil {
+				log.Info().Msgf("error reading thumbnails from account from db, with error: %v\n", err)
+			}
+		}
+		for _, dbURL := range thumbnailURLs {
+			u, err := url.Parse(dbURL)
+			if err != nil {
+				log.Info().Msgf("error reading url from thumbnails from account from db, with error: %v\n", err)
+			}
+			a.Thumbnails = append(a.Thumbnails, u)
+		}
+
+		if createdAt.Valid {
+			t, err := time.Parse(sqliteTimestampLayout, createdAt.String)
+			if err != nil {
+				log.Info().Msgf("error reading created_at from account from db, with error: %v\n", err)
+			} else {
+				a.CreatedAt = &t
+			}
+		}
+
+		result = append(result, a)
+	}
+
+	return result, rows.Err()
+}
+
+// UpsertPredictions UPSERTs predictions to the database.
+func (s SQLiteDBStateStorage) UpsertPredictions(ctx context.Context, ps []*core.Prediction) ([]*core.Prediction, error) {
+	if len(ps) == 0 {
+		return ps, nil
+	}
+
+	return ps, sqliteUpsertPredictions(ctx, s.db, ps)
+}
+
+// StoreEvolvedPrediction upserts the prediction and logs the changes of its PredictionStateValue in one transaction.
+func (s SQLiteDBStateStorage) StoreEvolvedPrediction(ctx context.Context, p *core.Prediction, changes []core.PredictionStateValueChange) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := sqliteUpsertPredictions(ctx, tx, []*core.Prediction{p}); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if err := sqliteLogPredictionStateValueChange(ctx, tx, c); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func sqliteUpsertPredictions(ctx context.Context, db sqlExecer, ps []*core.Prediction) error {
+	builder := newPGUpsertManyBuilder([]string{"uuid", "blob", "created_at", "posted_at", "tags", "post_url", "next_run_at"}, "predictions", "uuid")
+	seenUUIDs := map[string]bool{}
+	for i := range ps {
+		if ps[i].UUID == "" {
+			ps[i].UUID = uuid.NewString()
+		}
+		// Unlike Postgres, SQLite happily upserts the same row twice in one statement.
+		if seenUUIDs[ps[i].UUID] {
+			return fmt.Errorf("%w: %v", ErrDuplicateUpsert, ps[i].UUID)
+		}
+		seenUUIDs[ps[i].UUID] = true
+
+		blob, err := serializer.NewPredictionSerializer(nil).SerializeForDB(ps[i])
+		if err != nil {
+			log.Info().Msgf("Failed to marshal prediction, with error: %v\n", err)
+		}
+		tags, err := json.Marshal(ps[i].CalculateTags())
+		if err != nil {
+			log.Info().Msgf("Failed to marshal prediction tags, with error: %v\n", err)
+		}
+		builder.addRow(ps[i].UUID, string(blob), sqliteTimestamp(ps[i].CreatedAt), sqliteTimestamp(ps[i].PostedAt), string(tags), ps[i].PostURL, sqliteNextRunAt(*ps[i]))
+	}
+	query, args := builder.build()
+	_, err := db.ExecContext(ctx, query, args...)
+	return sqliteMapError(err)
+}
+
+// PausePrediction sets a prediction to paused on the database. Paused predictions are visible but don't evolve.
+func (s SQLiteDBStateStorage) PausePrediction(ctx context.Context, uuid string) error {
+	return s.updatePredictionFlag(ctx, "paused", true, uuid)
+}
+
+// UnpausePrediction sets a prediction to unpaused on the database. Paused predictions are visible but don't evolve.
+func (s SQLiteDBStateStorage) UnpausePrediction(ctx context.Context, uuid string) error {
+	return s.updatePredictionFlag(ctx, "paused", false, uuid)
+}
+
+// HidePrediction sets a prediction to hidden on the database. Hidden predictions are invisible but still evolve.
+func (s SQLiteDBStateStorage) HidePrediction(ctx context.Context, uuid string) error {
+	return s.updatePredictionFlag(ctx, "hidden", true, uuid)
+}
+
+// UnhidePrediction sets a prediction to visible on the database. Hidden predictions are invisible but still evolve.
+func (s SQLiteDBStateStorage) UnhidePrediction(ctx context.Context, uuid string) error {
+	return s.updatePredictionFlag(ctx, "hidden", false, uuid)
+}
+
+// DeletePrediction sets a prediction to deleted on the database. Deleted predictions are invisible and don't evolve.
+func (s SQLiteDBStateStorage) DeletePrediction(ctx context.Context, uuid string) error {
+	return s.updatePredictionFlag(ctx, "deleted", true, uuid)
+}
+
+// UndeletePrediction restores a deleted prediction on the database. Deleted predictions are invisible and don't evolve.
+func (s SQLiteDBStateStorage) UndeletePrediction(ctx context.Context, uuid string) error {
+	return s.updatePredictionFlag(ctx, "deleted", false, uuid)
+}
+
+func (s SQLiteDBStateStorage) updatePredictionFlag(ctx context.Context, column string, value bool, uuid string) error {
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf("UPDATE predictions SET %v = $1 WHERE uuid = $2", column), value, uuid)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return nil
+	}
+	if count == 0 {
+		return fmt.Errorf("uuid not found: %v", uuid)
+	}
+	return nil
+}
+
+// UpsertAccounts UPSERTs accounts to the database, except for aliases of other accounts.
+func (s SQLiteDBStateStorage) UpsertAccounts(ctx context.Context, as []*core.Account) ([]*core.Account, error) {
+	if len(as) == 0 {
+		return as, nil
+	}
+	aliases, err := s.GetAccountAliases(ctx, accountURLs(as))
+	if err != nil {
+		return as, err
+	}
+
+	builder := newPGUpsertManyBuilder([]string{"url", "account_type", "handle", "follower_count", "thumbnails", "name", "description", "created_at", "is_verified"}, "accounts", "url")
+	seenURLs := map[string]bool{}
+	for _, a := range as {
+		// Unlike Postgres, SQLite happily upserts the same row twice in one statement.
+		if seenURLs[a.URL.String()] {
+			return as, fmt.Errorf("%w: %v", ErrDuplicateUpsert, a.URL.String())
+		}
+		seenURLs[a.URL.String()] = true
+		if _, ok := aliases[a.URL.String()]; ok {
+			continue
+		}
+
+		thumbnails := []string{}
+		for _, thumb := range a.Thumbnails {
+			thumbnails = append(thumbnails, thumb.String())
+		}
+		jsonThumbnails, err := json.Marshal(thumbnails)
+		if err != nil {
+			log.Info().Msgf("Failed to marshal account thumbnails, with error: %v\n", err)
+		}
+		var createdAt interface{}
+		if a.CreatedAt != nil {
+			createdAt = a.CreatedAt.UTC().Format(sqliteTimestampLayout)
+		}
+		builder.addRow(a.URL.String(), a.AccountType, a.Handle, a.FollowerCount, string(jsonThumbnails), a.Name, a.Description, createdAt, a.IsVerified)
+	}
+	if builder.rowCount == 0 {
+		return as, nil
+	}
+	query, args := builder.build()
+	_, err = s.db.ExecContext(ctx, query, args...)
+	return as, sqliteMapError(err)
+}
+
+// DeleteAccount deletes an account from the database.
+func (s SQLiteDBStateStorage) DeleteAccount(ctx context.Context, url string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM accounts WHERE url = $1", url)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return nil
+	}
+	if count == 0 {
+		return fmt.Errorf("url not found: %v", url)
+	}
+	return nil
+}
+
+// MergeAccounts merges the account with fromURL into the account into on the database, in a transaction.
+func (s SQLiteDBStateStorage) MergeAccounts(ctx context.Context, fromURL string, into core.Account) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM accounts WHERE url = $1", fromURL)
+	if err != nil {
+		return 0, err
+	}
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return 0, fmt.Errorf("url not found: %v", fromURL)
+	}
+
+	res, err = tx.ExecContext(ctx, `UPDATE predictions
+		SET blob = json_set(blob, '$.postAuthorURL', $2, '$.postAuthor', $3)
+		WHERE json_extract(blob, '$.postAuthorURL') = $1`, fromURL, into.URL.String(), into.Handle)
+	if err != nil {
+		return 0, err
+	}
+	repointed, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	// Aliases of the merged account become aliases of the account it's merged into.
+	if _, err := tx.ExecContext(ctx, "UPDATE account_aliases SET alias_of = $2 WHERE alias_of = $1", fromURL, into.URL.String()); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO account_aliases (url, alias_of) VALUES ($1, $2) ON CONFLICT (url) DO UPDATE SET alias_of = EXCLUDED.alias_of", fromURL, into.URL.String()); err != nil {
+		return 0, err
+	}
+
+	return int(repointed), tx.Commit()
+}
+
+// GetAccountAliases returns the URL of the account that each of the urls is an alias of, from the database.
+func (s SQLiteDBStateStorage) GetAccountAliases(ctx context.Context, urls []string) (map[string]string, error) {
+	aliases := map[string]string{}
+	if len(urls) == 0 {
+		return aliases, nil
+	}
+	placeholders, args := []string{}, []interface{}{}
+	for i, u := range urls {
+		placeholders = append(placeholders, fmt.Sprintf("$%v", i+1))
+		args = append(args, u)
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT url, alias_of FROM account_aliases WHERE url IN (%v)", strings.Join(placeholders, ", ")), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var aliasURL, aliasOf string
+		if err := rows.Scan(&aliasURL, &aliasOf); err != nil {
+			return nil, err
+		}
+		aliases[aliasURL] = aliasOf
+	}
+	return aliases, rows.Err()
+}
+
+// LogPredictionStateValueChange logs the fact that a prediction changed PredictionStateValue to the database.
+func (s SQLiteDBStateStorage) LogPredictionStateValueChange(ctx context.Context, c core.PredictionStateValueChange) error {
+	return sqliteLogPredictionStateValueChange(ctx, s.db, c)
+}
+
+func sqliteLogPredictionStateValueChange(ctx context.Context, db sqlExecer, c core.PredictionStateValueChange) error {
+	ticks, err := marshalTicks(c.Ticks)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO prediction_state_value_change
+		(prediction_uuid, state_value, condition_name, condition_value, ticks, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		`, c.PredictionUUID, c.StateValue, c.ConditionName, c.ConditionValue, ticks, sqliteTimestamp(c.CreatedAt))
+
+	return err
+}
+
+// GetPredictionStateValueChanges SELECTs the state value changes of a prediction from the database, oldest first.
+func (s SQLiteDBStateStorage) GetPredictionStateValueChanges(ctx context.Context, predictionUUID string) ([]core.PredictionStateValueChange, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT prediction_uuid, state_value, condition_name, condition_value, ticks, created_at FROM prediction_state_value_change WHERE prediction_uuid = $1 ORDER BY created_at, id", predictionUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []core.PredictionStateValueChange{}
+	for rows.Next() {
+		var (
+			change           core.PredictionStateValueChange
+			ticks, createdAt sql.NullString
+		)
+		if err := rows.Scan(&change.PredictionUUID, &change.StateValue, &change.ConditionName, &change.ConditionValue, &ticks, &createdAt); err != nil {
+			return nil, err
+		}
+		if change.Ticks, err = unmarshalTicks(ticks); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(sqliteTimestampLayout, createdAt.String); err == nil {
+			change.CreatedAt = core.ISO8601(t.Format(time.RFC3339))
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// LogExchangeFailover logs the fact that a prediction's condition failed over to another exchange to the database.
+func (s SQLiteDBStateStorage) LogExchangeFailover(ctx context.Context, f core.ExchangeFailover) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO exchange_failovers
+		(prediction_uuid, condition_name, from_operand, to_operand, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		`, f.PredictionUUID, f.ConditionName, f.FromOperand, f.ToOperand, f.Reason, sqliteTimestamp(f.CreatedAt))
+
+	return err
+}
+
+// GetExchangeFailovers SELECTs the exchange failovers of a prediction's conditions from the database, oldest first.
+func (s SQLiteDBStateStorage) GetExchangeFailovers(ctx context.Context, predictionUUID string) ([]core.ExchangeFailover, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT prediction_uuid, condition_name, from_operand, to_operand, reason, created_at FROM exchange_failovers WHERE prediction_uuid = $1 ORDER BY created_at, rowid", predictionUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failovers := []core.ExchangeFailover{}
+	for rows.Next() {
+		var (
+			failover  core.ExchangeFailover
+			createdAt sql.NullString
+		)
+		if err := rows.Scan(&failover.PredictionUUID, &failover.ConditionName, &failover.FromOperand, &failover.ToOperand, &failover.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(sqliteTimestampLayout, createdAt.String); err == nil {
+			failover.CreatedAt = core.ISO8601(t.Format(time.RFC3339))
+		}
+		failovers = append(failovers, failover)
+	}
+	return failovers, rows.Err()
+}
+
+// AcquireLease takes the named lease for owner on the database. Expiry is checked against the database's clock.
+func (s SQLiteDBStateStorage) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO leases (name, owner, expires_at)
+		VALUES ($1, $2, datetime('now', $3))
+		ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE leases.owner = excluded.owner OR leases.expires_at <= datetime('now')
+		`, name, owner, fmt.Sprintf("%+.3f seconds", ttl.Seconds()))
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
+
+// ReleaseLease gives up the named lease on the database, if owner holds it.
+func (s SQLiteDBStateStorage) ReleaseLease(ctx context.Context, name, owner string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM leases WHERE name = $1 AND owner = $2", name, owner)
+	return err
+}
+
+// NonPendingPredictionInteractionExists checks the database to see if a predictions creation or finalization Tweet post happened.
+func (s SQLiteDBStateStorage) NonPendingPredictionInteractionExists(ctx context.Context, interaction core.PredictionInteraction) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+	SELECT EXISTS(SELECT * FROM prediction_interactions WHERE prediction_uuid = $1 AND post_url = $2 AND action_type = $3 AND status != 'PENDING');
+		`, interaction.PredictionUUID, interaction.PostURL, interaction.ActionType).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// InsertPredictionInteraction logs the fact that a Tweet was sent when a prediction was created or finalized.
+func (s SQLiteDBStateStorage) InsertPredictionInteraction(ctx context.Context, i core.PredictionInteraction) error {
+	_, err := s.db.ExecContext(ctx, `
+	INSERT INTO prediction_interactions (uuid, prediction_uuid, post_url, action_type, interaction_post_url, status, error) VALUES ($1, $2, $3, $4, $5, $6, $7);
+		`, uuid.NewString(), i.PredictionUUID, i.PostURL, i.ActionType, i.InteractionPostURL, i.Status, i.Error)
+	return sqliteMapError(err)
+}
+
+// UpdatePredictionInteractionStatus changes the status of a PredictionInteraction.
+func (s SQLiteDBStateStorage) UpdatePredictionInteractionStatus(ctx context.Context, i core.PredictionInteraction) error {
+	res, err := s.db.ExecContext(ctx, `
+	UPDATE prediction_interactions SET status = $1, error = $2 WHERE post_url = $3 AND action_type = $4 AND prediction_uuid = $5 AND status = 'PENDING';
+		`, i.Status, i.Error, i.PostURL, i.ActionType, i.PredictionUUID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("update of prediction interaction status didn't update any rows")
+	}
+	return nil
+}
+
+// GetPendingPredictionInteractions SELECTs pending prediction interactions from the database.
+func (s SQLiteDBStateStorage) GetPendingPredictionInteractions(ctx context.Context) ([]core.PredictionInteraction, error) {
+	// CURRENT_TIMESTAMP has second precision, so rowid breaks ties in insertion order.
+	rows, err := s.db.QueryContext(ctx, "SELECT prediction_uuid, post_url, action_type, interaction_post_url, status FROM prediction_interactions WHERE status = 'PENDING' ORDER BY created_at, rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	interactions := []core.PredictionInteraction{}
+	for rows.Next() {
+		var row core.PredictionInteraction
+
+		if err := rows.Scan(&row.PredictionUUID, &row.PostURL, &row.ActionType, &row.InteractionPostURL, &row.Status); err != nil {
+			log.Info().Err(err).Msg("error reading prediction interaction from db")
+		}
+
+		interactions = append(interactions, row)
+	}
+
+	return interactions, rows.Err()
+}
+
+// GetPredictionInteractions SELECTs prediction interactions in any status from the database, oldest first.
+func (s SQLiteDBStateStorage) GetPredictionInteractions(ctx context.Context, predictionUUID string) ([]core.PredictionInteraction, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT prediction_uuid, post_url, action_type, interaction_post_url, status, COALESCE(error, '') FROM prediction_interactions WHERE $1 = '' OR prediction_uuid = $1 ORDER BY created_at, rowid", predictionUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	interactions := []core.PredictionInteraction{}
+	for rows.Next() {
+		var row core.PredictionInteraction
+		if err := rows.Scan(&row.PredictionUUID, &row.PostURL, &row.ActionType, &row.InteractionPostURL, &row.Status, &row.Error); err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, row)
+	}
+	return interactions, rows.Err()
+}
+
+// sqliteTimestamp converts an ISO8601 timestamp to the format that SQLite timestamps are stored in, so that they sort
+// correctly regardless of the timezone they were expressed in.
+func sqliteTimestamp(iso core.ISO8601) string {
+	t, err := iso.Time()
+	if err != nil {
+		return string(iso)
+	}
+	return t.UTC().Format(sqliteTimestampLayout)
+}
+
+// sqliteNextRunAt is the value of the next_run_at column of a prediction. It's NULL if it should be evolved right away.
+func sqliteNextRunAt(p core.Prediction) interface{} {
+	nextRunTs := p.CalculateNextRunTs()
+	if nextRunTs == 0 {
+		return nil
+	}
+	return time.Unix(int64(nextRunTs), 0).UTC().Format(sqliteTimestampLayout)
+}
+
+// sqliteMapError wraps unique constraint violations with ErrUniqueConstraintViolation, so that callers don't need to
+// know about SQLite error codes.
+func sqliteMapError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return fmt.Errorf("%w: %v", ErrUniqueConstraintViolation, err)
+	}
+	return err
+}
+
+type sqlitePredictionsAuthorHandles struct{ authorHandles []string }
+
+func (f sqlitePredictionsAuthorHandles) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, authorHandle := range f.authorHandles {
+		args = append(args, authorHandle)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("json_extract(blob, '$.postAuthor') IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsAuthorURLs struct{ authorURLs []string }
+
+func (f sqlitePredictionsAuthorURLs) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, authorURL := range f.authorURLs {
+		args = append(args, authorURL)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("json_extract(blob, '$.postAuthorURL') IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsPredictionStateValues struct{ predictionStateValues []string }
+
+func (f sqlitePredictionsPredictionStateValues) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, rawPredictionStateValue := range f.predictionStateValues {
+		if _, err := core.PredictionStateValueFromString(rawPredictionStateValue); err != nil {
+			continue
+		}
+		args = append(args, rawPredictionStateValue)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("json_extract(blob, '$.state.value') IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsPredictionStateStatuses struct{ predictionStateStatuses []string }
+
+func (f sqlitePredictionsPredictionStateStatuses) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, rawPredictionStateStatus := range f.predictionStateStatuses {
+		if _, err := core.ConditionStatusFromString(rawPredictionStateStatus); err != nil {
+			continue
+		}
+		args = append(args, rawPredictionStateStatus)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("json_extract(blob, '$.state.status') IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsUUIDs struct{ uuids []string }
+
+func (f sqlitePredictionsUUIDs) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, uuid := range f.uuids {
+		args = append(args, uuid)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("uuid IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsTags struct{ tags []string }
+
+func (f sqlitePredictionsTags) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, tag := range f.tags {
+		args = append(args, tag)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(predictions.tags) WHERE json_each.value IN (%v))", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsTypes struct{ predictionTypes []core.PredictionType }
+
+func (f sqlitePredictionsTypes) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, predictionType := range f.predictionTypes {
+		args = append(args, predictionType.String())
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("json_extract(blob, '$.type') IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsPostedAt struct{ from, to time.Time }
+
+func (f sqlitePredictionsPostedAt) filter() (string, []interface{}) {
+	filters, args := []string{}, []interface{}{}
+	if !f.from.IsZero() {
+		filters, args = append(filters, "posted_at >= ∆"), append(args, f.from.UTC().Format(sqliteTimestampLayout))
+	}
+	if !f.to.IsZero() {
+		filters, args = append(filters, "posted_at < ∆"), append(args, f.to.UTC().Format(sqliteTimestampLayout))
+	}
+	return strings.Join(filters, " AND "), args
+}
+
+type sqlitePredictionsCoins struct{ coins []string }
+
+func (f sqlitePredictionsCoins) filter() (string, []interface{}) {
+	args := accountStatsCoinPatterns(f.coins)
+	likes := []string{}
+	for range args {
+		likes = append(likes, "upper(json_each.value) LIKE ∆")
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(predictions.tags) WHERE %v)", strings.Join(likes, " OR ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsURLs struct{ urls []string }
+
+func (f sqlitePredictionsURLs) filter() (string, []interface{}) {
+	args := []interface{}{}
+	for _, url := range f.urls {
+		args = append(args, url)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("json_extract(blob, '$.postUrl') IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
+
+type sqlitePredictionsDueAt struct{ dueAt core.ISO8601 }
+
+func (f sqlitePredictionsDueAt) filter() (string, []interface{}) {
+	if f.dueAt == "" {
+		return "", nil
+	}
+	return "(next_run_at IS NULL OR next_run_at <= ∆)", []interface{}{sqliteTimestamp(f.dueAt)}
+}
+
+type sqliteIncludeUIUnsupported struct{ includeUIUnsupported bool }
+
+func (f sqliteIncludeUIUnsupported) filter() (string, []interface{}) {
+	if f.includeUIUnsupported {
+		return "", nil
+	}
+	args := []interface{}{}
+	for predictionType := range core.UIUnsupportedPredictionTypes {
+		args = append(args, predictionType.String())
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf("json_extract(blob, '$.type') NOT IN (%v)", strings.Join(strings.Split(strings.Repeat("∆", len(args)), ""), ", ")), args
+	}
+	return "", nil
+}
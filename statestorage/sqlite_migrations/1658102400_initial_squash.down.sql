@@ -0,0 +1,4 @@
+DROP TABLE predictions;
+DROP TABLE prediction_state_value_change;
+DROP TABLE accounts;
+DROP TABLE prediction_interactions;
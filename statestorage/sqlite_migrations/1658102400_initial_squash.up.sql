@@ -0,0 +1,51 @@
+-- SQLite equivalent of the Postgres migrations up to 1656760569_alter_prediction_interactions_status.
+-- Timestamps are stored as UTC 'YYYY-MM-DD HH:MM:SS' text, so that they sort correctly.
+-- JSON columns (blob, tags, thumbnails) are stored as text, and queried with the JSON1 functions.
+
+CREATE TABLE predictions (
+    uuid text NOT NULL PRIMARY KEY,
+    blob text NOT NULL,
+    created_at text NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    posted_at text NOT NULL,
+    paused boolean,
+    deleted boolean,
+    hidden boolean,
+    tags text,
+    post_url text
+);
+
+CREATE INDEX predictions_created_at_idx ON predictions(created_at);
+CREATE INDEX predictions_posted_at_idx ON predictions(posted_at);
+CREATE UNIQUE INDEX predictions_post_url_idx ON predictions(post_url);
+
+CREATE TABLE prediction_state_value_change (
+    prediction_uuid text NOT NULL,
+    state_value text NOT NULL,
+    created_at text DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(prediction_uuid, state_value)
+);
+
+CREATE TABLE accounts (
+    url text PRIMARY KEY,
+    account_type text NOT NULL,
+    handle text,
+    follower_count integer,
+    thumbnails text,
+    name text,
+    description text,
+    created_at text,
+    is_verified boolean
+);
+
+CREATE TABLE prediction_interactions (
+    uuid text PRIMARY KEY,
+    post_url text NOT NULL,
+    action_type text NOT NULL,
+    interaction_post_url text NOT NULL,
+    prediction_uuid text NOT NULL,
+    created_at text DEFAULT CURRENT_TIMESTAMP,
+    status text NOT NULL DEFAULT 'POSTED',
+    error text
+);
+
+CREATE UNIQUE INDEX prediction_interactions_post_url_action_type_prediction_uuid_id ON prediction_interactions(post_url, action_type, prediction_uuid);
@@ -0,0 +1,10 @@
+CREATE TABLE exchange_failovers (
+    prediction_uuid text NOT NULL,
+    condition_name text NOT NULL,
+    from_operand text NOT NULL,
+    to_operand text NOT NULL,
+    reason text NOT NULL,
+    created_at text DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX exchange_failovers_prediction_uuid_idx ON exchange_failovers(prediction_uuid);
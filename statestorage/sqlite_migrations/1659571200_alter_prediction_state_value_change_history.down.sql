@@ -0,0 +1,14 @@
+CREATE TABLE prediction_state_value_change_squashed (
+    prediction_uuid text NOT NULL,
+    state_value text NOT NULL,
+    created_at text DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(prediction_uuid, state_value)
+);
+
+-- Only the latest change to each value fits in the old primary key.
+INSERT OR REPLACE INTO prediction_state_value_change_squashed (prediction_uuid, state_value, created_at)
+  SELECT prediction_uuid, state_value, created_at FROM prediction_state_value_change ORDER BY id;
+
+DROP TABLE prediction_state_value_change;
+
+ALTER TABLE prediction_state_value_change_squashed RENAME TO prediction_state_value_change;
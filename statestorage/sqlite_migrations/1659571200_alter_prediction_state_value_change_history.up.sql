@@ -0,0 +1,19 @@
+-- SQLite can't drop a primary key, so the table is rebuilt.
+CREATE TABLE prediction_state_value_change_history (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    prediction_uuid text NOT NULL,
+    state_value text NOT NULL,
+    condition_name text NOT NULL DEFAULT '',
+    condition_value text NOT NULL DEFAULT '',
+    ticks text,
+    created_at text DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO prediction_state_value_change_history (prediction_uuid, state_value, created_at)
+  SELECT prediction_uuid, state_value, created_at FROM prediction_state_value_change ORDER BY created_at;
+
+DROP TABLE prediction_state_value_change;
+
+ALTER TABLE prediction_state_value_change_history RENAME TO prediction_state_value_change;
+
+CREATE INDEX prediction_state_value_change_prediction_uuid_idx ON prediction_state_value_change(prediction_uuid);
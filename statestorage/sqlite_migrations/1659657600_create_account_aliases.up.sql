@@ -0,0 +1,5 @@
+CREATE TABLE account_aliases (
+    url text PRIMARY KEY,
+    alias_of text NOT NULL,
+    created_at text DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,72 @@
+package statestorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marianogappa/predictions/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite(t *testing.T) {
+	for _, ts := range storeContractTests {
+		t.Run(ts.name, func(t *testing.T) {
+			store, err := NewSQLiteDBStateStorage(filepath.Join(t.TempDir(), "predictions.db"))
+			require.Nil(t, err)
+			ts.test(t, &store)
+		})
+	}
+}
+
+func TestSQLitePersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "predictions.db")
+
+	store, err := NewSQLiteDBStateStorage(path)
+	require.Nil(t, err)
+	prediction, account := compile(t, sampleRawPrediction)
+	_, err = store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+	require.Nil(t, err)
+	_, err = store.UpsertAccounts(context.Background(), []*core.Account{account})
+	require.Nil(t, err)
+	require.Nil(t, store.HidePrediction(context.Background(), prediction.UUID))
+	require.Nil(t, store.db.Close())
+
+	store, err = NewSQLiteDBStateStorage(path)
+	require.Nil(t, err)
+
+	actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{Hidden: pBool(true)}, []string{}, 0, 0)
+	require.Nil(t, err)
+	require.Len(t, actualPreds, 1)
+	require.Equal(t, prediction.UUID, actualPreds[0].UUID)
+	require.Equal(t, prediction.PostedAt, actualPreds[0].PostedAt)
+
+	actualAccounts, err := store.GetAccounts(context.Background(), core.APIAccountFilters{}, []string{}, 0, 0)
+	require.Nil(t, err)
+	require.Len(t, actualAccounts, 1)
+	require.Equal(t, account.Handle, actualAccounts[0].Handle)
+}
+
+func TestSQLiteReadOnlyLeavesTheDatabaseAsItIs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "predictions.db")
+
+	_, err := NewSQLiteDBStateStorage(path, WithReadOnlySQLite())
+	require.NotNil(t, err)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	store, err := NewSQLiteDBStateStorage(path)
+	require.Nil(t, err)
+	prediction, _ := compile(t, sampleRawPrediction)
+	_, err = store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+	require.Nil(t, err)
+	require.Nil(t, store.db.Close())
+
+	store, err = NewSQLiteDBStateStorage(path, WithReadOnlySQLite())
+	require.Nil(t, err)
+	actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{UUIDs: []string{prediction.UUID}}, []string{}, 0, 0)
+	require.Nil(t, err)
+	require.Len(t, actualPreds, 1)
+	require.NotNil(t, store.HidePrediction(context.Background(), prediction.UUID))
+}
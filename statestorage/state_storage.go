@@ -1,33 +1,127 @@
 package statestorage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/marianogappa/predictions/core"
 )
 
-// StateStorage is the interface to the storage-layer. Currently the only implementation is Postgres.
+var (
+	// ErrDuplicateUpsert means: the same entity cannot be upserted twice in the same batch
+	ErrDuplicateUpsert = errors.New("the same entity cannot be upserted twice in the same batch")
+
+	// ErrUniqueConstraintViolation means: entity violates a unique constraint
+	ErrUniqueConstraintViolation = errors.New("entity violates a unique constraint")
+)
+
+// StateStorage is the interface to the storage-layer. The main implementation is Postgres; there's also a SQLite one
+// for small deployments, and an in-memory one for tests and quick local runs.
 // It might be wise to keep this interface, because Postgres might be convenient but it's a terrible choice for
 // this engine's persistence needs.
 type StateStorage interface {
-	GetPredictions(filters core.APIFilters, orderBys []string, limit, offset int) ([]core.Prediction, error)
-	GetAccounts(filters core.APIAccountFilters, orderBys []string, limit, offset int) ([]core.Account, error)
+	GetPredictions(ctx context.Context, filters core.APIFilters, orderBys []string, limit, offset int) ([]core.Prediction, error)
+	CountPredictions(ctx context.Context, filters core.APIFilters) (int, error)
+	GetPredictionStats(ctx context.Context, filters core.APIFilters) (core.PredictionStats, error)
+	// GetAccountStats calculates the AccountStats of the authors of the visible predictions that match the filters,
+	// like core.CalculateAccountStats does, but without loading the predictions. Only the top limit AccountStats are
+	// returned if limit > 0.
+	GetAccountStats(ctx context.Context, filters core.AccountStatsFilters, limit int) ([]core.AccountStats, error)
+	// GetNextPredictionRunAt returns the earliest time at which any of the filtered predictions should be evolved, or
+	// false if none match. It's the zero time if any of them should be evolved right away.
+	GetNextPredictionRunAt(ctx context.Context, filters core.APIFilters) (time.Time, bool, error)
+	GetAccounts(ctx context.Context, filters core.APIAccountFilters, orderBys []string, limit, offset int) ([]core.Account, error)
 	// TODO: add interface contract
-	UpsertPredictions([]*core.Prediction) ([]*core.Prediction, error)
-	UpsertAccounts([]*core.Account) ([]*core.Account, error)
-	LogPredictionStateValueChange(core.PredictionStateValueChange) error
-
-	NonPendingPredictionInteractionExists(core.PredictionInteraction) (bool, error)
-	InsertPredictionInteraction(core.PredictionInteraction) error
-	GetPendingPredictionInteractions() ([]core.PredictionInteraction, error)
-	UpdatePredictionInteractionStatus(core.PredictionInteraction) error
-
-	PausePrediction(uuid string) error
-	UnpausePrediction(uuid string) error
-	HidePrediction(uuid string) error
-	UnhidePrediction(uuid string) error
-	DeletePrediction(uuid string) error
-	UndeletePrediction(uuid string) error
-
-	DB() *sql.DB
+	UpsertPredictions(ctx context.Context, predictions []*core.Prediction) ([]*core.Prediction, error)
+	// UpsertAccounts upserts the accounts, except for those whose URL is an alias of another account's (see
+	// MergeAccounts), which are skipped.
+	UpsertAccounts(ctx context.Context, accounts []*core.Account) ([]*core.Account, error)
+	// DeleteAccount deletes the account with the given URL. It doesn't touch the predictions posted by it.
+	DeleteAccount(ctx context.Context, url string) error
+	// MergeAccounts merges the account with fromURL into the account into, all at once or not at all: the predictions
+	// posted by the former are re-pointed to the latter, the former is deleted, and fromURL becomes an alias of into's
+	// URL, so that the former isn't upserted back, e.g. when refetching it from one of its posts. It returns how many
+	// predictions were re-pointed.
+	MergeAccounts(ctx context.Context, fromURL string, into core.Account) (int, error)
+	// GetAccountAliases returns the URL of the account that each of the urls is an alias of, if any (see MergeAccounts).
+	GetAccountAliases(ctx context.Context, urls []string) (map[string]string, error)
+	LogPredictionStateValueChange(ctx context.Context, change core.PredictionStateValueChange) error
+	// StoreEvolvedPrediction upserts the prediction and logs the changes of its PredictionStateValue while it was
+	// evolved, all at once or not at all, so that the history never has changes to states that weren't stored.
+	StoreEvolvedPrediction(ctx context.Context, prediction *core.Prediction, changes []core.PredictionStateValueChange) error
+	// GetPredictionStateValueChanges returns the state value changes of a prediction, oldest first.
+	GetPredictionStateValueChanges(ctx context.Context, predictionUUID string) ([]core.PredictionStateValueChange, error)
+	LogExchangeFailover(ctx context.Context, failover core.ExchangeFailover) error
+	// GetExchangeFailovers returns the exchange failovers of a prediction's conditions, oldest first.
+	GetExchangeFailovers(ctx context.Context, predictionUUID string) ([]core.ExchangeFailover, error)
+
+	NonPendingPredictionInteractionExists(ctx context.Context, interaction core.PredictionInteraction) (bool, error)
+	InsertPredictionInteraction(ctx context.Context, interaction core.PredictionInteraction) error
+	GetPendingPredictionInteractions(ctx context.Context) ([]core.PredictionInteraction, error)
+	UpdatePredictionInteractionStatus(ctx context.Context, interaction core.PredictionInteraction) error
+	// GetPredictionInteractions returns the interactions of a prediction in any status, oldest first. If
+	// predictionUUID is empty, it returns the interactions of all predictions.
+	GetPredictionInteractions(ctx context.Context, predictionUUID string) ([]core.PredictionInteraction, error)
+
+	PausePrediction(ctx context.Context, uuid string) error
+	UnpausePrediction(ctx context.Context, uuid string) error
+	HidePrediction(ctx context.Context, uuid string) error
+	UnhidePrediction(ctx context.Context, uuid string) error
+	DeletePrediction(ctx context.Context, uuid string) error
+	UndeletePrediction(ctx context.Context, uuid string) error
+
+	// AcquireLease takes the named lease for owner until ttl from now, and returns whether it was taken. It's not taken
+	// if another owner holds it and it hasn't expired yet. The owner may call it again to extend the lease.
+	AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLease gives up the named lease, if owner holds it.
+	ReleaseLease(ctx context.Context, name, owner string) error
+}
+
+// finishedPredictionStateValues are the state values of the predictions that count towards core.AccountStats.
+var finishedPredictionStateValues = []string{core.CORRECT.String(), core.INCORRECT.String(), core.ANNULLED.String()}
+
+// accountStatsCoinPatterns are the LIKE patterns of the tags of predictions with an operand on any of the coins, e.g.
+// "COIN:BINANCE:BTC-USDT", "MARKETCAP:MESSARI:BTC" or "SMA(MARKETCAP:MESSARI:BTC,200D)" for "btc".
+func accountStatsCoinPatterns(coins []string) []interface{} {
+	patterns := []interface{}{}
+	for _, coin := range coins {
+		coin = strings.ToUpper(coin)
+		patterns = append(patterns, "%:"+coin+"-%", "%:"+coin, "%:"+coin+",%", "%:"+coin+")%")
+	}
+	return patterns
+}
+
+func accountURLs(accounts []*core.Account) []string {
+	urls := []string{}
+	for _, a := range accounts {
+		urls = append(urls, a.URL.String())
+	}
+	return urls
+}
+
+// marshalTicks serializes ticks for a JSON column, which is NULL when there are none.
+func marshalTicks(ticks map[string]core.Tick) (sql.NullString, error) {
+	if len(ticks) == 0 {
+		return sql.NullString{}, nil
+	}
+	bs, err := json.Marshal(ticks)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(bs), Valid: true}, nil
+}
+
+func unmarshalTicks(ticks sql.NullString) (map[string]core.Tick, error) {
+	if !ticks.Valid {
+		return nil, nil
+	}
+	res := map[string]core.Tick{}
+	if err := json.Unmarshal([]byte(ticks.String), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
@@ -0,0 +1,595 @@
+package statestorage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/predictions/core"
+	"github.com/stretchr/testify/require"
+)
+
+// storeContractTests are the semantics that every StateStorage must have. They don't require a database server, so
+// they run against every implementation that can be constructed in-process.
+var storeContractTests = []storeTest{
+	{
+		name: "prediction upsert: base case",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, _ := compile(t, sampleRawPrediction)
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+			require.Nil(t, err)
+			require.NotEmpty(t, prediction.UUID)
+
+			actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{UUIDs: []string{prediction.UUID}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 1)
+			require.Equal(t, prediction.PostURL, actualPreds[0].PostURL)
+		},
+	},
+	{
+		name: "prediction upsert: updates existing and keeps flags",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, _ := compile(t, sampleRawPrediction)
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+			require.Nil(t, err)
+			require.Nil(t, store.PausePrediction(context.Background(), prediction.UUID))
+
+			prediction.State.Value = core.CORRECT
+			_, err = store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+			require.Nil(t, err)
+
+			actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 1)
+			require.Equal(t, core.CORRECT, actualPreds[0].State.Value)
+			require.True(t, actualPreds[0].Paused)
+		},
+	},
+	{
+		name: "prediction upsert: two with same uuid fails",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, _ := compile(t, sampleRawPrediction)
+
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction, &prediction})
+			require.ErrorIs(t, err, ErrDuplicateUpsert)
+
+			actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 0)
+		},
+	},
+	{
+		name: "prediction upsert: different uuid with same url fails",
+		test: func(t *testing.T, store StateStorage) {
+			prediction1, _ := compile(t, sampleRawPrediction)
+			prediction2, _ := compile(t, sampleRawPrediction)
+
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction1})
+			require.Nil(t, err)
+			_, err = store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction2})
+			require.ErrorIs(t, err, ErrUniqueConstraintViolation)
+		},
+	},
+	{
+		name: "prediction flags",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, _ := compile(t, sampleRawPrediction)
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+			require.Nil(t, err)
+
+			require.Nil(t, store.HidePrediction(context.Background(), prediction.UUID))
+			require.Nil(t, store.DeletePrediction(context.Background(), prediction.UUID))
+
+			actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{Hidden: pBool(true), Deleted: pBool(true), Paused: pBool(false)}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 1)
+			require.True(t, actualPreds[0].Hidden)
+			require.True(t, actualPreds[0].Deleted)
+
+			require.Nil(t, store.UnhidePrediction(context.Background(), prediction.UUID))
+			require.Nil(t, store.UndeletePrediction(context.Background(), prediction.UUID))
+
+			actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{Hidden: pBool(true)}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 0)
+
+			require.NotNil(t, store.PausePrediction(context.Background(), "non-existent"))
+		},
+	},
+	{
+		name: "prediction filters",
+		test: func(t *testing.T, store StateStorage) {
+			prediction1, _ := compile(t, sampleRawPrediction)
+			prediction2, _ := compile(t, sampleRawPrediction)
+			prediction2.PostURL = "http://different.url"
+			prediction2.State.Value = core.CORRECT
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction1, &prediction2})
+			require.Nil(t, err)
+
+			actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{PredictionStateValues: []string{core.CORRECT.String()}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 1)
+			require.Equal(t, prediction2.PostURL, actualPreds[0].PostURL)
+
+			actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{URLs: []string{prediction1.PostURL}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 1)
+			require.Equal(t, prediction1.UUID, actualPreds[0].UUID)
+
+			actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{AuthorHandles: []string{"test author"}, Tags: []string{"COIN:BINANCE:BTC-USDT", "COIN:BINANCE:ETH-USDT"}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 2)
+
+			actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{Tags: []string{"COIN:BINANCE:ETH-USDT"}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 0)
+
+			// Invalid state values are ignored, like in Postgres.
+			actualPreds, err = store.GetPredictions(context.Background(), core.APIFilters{PredictionStateValues: []string{"INVALID"}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 2)
+		},
+	},
+	{
+		name: "prediction count & stats",
+		test: func(t *testing.T, store StateStorage) {
+			prediction1, _ := compile(t, sampleRawPrediction)
+			prediction2, _ := compile(t, sampleRawPrediction)
+			prediction2.PostURL = "http://different.url"
+			prediction2.State.Value = core.CORRECT
+			prediction3, _ := compile(t, sampleRawPrediction)
+			prediction3.PostURL = "http://another.url"
+			prediction3.State.Value = core.CORRECT
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction1, &prediction2, &prediction3})
+			require.Nil(t, err)
+			require.Nil(t, store.DeletePrediction(context.Background(), prediction3.UUID))
+
+			count, err := store.CountPredictions(context.Background(), core.APIFilters{})
+			require.Nil(t, err)
+			require.Equal(t, 3, count)
+
+			count, err = store.CountPredictions(context.Background(), core.APIFilters{Deleted: pBool(false)})
+			require.Nil(t, err)
+			require.Equal(t, 2, count)
+
+			stats, err := store.GetPredictionStats(context.Background(), core.APIFilters{})
+			require.Nil(t, err)
+			require.Equal(t, core.PredictionStats{Total: 3, ByStateValue: map[string]int{core.ONGOINGPREPREDICTION.String(): 1, core.CORRECT.String(): 2}}, stats)
+
+			stats, err = store.GetPredictionStats(context.Background(), core.APIFilters{UUIDs: []string{"00000000-0000-0000-0000-000000000000"}})
+			require.Nil(t, err)
+			require.Equal(t, core.PredictionStats{Total: 0, ByStateValue: map[string]int{}}, stats)
+		},
+	},
+	{
+		name: "prediction state value changes",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, _ := compile(t, sampleRawPrediction)
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+			require.Nil(t, err)
+
+			ticks := map[string]core.Tick{"COIN:BINANCE:BTC-USDT": {Timestamp: 1641168000, Value: 45000.5}}
+			changes := []core.PredictionStateValueChange{
+				{PredictionUUID: prediction.UUID, StateValue: core.INCORRECT.String(), ConditionName: "main", ConditionValue: core.FALSE.String(), Ticks: ticks, CreatedAt: tpToISO("2022-01-03 00:00:00")},
+				{PredictionUUID: prediction.UUID, StateValue: core.ONGOINGPREDICTION.String(), CreatedAt: tpToISO("2022-01-02 00:00:00")},
+				{PredictionUUID: prediction.UUID, StateValue: core.ONGOINGPREDICTION.String(), CreatedAt: tpToISO("2022-01-04 00:00:00")},
+				{PredictionUUID: prediction.UUID, StateValue: core.INCORRECT.String(), ConditionName: "main", ConditionValue: core.FALSE.String(), Ticks: ticks, CreatedAt: tpToISO("2022-01-05 00:00:00")},
+			}
+			for _, change := range changes {
+				require.Nil(t, store.LogPredictionStateValueChange(context.Background(), change))
+			}
+
+			actualChanges, err := store.GetPredictionStateValueChanges(context.Background(), prediction.UUID)
+			require.Nil(t, err)
+			require.Equal(t, []core.PredictionStateValueChange{changes[1], changes[0], changes[2], changes[3]}, actualChanges)
+
+			actualChanges, err = store.GetPredictionStateValueChanges(context.Background(), "00000000-0000-0000-0000-000000000000")
+			require.Nil(t, err)
+			require.Len(t, actualChanges, 0)
+		},
+	},
+	{
+		name: "storing an evolved prediction logs its state value changes, or neither if it fails",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, _ := compile(t, sampleRawPrediction)
+			prediction.State.Value = core.CORRECT
+			changes := []core.PredictionStateValueChange{
+				{StateValue: core.ONGOINGPREDICTION.String(), CreatedAt: tpToISO("2022-01-02 00:00:00")},
+				{StateValue: core.CORRECT.String(), ConditionName: "main", ConditionValue: core.TRUE.String(), CreatedAt: tpToISO("2022-01-03 00:00:00")},
+			}
+			require.Nil(t, store.StoreEvolvedPrediction(context.Background(), &prediction, nil))
+			for i := range changes {
+				changes[i].PredictionUUID = prediction.UUID
+			}
+			require.Nil(t, store.StoreEvolvedPrediction(context.Background(), &prediction, changes))
+
+			actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{UUIDs: []string{prediction.UUID}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 1)
+			require.Equal(t, core.CORRECT, actualPreds[0].State.Value)
+
+			actualChanges, err := store.GetPredictionStateValueChanges(context.Background(), prediction.UUID)
+			require.Nil(t, err)
+			require.Equal(t, changes, actualChanges)
+
+			// Another prediction with the same post url can't be stored, so its changes aren't logged either.
+			other, _ := compile(t, sampleRawPrediction)
+			other.UUID = "00000000-0000-0000-0000-000000000001"
+			err = store.StoreEvolvedPrediction(context.Background(), &other, []core.PredictionStateValueChange{
+				{PredictionUUID: other.UUID, StateValue: core.ONGOINGPREDICTION.String(), CreatedAt: tpToISO("2022-01-02 00:00:00")},
+			})
+			require.NotNil(t, err)
+
+			actualChanges, err = store.GetPredictionStateValueChanges(context.Background(), other.UUID)
+			require.Nil(t, err)
+			require.Len(t, actualChanges, 0)
+		},
+	},
+	{
+		name: "exchange failovers",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, _ := compile(t, sampleRawPrediction)
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+			require.Nil(t, err)
+
+			failovers := []core.ExchangeFailover{
+				{PredictionUUID: prediction.UUID, ConditionName: "main", FromOperand: "COIN:KUCOIN:BTC-USDT", ToOperand: "COIN:COINBASE:BTC-USDT", Reason: "no new ticks", CreatedAt: tpToISO("2022-01-03 00:00:00")},
+				{PredictionUUID: prediction.UUID, ConditionName: "main", FromOperand: "COIN:BINANCE:BTC-USDT", ToOperand: "COIN:KUCOIN:BTC-USDT", Reason: "market pair not found", CreatedAt: tpToISO("2022-01-02 00:00:00")},
+			}
+			for _, failover := range failovers {
+				require.Nil(t, store.LogExchangeFailover(context.Background(), failover))
+			}
+
+			actualFailovers, err := store.GetExchangeFailovers(context.Background(), prediction.UUID)
+			require.Nil(t, err)
+			require.Equal(t, []core.ExchangeFailover{failovers[1], failovers[0]}, actualFailovers)
+
+			actualFailovers, err = store.GetExchangeFailovers(context.Background(), "00000000-0000-0000-0000-000000000000")
+			require.Nil(t, err)
+			require.Len(t, actualFailovers, 0)
+		},
+	},
+	{
+		name: "prediction scanner pages through all predictions in uuid order",
+		test: func(t *testing.T, store StateStorage) {
+			uuids := []string{}
+			for i := 0; i < 5; i++ {
+				prediction, _ := compile(t, sampleRawPrediction)
+				prediction.PostURL = fmt.Sprintf("http://url.%v", i)
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+				require.Nil(t, err)
+				uuids = append(uuids, prediction.UUID)
+			}
+
+			actualUUIDs := []string{}
+			scanner := newPredictionScanner(context.Background(), store, filterAll, 2)
+			var prediction core.Prediction
+			for scanner.Scan(&prediction) {
+				actualUUIDs = append(actualUUIDs, prediction.UUID)
+			}
+			require.Nil(t, scanner.Error)
+			require.ElementsMatch(t, uuids, actualUUIDs)
+			require.IsIncreasing(t, actualUUIDs)
+		},
+	},
+	{
+		name: "cancelled context stops queries and the prediction scanner",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, _ := compile(t, sampleRawPrediction)
+			_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+			require.Nil(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err = store.GetPredictions(ctx, core.APIFilters{}, []string{}, 0, 0)
+			require.ErrorIs(t, err, context.Canceled)
+
+			scanner := newPredictionScanner(ctx, store, filterAll, 2)
+			require.False(t, scanner.Scan(&prediction))
+			require.ErrorIs(t, scanner.Error, context.Canceled)
+		},
+	},
+	{
+		name: "due predictions",
+		test: func(t *testing.T, store StateStorage) {
+			ctx := context.Background()
+			tp := func(s string) time.Time {
+				tm, _ := time.Parse("2006-01-02 15:04:05", s)
+				return tm
+			}
+
+			_, ok, err := GetNextEvolvablePredictionRunAt(ctx, store)
+			require.Nil(t, err)
+			require.False(t, ok)
+
+			unstarted, _ := compile(t, sampleRawPrediction)
+			unstarted.PostURL = "http://url.unstarted"
+			started, _ := compile(t, sampleRawPrediction)
+			started.PostURL = "http://url.started"
+			started.State.Status = core.STARTED
+			started.Predict.Predict.Literal.State.Status = core.STARTED
+			started.Predict.Predict.Literal.State.LastTs = int(tp("2022-01-02 10:00:00").Unix())
+			_, err = store.UpsertPredictions(ctx, []*core.Prediction{&unstarted, &started})
+			require.Nil(t, err)
+
+			dueURLs := func(now string) []string {
+				urls := []string{}
+				scanner := NewDuePredictionsScanner(ctx, store, tp(now))
+				var prediction core.Prediction
+				for scanner.Scan(&prediction) {
+					urls = append(urls, prediction.PostURL)
+				}
+				require.Nil(t, scanner.Error)
+				return urls
+			}
+			require.ElementsMatch(t, []string{unstarted.PostURL}, dueURLs("2022-01-02 10:01:59"))
+			require.ElementsMatch(t, []string{unstarted.PostURL, started.PostURL}, dueURLs("2022-01-02 10:02:00"))
+
+			nextRunAt, ok, err := GetNextEvolvablePredictionRunAt(ctx, store)
+			require.Nil(t, err)
+			require.True(t, ok)
+			require.True(t, nextRunAt.IsZero(), "unstarted predictions should be evolved right away")
+
+			require.Nil(t, store.DeletePrediction(ctx, unstarted.UUID))
+			nextRunAt, ok, err = GetNextEvolvablePredictionRunAt(ctx, store)
+			require.Nil(t, err)
+			require.True(t, ok)
+			require.True(t, tp("2022-01-02 10:02:00").Equal(nextRunAt))
+
+			require.Nil(t, store.DeletePrediction(ctx, started.UUID))
+			_, ok, err = GetNextEvolvablePredictionRunAt(ctx, store)
+			require.Nil(t, err)
+			require.False(t, ok)
+		},
+	},
+	{
+		name: "prediction limit & offset",
+		test: func(t *testing.T, store StateStorage) {
+			for i := 0; i < 3; i++ {
+				prediction, _ := compile(t, sampleRawPrediction)
+				prediction.PostURL = fmt.Sprintf("http://url.%v", i)
+				prediction.PostedAt = tpToISO(fmt.Sprintf("2022-01-0%v 00:00:00", i+1))
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+				require.Nil(t, err)
+			}
+
+			actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{}, []string{core.PredictionsPostedAtDesc.String()}, 2, 1)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 2)
+			require.Equal(t, "http://url.1", actualPreds[0].PostURL)
+			require.Equal(t, "http://url.0", actualPreds[1].PostURL)
+		},
+	},
+	{
+		name: "leases",
+		test: func(t *testing.T, store StateStorage) {
+			ctx := context.Background()
+
+			acquired, err := store.AcquireLease(ctx, "lease", "owner1", time.Minute)
+			require.Nil(t, err)
+			require.True(t, acquired)
+
+			acquired, err = store.AcquireLease(ctx, "lease", "owner2", time.Minute)
+			require.Nil(t, err)
+			require.False(t, acquired)
+
+			acquired, err = store.AcquireLease(ctx, "lease", "owner1", time.Minute)
+			require.Nil(t, err)
+			require.True(t, acquired, "the owner can extend its lease")
+
+			acquired, err = store.AcquireLease(ctx, "other lease", "owner2", time.Minute)
+			require.Nil(t, err)
+			require.True(t, acquired)
+
+			require.Nil(t, store.ReleaseLease(ctx, "lease", "owner2"))
+			acquired, err = store.AcquireLease(ctx, "lease", "owner2", time.Minute)
+			require.Nil(t, err)
+			require.False(t, acquired, "only the owner can release its lease")
+
+			require.Nil(t, store.ReleaseLease(ctx, "lease", "owner1"))
+			acquired, err = store.AcquireLease(ctx, "lease", "owner2", 0)
+			require.Nil(t, err)
+			require.True(t, acquired)
+
+			acquired, err = store.AcquireLease(ctx, "lease", "owner1", time.Minute)
+			require.Nil(t, err)
+			require.True(t, acquired, "expired leases can be taken over, e.g. when their owner crashed")
+		},
+	},
+	{
+		name: "prediction interactions",
+		test: func(t *testing.T, store StateStorage) {
+			interaction := core.PredictionInteraction{PostURL: "http://post.url", ActionType: "BECAME_FINAL", PredictionUUID: "uuid", Status: "PENDING"}
+			require.Nil(t, store.InsertPredictionInteraction(context.Background(), interaction))
+			require.NotNil(t, store.InsertPredictionInteraction(context.Background(), interaction))
+
+			exists, err := store.NonPendingPredictionInteractionExists(context.Background(), interaction)
+			require.Nil(t, err)
+			require.False(t, exists)
+
+			pending, err := store.GetPendingPredictionInteractions(context.Background())
+			require.Nil(t, err)
+			require.Equal(t, []core.PredictionInteraction{interaction}, pending)
+
+			interaction.Status = "POSTED"
+			require.Nil(t, store.UpdatePredictionInteractionStatus(context.Background(), interaction))
+			require.NotNil(t, store.UpdatePredictionInteractionStatus(context.Background(), interaction))
+
+			exists, err = store.NonPendingPredictionInteractionExists(context.Background(), interaction)
+			require.Nil(t, err)
+			require.True(t, exists)
+
+			pending, err = store.GetPendingPredictionInteractions(context.Background())
+			require.Nil(t, err)
+			require.Len(t, pending, 0)
+
+			other := core.PredictionInteraction{PostURL: "http://post.url", ActionType: "BECAME_FINAL", PredictionUUID: "other uuid", Status: "ERROR", Error: "failed"}
+			require.Nil(t, store.InsertPredictionInteraction(context.Background(), other))
+
+			all, err := store.GetPredictionInteractions(context.Background(), "")
+			require.Nil(t, err)
+			require.Equal(t, []core.PredictionInteraction{interaction, other}, all)
+
+			all, err = store.GetPredictionInteractions(context.Background(), "other uuid")
+			require.Nil(t, err)
+			require.Equal(t, []core.PredictionInteraction{other}, all)
+		},
+	},
+	{
+		name: "account upsert: two",
+		test: func(t *testing.T, store StateStorage) {
+			_, account1 := compile(t, sampleRawPrediction)
+			_, account2 := compile(t, sampleRawPrediction)
+			account2.URL, _ = url.Parse("http://twitter.com/different")
+			account2.Handle = "different"
+			account2.FollowerCount = account1.FollowerCount + 1
+			_, err := store.UpsertAccounts(context.Background(), []*core.Account{account1, account2})
+			require.Nil(t, err)
+
+			actualAccounts, err := store.GetAccounts(context.Background(), core.APIAccountFilters{}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualAccounts, 2)
+			require.Equal(t, account2.Handle, actualAccounts[0].Handle)
+			require.Equal(t, account1.Handle, actualAccounts[1].Handle)
+
+			actualAccounts, err = store.GetAccounts(context.Background(), core.APIAccountFilters{Handles: []string{"different"}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualAccounts, 1)
+			require.Equal(t, account2.URL.String(), actualAccounts[0].URL.String())
+		},
+	},
+	{
+		name: "account delete",
+		test: func(t *testing.T, store StateStorage) {
+			_, account1 := compile(t, sampleRawPrediction)
+			_, account2 := compile(t, sampleRawPrediction)
+			account2.URL, _ = url.Parse("http://twitter.com/different")
+			account2.Handle = "different"
+			_, err := store.UpsertAccounts(context.Background(), []*core.Account{account1, account2})
+			require.Nil(t, err)
+
+			require.Nil(t, store.DeleteAccount(context.Background(), account2.URL.String()))
+			require.NotNil(t, store.DeleteAccount(context.Background(), account2.URL.String()))
+
+			actualAccounts, err := store.GetAccounts(context.Background(), core.APIAccountFilters{}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualAccounts, 1)
+			require.Equal(t, account1.URL.String(), actualAccounts[0].URL.String())
+		},
+	},
+	{
+		name: "account stats",
+		test: func(t *testing.T, store StateStorage) {
+			var hiddenUUID string
+			for i, pred := range []struct {
+				author     string
+				value      core.PredictionStateValue
+				difficulty float64
+			}{
+				{"A", core.CORRECT, 0.5},
+				{"A", core.INCORRECT, 0},
+				{"B", core.ANNULLED, 0},
+				{"B", core.ONGOINGPREDICTION, 0},
+				{"B", core.CORRECT, 0},
+			} {
+				prediction, _ := compile(t, sampleRawPrediction)
+				prediction.PostURL = fmt.Sprintf("https://twitter.com/CryptoCapo_/status/%v", i)
+				prediction.PostAuthor = pred.author
+				prediction.PostAuthorURL = fmt.Sprintf("https://twitter.com/%v", pred.author)
+				prediction.State.Value = pred.value
+				prediction.State.Difficulty = pred.difficulty
+				_, err := store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+				require.Nil(t, err)
+				hiddenUUID = prediction.UUID
+			}
+			require.Nil(t, store.HidePrediction(context.Background(), hiddenUUID))
+
+			tss := []struct {
+				filters  core.AccountStatsFilters
+				limit    int
+				expected []core.AccountStats
+			}{
+				{
+					filters: core.AccountStatsFilters{},
+					expected: []core.AccountStats{
+						{Handle: "A", Correct: 1, Incorrect: 1, Accuracy: 0.5, Score: 0.5},
+						{Handle: "B", Annulled: 1},
+					},
+				},
+				{
+					filters:  core.AccountStatsFilters{AuthorHandles: []string{"B"}, Coins: []string{"btc"}, PredictionTypes: []core.PredictionType{core.PredictionTypeCoinOperatorFloatDeadline}},
+					expected: []core.AccountStats{{Handle: "B", Annulled: 1}},
+				},
+				{
+					filters:  core.AccountStatsFilters{PostedFrom: tp("2022-01-01 00:00:00"), PostedTo: tp("2022-01-02 00:00:01")},
+					expected: []core.AccountStats{{Handle: "A", Correct: 1, Incorrect: 1, Accuracy: 0.5, Score: 0.5}, {Handle: "B", Annulled: 1}},
+				},
+				{
+					filters:  core.AccountStatsFilters{},
+					limit:    1,
+					expected: []core.AccountStats{{Handle: "A", Correct: 1, Incorrect: 1, Accuracy: 0.5, Score: 0.5}},
+				},
+				{
+					filters:  core.AccountStatsFilters{AuthorURLs: []string{"https://twitter.com/B"}},
+					expected: []core.AccountStats{{Handle: "B", Annulled: 1}},
+				},
+				{filters: core.AccountStatsFilters{Coins: []string{"ETH", "USDT"}}, expected: []core.AccountStats{}},
+				{filters: core.AccountStatsFilters{PostedFrom: tp("2022-01-02 00:00:01")}, expected: []core.AccountStats{}},
+				{filters: core.AccountStatsFilters{PredictionTypes: []core.PredictionType{core.PredictionTypeCoinWillRange}}, expected: []core.AccountStats{}},
+			}
+			for _, ts := range tss {
+				actual, err := store.GetAccountStats(context.Background(), ts.filters, ts.limit)
+				require.Nil(t, err)
+				require.Equal(t, ts.expected, actual, "for filters %+v and limit %v", ts.filters, ts.limit)
+			}
+		},
+	},
+	{
+		name: "account merge",
+		test: func(t *testing.T, store StateStorage) {
+			prediction, account1 := compile(t, sampleRawPrediction)
+			_, account2 := compile(t, sampleRawPrediction)
+			account2.URL, _ = url.Parse("http://twitter.com/different")
+			account2.Handle = "different"
+			_, err := store.UpsertAccounts(context.Background(), []*core.Account{account1, account2})
+			require.Nil(t, err)
+			_, err = store.UpsertPredictions(context.Background(), []*core.Prediction{&prediction})
+			require.Nil(t, err)
+
+			repointed, err := store.MergeAccounts(context.Background(), account1.URL.String(), *account2)
+			require.Nil(t, err)
+			require.Equal(t, 1, repointed)
+			_, err = store.MergeAccounts(context.Background(), account1.URL.String(), *account2)
+			require.NotNil(t, err)
+
+			actualPreds, err := store.GetPredictions(context.Background(), core.APIFilters{AuthorURLs: []string{account2.URL.String()}}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualPreds, 1)
+			require.Equal(t, "different", actualPreds[0].PostAuthor)
+
+			aliases, err := store.GetAccountAliases(context.Background(), []string{account1.URL.String(), account2.URL.String()})
+			require.Nil(t, err)
+			require.Equal(t, map[string]string{account1.URL.String(): account2.URL.String()}, aliases)
+
+			// The merged account isn't upserted back
+			_, err = store.UpsertAccounts(context.Background(), []*core.Account{account1})
+			require.Nil(t, err)
+			actualAccounts, err := store.GetAccounts(context.Background(), core.APIAccountFilters{}, []string{}, 0, 0)
+			require.Nil(t, err)
+			require.Len(t, actualAccounts, 1)
+			require.Equal(t, account2.URL.String(), actualAccounts[0].URL.String())
+		},
+	},
+	{
+		name: "account upsert: two with same URL fails",
+		test: func(t *testing.T, store StateStorage) {
+			_, account1 := compile(t, sampleRawPrediction)
+			_, account2 := compile(t, sampleRawPrediction)
+			_, err := store.UpsertAccounts(context.Background(), []*core.Account{account1, account2})
+			require.ErrorIs(t, err, ErrDuplicateUpsert)
+		},
+	},
+}